@@ -0,0 +1,70 @@
+// Package atomicfile replaces files through a temporary file and a rename,
+// so readers and later runs never see a partially written one.
+package atomicfile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Write replaces the file at path, 0600, with what write puts in f. It
+// writes to a fresh temp file in the same directory that is renamed over
+// path only once write has succeeded, so a crash mid-write can't leave a
+// truncated file behind. A symlinked path (e.g. a dotfile manager's
+// ~/.aws/credentials) is resolved first, so the link's target is replaced
+// rather than the link itself. Errors from write are returned unwrapped.
+func Write(path string, write func(f *os.File) error) (err error) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("resolve %s: %w", path, err)
+	}
+
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp file for %s: %w", path, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			if rmErr := os.Remove(tmp.Name()); rmErr != nil && !errors.Is(rmErr, fs.ErrNotExist) {
+				err = errors.Join(err, rmErr)
+			}
+		}
+	}()
+
+	// CreateTemp already uses 0600; make it explicit rather than rely on it.
+	if err := tmp.Chmod(0o600); err != nil {
+		return fmt.Errorf("chmod %s: %w", tmp.Name(), err)
+	}
+	if err := write(tmp); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("sync %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// WriteString replaces the file at path with content, as Write does.
+func WriteString(path, content string) error {
+	return Write(path, func(f *os.File) error {
+		if _, err := f.WriteString(content); err != nil {
+			return fmt.Errorf("write %s: %w", f.Name(), err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,114 @@
+package atomicfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	tests := map[string]struct {
+		existing   string // "" means no file yet
+		writeErr   error
+		wantData   string
+		wantErrMsg string
+	}{
+		"creates the file": {
+			wantData: "new backup",
+		},
+		"replaces an existing file": {
+			existing: "old backup",
+			wantData: "new backup",
+		},
+		"failed write keeps the old file": {
+			existing:   "old backup",
+			writeErr:   errors.New("disk full"),
+			wantData:   "old backup",
+			wantErrMsg: "disk full",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "sesh.enc")
+			if tc.existing != "" {
+				if err := os.WriteFile(path, []byte(tc.existing), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := Write(path, func(f *os.File) error {
+				if _, err := f.WriteString("new backup"); err != nil {
+					return err
+				}
+				return tc.writeErr
+			})
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("Write() error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+			} else if err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.wantData {
+				t.Errorf("file = %q, want %q", data, tc.wantData)
+			}
+			if tc.writeErr == nil {
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if perm := info.Mode().Perm(); perm != 0o600 {
+					t.Errorf("permissions = %04o, want 0600", perm)
+				}
+			}
+
+			leftovers, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(leftovers) != 1 {
+				t.Errorf("directory has %d files, want only the written file: %v", len(leftovers), leftovers)
+			}
+		})
+	}
+}
+
+func TestWriteString_Symlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	if err := os.WriteFile(target, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteString(link, "new"); err != nil {
+		t.Fatalf("WriteString() error = %v", err)
+	}
+
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Error("symlink was replaced by a regular file")
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Errorf("target = %q, want %q", data, "new")
+	}
+}
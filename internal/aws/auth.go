@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bashhack/sesh/internal/secure"
 )
@@ -21,6 +23,10 @@ type Credentials struct {
 	SecretAccessKey string `json:"SecretAccessKey"`
 	SessionToken    string `json:"SessionToken"`
 	Expiration      string `json:"Expiration"`
+	// IssuedAt is when STS issued the credentials, RFC 3339 like
+	// Expiration. get-session-token doesn't report it, so GetSessionToken
+	// records when the response arrived.
+	IssuedAt string `json:"IssuedAt,omitempty"`
 }
 
 // ZeroSecrets zeroes out the sensitive fields in the credentials
@@ -49,9 +55,42 @@ type ListDevicesResponse struct {
 	MFADevices []MFADevice `json:"MFADevices"`
 }
 
+// CallerIdentity is the JSON response from aws sts get-caller-identity.
+type CallerIdentity struct {
+	UserID  string `json:"UserId"`
+	Account string `json:"Account"`
+	Arn     string `json:"Arn"`
+}
+
+// SessionOptions are the optional get-session-token settings. Zero values
+// leave the choice to the AWS CLI: the profile's region and STS's default
+// session length.
+type SessionOptions struct {
+	Region   string
+	Duration time.Duration
+}
+
+// cleanEnv returns the current environment without any AWS credential
+// variables, so the aws CLI uses the profile's long-term credentials
+// regardless of what session the user's shell is already in.
+func cleanEnv() []string {
+	env := os.Environ()
+	clean := make([]string, 0, len(env))
+	for _, e := range env {
+		// Filter out all AWS credential variables that might interfere
+		if !strings.HasPrefix(e, "AWS_SESSION_TOKEN=") &&
+			!strings.HasPrefix(e, "AWS_SECURITY_TOKEN=") &&
+			!strings.HasPrefix(e, "AWS_ACCESS_KEY_ID=") &&
+			!strings.HasPrefix(e, "AWS_SECRET_ACCESS_KEY=") {
+			clean = append(clean, e)
+		}
+	}
+	return clean
+}
+
 // GetSessionToken calls aws sts get-session-token with the given MFA serial and TOTP code,
 // returning temporary credentials. The code byte slice is zeroed after use.
-func GetSessionToken(profile, serial string, code []byte) (Credentials, error) {
+func GetSessionToken(profile, serial string, code []byte, opts SessionOptions) (Credentials, error) {
 	// Convert code to string for command execution but ensure it's zeroed
 	codeStr := string(code)
 	defer secure.SecureZeroString(codeStr)
@@ -64,25 +103,15 @@ func GetSessionToken(profile, serial string, code []byte) (Credentials, error) {
 	if profile != "" {
 		args = append(args, "--profile", profile)
 	}
+	if opts.Region != "" {
+		args = append(args, "--region", opts.Region)
+	}
+	if opts.Duration > 0 {
+		args = append(args, "--duration-seconds", strconv.Itoa(int(opts.Duration/time.Second)))
+	}
 
 	cmd := execCommand("aws", args...)
-
-	// Create a clean environment without any AWS credential variables
-	// to avoid interference with the command execution,
-	// ensuring consistent behavior regardless of the user's
-	// current environment state
-	env := os.Environ()
-	cleanEnv := make([]string, 0, len(env))
-	for _, e := range env {
-		// Filter out all AWS credential variables that might interfere
-		if !strings.HasPrefix(e, "AWS_SESSION_TOKEN=") &&
-			!strings.HasPrefix(e, "AWS_SECURITY_TOKEN=") &&
-			!strings.HasPrefix(e, "AWS_ACCESS_KEY_ID=") &&
-			!strings.HasPrefix(e, "AWS_SECRET_ACCESS_KEY=") {
-			cleanEnv = append(cleanEnv, e)
-		}
-	}
-	cmd.Env = cleanEnv
+	cmd.Env = cleanEnv()
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -110,6 +139,9 @@ func GetSessionToken(profile, serial string, code []byte) (Credentials, error) {
 	secure.SecureZeroBytes(stdout.Bytes())
 	secure.SecureZeroBytes(stderr.Bytes())
 
+	if parsed.Credentials.IssuedAt == "" {
+		parsed.Credentials.IssuedAt = time.Now().UTC().Format(time.RFC3339)
+	}
 	return parsed.Credentials, nil
 }
 
@@ -137,3 +169,33 @@ func GetFirstMFADevice(profile string) (string, error) {
 
 	return parsed.MFADevices[0].SerialNumber, nil
 }
+
+// GetCallerIdentity calls aws sts get-caller-identity for the given AWS CLI
+// profile. Like GetSessionToken it runs without any AWS credential variables
+// from the environment, so it reports who the profile itself is.
+func GetCallerIdentity(profile string) (CallerIdentity, error) {
+	args := []string{"sts", "get-caller-identity", "--output", "json"}
+	if profile != "" {
+		args = append(args, "--profile", profile)
+	}
+
+	cmd := execCommand("aws", args...)
+	cmd.Env = cleanEnv()
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return CallerIdentity{}, fmt.Errorf("failed to run aws sts get-caller-identity: %w\nStderr: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var parsed CallerIdentity
+	if err := json.Unmarshal(stdout.Bytes(), &parsed); err != nil {
+		return CallerIdentity{}, fmt.Errorf("failed to parse caller identity: %w", err)
+	}
+	if parsed.Arn == "" {
+		return CallerIdentity{}, fmt.Errorf("aws sts get-caller-identity returned no ARN")
+	}
+	return parsed, nil
+}
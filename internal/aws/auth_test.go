@@ -5,7 +5,10 @@ import (
 	"errors"
 	"os"
 	"os/exec"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bashhack/sesh/internal/testutil"
 )
@@ -41,7 +44,7 @@ func TestGetSessionToken_Success(t *testing.T) {
 
 	execCommand = MockExecCommand(string(mockRespJSON), nil)
 
-	creds, err := GetSessionToken("test-profile", "arn:aws:iam::123456789012:mfa/test", []byte("123456"))
+	creds, err := GetSessionToken("test-profile", "arn:aws:iam::123456789012:mfa/test", []byte("123456"), SessionOptions{})
 
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
@@ -64,13 +67,59 @@ func TestGetSessionToken_Success(t *testing.T) {
 	}
 }
 
+func TestGetSessionToken_IssuedAt(t *testing.T) {
+	tests := map[string]struct {
+		issuedAt string
+		want     string // empty: expect the time the response arrived
+	}{
+		"missing from the response": {},
+		"reported by the response":  {issuedAt: "2025-01-01T00:00:00Z", want: "2025-01-01T00:00:00Z"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			origExecCommand := execCommand
+			defer func() { execCommand = origExecCommand }()
+
+			resp, err := json.Marshal(SessionTokenResponse{Credentials: Credentials{
+				AccessKeyID: "MOCK-ACCESS-KEY",
+				Expiration:  "2030-01-01T00:00:00Z",
+				IssuedAt:    tc.issuedAt,
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			execCommand = MockExecCommand(string(resp), nil)
+
+			before := time.Now().Truncate(time.Second)
+			creds, err := GetSessionToken("test-profile", "arn:aws:iam::123456789012:mfa/test", []byte("123456"), SessionOptions{})
+			if err != nil {
+				t.Fatalf("GetSessionToken() error = %v", err)
+			}
+			if tc.want != "" {
+				if creds.IssuedAt != tc.want {
+					t.Errorf("IssuedAt = %q, want %q", creds.IssuedAt, tc.want)
+				}
+				return
+			}
+			issued, err := time.Parse(time.RFC3339, creds.IssuedAt)
+			if err != nil {
+				t.Fatalf("IssuedAt %q is not RFC 3339: %v", creds.IssuedAt, err)
+			}
+			if issued.Before(before) || issued.After(time.Now()) {
+				t.Errorf("IssuedAt = %v, want the time of the call (after %v)", issued, before)
+			}
+		})
+	}
+}
+
 func TestGetSessionToken_CommandError(t *testing.T) {
 	origExecCommand := execCommand
 	defer func() { execCommand = origExecCommand }()
 
 	execCommand = MockExecCommand("", errors.New("command failed"))
 
-	_, err := GetSessionToken("test-profile", "arn:aws:iam::123456789012:mfa/test", []byte("123456"))
+	_, err := GetSessionToken("test-profile", "arn:aws:iam::123456789012:mfa/test", []byte("123456"), SessionOptions{})
 
 	if err == nil {
 		t.Error("Expected error, got nil")
@@ -83,7 +132,7 @@ func TestGetSessionToken_InvalidJSON(t *testing.T) {
 
 	execCommand = MockExecCommand("not json", nil)
 
-	_, err := GetSessionToken("test-profile", "arn:aws:iam::123456789012:mfa/test", []byte("123456"))
+	_, err := GetSessionToken("test-profile", "arn:aws:iam::123456789012:mfa/test", []byte("123456"), SessionOptions{})
 
 	if err == nil || err.Error() == "" {
 		t.Error("Expected JSON parsing error, got nil or empty")
@@ -116,7 +165,7 @@ func TestGetSessionToken_EmptyProfile(t *testing.T) {
 		return cmd
 	}
 
-	_, err := GetSessionToken("", "arn:aws:iam::123456789012:mfa/test", []byte("123456"))
+	_, err := GetSessionToken("", "arn:aws:iam::123456789012:mfa/test", []byte("123456"), SessionOptions{})
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -129,6 +178,55 @@ func TestGetSessionToken_EmptyProfile(t *testing.T) {
 	}
 }
 
+func TestGetSessionToken_Options(t *testing.T) {
+	origExecCommand := execCommand
+	defer func() { execCommand = origExecCommand }()
+
+	tests := map[string]struct {
+		opts     SessionOptions
+		wantArgs []string
+		noArgs   []string
+	}{
+		"defaults leave region and duration to the CLI": {
+			noArgs: []string{"--region", "--duration-seconds"},
+		},
+		"region": {
+			opts:     SessionOptions{Region: "eu-west-1"},
+			wantArgs: []string{"--region", "eu-west-1"},
+			noArgs:   []string{"--duration-seconds"},
+		},
+		"duration in seconds": {
+			opts:     SessionOptions{Duration: 2 * time.Hour},
+			wantArgs: []string{"--duration-seconds", "7200"},
+			noArgs:   []string{"--region"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var capturedArgs []string
+			execCommand = func(_ string, args ...string) *exec.Cmd {
+				capturedArgs = args
+				return exec.Command("echo", `{"Credentials":{"AccessKeyId":"MOCK-ACCESS-KEY"}}`)
+			}
+
+			if _, err := GetSessionToken("", "arn:aws:iam::123456789012:mfa/test", []byte("123456"), tc.opts); err != nil {
+				t.Fatalf("GetSessionToken() error = %v", err)
+			}
+
+			joined := strings.Join(capturedArgs, " ")
+			if want := strings.Join(tc.wantArgs, " "); want != "" && !strings.Contains(joined, want) {
+				t.Errorf("args = %q, want to contain %q", joined, want)
+			}
+			for _, arg := range tc.noArgs {
+				if slices.Contains(capturedArgs, arg) {
+					t.Errorf("args = %q, should not contain %s", joined, arg)
+				}
+			}
+		})
+	}
+}
+
 func TestGetSessionToken_Integration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping AWS integration test in short mode")
@@ -143,7 +241,7 @@ func TestGetSessionToken_Integration(t *testing.T) {
 	// I'm just aiming for coverage here and ensuring the function
 	// handles basic error cases correctly
 
-	_, err := GetSessionToken("nonexistent-profile", "invalid-serial", []byte("123456"))
+	_, err := GetSessionToken("nonexistent-profile", "invalid-serial", []byte("123456"), SessionOptions{})
 	if err == nil {
 		t.Error("Expected error for invalid AWS credentials, got nil")
 	}
@@ -304,3 +402,84 @@ func TestCredentials_ZeroSecrets(t *testing.T) {
 		})
 	}
 }
+
+func TestGetCallerIdentity(t *testing.T) {
+	const identityJSON = `{"UserId":"AIDAEXAMPLE","Account":"123456789012","Arn":"arn:aws:iam::123456789012:user/alice"}`
+
+	tests := map[string]struct {
+		profile  string
+		output   string
+		fail     bool
+		wantArgs []string
+		want     CallerIdentity
+		wantErr  string
+	}{
+		"named profile": {
+			profile:  "prod",
+			output:   identityJSON,
+			wantArgs: []string{"sts", "get-caller-identity", "--output", "json", "--profile", "prod"},
+			want:     CallerIdentity{UserID: "AIDAEXAMPLE", Account: "123456789012", Arn: "arn:aws:iam::123456789012:user/alice"},
+		},
+		"default profile": {
+			output:   identityJSON,
+			wantArgs: []string{"sts", "get-caller-identity", "--output", "json"},
+			want:     CallerIdentity{UserID: "AIDAEXAMPLE", Account: "123456789012", Arn: "arn:aws:iam::123456789012:user/alice"},
+		},
+		"command fails": {
+			profile: "prod",
+			fail:    true,
+			wantErr: "failed to run aws sts get-caller-identity",
+		},
+		"invalid json": {
+			output:  "not json",
+			wantErr: "failed to parse caller identity",
+		},
+		"no arn": {
+			output:  `{"Account":"123456789012"}`,
+			wantErr: "returned no ARN",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			origExecCommand := execCommand
+			defer func() { execCommand = origExecCommand }()
+
+			t.Setenv("AWS_SESSION_TOKEN", "session-from-the-shell")
+
+			var gotArgs []string
+			var cmd *exec.Cmd
+			execCommand = func(_ string, args ...string) *exec.Cmd {
+				gotArgs = args
+				if tc.fail {
+					cmd = exec.Command("false")
+				} else {
+					cmd = exec.Command("echo", tc.output)
+				}
+				return cmd
+			}
+
+			got, err := GetCallerIdentity(tc.profile)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("GetCallerIdentity() error = %v, want containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetCallerIdentity() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("GetCallerIdentity() = %+v, want %+v", got, tc.want)
+			}
+			if !slices.Equal(gotArgs, tc.wantArgs) {
+				t.Errorf("args = %v, want %v", gotArgs, tc.wantArgs)
+			}
+			for _, e := range cmd.Env {
+				if strings.HasPrefix(e, "AWS_SESSION_TOKEN=") {
+					t.Error("AWS_SESSION_TOKEN should be stripped from the command's environment")
+				}
+			}
+		})
+	}
+}
@@ -4,10 +4,13 @@ package aws
 type Provider interface {
 	// GetSessionToken gets temporary AWS credentials using MFA
 	// The code is provided as a byte slice so it can be securely zeroed after use
-	GetSessionToken(profile, serial string, code []byte) (Credentials, error)
+	GetSessionToken(profile, serial string, code []byte, opts SessionOptions) (Credentials, error)
 
 	// GetFirstMFADevice retrieves the first MFA device for the current user
 	GetFirstMFADevice(profile string) (string, error)
+
+	// GetCallerIdentity reports the ARN and account a profile's credentials belong to
+	GetCallerIdentity(profile string) (CallerIdentity, error)
 }
 
 // DefaultProvider is the default implementation using aws-cli
@@ -16,8 +19,8 @@ type DefaultProvider struct{}
 var _ Provider = (*DefaultProvider)(nil)
 
 // GetSessionToken implements the Provider interface
-func (p *DefaultProvider) GetSessionToken(profile, serial string, code []byte) (Credentials, error) {
-	return GetSessionToken(profile, serial, code)
+func (p *DefaultProvider) GetSessionToken(profile, serial string, code []byte, opts SessionOptions) (Credentials, error) {
+	return GetSessionToken(profile, serial, code, opts)
 }
 
 // GetFirstMFADevice implements the Provider interface
@@ -25,6 +28,11 @@ func (p *DefaultProvider) GetFirstMFADevice(profile string) (string, error) {
 	return GetFirstMFADevice(profile)
 }
 
+// GetCallerIdentity implements the Provider interface
+func (p *DefaultProvider) GetCallerIdentity(profile string) (CallerIdentity, error) {
+	return GetCallerIdentity(profile)
+}
+
 // NewDefaultProvider creates a new DefaultProvider
 func NewDefaultProvider() Provider {
 	return &DefaultProvider{}
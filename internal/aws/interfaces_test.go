@@ -42,7 +42,7 @@ func TestDefaultProviderGetSessionToken(t *testing.T) {
 	}
 
 	provider := NewDefaultProvider()
-	creds, err := provider.GetSessionToken("test-profile", "test-serial", []byte("123456"))
+	creds, err := provider.GetSessionToken("test-profile", "test-serial", []byte("123456"), SessionOptions{})
 
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
@@ -5,18 +5,19 @@ import "github.com/bashhack/sesh/internal/aws"
 
 // MockProvider is a test double for aws.Provider.
 type MockProvider struct {
-	GetSessionTokenFunc   func(profile, serial string, code []byte) (aws.Credentials, error)
+	GetSessionTokenFunc   func(profile, serial string, code []byte, opts aws.SessionOptions) (aws.Credentials, error)
 	GetFirstMFADeviceFunc func(profile string) (string, error)
+	GetCallerIdentityFunc func(profile string) (aws.CallerIdentity, error)
 }
 
 var _ aws.Provider = (*MockProvider)(nil)
 
 // GetSessionToken returns temporary AWS credentials using MFA, or a zero value if the func is not set.
-func (m *MockProvider) GetSessionToken(profile, serial string, code []byte) (aws.Credentials, error) {
+func (m *MockProvider) GetSessionToken(profile, serial string, code []byte, opts aws.SessionOptions) (aws.Credentials, error) {
 	if m.GetSessionTokenFunc == nil {
 		return aws.Credentials{}, nil
 	}
-	return m.GetSessionTokenFunc(profile, serial, code)
+	return m.GetSessionTokenFunc(profile, serial, code, opts)
 }
 
 // GetFirstMFADevice returns the first MFA device for the given profile, or a zero value if the func is not set.
@@ -26,3 +27,11 @@ func (m *MockProvider) GetFirstMFADevice(profile string) (string, error) {
 	}
 	return m.GetFirstMFADeviceFunc(profile)
 }
+
+// GetCallerIdentity returns the identity behind the given profile, or a zero value if the func is not set.
+func (m *MockProvider) GetCallerIdentity(profile string) (aws.CallerIdentity, error) {
+	if m.GetCallerIdentityFunc == nil {
+		return aws.CallerIdentity{}, nil
+	}
+	return m.GetCallerIdentityFunc(profile)
+}
@@ -2,6 +2,7 @@ package aws
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/bashhack/sesh/internal/subshell"
 )
@@ -69,6 +70,19 @@ sesh_status() {
   fi
 }
 
+# Remaining session time for prompt formats that use {expires}
+sesh_remaining() {
+  [ -z "$SESH_EXPIRY" ] && return
+  remaining=$(( SESH_EXPIRY - $(date +%s) ))
+  if [ $remaining -le 0 ]; then
+    echo "expired"
+  elif [ $remaining -ge 3600 ]; then
+    echo "$((remaining / 3600))h$(( (remaining % 3600) / 60 ))m"
+  else
+    echo "$((remaining / 60))m"
+  fi
+}
+
 # Shortcut to verify AWS credentials
 verify_aws() {
   if [ "$SESH_SERVICE" != "aws" ]; then
@@ -145,16 +159,38 @@ PS1="(sesh:aws) $PS1"
 )
 
 // AWSShellCustomizer implements subshell.ShellCustomizer for AWS
-type AWSShellCustomizer struct{}
+type AWSShellCustomizer struct {
+	// promptFormat is a --prompt-format value, rendered per shell with
+	// promptVars (see subshell.RenderPrompt). Empty selects the built-in
+	// "(sesh:aws) " prefix.
+	promptFormat string
+	promptVars   subshell.PromptVars
+}
 
 // GetZshInitScript returns the zsh init script for the AWS subshell prompt.
 func (c *AWSShellCustomizer) GetZshInitScript() string {
-	return ZshPrompt
+	if c.promptFormat == "" {
+		return ZshPrompt
+	}
+	// PROMPT_SUBST lets a {expires} placeholder re-evaluate on every prompt.
+	return fmt.Sprintf(`
+setopt PROMPT_SUBST
+PROMPT=%s"${PROMPT}"
+
+%s
+`, shellQuote(subshell.RenderPrompt(c.promptFormat, c.promptVars, subshell.PromptZsh)), SubshellFunctions)
 }
 
 // GetBashInitScript returns the bash init script for the AWS subshell prompt.
 func (c *AWSShellCustomizer) GetBashInitScript() string {
-	return BashPrompt
+	if c.promptFormat == "" {
+		return BashPrompt
+	}
+	return fmt.Sprintf(`
+PS1=%s"$PS1"
+
+%s
+`, shellQuote(subshell.RenderPrompt(c.promptFormat, c.promptVars, subshell.PromptBash)), SubshellFunctions)
 }
 
 // GetFallbackInitScript returns the init script for non-zsh/bash shells.
@@ -171,3 +207,16 @@ func (c *AWSShellCustomizer) GetPromptPrefix() string {
 func NewCustomizer() *AWSShellCustomizer {
 	return &AWSShellCustomizer{}
 }
+
+// NewCustomizerWithPrompt creates an AWS shell customizer that prefixes the
+// zsh and bash prompts with format, rendered with vars, instead of the
+// default.
+func NewCustomizerWithPrompt(format string, vars subshell.PromptVars) *AWSShellCustomizer {
+	return &AWSShellCustomizer{promptFormat: format, promptVars: vars}
+}
+
+// shellQuote single-quotes s so the shell assigns it literally; expansion of
+// the prompt happens later, when the shell draws it.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -3,6 +3,8 @@ package aws
 import (
 	"strings"
 	"testing"
+
+	"github.com/bashhack/sesh/internal/subshell"
 )
 
 func TestNewCustomizer(t *testing.T) {
@@ -129,3 +131,58 @@ func TestAWSShellCustomizerInterface(t *testing.T) {
 		}
 	})
 }
+
+func TestAWSShellCustomizer_CustomPrompt(t *testing.T) {
+	customizer := NewCustomizerWithPrompt("(aws:{profile} {expires}) ", subshell.PromptVars{Provider: "aws", Profile: "prod"})
+
+	zsh := customizer.GetZshInitScript()
+	if !strings.Contains(zsh, `PROMPT='(aws:prod $(sesh_remaining)) '"${PROMPT}"`) {
+		t.Errorf("zsh script missing custom prompt: %q", zsh)
+	}
+	if !strings.Contains(zsh, "setopt PROMPT_SUBST") {
+		t.Error("zsh script should enable PROMPT_SUBST for a custom prompt")
+	}
+	if strings.Contains(zsh, "(sesh:aws)") {
+		t.Error("zsh script should not contain the default prompt")
+	}
+
+	bash := customizer.GetBashInitScript()
+	if !strings.Contains(bash, `PS1='(aws:prod $(sesh_remaining)) '"$PS1"`) {
+		t.Errorf("bash script missing custom prompt: %q", bash)
+	}
+
+	for _, script := range []string{zsh, bash} {
+		if !strings.Contains(script, "sesh_remaining()") {
+			t.Error("init script should define the sesh_remaining helper")
+		}
+	}
+}
+
+func TestAWSShellCustomizer_CustomPromptQuotesProfile(t *testing.T) {
+	customizer := NewCustomizerWithPrompt("({profile}) ", subshell.PromptVars{Provider: "aws", Profile: "$(id)`id`%n"})
+
+	if zsh := customizer.GetZshInitScript(); !strings.Contains(zsh, "PROMPT='(\\$(id)\\`id\\`%%n) '") {
+		t.Errorf("zsh script should escape the profile: %q", zsh)
+	}
+	if bash := customizer.GetBashInitScript(); !strings.Contains(bash, "PS1='(\\\\$(id)\\\\`id\\\\`%n) '") {
+		t.Errorf("bash script should escape the profile: %q", bash)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]struct {
+		in   string
+		want string
+	}{
+		"plain":        {in: "(sesh) ", want: "'(sesh) '"},
+		"single quote": {in: "it's ", want: `'it'\''s '`},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := shellQuote(tc.in); got != tc.want {
+				t.Errorf("shellQuote(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package aws
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// EncodeCredentials returns c as base64-encoded JSON on a single line, in
+// the same shape as the Credentials object from get-session-token, so it
+// can pass through systems that mangle shell-special characters.
+func EncodeCredentials(c Credentials) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode credentials: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecodeCredentials reverses EncodeCredentials. Surrounding whitespace is
+// ignored, so a value read from a file or pipe can be passed as is.
+func DecodeCredentials(s string) (Credentials, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return Credentials{}, fmt.Errorf("credentials are not valid base64: %w", err)
+	}
+	var c Credentials
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Credentials{}, fmt.Errorf("credentials are not a JSON credential object: %w", err)
+	}
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return Credentials{}, fmt.Errorf("credentials are missing AccessKeyId or SecretAccessKey")
+	}
+	return c, nil
+}
@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDecodeCredentials(t *testing.T) {
+	creds := Credentials{
+		AccessKeyID:     "ASIAEXAMPLE",
+		SecretAccessKey: "wJalr/XUtnFEMI+K7MDENG'bPxRfi\"CY",
+		SessionToken:    "FwoGZXIvYXdzE$(rm -rf)`x`;|&",
+		Expiration:      "2030-01-01T00:00:00Z",
+		IssuedAt:        "2029-12-31T12:00:00Z",
+	}
+
+	encoded, err := EncodeCredentials(creds)
+	if err != nil {
+		t.Fatalf("EncodeCredentials() error = %v", err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), `"IssuedAt":"2029-12-31T12:00:00Z"`) {
+		t.Errorf("encoded JSON %s is missing IssuedAt", raw)
+	}
+	if strings.ContainsAny(encoded, "\n'\"$`;|& ") {
+		t.Errorf("encoded value %q contains shell-special characters", encoded)
+	}
+
+	decoded, err := DecodeCredentials(encoded + "\n")
+	if err != nil {
+		t.Fatalf("DecodeCredentials() error = %v", err)
+	}
+	if decoded != creds {
+		t.Errorf("round trip = %+v, want %+v", decoded, creds)
+	}
+}
+
+func TestDecodeCredentials_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		in         string
+		wantErrMsg string
+	}{
+		"not base64": {
+			in:         "not base64!",
+			wantErrMsg: "not valid base64",
+		},
+		"not JSON": {
+			in:         base64.StdEncoding.EncodeToString([]byte("export AWS_ACCESS_KEY_ID=x")),
+			wantErrMsg: "not a JSON credential object",
+		},
+		"missing keys": {
+			in:         base64.StdEncoding.EncodeToString([]byte(`{"SessionToken":"x"}`)),
+			wantErrMsg: "missing AccessKeyId or SecretAccessKey",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := DecodeCredentials(tc.in)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+				t.Errorf("DecodeCredentials() error = %v, want to contain %q", err, tc.wantErrMsg)
+			}
+		})
+	}
+}
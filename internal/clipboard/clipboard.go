@@ -2,6 +2,7 @@
 package clipboard
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"os"
@@ -14,15 +15,63 @@ import (
 )
 
 var (
-	execCommand = exec.Command
-	runtimeGOOS = runtime.GOOS
+	execCommand  = exec.Command
+	execLookPath = exec.LookPath
+	runtimeGOOS  = runtime.GOOS
 )
 
+// ErrNoTool is returned by Tool when no clipboard command is installed.
+var ErrNoTool = errors.New("no clipboard tool found; install xclip or wl-clipboard, or omit --clip to print the code")
+
+// linuxTools are the Linux clipboard commands, in order of preference,
+// with the arguments that make them read the clipboard from stdin.
+var linuxTools = [][]string{
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+}
+
+// Tool returns the command Copy would use on this platform (pbcopy on
+// macOS, wl-copy or xclip on Linux), or ErrNoTool if none is installed.
+func Tool() (string, error) {
+	switch runtimeGOOS {
+	case "darwin":
+		if _, err := execLookPath("pbcopy"); err == nil {
+			return "pbcopy", nil
+		}
+	case "linux":
+		if tool := linuxTool(); tool != nil {
+			return tool[0], nil
+		}
+	}
+	return "", ErrNoTool
+}
+
+// linuxTool returns the first installed entry of linuxTools, or nil.
+func linuxTool() []string {
+	for _, tool := range linuxTools {
+		if _, err := execLookPath(tool[0]); err == nil {
+			return tool
+		}
+	}
+	return nil
+}
+
 // Copy copies text to the clipboard and returns an error if unsuccessful
 func Copy(text string) error {
 	switch runtimeGOOS {
 	case "darwin":
+		// Prefer a copy marked concealed so clipboard history managers
+		// skip it; plain pbcopy is the fallback when that isn't possible.
+		if err := copyConcealedOSX(text); err == nil {
+			return nil
+		}
 		return copyOSX(text)
+	case "linux":
+		tool := linuxTool()
+		if tool == nil {
+			return ErrNoTool
+		}
+		return writeToCommand(execCommand(tool[0], tool[1:]...), text)
 	default:
 		return fmt.Errorf("unsupported platform: %s", runtimeGOOS)
 	}
@@ -97,9 +146,34 @@ fi`
 	return nil
 }
 
+// concealedPasteboardScript is a JXA script that reads text from stdin and
+// writes it to the general pasteboard alongside the nspasteboard.org
+// "concealed" and "transient" marker types. Clipboard managers that honor
+// the convention (Maccy, Alfred, Raycast, Paste, ...) don't record such
+// entries in their history. The text goes through stdin rather than argv
+// so it never shows up in the process list.
+const concealedPasteboardScript = `ObjC.import('AppKit');
+var data = $.NSFileHandle.fileHandleWithStandardInput.readDataToEndOfFile;
+var text = $.NSString.alloc.initWithDataEncoding(data, $.NSUTF8StringEncoding);
+var pb = $.NSPasteboard.generalPasteboard;
+pb.clearContents;
+if (!pb.setStringForType(text, 'public.utf8-plain-text')) { throw new Error('pasteboard write failed'); }
+pb.setStringForType('', 'org.nspasteboard.ConcealedType');
+pb.setStringForType('', 'org.nspasteboard.TransientType');`
+
+// copyConcealedOSX copies text to the clipboard on macOS, marked as
+// concealed and transient for clipboard history managers.
+func copyConcealedOSX(text string) error {
+	return writeToCommand(execCommand("osascript", "-l", "JavaScript", "-e", concealedPasteboardScript), text)
+}
+
 // copyOSX copies text to clipboard on macOS
 func copyOSX(text string) error {
-	cmd := execCommand("pbcopy")
+	return writeToCommand(execCommand("pbcopy"), text)
+}
+
+// writeToCommand runs cmd with text written to its stdin.
+func writeToCommand(cmd *exec.Cmd, text string) error {
 	pipe, err := cmd.StdinPipe()
 	if err != nil {
 		return err
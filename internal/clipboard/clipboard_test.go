@@ -1,7 +1,9 @@
 package clipboard
 
 import (
+	"errors"
 	"os/exec"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -29,7 +31,8 @@ func TestCopy(t *testing.T) {
 				if name == "pbcopy" {
 					return exec.Command("cat")
 				}
-				return nil
+				// osascript unavailable: falls back to pbcopy.
+				return exec.Command("false")
 			},
 			wantErr: false,
 		},
@@ -40,7 +43,8 @@ func TestCopy(t *testing.T) {
 				if name == "pbcopy" {
 					return exec.Command("cat")
 				}
-				return nil
+				// osascript unavailable: falls back to pbcopy.
+				return exec.Command("false")
 			},
 			wantErr: false,
 		},
@@ -54,9 +58,9 @@ func TestCopy(t *testing.T) {
 		},
 		"unsupported platform": {
 			text:    "test text",
-			goos:    "linux",
+			goos:    "freebsd",
 			wantErr: true,
-			errMsg:  "unsupported platform: linux",
+			errMsg:  "unsupported platform: freebsd",
 		},
 		"windows platform": {
 			text:    "test text",
@@ -87,6 +91,139 @@ func TestCopy(t *testing.T) {
 	}
 }
 
+func TestTool(t *testing.T) {
+	tests := map[string]struct {
+		goos      string
+		installed []string
+		want      string
+		wantErr   bool
+	}{
+		"macOS pbcopy":          {goos: "darwin", installed: []string{"pbcopy"}, want: "pbcopy"},
+		"macOS without it":      {goos: "darwin", wantErr: true},
+		"linux prefers wayland": {goos: "linux", installed: []string{"xclip", "wl-copy"}, want: "wl-copy"},
+		"linux xclip":           {goos: "linux", installed: []string{"xclip"}, want: "xclip"},
+		"headless linux":        {goos: "linux", wantErr: true},
+		"unsupported":           {goos: "windows", installed: []string{"pbcopy"}, wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			origGOOS, origLookPath := runtimeGOOS, execLookPath
+			defer func() { runtimeGOOS, execLookPath = origGOOS, origLookPath }()
+
+			runtimeGOOS = tc.goos
+			execLookPath = func(file string) (string, error) {
+				if slices.Contains(tc.installed, file) {
+					return "/usr/bin/" + file, nil
+				}
+				return "", exec.ErrNotFound
+			}
+
+			got, err := Tool()
+			if tc.wantErr {
+				if !errors.Is(err, ErrNoTool) {
+					t.Fatalf("Tool() error = %v, want ErrNoTool", err)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Errorf("Tool() = (%q, %v), want %q", got, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestCopy_Linux(t *testing.T) {
+	origGOOS, origLookPath, origCmd := runtimeGOOS, execLookPath, execCommand
+	defer func() { runtimeGOOS, execLookPath, execCommand = origGOOS, origLookPath, origCmd }()
+
+	runtimeGOOS = "linux"
+	execLookPath = func(file string) (string, error) {
+		if file == "xclip" {
+			return "/usr/bin/xclip", nil
+		}
+		return "", exec.ErrNotFound
+	}
+	var ran []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		ran = append([]string{name}, args...)
+		return exec.Command("cat")
+	}
+
+	if err := Copy("123456"); err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if want := []string{"xclip", "-selection", "clipboard"}; !slices.Equal(ran, want) {
+		t.Errorf("ran %q, want %q", ran, want)
+	}
+
+	execLookPath = func(string) (string, error) { return "", exec.ErrNotFound }
+	if err := Copy("123456"); !errors.Is(err, ErrNoTool) {
+		t.Errorf("Copy() with no tool error = %v, want ErrNoTool", err)
+	}
+}
+
+// TestCopy_RequestsConcealedType asserts the darwin copy marks the
+// pasteboard entry as concealed and transient, and only falls back to a
+// plain pbcopy when that fails.
+func TestCopy_RequestsConcealedType(t *testing.T) {
+	originalExecCommand := execCommand
+	originalRuntimeGOOS := runtimeGOOS
+	defer func() {
+		execCommand = originalExecCommand
+		runtimeGOOS = originalRuntimeGOOS
+	}()
+	runtimeGOOS = "darwin"
+
+	tests := map[string]struct {
+		osascript  string // command standing in for osascript
+		wantPbcopy bool
+	}{
+		"concealed copy succeeds": {
+			osascript: "cat",
+		},
+		"concealed copy fails": {
+			osascript:  "false",
+			wantPbcopy: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var script string
+			var usedPbcopy bool
+			execCommand = func(name string, args ...string) *exec.Cmd {
+				switch name {
+				case "osascript":
+					if len(args) == 4 && args[0] == "-l" && args[1] == "JavaScript" && args[2] == "-e" {
+						script = args[3]
+					}
+					return exec.Command(tc.osascript)
+				case "pbcopy":
+					usedPbcopy = true
+					return exec.Command("cat")
+				}
+				return exec.Command("false")
+			}
+
+			if err := Copy("the-secret"); err != nil {
+				t.Fatalf("Copy() error = %v", err)
+			}
+			for _, marker := range []string{"org.nspasteboard.ConcealedType", "org.nspasteboard.TransientType"} {
+				if !strings.Contains(script, marker) {
+					t.Errorf("osascript script should set %s, got:\n%s", marker, script)
+				}
+			}
+			if strings.Contains(script, "the-secret") {
+				t.Error("secret must be passed on stdin, not in the script")
+			}
+			if usedPbcopy != tc.wantPbcopy {
+				t.Errorf("pbcopy used = %v, want %v", usedPbcopy, tc.wantPbcopy)
+			}
+		})
+	}
+}
+
 func TestCopyOSX(t *testing.T) {
 	originalExecCommand := execCommand
 	defer func() {
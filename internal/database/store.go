@@ -151,6 +151,12 @@ func (s *Store) ListEntries(service string) (_ []keychain.KeychainEntry, err err
 	return entries, rows.Err()
 }
 
+// List filters the rows from ListEntries; the service type is derived
+// from each key since the table has no separate index.
+func (s *Store) List(filter keychain.EntryFilter) ([]keychain.KeychainEntryMeta, error) {
+	return keychain.ListFromEntries(s.ListEntries, filter)
+}
+
 func (s *Store) DeleteEntry(account, service string) error {
 	res, err := s.db.Exec(
 		`DELETE FROM passwords WHERE service = ? AND account = ?`,
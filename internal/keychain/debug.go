@@ -0,0 +1,36 @@
+package keychain
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// debugOutput receives keychain timing lines when set (see SetDebugOutput).
+// nil disables timing entirely.
+var debugOutput io.Writer
+
+// timeNow is the clock used for operation timing. Mockable for tests.
+var timeNow = time.Now
+
+// SetDebugOutput enables timing of keychain operations, written to w as
+// "keychain <op> took <duration>". Pass nil to disable. It is meant to be
+// set once at startup (--debug), not toggled concurrently.
+func SetDebugOutput(w io.Writer) {
+	debugOutput = w
+}
+
+// timeOp starts timing op and returns a func that reports the elapsed time,
+// for use as `defer timeOp("GetSecret")()`. It's a no-op unless debug output
+// is enabled.
+func timeOp(op string) func() {
+	w := debugOutput
+	if w == nil {
+		return func() {}
+	}
+	start := timeNow()
+	return func() {
+		elapsed := timeNow().Sub(start).Round(time.Millisecond)
+		_, _ = fmt.Fprintf(w, "keychain %s took %s\n", op, elapsed) //nolint:errcheck // best-effort diagnostics
+	}
+}
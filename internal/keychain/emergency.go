@@ -0,0 +1,153 @@
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/bashhack/sesh/internal/secure"
+	"github.com/bashhack/sesh/internal/theme"
+)
+
+// EmergencyEntry is one secret held by an EmergencyStore. Exported
+// bundles don't record the keychain account an entry was stored under, so
+// entries are matched by service key alone.
+type EmergencyEntry struct {
+	Service string
+	Secret  []byte
+}
+
+// EmergencyStore is a read-only, in-memory set of secrets decrypted from
+// an exported bundle. It backs Failover when the keychain is locked.
+type EmergencyStore struct {
+	entries []EmergencyEntry
+}
+
+// NewEmergencyStore returns a store serving entries. It takes ownership of
+// each Secret; Close zeroes them.
+func NewEmergencyStore(entries []EmergencyEntry) *EmergencyStore {
+	return &EmergencyStore{entries: entries}
+}
+
+// GetSecret returns a copy of the secret stored under service. account
+// only appears in the error.
+func (s *EmergencyStore) GetSecret(account, service string) ([]byte, error) {
+	for _, e := range s.entries {
+		if e.Service == service {
+			secret := make([]byte, len(e.Secret))
+			copy(secret, e.Secret)
+			return secret, nil
+		}
+	}
+	return nil, fmt.Errorf("%w in emergency store for account %q and service %q", ErrNotFound, account, service)
+}
+
+// Close zeroes every secret in the store.
+func (s *EmergencyStore) Close() {
+	for _, e := range s.entries {
+		secure.SecureZeroBytes(e.Secret)
+	}
+	s.entries = nil
+}
+
+// EmergencyLoader decrypts the emergency bundle. Failover calls it at
+// most once, the first time the keychain turns out to be locked, so the
+// bundle's passphrase is only asked for when it is needed.
+type EmergencyLoader func() (*EmergencyStore, error)
+
+// Failover is a Provider that reads from an EmergencyStore when the
+// wrapped Provider reports ErrKeychainLocked. Everything else, including
+// listing and deleting, goes to the wrapped Provider unchanged.
+type Failover struct {
+	Provider
+
+	load EmergencyLoader
+	warn io.Writer
+
+	once    sync.Once
+	store   *EmergencyStore
+	loadErr error
+}
+
+var _ Provider = (*Failover)(nil)
+
+// NewFailover wraps primary so locked-keychain reads are served by the
+// store load returns. Each secret served that way is reported on warn.
+func NewFailover(primary Provider, load EmergencyLoader, warn io.Writer) *Failover {
+	return &Failover{Provider: primary, load: load, warn: warn}
+}
+
+// emergency loads the store on first use.
+func (f *Failover) emergency() (*EmergencyStore, error) {
+	f.once.Do(func() {
+		f.store, f.loadErr = f.load()
+	})
+	return f.store, f.loadErr
+}
+
+// fallback serves account/service from the emergency store after the
+// primary read failed with primaryErr.
+func (f *Failover) fallback(account, service string, primaryErr error) ([]byte, error) {
+	if !errors.Is(primaryErr, ErrKeychainLocked) {
+		return nil, primaryErr
+	}
+	store, err := f.emergency()
+	if err != nil {
+		return nil, fmt.Errorf("%w; emergency store unavailable: %w", primaryErr, err)
+	}
+	secret, err := store.GetSecret(account, service)
+	if err != nil {
+		return nil, fmt.Errorf("%w; %w", primaryErr, err)
+	}
+	if f.warn != nil {
+		_, _ = fmt.Fprintf(f.warn, theme.Warning()+" Keychain locked; using %s from the emergency store\n", service) //nolint:errcheck // best-effort notice
+	}
+	return secret, nil
+}
+
+// GetSecret implements the Provider interface
+func (f *Failover) GetSecret(account, service string) ([]byte, error) {
+	secret, err := f.Provider.GetSecret(account, service)
+	if err == nil {
+		return secret, nil
+	}
+	return f.fallback(account, service, err)
+}
+
+// GetSecretString implements the Provider interface
+func (f *Failover) GetSecretString(account, service string) (string, error) {
+	secret, err := f.GetSecret(account, service)
+	if err != nil {
+		return "", err
+	}
+	defer secure.SecureZeroBytes(secret)
+	return string(secret), nil
+}
+
+// Close zeroes the emergency store's secrets if it was loaded.
+func (f *Failover) Close() {
+	if f.store != nil {
+		f.store.Close()
+	}
+}
+
+// SetSecret implements the Provider interface. The emergency store is
+// read-only, so a locked keychain still fails the write, with a note that
+// nothing was saved.
+func (f *Failover) SetSecret(account, service string, secret []byte) error {
+	return readOnlyErr(f.Provider.SetSecret(account, service, secret))
+}
+
+// SetSecretString implements the Provider interface
+func (f *Failover) SetSecretString(account, service, secret string) error {
+	return readOnlyErr(f.Provider.SetSecretString(account, service, secret))
+}
+
+// readOnlyErr explains a write that failed on a locked keychain.
+func readOnlyErr(err error) error {
+	if errors.Is(err, ErrKeychainLocked) {
+		return fmt.Errorf("%w; the emergency store is read-only, so nothing was saved", err)
+	}
+	return err
+}
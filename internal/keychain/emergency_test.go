@@ -0,0 +1,150 @@
+package keychain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// lockableProvider is a Provider whose reads and writes fail with err.
+// The embedded nil Provider panics if anything else is called.
+type lockableProvider struct {
+	Provider
+	secrets map[string]string
+	err     error
+}
+
+func (p *lockableProvider) GetSecret(_, service string) ([]byte, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return []byte(p.secrets[service]), nil
+}
+
+func (p *lockableProvider) GetMFASerialBytes(account, profile string) ([]byte, error) {
+	return p.GetSecret(account, "sesh-aws-serial/"+profile)
+}
+
+func (p *lockableProvider) SetSecret(_, _ string, _ []byte) error {
+	return p.err
+}
+
+func (p *lockableProvider) SetSecretString(_, _, _ string) error {
+	return p.err
+}
+
+func TestFailover_GetSecret(t *testing.T) {
+	locked := fmt.Errorf("%w: reading account %q", ErrKeychainLocked, "alice")
+
+	tests := map[string]struct {
+		primaryErr error
+		loadErr    error
+		service    string
+		want       string
+		wantErr    string
+		wantWarn   bool
+		wantLoads  int
+	}{
+		"unlocked keychain is used": {
+			service: "sesh-password/password/github/alice",
+			want:    "from-keychain",
+		},
+		"locked keychain fails over": {
+			primaryErr: locked,
+			service:    "sesh-password/password/github/alice",
+			want:       "from-bundle",
+			wantWarn:   true,
+			wantLoads:  1,
+		},
+		"other errors don't fail over": {
+			primaryErr: errors.New("keychain read failed"),
+			service:    "sesh-password/password/github/alice",
+			wantErr:    "keychain read failed",
+		},
+		"missing from the emergency store": {
+			primaryErr: locked,
+			service:    "sesh-totp/github",
+			wantErr:    "secret not found",
+			wantLoads:  1,
+		},
+		"bundle fails to load": {
+			primaryErr: locked,
+			loadErr:    errors.New("wrong password or corrupted export"),
+			service:    "sesh-password/password/github/alice",
+			wantErr:    "emergency store unavailable: wrong password",
+			wantLoads:  1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			primary := &lockableProvider{
+				secrets: map[string]string{"sesh-password/password/github/alice": "from-keychain"},
+				err:     tc.primaryErr,
+			}
+			loads := 0
+			load := func() (*EmergencyStore, error) {
+				loads++
+				if tc.loadErr != nil {
+					return nil, tc.loadErr
+				}
+				return NewEmergencyStore([]EmergencyEntry{
+					{Service: "sesh-password/password/github/alice", Secret: []byte("from-bundle")},
+				}), nil
+			}
+			var warn bytes.Buffer
+			f := NewFailover(primary, load, &warn)
+			defer f.Close()
+
+			// Twice, to show the bundle is decrypted at most once.
+			for range 2 {
+				got, err := f.GetSecret("alice", tc.service)
+				if tc.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+						t.Fatalf("GetSecret() error = %v, want to contain %q", err, tc.wantErr)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("GetSecret() error = %v", err)
+				}
+				if string(got) != tc.want {
+					t.Errorf("GetSecret() = %q, want %q", got, tc.want)
+				}
+			}
+			if loads != tc.wantLoads {
+				t.Errorf("loaded the emergency store %d times, want %d", loads, tc.wantLoads)
+			}
+			if gotWarn := strings.Contains(warn.String(), "emergency store"); gotWarn != tc.wantWarn {
+				t.Errorf("warning = %q, want one: %v", warn.String(), tc.wantWarn)
+			}
+		})
+	}
+}
+
+func TestFailover_SetSecretIsReadOnly(t *testing.T) {
+	f := NewFailover(&lockableProvider{err: ErrKeychainLocked}, func() (*EmergencyStore, error) {
+		t.Fatal("writes must not load the emergency store")
+		return nil, nil
+	}, nil)
+
+	err := f.SetSecret("alice", "sesh-totp/github", []byte("secret"))
+	if !errors.Is(err, ErrKeychainLocked) || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("SetSecret() error = %v, want a locked, read-only error", err)
+	}
+}
+
+func TestEmergencyStore_Close(t *testing.T) {
+	secret := []byte("hunter2")
+	s := NewEmergencyStore([]EmergencyEntry{{Service: "svc", Secret: secret}})
+	s.Close()
+
+	if !bytes.Equal(secret, make([]byte, len(secret))) {
+		t.Errorf("Close() left secret %q, want it zeroed", secret)
+	}
+	if _, err := s.GetSecret("alice", "svc"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetSecret() after Close error = %v, want ErrNotFound", err)
+	}
+}
@@ -1,6 +1,9 @@
 package keychain
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Provider defines the interface for credential storage operations.
 // Implementations include the macOS system keychain and the SQLite store.
@@ -26,6 +29,9 @@ type Provider interface {
 	// ListEntries lists all entries whose service key starts with the given prefix.
 	ListEntries(service string) ([]KeychainEntry, error)
 
+	// List returns the entries matching filter, with their service type.
+	List(filter EntryFilter) ([]KeychainEntryMeta, error)
+
 	// DeleteEntry removes an entry.
 	DeleteEntry(account, service string) error
 
@@ -55,6 +61,24 @@ type TimestampedStore interface {
 	SetDescriptionAt(service, account, description string, updatedAt time.Time) error
 }
 
+// UnindexedStore is an optional interface for credential backends that keep
+// a separate listing index which can be skipped on write. The macOS keychain
+// backend implements it; the SQLite store does not (its rows are the index).
+type UnindexedStore interface {
+	// SetSecretUnindexed stores a secret without writing listing metadata.
+	SetSecretUnindexed(account, service string, secret []byte) error
+}
+
+// UnindexedLister is an optional interface for credential backends whose
+// listing index can fall out of step with the items it describes, since a
+// failed metadata write doesn't fail the store. The macOS keychain backend
+// implements it; the SQLite store does not (its rows are the index).
+type UnindexedLister interface {
+	// ListUnindexed returns the items in the servicePrefix namespace that
+	// hold a secret but have no listing metadata.
+	ListUnindexed(servicePrefix string) ([]KeychainEntry, error)
+}
+
 // KeychainEntry represents an entry in the credential store.
 type KeychainEntry struct {
 	CreatedAt   time.Time
@@ -64,10 +88,70 @@ type KeychainEntry struct {
 	Description string
 }
 
+// EntryFilter selects entries for Provider.List. Empty fields match
+// every entry; set fields must all match.
+type EntryFilter struct {
+	// ServicePrefix matches service keys that start with it
+	// (e.g. "sesh-totp/github").
+	ServicePrefix string
+	// Account matches the entry's account exactly.
+	Account string
+	// ServiceType matches the key's namespace, the part before the first
+	// "/" (e.g. "sesh-aws", which excludes "sesh-aws-serial/...").
+	ServiceType string
+}
+
+// Matches reports whether entry passes every set field of f.
+func (f EntryFilter) Matches(entry KeychainEntryMeta) bool {
+	if f.ServicePrefix != "" && !strings.HasPrefix(entry.Service, f.ServicePrefix) {
+		return false
+	}
+	if f.Account != "" && entry.Account != f.Account {
+		return false
+	}
+	if f.ServiceType != "" && getServicePrefix(entry.Service) != f.ServiceType {
+		return false
+	}
+	return true
+}
+
+// ListFromEntries implements Provider.List on top of a backend's
+// prefix-matching ListEntries, for stores without a separate metadata
+// index. The service type is derived from each key.
+func ListFromEntries(listEntries func(prefix string) ([]KeychainEntry, error), filter EntryFilter) ([]KeychainEntryMeta, error) {
+	prefix := filter.ServicePrefix
+	if prefix == "" {
+		prefix = filter.ServiceType
+	}
+	entries, err := listEntries(prefix)
+	if err != nil {
+		return nil, err
+	}
+	var result []KeychainEntryMeta
+	for _, e := range entries {
+		meta := KeychainEntryMeta{
+			CreatedAt:   e.CreatedAt,
+			UpdatedAt:   e.UpdatedAt,
+			Service:     e.Service,
+			Account:     e.Account,
+			Description: e.Description,
+			ServiceType: getServicePrefix(e.Service),
+		}
+		if filter.Matches(meta) {
+			result = append(result, meta)
+		}
+	}
+	return result, nil
+}
+
 // DefaultProvider is the default implementation using the system keychain
 type DefaultProvider struct{}
 
-var _ Provider = (*DefaultProvider)(nil)
+var (
+	_ Provider        = (*DefaultProvider)(nil)
+	_ UnindexedStore  = (*DefaultProvider)(nil)
+	_ UnindexedLister = (*DefaultProvider)(nil)
+)
 
 // GetSecret implements the Provider interface
 func (p *DefaultProvider) GetSecret(account, service string) ([]byte, error) {
@@ -99,11 +183,26 @@ func (p *DefaultProvider) ListEntries(service string) ([]KeychainEntry, error) {
 	return ListEntries(service)
 }
 
+// List implements the Provider interface
+func (p *DefaultProvider) List(filter EntryFilter) ([]KeychainEntryMeta, error) {
+	return List(filter)
+}
+
 // DeleteEntry implements the Provider interface
 func (p *DefaultProvider) DeleteEntry(account, service string) error {
 	return DeleteEntry(account, service)
 }
 
+// SetSecretUnindexed implements the UnindexedStore interface
+func (p *DefaultProvider) SetSecretUnindexed(account, service string, secret []byte) error {
+	return SetSecretBytesUnindexed(account, service, secret)
+}
+
+// ListUnindexed implements the UnindexedLister interface
+func (p *DefaultProvider) ListUnindexed(servicePrefix string) ([]KeychainEntry, error) {
+	return ListUnindexed(servicePrefix)
+}
+
 // SetDescription implements the Provider interface
 func (p *DefaultProvider) SetDescription(service, account, description string) error {
 	servicePrefix := getServicePrefix(service)
@@ -3,6 +3,8 @@ package keychain
 import (
 	"os"
 	"os/exec"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -88,6 +90,43 @@ func TestDefaultProviderSetSecret(t *testing.T) {
 	}
 }
 
+func TestDefaultProviderSetSecretUnindexed(t *testing.T) {
+	orig := saveMocks()
+	defer orig.restore()
+
+	origLoad := loadEntryMetadataImpl
+	origSave := saveEntryMetadataImpl
+	defer func() {
+		loadEntryMetadataImpl = origLoad
+		saveEntryMetadataImpl = origSave
+	}()
+	loadEntryMetadataImpl = func(servicePrefix string) ([]KeychainEntryMeta, error) {
+		t.Error("metadata should not be loaded for an unindexed write")
+		return nil, nil
+	}
+	saveEntryMetadataImpl = func(meta []KeychainEntryMeta) error {
+		t.Error("metadata should not be saved for an unindexed write")
+		return nil
+	}
+
+	secretWritten := false
+	execSecretInput = func(cmd *exec.Cmd, input []byte) error {
+		secretWritten = true
+		return nil
+	}
+
+	store, ok := NewDefaultProvider().(UnindexedStore)
+	if !ok {
+		t.Fatal("DefaultProvider should implement UnindexedStore")
+	}
+	if err := store.SetSecretUnindexed("testuser", "sesh-totp/github", []byte("test-secret")); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !secretWritten {
+		t.Error("secret was not written to the keychain")
+	}
+}
+
 func TestDefaultProviderGetSecretString(t *testing.T) {
 	orig := saveMocks()
 	defer orig.restore()
@@ -177,6 +216,144 @@ func TestDefaultProviderListEntries(t *testing.T) {
 	}
 }
 
+// syntheticMetadata covers two accounts, three service types and a
+// sesh-aws-serial entry whose key shares the "sesh-aws" byte prefix.
+var syntheticMetadata = []KeychainEntryMeta{
+	{Service: "sesh-aws/default", Account: "alice", ServiceType: "sesh-aws"},
+	{Service: "sesh-aws-serial/default", Account: "alice", ServiceType: "sesh-aws-serial"},
+	{Service: "sesh-totp/github", Account: "alice", ServiceType: "sesh-totp"},
+	{Service: "sesh-totp/github/work", Account: "alice", ServiceType: "sesh-totp"},
+	{Service: "sesh-totp/github", Account: "bob", ServiceType: "sesh-totp"},
+	{Service: "sesh-password/password/github/alice", Account: "alice"}, // legacy: no ServiceType
+}
+
+func TestDefaultProviderList(t *testing.T) {
+	originalLoadAll := loadAllEntryMetadataImpl
+	defer func() { loadAllEntryMetadataImpl = originalLoadAll }()
+	loadAllEntryMetadataImpl = func() ([]KeychainEntryMeta, error) {
+		return syntheticMetadata, nil
+	}
+
+	tests := map[string]struct {
+		filter EntryFilter
+		want   []string // service|account
+	}{
+		"no filter returns everything": {
+			filter: EntryFilter{},
+			want: []string{
+				"sesh-aws/default|alice", "sesh-aws-serial/default|alice", "sesh-totp/github|alice",
+				"sesh-totp/github/work|alice", "sesh-totp/github|bob", "sesh-password/password/github/alice|alice",
+			},
+		},
+		"service prefix": {
+			filter: EntryFilter{ServicePrefix: "sesh-totp/github"},
+			want:   []string{"sesh-totp/github|alice", "sesh-totp/github/work|alice", "sesh-totp/github|bob"},
+		},
+		"account": {
+			filter: EntryFilter{Account: "bob"},
+			want:   []string{"sesh-totp/github|bob"},
+		},
+		"service type excludes prefix siblings": {
+			filter: EntryFilter{ServiceType: "sesh-aws"},
+			want:   []string{"sesh-aws/default|alice"},
+		},
+		"service type derived for legacy entries": {
+			filter: EntryFilter{ServiceType: "sesh-password"},
+			want:   []string{"sesh-password/password/github/alice|alice"},
+		},
+		"prefix and account": {
+			filter: EntryFilter{ServicePrefix: "sesh-totp/github", Account: "alice"},
+			want:   []string{"sesh-totp/github|alice", "sesh-totp/github/work|alice"},
+		},
+		"type and account": {
+			filter: EntryFilter{ServiceType: "sesh-totp", Account: "bob"},
+			want:   []string{"sesh-totp/github|bob"},
+		},
+		"prefix and type": {
+			filter: EntryFilter{ServicePrefix: "sesh-aws", ServiceType: "sesh-aws-serial"},
+			want:   []string{"sesh-aws-serial/default|alice"},
+		},
+		"all three": {
+			filter: EntryFilter{ServicePrefix: "sesh-totp/github/", ServiceType: "sesh-totp", Account: "alice"},
+			want:   []string{"sesh-totp/github/work|alice"},
+		},
+		"no match": {
+			filter: EntryFilter{ServiceType: "sesh-totp", Account: "carol"},
+			want:   nil,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			entries, err := NewDefaultProvider().List(tc.filter)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Service+"|"+e.Account)
+				if e.ServiceType == "" {
+					t.Errorf("entry %s has no ServiceType", e.Service)
+				}
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("List(%+v) = %v, want %v", tc.filter, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestListFromEntries(t *testing.T) {
+	var queried string
+	listEntries := func(prefix string) ([]KeychainEntry, error) {
+		queried = prefix
+		var out []KeychainEntry
+		for _, m := range syntheticMetadata {
+			if strings.HasPrefix(m.Service, prefix) {
+				out = append(out, m.Entry())
+			}
+		}
+		return out, nil
+	}
+
+	tests := map[string]struct {
+		filter      EntryFilter
+		wantQueried string
+		want        []string
+	}{
+		"queries by service prefix": {
+			filter:      EntryFilter{ServicePrefix: "sesh-totp/github", Account: "alice"},
+			wantQueried: "sesh-totp/github",
+			want:        []string{"sesh-totp/github|alice", "sesh-totp/github/work|alice"},
+		},
+		"falls back to service type for the query": {
+			filter:      EntryFilter{ServiceType: "sesh-aws"},
+			wantQueried: "sesh-aws",
+			want:        []string{"sesh-aws/default|alice"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			entries, err := ListFromEntries(listEntries, tc.filter)
+			if err != nil {
+				t.Fatalf("ListFromEntries() error = %v", err)
+			}
+			if queried != tc.wantQueried {
+				t.Errorf("queried prefix %q, want %q", queried, tc.wantQueried)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Service+"|"+e.Account)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("ListFromEntries(%+v) = %v, want %v", tc.filter, got, tc.want)
+			}
+		})
+	}
+}
+
 // TestDefaultProviderDeleteEntry uses subprocess pattern because
 // DeleteEntry calls execCommand + cmd.Run() directly.
 func TestDefaultProviderDeleteEntry(t *testing.T) {
@@ -3,6 +3,7 @@ package keychain
 
 import (
 	"bytes"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os/exec"
@@ -16,9 +17,33 @@ import (
 // ErrNotFound is returned when a keychain item does not exist.
 var ErrNotFound = errors.New("secret not found in keychain")
 
+// ErrKeychainLocked is returned when the keychain is locked and couldn't
+// be unlocked: the unlock prompt was refused or there was no way to show
+// one (e.g. over SSH).
+var ErrKeychainLocked = errors.New("keychain is locked")
+
 // exitCodeItemNotFound is the macOS `security` command exit code for errSecItemNotFound.
 const exitCodeItemNotFound = 44
 
+// `security` exits with the low byte of the failing OSStatus. These two
+// mean the keychain is locked: errSecInteractionNotAllowed (no way to
+// prompt for the password) and errSecAuthFailed (the prompt was refused).
+const (
+	exitCodeInteractionNotAllowed = 36
+	exitCodeAuthFailed            = 51
+)
+
+// isLockedExit reports whether err is a `security` exit caused by a
+// locked keychain.
+func isLockedExit(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	code := exitErr.ExitCode()
+	return code == exitCodeInteractionNotAllowed || code == exitCodeAuthFailed
+}
+
 // execCommand is kept for the one case (delete) that needs *exec.Cmd for stderr + Run().
 // For new code, prefer the higher-level mockable functions below.
 var execCommand = exec.Command
@@ -41,6 +66,8 @@ var execSecretInput = secure.ExecWithSecretInput
 // GetSecretBytes retrieves a secret from the keychain as a byte slice
 // This is the more secure variant of GetSecret
 func GetSecretBytes(account, service string) ([]byte, error) {
+	defer timeOp("GetSecret")()
+
 	if account == "" {
 		user, err := getCurrentUser()
 		if err != nil {
@@ -62,6 +89,9 @@ func GetSecretBytes(account, service string) ([]byte, error) {
 		if errors.As(err, &exitErr) && exitErr.ExitCode() == exitCodeItemNotFound {
 			return nil, fmt.Errorf("%w for account %q and service %q", ErrNotFound, account, service)
 		}
+		if isLockedExit(err) {
+			return nil, fmt.Errorf("%w: reading account %q and service %q", ErrKeychainLocked, account, service)
+		}
 		return nil, fmt.Errorf("keychain read failed for account %q and service %q: %w", account, service, err)
 	}
 
@@ -74,6 +104,17 @@ func GetSecretBytes(account, service string) ([]byte, error) {
 		}
 	}
 
+	// Items stored with binary data (e.g. `add-generic-password -X`)
+	// come back from `-w` as a hex dump rather than the raw bytes.
+	if decoded, ok := decodeHexOutput(secret); ok {
+		if storedAsBinary(account, service) {
+			secure.SecureZeroBytes(secret)
+			secret = decoded
+		} else {
+			secure.SecureZeroBytes(decoded)
+		}
+	}
+
 	// Make a defensive copy to return
 	result := make([]byte, len(secret))
 	copy(result, secret)
@@ -84,6 +125,61 @@ func GetSecretBytes(account, service string) ([]byte, error) {
 	return result, nil
 }
 
+// decodeHexOutput reports whether out could be the hex dump `security -w`
+// prints for data that isn't printable text, and if so returns the decoded
+// bytes. `security` only hex-dumps when the stored bytes aren't printable,
+// so hex that decodes to printable text is taken to be the literal secret.
+// A text secret can still be lowercase hex of its own (an API token, say),
+// so callers confirm with storedAsBinary before using the decoded bytes.
+func decodeHexOutput(out []byte) ([]byte, bool) {
+	if len(out) == 0 || len(out)%2 != 0 {
+		return nil, false
+	}
+	for _, c := range out {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return nil, false
+		}
+	}
+
+	decoded := make([]byte, hex.DecodedLen(len(out)))
+	if _, err := hex.Decode(decoded, out); err != nil {
+		secure.SecureZeroBytes(decoded)
+		return nil, false
+	}
+	for _, c := range decoded {
+		if c < 0x20 || c > 0x7e {
+			return decoded, true
+		}
+	}
+	secure.SecureZeroBytes(decoded)
+	return nil, false
+}
+
+// storedAsBinary reports whether the item for account and service holds
+// binary data. With -g, `security` prints the password to stderr as
+// `password: 0x<HEX>  "..."` for binary data and `password: "..."` for
+// text. It is a variable so we can swap it out in tests.
+var storedAsBinary = func(account, service string) bool {
+	cmd := execCommand("security", "find-generic-password",
+		"-a", account,
+		"-s", service,
+		"-g",
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	defer secure.SecureZeroBytes(stderr.Bytes())
+	if err != nil {
+		return false
+	}
+	for line := range bytes.SplitSeq(stderr.Bytes(), []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("password: 0x")) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetSecretString retrieves a secret from the keychain as a string
 // This is provided for backward compatibility but is less secure
 // than GetSecretBytes
@@ -103,6 +199,19 @@ func GetSecretString(account, service string) (string, error) {
 // SetSecretBytes sets a byte slice secret in the keychain
 // This is the more secure variant of SetSecret
 func SetSecretBytes(account, service string, secret []byte) error {
+	return setSecretBytes(account, service, secret, true)
+}
+
+// SetSecretBytesUnindexed stores a secret without adding it to the metadata
+// index. The entry is readable by exact service key but won't appear in
+// -list until something indexes it (e.g. SetDescription).
+func SetSecretBytesUnindexed(account, service string, secret []byte) error {
+	return setSecretBytes(account, service, secret, false)
+}
+
+func setSecretBytes(account, service string, secret []byte, index bool) error {
+	defer timeOp("SetSecret")()
+
 	// Create a defensive copy to avoid mutating the caller's data
 	secretCopy := make([]byte, len(secret))
 	copy(secretCopy, secret)
@@ -138,9 +247,16 @@ func SetSecretBytes(account, service string, secret []byte) error {
 	// Provide the command via stdin
 	err := execSecretInput(cmd, []byte(addCmd+"\n"))
 	if err != nil {
+		if isLockedExit(err) {
+			return fmt.Errorf("%w: writing account %q and service %q", ErrKeychainLocked, account, service)
+		}
 		return fmt.Errorf("failed to set secret in keychain: %w", err)
 	}
 
+	if !index {
+		return nil
+	}
+
 	// Store in metadata system — required for ListEntries and DeleteEntry to find this entry
 	serviceType := getServicePrefix(service)
 	if err := StoreEntryMetadata(serviceType, service, account, service); err != nil {
@@ -163,6 +279,8 @@ func SetSecretString(account, service, secret string) error {
 // GetMFASerialBytes retrieves the MFA device serial number from keychain as bytes
 // This is more secure than GetMFASerial
 func GetMFASerialBytes(account, profile string) ([]byte, error) {
+	defer timeOp("GetMFASerial")()
+
 	if account == "" {
 		user, err := getCurrentUser()
 		if err != nil {
@@ -190,6 +308,9 @@ func GetMFASerialBytes(account, profile string) ([]byte, error) {
 		if errors.As(err, &exitErr) && exitErr.ExitCode() == exitCodeItemNotFound {
 			return nil, fmt.Errorf("%w for account %q and service %q", ErrNotFound, account, service)
 		}
+		if isLockedExit(err) {
+			return nil, fmt.Errorf("%w: reading account %q and service %q", ErrKeychainLocked, account, service)
+		}
 		return nil, fmt.Errorf("keychain read failed for account %q and service %q: %w", account, service, err)
 	}
 
@@ -205,6 +326,8 @@ func GetMFASerialBytes(account, profile string) ([]byte, error) {
 
 // ListEntries lists all entries for a given service prefix
 func ListEntries(servicePrefix string) ([]KeychainEntry, error) {
+	defer timeOp("ListEntries")()
+
 	// Use the metadata system to get entries - no fallback to insecure dump-keychain
 	metaEntries, err := LoadEntryMetadata(servicePrefix)
 	if err != nil {
@@ -226,8 +349,34 @@ func ListEntries(servicePrefix string) ([]KeychainEntry, error) {
 	return entries, nil
 }
 
+// List returns the metadata entries matching filter. Unlike ListEntries,
+// which matches one service type exactly, it can narrow by key prefix and
+// account in the same pass.
+func List(filter EntryFilter) ([]KeychainEntryMeta, error) {
+	defer timeOp("List")()
+
+	all, err := LoadAllEntryMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load entry metadata: %w", err)
+	}
+
+	var entries []KeychainEntryMeta
+	for _, meta := range all {
+		if !filter.Matches(meta) {
+			continue
+		}
+		if meta.ServiceType == "" {
+			meta.ServiceType = getServicePrefix(meta.Service)
+		}
+		entries = append(entries, meta)
+	}
+	return entries, nil
+}
+
 // DeleteEntry deletes an entry from the keychain
 func DeleteEntry(account, service string) error {
+	defer timeOp("DeleteEntry")()
+
 	if account == "" {
 		user, err := getCurrentUser()
 		if err != nil {
@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bashhack/sesh/internal/testutil"
 )
@@ -153,6 +154,43 @@ func TestGetSecretBytesNotFound(t *testing.T) {
 	}
 }
 
+// TestGetSecretBytesLocked uses the subprocess mock pattern (pattern 2)
+// for the same reason as TestGetSecretBytesNotFound.
+func TestGetSecretBytesLocked(t *testing.T) {
+	tests := map[string]struct {
+		exitCode   string
+		wantLocked bool
+	}{
+		"interaction not allowed": {exitCode: "36", wantLocked: true},
+		"unlock refused":          {exitCode: "51", wantLocked: true},
+		"other failure":           {exitCode: "1"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			orig := saveMocks()
+			defer orig.restore()
+
+			execCommand = func(command string, args ...string) *exec.Cmd {
+				cs := []string{"-test.run=TestHelperProcess", "--", command}
+				cs = append(cs, args...)
+				cmd := exec.Command(os.Args[0], cs...)
+				cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1", "MOCK_ERROR=1", "MOCK_EXIT_CODE=" + tc.exitCode}
+				return cmd
+			}
+			captureSecure = orig.captureSecure
+
+			_, err := GetSecretBytes("testuser", "test-service")
+			if err == nil {
+				t.Fatal("Expected error but got nil")
+			}
+			if got := errors.Is(err, ErrKeychainLocked); got != tc.wantLocked {
+				t.Errorf("errors.Is(%v, ErrKeychainLocked) = %v, want %v", err, got, tc.wantLocked)
+			}
+		})
+	}
+}
+
 func TestGetMFASerialSuccess(t *testing.T) {
 	orig := saveMocks()
 	defer orig.restore()
@@ -795,6 +833,7 @@ func TestHelperProcess(t *testing.T) {
 				}
 				os.Exit(exitCode)
 			}
+			fmt.Fprint(os.Stderr, os.Getenv("MOCK_STDERR"))
 			fmt.Print(os.Getenv("MOCK_OUTPUT"))
 			os.Exit(0)
 		}
@@ -812,3 +851,167 @@ func TestHelperProcess(t *testing.T) {
 		os.Exit(0)
 	}
 }
+
+func TestGetSecretBytes_HexEncodedOutput(t *testing.T) {
+	tests := map[string]struct {
+		service string
+		output  string
+		binary  bool
+		want    []byte
+	}{
+		"plain base32 secret": {
+			service: "sesh-totp/github",
+			output:  "JBSWY3DPEHPK3PXP\n",
+			want:    []byte("JBSWY3DPEHPK3PXP"),
+		},
+		"hex dump of binary data is decoded": {
+			service: "sesh-totp/github",
+			output:  "00ff10fe7f\n",
+			binary:  true,
+			want:    []byte{0x00, 0xff, 0x10, 0xfe, 0x7f},
+		},
+		"hex dump on aws service is decoded": {
+			service: "sesh-aws/default",
+			output:  "deadbeef",
+			binary:  true,
+			want:    []byte{0xde, 0xad, 0xbe, 0xef},
+		},
+		"hex dump on password service is decoded": {
+			service: "sesh-password/password/github",
+			output:  "00ff10fe",
+			binary:  true,
+			want:    []byte{0x00, 0xff, 0x10, 0xfe},
+		},
+		"hex dump under a custom prefix is decoded": {
+			service: "acme-totp/github",
+			output:  "00ff10fe",
+			binary:  true,
+			want:    []byte{0x00, 0xff, 0x10, 0xfe},
+		},
+		"hex that decodes to printable text is left alone": {
+			service: "sesh-totp/github",
+			output:  "4142434445",
+			want:    []byte("4142434445"),
+		},
+		"uppercase hex is not a security hex dump": {
+			service: "sesh-totp/github",
+			output:  "DEADBEEF",
+			want:    []byte("DEADBEEF"),
+		},
+		"odd-length hex is left alone": {
+			service: "sesh-totp/github",
+			output:  "abc",
+			want:    []byte("abc"),
+		},
+		"hex text secret is returned verbatim": {
+			service: "sesh-password/api_key/stripe",
+			output:  "00ff10fe",
+			want:    []byte("00ff10fe"),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			orig := saveMocks()
+			defer orig.restore()
+			origBinary := storedAsBinary
+			defer func() { storedAsBinary = origBinary }()
+
+			captureSecure = func(cmd *exec.Cmd) ([]byte, error) {
+				return []byte(tc.output), nil
+			}
+			storedAsBinary = func(_, _ string) bool { return tc.binary }
+
+			got, err := GetSecretBytes("testuser", tc.service)
+			if err != nil {
+				t.Fatalf("GetSecretBytes() unexpected error: %v", err)
+			}
+			if !bytes.Equal(got, tc.want) {
+				t.Errorf("GetSecretBytes() = %x, want %x", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStoredAsBinary(t *testing.T) {
+	tests := map[string]struct {
+		stderr string
+		fail   bool
+		want   bool
+	}{
+		"binary item":   {stderr: "password: 0x00FF10FE  \"\\000\\377\\020\\376\"\n", want: true},
+		"text item":     {stderr: "password: \"00ff10fe\"\n"},
+		"lookup failed": {fail: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			orig := saveMocks()
+			defer orig.restore()
+
+			execCommand = func(command string, args ...string) *exec.Cmd {
+				cs := []string{"-test.run=TestHelperProcess", "--", command}
+				cs = append(cs, args...)
+				cmd := exec.Command(os.Args[0], cs...)
+				cmd.Env = []string{"GO_WANT_HELPER_PROCESS=1", "MOCK_STDERR=" + tc.stderr}
+				if tc.fail {
+					cmd.Env = append(cmd.Env, "MOCK_ERROR=1")
+				}
+				return cmd
+			}
+
+			if got := storedAsBinary("testuser", "sesh-password/api_key/stripe"); got != tc.want {
+				t.Errorf("storedAsBinary() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetSecretBytes_DebugTiming(t *testing.T) {
+	orig := saveMocks()
+	defer orig.restore()
+	origNow := timeNow
+	defer func() { timeNow = origNow }()
+	defer SetDebugOutput(nil)
+
+	captureSecure = func(cmd *exec.Cmd) ([]byte, error) {
+		return []byte("test-secret"), nil
+	}
+
+	// Each clock read advances 820ms, so start→stop spans exactly one step.
+	clock := time.Unix(1_700_000_000, 0)
+	timeNow = func() time.Time {
+		clock = clock.Add(820 * time.Millisecond)
+		return clock
+	}
+
+	tests := map[string]struct {
+		debug   bool
+		wantLog string
+	}{
+		"debug enabled records timing":   {debug: true, wantLog: "keychain GetSecret took 820ms\n"},
+		"debug disabled records nothing": {debug: false, wantLog: ""},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if tc.debug {
+				SetDebugOutput(&buf)
+			} else {
+				SetDebugOutput(nil)
+			}
+
+			got, err := GetSecretBytes("testuser", "test-service")
+			if err != nil {
+				t.Fatalf("GetSecretBytes: %v", err)
+			}
+			if string(got) != "test-secret" {
+				t.Errorf("GetSecretBytes() = %q, want %q", got, "test-secret")
+			}
+			if buf.String() != tc.wantLog {
+				t.Errorf("debug output = %q, want %q", buf.String(), tc.wantLog)
+			}
+		})
+	}
+}
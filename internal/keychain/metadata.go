@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/klauspost/compress/zstd"
 
@@ -42,6 +44,17 @@ type KeychainEntryMeta struct {
 	ServiceType string    `json:"service_type"` // Service type (aws, totp, etc.)
 }
 
+// Entry returns meta as a KeychainEntry, dropping the service type.
+func (m KeychainEntryMeta) Entry() KeychainEntry {
+	return KeychainEntry{
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+		Service:     m.Service,
+		Account:     m.Account,
+		Description: m.Description,
+	}
+}
+
 // StoreEntryMetadata adds or updates metadata for a keychain entry
 func StoreEntryMetadata(servicePrefix, service, account, description string) error {
 	// Load all existing metadata - get all entries regardless of type
@@ -132,6 +145,8 @@ func LoadAllEntryMetadata() ([]KeychainEntryMeta, error) {
 }
 
 var loadAllEntryMetadataImpl = func() ([]KeychainEntryMeta, error) {
+	defer timeOp("LoadMetadata")()
+
 	metaService := constants.MetadataServiceName
 	metaAccount := "metadata"
 
@@ -149,8 +164,16 @@ var loadAllEntryMetadataImpl = func() ([]KeychainEntryMeta, error) {
 		return []KeychainEntryMeta{}, nil
 	}
 
-	// Get the base64 encoded data from direct command
-	b64Data := out.String()
+	return parseEntryMetadata(out.Bytes())
+}
+
+// parseEntryMetadata decodes the metadata item as printed by `security -w`:
+// base64 of zstd-compressed JSON, or plain JSON from older versions.
+// Entries that can't be real sesh keychain items are dropped rather than
+// listed; see validEntryMeta.
+func parseEntryMetadata(out []byte) ([]KeychainEntryMeta, error) {
+	// `security -w` ends its output with a newline
+	b64Data := string(bytes.TrimSpace(out))
 
 	// If there's no data, return empty slice
 	if b64Data == "" {
@@ -183,11 +206,38 @@ var loadAllEntryMetadataImpl = func() ([]KeychainEntryMeta, error) {
 		return nil, fmt.Errorf("failed to parse metadata: %w", err)
 	}
 
-	return entries, nil
+	valid := entries[:0]
+	for _, e := range entries {
+		if validEntryMeta(e) {
+			valid = append(valid, e)
+		}
+	}
+	return valid, nil
+}
+
+// validEntryMeta reports whether e names an item sesh could have stored:
+// a non-empty account and a service key in a sesh namespace, both printable
+// UTF-8. json.Unmarshal turns invalid UTF-8 into U+FFFD, so that rune marks
+// a key that was binary or mis-encoded when written; such a key couldn't be
+// read back with `security` anyway.
+func validEntryMeta(e KeychainEntryMeta) bool {
+	if !strings.HasPrefix(e.Service, "sesh-") || e.Account == "" {
+		return false
+	}
+	for _, s := range []string{e.Service, e.Account} {
+		for _, r := range s {
+			if r == utf8.RuneError || unicode.IsControl(r) {
+				return false
+			}
+		}
+	}
+	return true
 }
 
 // saveEntryMetadataImpl is the implementation of saveEntryMetadata - variable so it can be changed in tests
 var saveEntryMetadataImpl = func(entries []KeychainEntryMeta) error {
+	defer timeOp("SaveMetadata")()
+
 	metaService := constants.MetadataServiceName
 	metaAccount := "metadata"
 
@@ -1,6 +1,9 @@
 package keychain
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"slices"
 	"testing"
 )
 
@@ -40,3 +43,72 @@ func TestMetadataFunctions(t *testing.T) {
 		})
 	}
 }
+
+func TestParseEntryMetadata(t *testing.T) {
+	encode := func(t *testing.T, entries []KeychainEntryMeta) string {
+		t.Helper()
+		data, err := json.Marshal(entries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(zstdEncoder.EncodeAll(data, nil)) + "\n"
+	}
+	good := KeychainEntryMeta{Service: "sesh-totp/github", Account: "alice", ServiceType: "sesh-totp"}
+
+	tests := map[string]struct {
+		out         func(t *testing.T) string
+		wantService []string
+		wantErr     bool
+	}{
+		"compressed base64 with trailing newline": {
+			out:         func(t *testing.T) string { return encode(t, []KeychainEntryMeta{good}) },
+			wantService: []string{"sesh-totp/github"},
+		},
+		"plain JSON from older versions": {
+			out:         func(t *testing.T) string { return `[{"service":"sesh-aws/dev","account":"alice"}]` },
+			wantService: []string{"sesh-aws/dev"},
+		},
+		"empty output": {
+			out: func(t *testing.T) string { return "\n" },
+		},
+		"unrelated and malformed items are dropped": {
+			out: func(t *testing.T) string {
+				return encode(t, []KeychainEntryMeta{
+					good,
+					{Service: "www.example.com", Account: "alice"},
+					{Service: "sesh-totp/gitlab", Account: ""},
+					{Service: "sesh-totp/bin\x00ary", Account: "alice"},
+					{Service: "sesh-totp/ok", Account: "bad\nname"},
+					{Service: "sesh-mfa", Account: "alice"},
+				})
+			},
+			wantService: []string{"sesh-totp/github", "sesh-mfa"},
+		},
+		"invalid UTF-8 key is dropped": {
+			out: func(t *testing.T) string {
+				return `[{"service":"sesh-totp/caf` + "\xe9" + `","account":"alice"},{"service":"sesh-totp/cafe","account":"alice"}]`
+			},
+			wantService: []string{"sesh-totp/cafe"},
+		},
+		"binary data is an error": {
+			out:     func(t *testing.T) string { return "\x00\x01\x02<binary data>" },
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			entries, err := parseEntryMetadata([]byte(tc.out(t)))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseEntryMetadata() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Service)
+			}
+			if !slices.Equal(got, tc.wantService) {
+				t.Errorf("services = %q, want %q", got, tc.wantService)
+			}
+		})
+	}
+}
@@ -14,17 +14,22 @@ import (
 // SetSecretAtFunc and SetDescriptionAtFunc are present so MockProvider can
 // stand in for a keychain.TimestampedStore in tests; if either is wired,
 // the mock satisfies the type assertion `provider.(keychain.TimestampedStore)`.
+// SetSecretUnindexedFunc does the same for keychain.UnindexedStore, and
+// ListUnindexedFunc for keychain.UnindexedLister.
 type MockProvider struct {
-	GetSecretFunc         func(account, service string) ([]byte, error)
-	SetSecretFunc         func(account, service string, secret []byte) error
-	GetSecretStringFunc   func(account, service string) (string, error)
-	SetSecretStringFunc   func(account, service, secret string) error
-	GetMFASerialBytesFunc func(account, profile string) ([]byte, error)
-	ListEntriesFunc       func(service string) ([]keychain.KeychainEntry, error)
-	DeleteEntryFunc       func(account, service string) error
-	SetDescriptionFunc    func(service, account, description string) error
-	SetSecretAtFunc       func(account, service string, secret []byte, createdAt, updatedAt time.Time) error
-	SetDescriptionAtFunc  func(service, account, description string, updatedAt time.Time) error
+	GetSecretFunc          func(account, service string) ([]byte, error)
+	SetSecretFunc          func(account, service string, secret []byte) error
+	GetSecretStringFunc    func(account, service string) (string, error)
+	SetSecretStringFunc    func(account, service, secret string) error
+	GetMFASerialBytesFunc  func(account, profile string) ([]byte, error)
+	ListEntriesFunc        func(service string) ([]keychain.KeychainEntry, error)
+	ListFunc               func(filter keychain.EntryFilter) ([]keychain.KeychainEntryMeta, error)
+	DeleteEntryFunc        func(account, service string) error
+	SetDescriptionFunc     func(service, account, description string) error
+	SetSecretAtFunc        func(account, service string, secret []byte, createdAt, updatedAt time.Time) error
+	SetDescriptionAtFunc   func(service, account, description string, updatedAt time.Time) error
+	SetSecretUnindexedFunc func(account, service string, secret []byte) error
+	ListUnindexedFunc      func(servicePrefix string) ([]keychain.KeychainEntry, error)
 }
 
 // GetSecret implements the keychain.Provider interface
@@ -75,6 +80,16 @@ func (m *MockProvider) ListEntries(service string) ([]keychain.KeychainEntry, er
 	return m.ListEntriesFunc(service)
 }
 
+// List implements the keychain.Provider interface. Falls back to
+// filtering ListEntriesFunc's results when ListFunc is unset, so tests
+// that wire only ListEntriesFunc keep working.
+func (m *MockProvider) List(filter keychain.EntryFilter) ([]keychain.KeychainEntryMeta, error) {
+	if m.ListFunc == nil {
+		return keychain.ListFromEntries(m.ListEntries, filter)
+	}
+	return m.ListFunc(filter)
+}
+
 // DeleteEntry implements the keychain.Provider interface
 func (m *MockProvider) DeleteEntry(account, service string) error {
 	if m.DeleteEntryFunc == nil {
@@ -116,3 +131,24 @@ func (m *MockProvider) SetDescriptionAt(service, account, description string, up
 	}
 	return nil
 }
+
+// SetSecretUnindexed implements keychain.UnindexedStore. Falls back to
+// SetSecretFunc when SetSecretUnindexedFunc is unset.
+func (m *MockProvider) SetSecretUnindexed(account, service string, secret []byte) error {
+	if m.SetSecretUnindexedFunc != nil {
+		return m.SetSecretUnindexedFunc(account, service, secret)
+	}
+	if m.SetSecretFunc != nil {
+		return m.SetSecretFunc(account, service, secret)
+	}
+	return nil
+}
+
+// ListUnindexed implements keychain.UnindexedLister. Returns nothing when
+// ListUnindexedFunc is unset, as if every item were indexed.
+func (m *MockProvider) ListUnindexed(servicePrefix string) ([]keychain.KeychainEntry, error) {
+	if m.ListUnindexedFunc == nil {
+		return nil, nil
+	}
+	return m.ListUnindexedFunc(servicePrefix)
+}
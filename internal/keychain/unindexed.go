@@ -0,0 +1,122 @@
+package keychain
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bashhack/sesh/internal/constants"
+)
+
+// keychainItem is one generic password item's service and account, as
+// `security dump-keychain` prints them.
+type keychainItem struct {
+	Service string
+	Account string
+}
+
+// dumpKeychain runs `security dump-keychain` without -d, which prints each
+// item's attributes but never its secret. Mockable for tests.
+var dumpKeychain = func() ([]byte, error) {
+	defer timeOp("DumpKeychain")()
+	return execCommand("security", "dump-keychain").Output()
+}
+
+// ListUnindexed returns the items in the servicePrefix namespace (e.g.
+// "sesh-totp") that exist in the keychain but are missing from the
+// metadata index, so --list would not show them. This happens when
+// StoreEntryMetadata fails after the secret was stored, or with
+// --setup --no-metadata. Only attributes are read, never secrets.
+func ListUnindexed(servicePrefix string) ([]KeychainEntry, error) {
+	defer timeOp("ListUnindexed")()
+
+	out, err := dumpKeychain()
+	if err != nil {
+		return nil, fmt.Errorf("failed to enumerate keychain items: %w", err)
+	}
+	meta, err := LoadAllEntryMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load entry metadata: %w", err)
+	}
+
+	indexed := make(map[keychainItem]bool, len(meta))
+	for _, m := range meta {
+		indexed[keychainItem{Service: m.Service, Account: m.Account}] = true
+	}
+
+	var entries []KeychainEntry
+	seen := make(map[keychainItem]bool)
+	for _, item := range parseKeychainDump(out) {
+		if getServicePrefix(item.Service) != servicePrefix || item.Service == constants.MetadataServiceName {
+			continue
+		}
+		if indexed[item] || seen[item] {
+			continue
+		}
+		seen[item] = true
+		entries = append(entries, KeychainEntry{Service: item.Service, Account: item.Account})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Service != entries[j].Service {
+			return entries[i].Service < entries[j].Service
+		}
+		return entries[i].Account < entries[j].Account
+	})
+	return entries, nil
+}
+
+// parseKeychainDump extracts the generic password items from `security
+// dump-keychain` output. Each item starts with a "keychain:" line and lists
+// its attributes as `"svce"<blob>="value"`, or as hex when the value isn't
+// printable: `"svce"<blob>=0x7365... "se..."`.
+func parseKeychainDump(out []byte) []keychainItem {
+	var items []keychainItem
+	var current keychainItem
+	generic := false
+
+	flush := func() {
+		if generic && current.Service != "" {
+			items = append(items, current)
+		}
+		current, generic = keychainItem{}, false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "keychain:"):
+			flush()
+		case line == `class: "genp"`:
+			generic = true
+		case strings.HasPrefix(line, `"svce"<blob>=`):
+			current.Service = dumpValue(strings.TrimPrefix(line, `"svce"<blob>=`))
+		case strings.HasPrefix(line, `"acct"<blob>=`):
+			current.Account = dumpValue(strings.TrimPrefix(line, `"acct"<blob>=`))
+		}
+	}
+	flush()
+	return items
+}
+
+// dumpValue decodes one attribute value from dump-keychain output: a quoted
+// string, a hex blob, or <NULL>.
+func dumpValue(v string) string {
+	if hexPart, ok := strings.CutPrefix(v, "0x"); ok {
+		hexPart, _, _ = strings.Cut(hexPart, " ")
+		b, err := hex.DecodeString(hexPart)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+	if s, err := strconv.Unquote(v); err == nil {
+		return s
+	}
+	return strings.Trim(v, `"`)
+}
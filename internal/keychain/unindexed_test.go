@@ -0,0 +1,119 @@
+package keychain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const sampleDump = `keychain: "/Users/alice/Library/Keychains/login.keychain-db"
+version: 512
+class: "genp"
+attributes:
+    0x00000007 <blob>="sesh-totp/github"
+    "acct"<blob>="alice"
+    "svce"<blob>="sesh-totp/github"
+keychain: "/Users/alice/Library/Keychains/login.keychain-db"
+version: 512
+class: "genp"
+attributes:
+    "acct"<blob>="alice"
+    "svce"<blob>="sesh-totp/gitlab"
+keychain: "/Users/alice/Library/Keychains/login.keychain-db"
+version: 512
+class: "genp"
+attributes:
+    "acct"<blob>=0x616C696365  "alice"
+    "svce"<blob>=0x736573682D746F74702F626F78  "sesh-totp/box"
+keychain: "/Users/alice/Library/Keychains/login.keychain-db"
+version: 512
+class: "inet"
+attributes:
+    "acct"<blob>="alice"
+    "srvr"<blob>="sesh-totp/web"
+keychain: "/Users/alice/Library/Keychains/login.keychain-db"
+version: 512
+class: "genp"
+attributes:
+    "acct"<blob>="metadata"
+    "svce"<blob>="sesh-metadata"
+keychain: "/Users/alice/Library/Keychains/login.keychain-db"
+version: 512
+class: "genp"
+attributes:
+    "acct"<blob>="alice"
+    "svce"<blob>="sesh-password/password/github/alice"
+`
+
+func TestParseKeychainDump(t *testing.T) {
+	got := parseKeychainDump([]byte(sampleDump))
+	want := []keychainItem{
+		{Service: "sesh-totp/github", Account: "alice"},
+		{Service: "sesh-totp/gitlab", Account: "alice"},
+		{Service: "sesh-totp/box", Account: "alice"},
+		{Service: "sesh-metadata", Account: "metadata"},
+		{Service: "sesh-password/password/github/alice", Account: "alice"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseKeychainDump() =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestListUnindexed(t *testing.T) {
+	tests := map[string]struct {
+		prefix  string
+		meta    []KeychainEntryMeta
+		dumpErr error
+		want    []KeychainEntry
+		wantErr bool
+	}{
+		"secret present but no metadata entry": {
+			prefix: "sesh-totp",
+			meta: []KeychainEntryMeta{
+				{Service: "sesh-totp/github", Account: "alice", ServiceType: "sesh-totp"},
+			},
+			want: []KeychainEntry{
+				{Service: "sesh-totp/box", Account: "alice"},
+				{Service: "sesh-totp/gitlab", Account: "alice"},
+			},
+		},
+		"all indexed": {
+			prefix: "sesh-password",
+			meta: []KeychainEntryMeta{
+				{Service: "sesh-password/password/github/alice", Account: "alice", ServiceType: "sesh-password"},
+			},
+		},
+		"index for another account doesn't count": {
+			prefix: "sesh-password",
+			meta: []KeychainEntryMeta{
+				{Service: "sesh-password/password/github/alice", Account: "bob", ServiceType: "sesh-password"},
+			},
+			want: []KeychainEntry{
+				{Service: "sesh-password/password/github/alice", Account: "alice"},
+			},
+		},
+		"dump fails": {
+			prefix:  "sesh-totp",
+			dumpErr: errors.New("exit status 1"),
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			origDump, origLoadAll := dumpKeychain, loadAllEntryMetadataImpl
+			defer func() { dumpKeychain, loadAllEntryMetadataImpl = origDump, origLoadAll }()
+
+			dumpKeychain = func() ([]byte, error) { return []byte(sampleDump), tc.dumpErr }
+			loadAllEntryMetadataImpl = func() ([]KeychainEntryMeta, error) { return tc.meta, nil }
+
+			got, err := ListUnindexed(tc.prefix)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ListUnindexed() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ListUnindexed() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -16,12 +16,16 @@ import (
 )
 
 // Build constructs a service key from a namespace and variable segments.
-// It returns an error if any segment is empty or contains "/".
+// It returns an error if any segment is empty, only whitespace, or
+// contains "/".
 func Build(namespace string, segments ...string) (string, error) {
 	for _, seg := range segments {
 		if seg == "" {
 			return "", fmt.Errorf("keyformat: segment must not be empty")
 		}
+		if strings.TrimSpace(seg) == "" {
+			return "", fmt.Errorf("keyformat: segment %q must not be only whitespace", seg)
+		}
 		if strings.Contains(seg, "/") {
 			return "", fmt.Errorf("keyformat: segment %q must not contain '/'", seg)
 		}
@@ -56,6 +56,11 @@ func TestBuild(t *testing.T) {
 			segments:  []string{""},
 			wantErr:   true,
 		},
+		"whitespace segment is rejected": {
+			namespace: "sesh-totp",
+			segments:  []string{" \t"},
+			wantErr:   true,
+		},
 	}
 
 	for name, tc := range tests {
@@ -0,0 +1,186 @@
+package migration
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bashhack/sesh/internal/constants"
+	"github.com/bashhack/sesh/internal/keychain"
+	"github.com/bashhack/sesh/internal/keyformat"
+	"github.com/bashhack/sesh/internal/secure"
+)
+
+// Before service keys were namespaced with "/", AWS entries were stored
+// under flat keys: the TOTP secret as "sesh-mfa" (default profile) or
+// "sesh-mfa-<profile>", and the MFA serial as "sesh-mfa-serial" or
+// "sesh-mfa-serial-<profile>".
+const (
+	legacyAWSKey    = "sesh-mfa"
+	legacySerialKey = "sesh-mfa-serial"
+)
+
+// LegacyMove is one legacy entry and the key it belongs under now.
+type LegacyMove struct {
+	From    string
+	To      string
+	Account string
+}
+
+// LegacyResult reports what MigrateLegacy did.
+type LegacyResult struct {
+	Moved []LegacyMove
+	// Skipped entries were left in place because their new key already
+	// holds a secret, e.g. from a setup run after upgrading.
+	Skipped []LegacyMove
+	Errors  []string
+}
+
+// legacyTarget maps a legacy service key to its current key. ok is false
+// for keys that aren't legacy AWS keys.
+func legacyTarget(service string) (target string, serial bool, ok bool) {
+	var profile string
+	switch {
+	case service == legacySerialKey:
+		serial = true
+	case strings.HasPrefix(service, legacySerialKey+"-"):
+		serial, profile = true, strings.TrimPrefix(service, legacySerialKey+"-")
+	case service == legacyAWSKey:
+	case strings.HasPrefix(service, legacyAWSKey+"-"):
+		profile = strings.TrimPrefix(service, legacyAWSKey+"-")
+	default:
+		return "", false, false
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	prefix := constants.AWSServicePrefix
+	if serial {
+		prefix = constants.AWSServiceMFAPrefix
+	}
+	target, err := keyformat.Build(prefix, profile)
+	if err != nil {
+		return "", false, false
+	}
+	return target, serial, true
+}
+
+// PlanLegacy finds the legacy AWS entries in kc. Entries missing from the
+// listing index are still found for the default profile, whose keys are
+// fixed, by reading them under account.
+func PlanLegacy(kc keychain.Provider, account string) ([]LegacyMove, error) {
+	listed, err := kc.List(keychain.EntryFilter{ServicePrefix: legacyAWSKey})
+	if err != nil {
+		return nil, fmt.Errorf("list %s entries: %w", legacyAWSKey, err)
+	}
+
+	var plan []LegacyMove
+	seen := make(map[entryKey]bool)
+	add := func(service, acct string) {
+		target, _, ok := legacyTarget(service)
+		if !ok || seen[entryKey{service: service, account: acct}] {
+			return
+		}
+		seen[entryKey{service: service, account: acct}] = true
+		plan = append(plan, LegacyMove{From: service, To: target, Account: acct})
+	}
+
+	for _, e := range listed {
+		add(e.Service, e.Account)
+	}
+	for _, service := range []string{legacyAWSKey, legacySerialKey} {
+		if seen[entryKey{service: service, account: account}] {
+			continue
+		}
+		secret, err := kc.GetSecret(account, service)
+		switch {
+		case err == nil:
+			secure.SecureZeroBytes(secret)
+			add(service, account)
+		case errors.Is(err, keychain.ErrNotFound):
+		default:
+			return nil, fmt.Errorf("read %s: %w", service, err)
+		}
+	}
+
+	// Serials first, as in setup: an interrupted run then leaves no secret
+	// under a new key without its serial.
+	slices.SortFunc(plan, func(a, b LegacyMove) int {
+		_, aSerial, _ := legacyTarget(a.From)
+		_, bSerial, _ := legacyTarget(b.From)
+		if aSerial != bSerial {
+			if aSerial {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(strings.Compare(a.From, b.From), strings.Compare(a.Account, b.Account))
+	})
+	return plan, nil
+}
+
+// MigrateLegacy moves legacy AWS entries (secret, serial and listing
+// description) to the keys the current version reads. Each entry is
+// written under its new key before the legacy one is deleted, and an
+// entry whose new key is already taken is skipped, so running it again
+// is harmless.
+func MigrateLegacy(kc keychain.Provider, account string) (LegacyResult, error) {
+	var result LegacyResult
+
+	plan, err := PlanLegacy(kc, account)
+	if err != nil {
+		return result, err
+	}
+
+	descriptions := make(map[entryKey]string)
+	if listed, err := kc.List(keychain.EntryFilter{ServicePrefix: legacyAWSKey}); err == nil {
+		for _, e := range listed {
+			descriptions[entryKey{service: e.Service, account: e.Account}] = e.Description
+		}
+	}
+
+	for _, move := range plan {
+		existing, err := kc.GetSecret(move.Account, move.To)
+		switch {
+		case err == nil:
+			secure.SecureZeroBytes(existing)
+			result.Skipped = append(result.Skipped, move)
+			continue
+		case errors.Is(err, keychain.ErrNotFound):
+		default:
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: failed to check %s: %v", move.From, move.To, err))
+			continue
+		}
+
+		secret, err := kc.GetSecret(move.Account, move.From)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: failed to read: %v", move.From, err))
+			continue
+		}
+		err = kc.SetSecret(move.Account, move.To, secret)
+		secure.SecureZeroBytes(secret)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: failed to write %s: %v", move.From, move.To, err))
+			continue
+		}
+
+		// Legacy metadata often used the key itself as the description;
+		// the new key already gets that by default.
+		if desc := descriptions[entryKey{service: move.From, account: move.Account}]; desc != "" && desc != move.From {
+			if err := kc.SetDescription(move.To, move.Account, desc); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: migrated but description failed: %v", move.From, err))
+			}
+		}
+
+		if err := kc.DeleteEntry(move.Account, move.From); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: copied to %s but failed to remove the legacy entry: %v", move.From, move.To, err))
+			continue
+		}
+		result.Moved = append(result.Moved, move)
+	}
+
+	return result, nil
+}
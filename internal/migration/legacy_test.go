@@ -0,0 +1,162 @@
+package migration
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bashhack/sesh/internal/keychain"
+	"github.com/bashhack/sesh/internal/keychain/mocks"
+)
+
+// legacyProvider extends entryStore's mock with the prefix-matching List
+// and DeleteEntry that MigrateLegacy uses. unindexed services are
+// readable but missing from the listing, like entries with no metadata.
+func (s *entryStore) legacyProvider(unindexed ...string) *mocks.MockProvider {
+	p := s.provider()
+	p.ListFunc = func(filter keychain.EntryFilter) ([]keychain.KeychainEntryMeta, error) {
+		var entries []keychain.KeychainEntryMeta
+		for svc := range s.data {
+			if slices.Contains(unindexed, svc) || !strings.HasPrefix(svc, filter.ServicePrefix) {
+				continue
+			}
+			entries = append(entries, keychain.KeychainEntryMeta{
+				Service:     svc,
+				Account:     s.accounts[svc],
+				Description: s.descriptions[svc],
+			})
+		}
+		return entries, nil
+	}
+	p.DeleteEntryFunc = func(_, service string) error {
+		delete(s.data, service)
+		delete(s.descriptions, service)
+		return nil
+	}
+	return p
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	tests := map[string]struct {
+		setup       func(*entryStore)
+		unindexed   []string
+		wantMoved   []string // "from -> to"
+		wantSkipped []string
+		wantData    map[string]string
+		wantDesc    map[string]string
+	}{
+		"default profile secret and serial": {
+			setup: func(s *entryStore) {
+				s.add("sesh-mfa", []byte("SECRET"), "sesh-mfa")
+				s.add("sesh-mfa-serial", []byte("arn:aws:iam::123:mfa/user"), "")
+			},
+			wantMoved: []string{
+				"sesh-mfa-serial -> sesh-aws-serial/default",
+				"sesh-mfa -> sesh-aws/default",
+			},
+			wantData: map[string]string{
+				"sesh-aws/default":        "SECRET",
+				"sesh-aws-serial/default": "arn:aws:iam::123:mfa/user",
+			},
+		},
+		"named profiles keep their description": {
+			setup: func(s *entryStore) {
+				s.add("sesh-mfa-dev", []byte("DEV"), "Dev account")
+				s.add("sesh-mfa-serial-dev", []byte("arn:dev"), "")
+				s.add("sesh-mfa-prod", []byte("PROD"), "")
+			},
+			wantMoved: []string{
+				"sesh-mfa-serial-dev -> sesh-aws-serial/dev",
+				"sesh-mfa-dev -> sesh-aws/dev",
+				"sesh-mfa-prod -> sesh-aws/prod",
+			},
+			wantData: map[string]string{
+				"sesh-aws/dev":        "DEV",
+				"sesh-aws-serial/dev": "arn:dev",
+				"sesh-aws/prod":       "PROD",
+			},
+			wantDesc: map[string]string{"sesh-aws/dev": "Dev account"},
+		},
+		"unindexed default entries are still found": {
+			setup: func(s *entryStore) {
+				s.add("sesh-mfa", []byte("SECRET"), "")
+			},
+			unindexed: []string{"sesh-mfa"},
+			wantMoved: []string{"sesh-mfa -> sesh-aws/default"},
+			wantData:  map[string]string{"sesh-aws/default": "SECRET"},
+		},
+		"existing new entry is not overwritten": {
+			setup: func(s *entryStore) {
+				s.add("sesh-mfa", []byte("OLD"), "")
+				s.add("sesh-aws/default", []byte("NEW"), "")
+			},
+			wantSkipped: []string{"sesh-mfa -> sesh-aws/default"},
+			wantData: map[string]string{
+				"sesh-mfa":         "OLD",
+				"sesh-aws/default": "NEW",
+			},
+		},
+		"current entries are left alone": {
+			setup: func(s *entryStore) {
+				s.add("sesh-aws/dev", []byte("DEV"), "")
+				s.add("sesh-totp/github", []byte("GH"), "")
+			},
+			wantData: map[string]string{
+				"sesh-aws/dev":     "DEV",
+				"sesh-totp/github": "GH",
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			store := newEntryStore()
+			tc.setup(store)
+			kc := store.legacyProvider(tc.unindexed...)
+
+			result, err := MigrateLegacy(kc, "testuser")
+			if err != nil {
+				t.Fatalf("MigrateLegacy() error = %v", err)
+			}
+			if len(result.Errors) > 0 {
+				t.Fatalf("MigrateLegacy() errors = %v", result.Errors)
+			}
+			if got := formatMoves(result.Moved); !slices.Equal(got, tc.wantMoved) {
+				t.Errorf("moved = %v, want %v", got, tc.wantMoved)
+			}
+			if got := formatMoves(result.Skipped); !slices.Equal(got, tc.wantSkipped) {
+				t.Errorf("skipped = %v, want %v", got, tc.wantSkipped)
+			}
+			if len(store.data) != len(tc.wantData) {
+				t.Errorf("store has %d entries, want %d: %v", len(store.data), len(tc.wantData), store.data)
+			}
+			for service, want := range tc.wantData {
+				if got := string(store.data[service]); got != want {
+					t.Errorf("%s = %q, want %q", service, got, want)
+				}
+			}
+			for service, want := range tc.wantDesc {
+				if got := store.descriptions[service]; got != want {
+					t.Errorf("%s description = %q, want %q", service, got, want)
+				}
+			}
+
+			// A second run finds nothing left to move.
+			again, err := MigrateLegacy(kc, "testuser")
+			if err != nil {
+				t.Fatalf("second MigrateLegacy() error = %v", err)
+			}
+			if len(again.Moved) != 0 {
+				t.Errorf("second run moved %v, want nothing", formatMoves(again.Moved))
+			}
+		})
+	}
+}
+
+func formatMoves(moves []LegacyMove) []string {
+	var out []string
+	for _, m := range moves {
+		out = append(out, m.From+" -> "+m.To)
+	}
+	return out
+}
@@ -216,6 +216,9 @@ func (s *prefixMatchStore) ListEntries(prefix string) ([]keychain.KeychainEntry,
 	}
 	return out, nil
 }
+func (s *prefixMatchStore) List(filter keychain.EntryFilter) ([]keychain.KeychainEntryMeta, error) {
+	return keychain.ListFromEntries(s.ListEntries, filter)
+}
 func (s *prefixMatchStore) DeleteEntry(_, _ string) error       { return nil }
 func (s *prefixMatchStore) SetDescription(_, _, _ string) error { return nil }
 
@@ -392,7 +395,10 @@ func (d *bareDest) GetSecretString(_, _ string) (string, error)            { ret
 func (d *bareDest) SetSecretString(_, _, _ string) error                   { return nil }
 func (d *bareDest) GetMFASerialBytes(_, _ string) ([]byte, error)          { return nil, keychain.ErrNotFound }
 func (d *bareDest) ListEntries(_ string) ([]keychain.KeychainEntry, error) { return nil, nil }
-func (d *bareDest) DeleteEntry(_, _ string) error                          { return nil }
+func (d *bareDest) List(_ keychain.EntryFilter) ([]keychain.KeychainEntryMeta, error) {
+	return nil, nil
+}
+func (d *bareDest) DeleteEntry(_, _ string) error { return nil }
 func (d *bareDest) SetDescription(_, _, description string) error {
 	d.descriptionCalls++
 	d.lastDescription = description
@@ -0,0 +1,91 @@
+// Package notify shows desktop notifications and fires them ahead of a
+// credential expiry.
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	execLookPath = exec.LookPath
+	runtimeGOOS  = runtime.GOOS
+)
+
+// runCommand is a variable so we can swap it out in tests
+var runCommand = func(name string, args ...string) error {
+	return exec.Command(name, args...).Run()
+}
+
+// Notifier shows a desktop notification.
+type Notifier interface {
+	Notify(title, message string) error
+}
+
+// New returns the notifier for this platform: osascript on macOS,
+// notify-send on Linux. It fails if the platform's tool isn't installed.
+func New() (Notifier, error) {
+	var tool string
+	var n Notifier
+	switch runtimeGOOS {
+	case "darwin":
+		tool, n = "osascript", osascriptNotifier{}
+	case "linux":
+		tool, n = "notify-send", notifySendNotifier{}
+	default:
+		return nil, fmt.Errorf("desktop notifications are not supported on %s", runtimeGOOS)
+	}
+	if _, err := execLookPath(tool); err != nil {
+		return nil, fmt.Errorf("desktop notifications need %s, which was not found: %w", tool, err)
+	}
+	return n, nil
+}
+
+// osascriptNotifier uses AppleScript's display notification.
+type osascriptNotifier struct{}
+
+func (osascriptNotifier) Notify(title, message string) error {
+	script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))
+	if err := runCommand("osascript", "-e", script); err != nil {
+		return fmt.Errorf("osascript: %w", err)
+	}
+	return nil
+}
+
+// appleScriptString quotes s as an AppleScript string literal, which only
+// escapes backslashes and double quotes.
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// notifySendNotifier uses libnotify's notify-send.
+type notifySendNotifier struct{}
+
+func (notifySendNotifier) Notify(title, message string) error {
+	// -- keeps a title starting with "-" from being read as an option
+	if err := runCommand("notify-send", "--", title, message); err != nil {
+		return fmt.Errorf("notify-send: %w", err)
+	}
+	return nil
+}
+
+// ExpiryFromEnv reads the SESH_EXPIRY (Unix seconds) a subshell is given
+// from env, a list of KEY=value pairs.
+func ExpiryFromEnv(env []string) (time.Time, bool) {
+	for _, kv := range env {
+		raw, ok := strings.CutPrefix(kv, "SESH_EXPIRY=")
+		if !ok {
+			continue
+		}
+		secs, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || secs <= 0 {
+			return time.Time{}, false
+		}
+		return time.Unix(secs, 0), true
+	}
+	return time.Time{}, false
+}
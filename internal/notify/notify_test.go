@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	tests := map[string]struct {
+		goos     string
+		missing  bool
+		wantTool string
+		wantErr  bool
+	}{
+		"macOS":            {goos: "darwin", wantTool: "osascript"},
+		"linux":            {goos: "linux", wantTool: "notify-send"},
+		"linux without it": {goos: "linux", missing: true, wantErr: true},
+		"unsupported":      {goos: "windows", wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			origGOOS, origLookPath, origRun := runtimeGOOS, execLookPath, runCommand
+			defer func() { runtimeGOOS, execLookPath, runCommand = origGOOS, origLookPath, origRun }()
+
+			runtimeGOOS = tc.goos
+			execLookPath = func(file string) (string, error) {
+				if tc.missing {
+					return "", errors.New("not found")
+				}
+				return "/usr/bin/" + file, nil
+			}
+			var ran string
+			runCommand = func(name string, args ...string) error {
+				ran = name
+				return nil
+			}
+
+			n, err := New()
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("New() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if err := n.Notify("sesh", "hello"); err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+			if ran != tc.wantTool {
+				t.Errorf("ran %q, want %q", ran, tc.wantTool)
+			}
+		})
+	}
+}
+
+func TestOsascriptNotifier_Quoting(t *testing.T) {
+	origRun := runCommand
+	defer func() { runCommand = origRun }()
+
+	var got []string
+	runCommand = func(name string, args ...string) error {
+		got = append([]string{name}, args...)
+		return nil
+	}
+
+	if err := (osascriptNotifier{}).Notify(`say "hi"`, `back\slash`); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"osascript", "-e", `display notification "back\\slash" with title "say \"hi\""`}
+	if !slices.Equal(got, want) {
+		t.Errorf("ran %q, want %q", got, want)
+	}
+}
+
+func TestExpiryFromEnv(t *testing.T) {
+	tests := map[string]struct {
+		env    []string
+		want   time.Time
+		wantOK bool
+	}{
+		"present":  {env: []string{"HOME=/x", "SESH_EXPIRY=1900000000"}, want: time.Unix(1900000000, 0), wantOK: true},
+		"missing":  {env: []string{"HOME=/x"}},
+		"invalid":  {env: []string{"SESH_EXPIRY=soon"}},
+		"not unix": {env: []string{"SESH_EXPIRY=0"}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := ExpiryFromEnv(tc.env)
+			if ok != tc.wantOK || !got.Equal(tc.want) {
+				t.Errorf("ExpiryFromEnv() = (%v, %v), want (%v, %v)", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
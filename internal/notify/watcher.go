@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultLead is how long before expiry the watcher notifies when no lead
+// time is given.
+const DefaultLead = 2 * time.Minute
+
+// Watcher sends one notification Lead before an expiry.
+type Watcher struct {
+	Notifier Notifier
+	Lead     time.Duration
+	// Now and After are the clock, swappable in tests.
+	Now   func() time.Time
+	After func(time.Duration) <-chan time.Time
+}
+
+// NewWatcher returns a Watcher on the real clock.
+func NewWatcher(n Notifier, lead time.Duration) *Watcher {
+	return &Watcher{Notifier: n, Lead: lead, Now: time.Now, After: time.After}
+}
+
+// delay returns how long to wait before notifying. Inside the lead window
+// that is zero; once expiry has passed there is nothing to warn about.
+func (w *Watcher) delay(expiry time.Time) (time.Duration, bool) {
+	now := w.Now()
+	if !now.Before(expiry) {
+		return 0, false
+	}
+	return max(expiry.Add(-w.Lead).Sub(now), 0), true
+}
+
+// Watch waits until Lead before expiry and then notifies, naming what
+// expires in label (e.g. "aws"). It returns early, without notifying, when
+// stop is closed, and returns the notifier's error if it fails.
+func (w *Watcher) Watch(expiry time.Time, label string, stop <-chan struct{}) error {
+	d, ok := w.delay(expiry)
+	if !ok {
+		return nil
+	}
+	select {
+	case <-stop:
+		return nil
+	case <-w.After(d):
+	}
+
+	left := max(expiry.Sub(w.Now()), 0).Round(time.Second)
+	return w.Notifier.Notify("sesh", fmt.Sprintf("%s credentials expire in %s (at %s)", label, left, expiry.Format("15:04")))
+}
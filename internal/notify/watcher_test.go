@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type mockNotifier struct {
+	calls   []string
+	titles  []string
+	failure error
+}
+
+func (m *mockNotifier) Notify(title, message string) error {
+	m.titles = append(m.titles, title)
+	m.calls = append(m.calls, message)
+	return m.failure
+}
+
+func TestWatcher_Watch(t *testing.T) {
+	base := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		expiry      time.Time
+		lead        time.Duration
+		stopFirst   bool
+		failure     error
+		wantWait    time.Duration
+		wantWaited  bool
+		wantMessage string
+		wantErr     bool
+	}{
+		"waits until lead before expiry": {
+			expiry:      base.Add(time.Hour),
+			lead:        2 * time.Minute,
+			wantWait:    58 * time.Minute,
+			wantWaited:  true,
+			wantMessage: "aws credentials expire in 2m0s (at 13:00)",
+		},
+		"inside the lead window fires at once": {
+			expiry:      base.Add(30 * time.Second),
+			lead:        2 * time.Minute,
+			wantWait:    0,
+			wantWaited:  true,
+			wantMessage: "aws credentials expire in 30s (at 12:00)",
+		},
+		"already expired never fires": {
+			expiry: base.Add(-time.Minute),
+			lead:   2 * time.Minute,
+		},
+		"stopped before the lead time": {
+			expiry:     base.Add(time.Hour),
+			lead:       2 * time.Minute,
+			stopFirst:  true,
+			wantWait:   58 * time.Minute,
+			wantWaited: true,
+		},
+		"notifier error is returned": {
+			expiry:      base.Add(time.Hour),
+			lead:        5 * time.Minute,
+			failure:     errors.New("no display"),
+			wantWait:    55 * time.Minute,
+			wantWaited:  true,
+			wantMessage: "aws credentials expire in 5m0s (at 13:00)",
+			wantErr:     true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			now := base
+			n := &mockNotifier{failure: tc.failure}
+			stop := make(chan struct{})
+			var waited []time.Duration
+
+			w := &Watcher{
+				Notifier: n,
+				Lead:     tc.lead,
+				Now:      func() time.Time { return now },
+				After: func(d time.Duration) <-chan time.Time {
+					waited = append(waited, d)
+					ch := make(chan time.Time, 1)
+					if tc.stopFirst {
+						close(stop)
+						return ch // never fires
+					}
+					now = now.Add(d)
+					ch <- now
+					return ch
+				},
+			}
+
+			err := w.Watch(tc.expiry, "aws", stop)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Watch() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if tc.wantWaited {
+				if len(waited) != 1 || waited[0] != tc.wantWait {
+					t.Errorf("waited %v, want [%s]", waited, tc.wantWait)
+				}
+			} else if len(waited) != 0 {
+				t.Errorf("waited %v, want no wait", waited)
+			}
+
+			if tc.wantMessage == "" {
+				if len(n.calls) != 0 {
+					t.Errorf("notified %q, want no notification", n.calls)
+				}
+				return
+			}
+			if len(n.calls) != 1 || n.calls[0] != tc.wantMessage || n.titles[0] != "sesh" {
+				t.Errorf("notified %q (titles %q), want one %q", n.calls, n.titles, tc.wantMessage)
+			}
+		})
+	}
+}
@@ -213,7 +213,7 @@ func (m *Manager) Import(r io.Reader, opts ImportOptions) (ImportResult, error)
 			continue
 		}
 		if !validEntryTypes[e.Type] {
-			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s: invalid entry type %q", e.Service, e.Username, e.Type))
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s: invalid entry type %q", e.Service, e.Username, string(e.Type)))
 			continue
 		}
 
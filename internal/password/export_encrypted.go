@@ -123,36 +123,66 @@ func (m *Manager) ExportEncrypted(w io.Writer, opts ExportOptions, password []by
 
 // ImportEncrypted reads a password-encrypted export from r and imports it.
 func (m *Manager) ImportEncrypted(r io.Reader, opts ImportOptions, password []byte) (ImportResult, error) {
+	payload, err := decryptEnvelope(r, password)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	// Zeroes the JSON envelope buffer once Import returns, but the parsed
+	// entry secrets land in immutable Go strings inside the unmarshalled
+	// structs and outlive this defer — same caveat as elsewhere in the
+	// codebase. See internal/secure/memory.go for the broader context.
+	defer secure.SecureZeroBytes(payload)
+
+	forwardOpts := opts
+	forwardOpts.Format = FormatJSON
+	return m.Import(bytes.NewReader(payload), forwardOpts)
+}
+
+// DecryptExport reads a password-encrypted export from r and returns its
+// entries without importing them, for callers that only need the secrets
+// in memory (the emergency store).
+func DecryptExport(r io.Reader, password []byte) ([]ExportEntry, error) {
+	payload, err := decryptEnvelope(r, password)
+	if err != nil {
+		return nil, err
+	}
+	defer secure.SecureZeroBytes(payload)
+	return readJSON(bytes.NewReader(payload))
+}
+
+// decryptEnvelope validates the envelope read from r and returns its
+// decrypted JSON payload. The caller must zero the result.
+func decryptEnvelope(r io.Reader, password []byte) ([]byte, error) {
 	if len(password) == 0 {
-		return ImportResult{}, fmt.Errorf("password cannot be empty")
+		return nil, fmt.Errorf("password cannot be empty")
 	}
 
 	var envelope EncryptedEnvelope
 	if err := json.NewDecoder(r).Decode(&envelope); err != nil {
-		return ImportResult{}, fmt.Errorf("read envelope: %w", err)
+		return nil, fmt.Errorf("read envelope: %w", err)
 	}
 
 	if envelope.Version != encryptedExportVersion {
-		return ImportResult{}, fmt.Errorf("unsupported envelope version %d (expected %d)", envelope.Version, encryptedExportVersion)
+		return nil, fmt.Errorf("unsupported envelope version %d (expected %d)", envelope.Version, encryptedExportVersion)
 	}
 	if envelope.Algorithm != "argon2id" {
-		return ImportResult{}, fmt.Errorf("unsupported algorithm %q", envelope.Algorithm)
+		return nil, fmt.Errorf("unsupported algorithm %q", envelope.Algorithm)
 	}
 	if err := validateEncryptedExportParams(envelope.Params); err != nil {
-		return ImportResult{}, err
+		return nil, err
 	}
 
 	salt, err := base64.StdEncoding.DecodeString(envelope.Salt)
 	if err != nil {
-		return ImportResult{}, fmt.Errorf("decode salt: %w", err)
+		return nil, fmt.Errorf("decode salt: %w", err)
 	}
 	if len(salt) < 16 {
-		return ImportResult{}, fmt.Errorf("envelope salt too short: %d bytes (min 16)", len(salt))
+		return nil, fmt.Errorf("envelope salt too short: %d bytes (min 16)", len(salt))
 	}
 
 	ciphertext, err := base64.StdEncoding.DecodeString(envelope.Ciphertext)
 	if err != nil {
-		return ImportResult{}, fmt.Errorf("decode ciphertext: %w", err)
+		return nil, fmt.Errorf("decode ciphertext: %w", err)
 	}
 
 	p := envelope.Params
@@ -161,17 +191,9 @@ func (m *Manager) ImportEncrypted(r io.Reader, opts ImportOptions, password []by
 
 	payload, err := gcmOpen(key, ciphertext)
 	if err != nil {
-		return ImportResult{}, fmt.Errorf("wrong password or corrupted export: %w", err)
+		return nil, fmt.Errorf("wrong password or corrupted export: %w", err)
 	}
-	// Zeroes the JSON envelope buffer once Import returns, but the parsed
-	// entry secrets land in immutable Go strings inside the unmarshalled
-	// structs and outlive this defer — same caveat as elsewhere in the
-	// codebase. See internal/secure/memory.go for the broader context.
-	defer secure.SecureZeroBytes(payload)
-
-	forwardOpts := opts
-	forwardOpts.Format = FormatJSON
-	return m.Import(bytes.NewReader(payload), forwardOpts)
+	return payload, nil
 }
 
 // gcmSeal returns nonce || ciphertext || tag.
@@ -84,6 +84,10 @@ func (s *inMemoryStore) ListEntries(prefix string) ([]keychain.KeychainEntry, er
 	return out, nil
 }
 
+func (s *inMemoryStore) List(filter keychain.EntryFilter) ([]keychain.KeychainEntryMeta, error) {
+	return keychain.ListFromEntries(s.ListEntries, filter)
+}
+
 func (s *inMemoryStore) DeleteEntry(account, service string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
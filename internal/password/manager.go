@@ -30,6 +30,23 @@ const (
 	EntryTypeNote EntryType = "secure_note"
 )
 
+// entryTypeNames are the display names for listings and search results.
+var entryTypeNames = map[EntryType]string{
+	EntryTypePassword: "Password",
+	EntryTypeAPIKey:   "API Key",
+	EntryTypeTOTP:     "TOTP",
+	EntryTypeNote:     "Secure Note",
+}
+
+// String returns the display name of t, such as "API Key". Unknown types
+// are returned as is, so they still show up in messages.
+func (t EntryType) String() string {
+	if name, ok := entryTypeNames[t]; ok {
+		return name
+	}
+	return string(t)
+}
+
 var validEntryTypes = map[EntryType]bool{
 	EntryTypePassword: true,
 	EntryTypeAPIKey:   true,
@@ -132,9 +149,9 @@ func (m *Manager) StorePassword(service, username string, password []byte, entry
 	// Store metadata for organization. Use the timestamp-aware path when
 	// available so the description write doesn't clobber the preserved
 	// updated_at.
-	description := fmt.Sprintf("%s for %s", entryType, service)
+	description := fmt.Sprintf("%s for %s", string(entryType), service)
 	if username != "" {
-		description = fmt.Sprintf("%s (%s) for %s", entryType, username, service)
+		description = fmt.Sprintf("%s (%s) for %s", string(entryType), username, service)
 	}
 
 	if timestampAware && preserveTimestamps {
@@ -227,19 +244,18 @@ func (m *Manager) GetTOTPParams(service, username string) totp.Params {
 		return totp.Params{}
 	}
 
-	entries, err := m.keychain.ListEntries(serviceKey)
-	if err != nil || len(entries) == 0 {
+	entries, err := m.keychain.List(keychain.EntryFilter{ServicePrefix: serviceKey, Account: m.user})
+	if err != nil {
 		return totp.Params{}
 	}
-	// ListEntries is a prefix query in the SQLite backend — verify the
-	// first entry matches the exact (service, account) we read the secret
-	// under, so neither a prefix sibling nor a cross-user entry can spoof
-	// the params.
-	if entries[0].Service != serviceKey || entries[0].Account != m.user {
-		return totp.Params{}
+	// The filter matches by prefix — require the exact key we read the
+	// secret under, so a prefix sibling can't spoof the params.
+	for _, e := range entries {
+		if e.Service == serviceKey {
+			return totp.ParseParams(e.Description)
+		}
 	}
-
-	return totp.ParseParams(entries[0].Description)
+	return totp.Params{}
 }
 
 // GenerateTOTPCode generates a TOTP code for a stored secret,
@@ -263,13 +279,17 @@ func (m *Manager) GenerateTOTPCode(service, username string) (string, error) {
 
 // ListEntries returns all password entries
 func (m *Manager) ListEntries() ([]Entry, error) {
-	keychainEntries, err := m.keychain.ListEntries(constants.PasswordServicePrefix)
+	keychainEntries, err := m.keychain.List(keychain.EntryFilter{
+		ServiceType: constants.PasswordServicePrefix,
+		Account:     m.user,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list entries: %w", err)
 	}
 
 	entries := make([]Entry, 0, len(keychainEntries))
-	for _, kEntry := range keychainEntries {
+	for _, meta := range keychainEntries {
+		kEntry := meta.Entry()
 		entry, err := m.parseEntry(&kEntry)
 		if err != nil {
 			// Skip invalid entries but don't fail completely
@@ -298,15 +318,14 @@ func (m *Manager) EntryExists(service, username string, entryType EntryType) (bo
 	if err != nil {
 		return false, fmt.Errorf("failed to build service key: %w", err)
 	}
-	entries, err := m.keychain.ListEntries(serviceKey)
+	entries, err := m.keychain.List(keychain.EntryFilter{ServicePrefix: serviceKey, Account: m.user})
 	if err != nil {
 		return false, fmt.Errorf("failed to list entries: %w", err)
 	}
-	// ListEntries is a prefix query in the SQLite backend — require exact
-	// (service, account) match so a sibling like "github/alice" vs
-	// "github/alicia" or a cross-user entry can't register as a hit.
+	// The filter matches by prefix — require the exact key so a sibling
+	// like "github/alice" vs "github/alicia" can't register as a hit.
 	for _, e := range entries {
-		if e.Service == serviceKey && e.Account == m.user {
+		if e.Service == serviceKey {
 			return true, nil
 		}
 	}
@@ -451,8 +470,13 @@ func (m *Manager) DeleteEntry(service, username string, entryType EntryType) err
 }
 
 // generateServiceKey creates a unique service key for keychain storage.
-// Format: sesh-password/{type}/{service}[/{username}]
 func (m *Manager) generateServiceKey(service, username string, entryType EntryType) (string, error) {
+	return ServiceKey(service, username, entryType)
+}
+
+// ServiceKey returns the keychain service key an entry is stored under.
+// Format: sesh-password/{type}/{service}[/{username}]
+func ServiceKey(service, username string, entryType EntryType) (string, error) {
 	segments := []string{string(entryType), service}
 	if username != "" {
 		segments = append(segments, username)
@@ -1126,3 +1126,24 @@ func TestGenerateTOTPCode(t *testing.T) {
 		})
 	}
 }
+
+func TestEntryType_String(t *testing.T) {
+	tests := map[string]struct {
+		entryType EntryType
+		want      string
+	}{
+		"password":    {entryType: EntryTypePassword, want: "Password"},
+		"api key":     {entryType: EntryTypeAPIKey, want: "API Key"},
+		"totp":        {entryType: EntryTypeTOTP, want: "TOTP"},
+		"secure note": {entryType: EntryTypeNote, want: "Secure Note"},
+		"unknown":     {entryType: "ssh_key", want: "ssh_key"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.entryType.String(); got != tc.want {
+				t.Errorf("String() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+// Package prompt bounds how long an interactive read waits for input, so a
+// prompt left unanswered in a script fails instead of hanging.
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout is returned when no input arrived before the timeout.
+var ErrTimeout = errors.New("timed out waiting for input")
+
+// timeAfter is a variable so we can swap it out in tests
+var timeAfter = time.After
+
+type result[T any] struct {
+	value T
+	err   error
+}
+
+// WithTimeout runs read and returns what it returns, or an error wrapping
+// ErrTimeout once d has passed. A d of zero or less waits forever.
+//
+// A read can't be interrupted, so on timeout it is abandoned: its
+// goroutine stays blocked until input arrives or the process exits, and
+// whatever it reads later is dropped. Callers are expected to give up on
+// the error rather than prompt again on the same input.
+func WithTimeout[T any](d time.Duration, read func() (T, error)) (T, error) {
+	if d <= 0 {
+		return read()
+	}
+
+	done := make(chan result[T], 1)
+	go func() {
+		v, err := read()
+		done <- result[T]{value: v, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-timeAfter(d):
+		var zero T
+		return zero, fmt.Errorf("%w after %s", ErrTimeout, d)
+	}
+}
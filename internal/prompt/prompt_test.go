@@ -0,0 +1,84 @@
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingReader never returns, like a terminal nobody is typing into.
+type blockingReader struct{ unblock chan struct{} }
+
+func (b blockingReader) Read(p []byte) (int, error) {
+	<-b.unblock
+	return 0, io.EOF
+}
+
+func TestWithTimeout(t *testing.T) {
+	tests := map[string]struct {
+		reader  func(t *testing.T) io.Reader
+		timeout time.Duration
+		want    string
+		wantErr error
+	}{
+		"input before the timeout": {
+			reader:  func(t *testing.T) io.Reader { return strings.NewReader("123456\n") },
+			timeout: time.Minute,
+			want:    "123456\n",
+		},
+		"no input times out": {
+			reader: func(t *testing.T) io.Reader {
+				b := blockingReader{unblock: make(chan struct{})}
+				t.Cleanup(func() { close(b.unblock) })
+				return b
+			},
+			timeout: 10 * time.Millisecond,
+			wantErr: ErrTimeout,
+		},
+		"zero timeout waits for the read": {
+			reader: func(t *testing.T) io.Reader { return strings.NewReader("\n") },
+			want:   "\n",
+		},
+		"read errors pass through": {
+			reader:  func(t *testing.T) io.Reader { return strings.NewReader("") },
+			timeout: time.Minute,
+			wantErr: io.EOF,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := bufio.NewReader(tc.reader(t))
+			got, err := WithTimeout(tc.timeout, func() (string, error) {
+				return r.ReadString('\n')
+			})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("WithTimeout() error = %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("WithTimeout() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithTimeout_Message(t *testing.T) {
+	origAfter := timeAfter
+	defer func() { timeAfter = origAfter }()
+	fired := make(chan time.Time, 1)
+	fired <- time.Time{}
+	timeAfter = func(time.Duration) <-chan time.Time { return fired }
+
+	block := make(chan struct{})
+	defer close(block)
+	_, err := WithTimeout(30*time.Second, func() (int, error) {
+		<-block
+		return 0, nil
+	})
+	if err == nil || err.Error() != "timed out waiting for input after 30s" {
+		t.Errorf("error = %v, want %q", err, "timed out waiting for input after 30s")
+	}
+}
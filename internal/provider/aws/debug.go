@@ -0,0 +1,38 @@
+package aws
+
+import (
+	"fmt"
+	"io"
+)
+
+// debugOutput receives the --debug output when set (see SetDebugOutput).
+// nil disables it.
+var debugOutput io.Writer
+
+// SetDebugOutput sends the provider's --debug output to w. That is two
+// things: the "aws retry: ..." trace of the decisions sessionTokenFromSecret
+// makes between the current, next and future window codes, and progress
+// notes such as the keychain read that are noise at the default verbosity.
+// Pass nil to disable both. It is meant to be set once at startup, not
+// toggled concurrently.
+func SetDebugOutput(w io.Writer) {
+	debugOutput = w
+}
+
+// debugf writes one retry trace line. Codes themselves are never traced.
+func debugf(format string, args ...any) {
+	w := debugOutput
+	if w == nil {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "aws retry: "+format+"\n", args...) //nolint:errcheck // best-effort diagnostics
+}
+
+// debugNote writes a progress note, as is, only under --debug.
+func debugNote(msg string) {
+	w := debugOutput
+	if w == nil {
+		return
+	}
+	_, _ = fmt.Fprintln(w, msg) //nolint:errcheck // best-effort diagnostics
+}
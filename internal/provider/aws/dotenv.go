@@ -0,0 +1,89 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bashhack/sesh/internal/atomicfile"
+)
+
+// dotenvPrefix returns the variable prefix --append uses for a profile in
+// a dotenv file: the profile name upper-cased, with anything other than
+// letters and digits turned into "_", then "_". "dev" gives DEV_ and
+// "my-team" MY_TEAM_; the default profile is DEFAULT_.
+func dotenvPrefix(profile string) string {
+	if profile == "" {
+		profile = "default"
+	}
+	b := []byte(strings.ToUpper(profile))
+	for i, c := range b {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			b[i] = '_'
+		}
+	}
+	return string(b) + "_"
+}
+
+// renderCredentialsDotenv renders session variables as KEY=value lines,
+// each key prefixed, plus AWS_CREDENTIAL_EXPIRATION for the expiry. STS
+// values are base64 and need no quoting.
+func renderCredentialsDotenv(prefix string, vars map[string]string, expiry time.Time) string {
+	all := make(map[string]string, len(vars)+1)
+	for k, v := range vars {
+		all[k] = v
+	}
+	all["AWS_CREDENTIAL_EXPIRATION"] = expiry.UTC().Format(time.RFC3339)
+
+	keys := make([]string, 0, len(all))
+	for k := range all {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s%s=%s\n", prefix, k, all[k])
+	}
+	return b.String()
+}
+
+// mergeDotenv returns content with the lines that set any of block's
+// variables removed and block appended, so re-running for a profile
+// replaces its variables and leaves the other profiles' alone.
+func mergeDotenv(content, block string) string {
+	replace := map[string]bool{}
+	for line := range strings.Lines(block) {
+		if key, _, ok := strings.Cut(line, "="); ok {
+			replace[key] = true
+		}
+	}
+
+	var out strings.Builder
+	for line := range strings.Lines(content) {
+		key, _, ok := strings.Cut(line, "=")
+		if ok && replace[strings.TrimPrefix(strings.TrimSpace(key), "export ")] {
+			continue
+		}
+		out.WriteString(line)
+	}
+	if out.Len() > 0 && !strings.HasSuffix(out.String(), "\n") {
+		out.WriteString("\n")
+	}
+	out.WriteString(block)
+	return out.String()
+}
+
+// appendDotenv merges block into the dotenv file at path, creating it if
+// needed. The file is written with 0600 perms.
+func appendDotenv(path, block string) error {
+	existing, err := os.ReadFile(path) //nolint:gosec // path is the user's explicit --output-file
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	return atomicfile.WriteString(path, mergeDotenv(string(existing), block))
+}
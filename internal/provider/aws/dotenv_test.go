@@ -0,0 +1,188 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bashhack/sesh/internal/aws"
+	awsMocks "github.com/bashhack/sesh/internal/aws/mocks"
+	keychainMocks "github.com/bashhack/sesh/internal/keychain/mocks"
+	"github.com/bashhack/sesh/internal/provider"
+	"github.com/bashhack/sesh/internal/testutil"
+	totpMocks "github.com/bashhack/sesh/internal/totp/mocks"
+)
+
+func TestDotenvPrefix(t *testing.T) {
+	tests := map[string]struct {
+		profile string
+		want    string
+	}{
+		"default profile": {profile: "", want: "DEFAULT_"},
+		"simple name":     {profile: "dev", want: "DEV_"},
+		"punctuation":     {profile: "my-team.prod", want: "MY_TEAM_PROD_"},
+		"digits kept":     {profile: "acct2", want: "ACCT2_"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := dotenvPrefix(tc.profile); got != tc.want {
+				t.Errorf("dotenvPrefix(%q) = %q, want %q", tc.profile, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRenderCredentialsDotenv(t *testing.T) {
+	vars := map[string]string{
+		"AWS_ACCESS_KEY_ID":     "AKIA123",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"AWS_SESSION_TOKEN":     "token",
+	}
+	expiry := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	got := renderCredentialsDotenv("DEV_", vars, expiry)
+	want := "DEV_AWS_ACCESS_KEY_ID=AKIA123\n" +
+		"DEV_AWS_CREDENTIAL_EXPIRATION=2026-01-02T03:04:05Z\n" +
+		"DEV_AWS_SECRET_ACCESS_KEY=secret\n" +
+		"DEV_AWS_SESSION_TOKEN=token\n"
+	if got != want {
+		t.Errorf("renderCredentialsDotenv() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMergeDotenv(t *testing.T) {
+	block := "DEV_A=new\nDEV_B=new\n"
+
+	tests := map[string]struct {
+		content string
+		want    string
+	}{
+		"empty file": {
+			content: "",
+			want:    block,
+		},
+		"keeps other profiles": {
+			content: "PROD_A=keep\n",
+			want:    "PROD_A=keep\n" + block,
+		},
+		"replaces the profile's variables": {
+			content: "# creds\nDEV_A=old\nPROD_A=keep\nexport DEV_B=old\n",
+			want:    "# creds\nPROD_A=keep\n" + block,
+		},
+		"file without trailing newline": {
+			content: "PROD_A=keep",
+			want:    "PROD_A=keep\n" + block,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := mergeDotenv(tc.content, block); got != tc.want {
+				t.Errorf("mergeDotenv() =\n%q\nwant\n%q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProvider_GetCredentials_AppendTwoProfiles(t *testing.T) {
+	defer testutil.DiscardStderr(t)()
+
+	tests := map[string]struct {
+		format string
+		want   []string
+	}{
+		"ini sections": {
+			format: formatINI,
+			want: []string{
+				"[dev-sesh]\naws_access_key_id = ASIA-dev\n",
+				"[prod-sesh]\naws_access_key_id = ASIA-prod\n",
+			},
+		},
+		"prefixed dotenv": {
+			format: formatEnv,
+			want: []string{
+				"DEV_AWS_ACCESS_KEY_ID=ASIA-dev\n",
+				"DEV_AWS_CREDENTIAL_EXPIRATION=2030-01-01T00:00:00Z\n",
+				"DEV_AWS_SESSION_TOKEN=token-dev\n",
+				"PROD_AWS_ACCESS_KEY_ID=ASIA-prod\n",
+				"PROD_AWS_SECRET_ACCESS_KEY=secret-prod\n",
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "credentials")
+
+			for _, profile := range []string{"dev", "prod", "dev"} {
+				p := &Provider{
+					aws: &awsMocks.MockProvider{
+						GetSessionTokenFunc: func(profile, _ string, _ []byte, _ aws.SessionOptions) (aws.Credentials, error) {
+							return aws.Credentials{
+								AccessKeyID:     "ASIA-" + profile,
+								SecretAccessKey: "secret-" + profile,
+								SessionToken:    "token-" + profile,
+								Expiration:      "2030-01-01T00:00:00Z",
+							}, nil
+						},
+					},
+					keychain: &keychainMocks.MockProvider{
+						GetSecretFunc: func(_, service string) ([]byte, error) {
+							if strings.HasPrefix(service, "sesh-aws-serial/") {
+								return []byte("arn:aws:iam::123456789012:mfa/user"), nil
+							}
+							return []byte("MYSECRET"), nil
+						},
+					},
+					totp: &totpMocks.MockProvider{
+						GenerateConsecutiveCodesBytesFunc: func([]byte) (string, string, error) {
+							return "123456", "654321", nil
+						},
+					},
+					KeyUser:      provider.KeyUser{User: "testuser"},
+					KeyPrefix:    "sesh-aws",
+					profile:      profile,
+					format:       tc.format,
+					outputFile:   path,
+					appendOutput: true,
+				}
+
+				if p.ShouldUseSubshell() {
+					t.Fatal("ShouldUseSubshell() = true, want false with --output-file")
+				}
+				if err := p.ValidateRequest(); err != nil {
+					t.Fatalf("ValidateRequest() error = %v", err)
+				}
+				creds, err := p.GetCredentials()
+				if err != nil {
+					t.Fatalf("GetCredentials() error = %v", err)
+				}
+				if len(creds.Variables) != 0 {
+					t.Errorf("Variables = %v, want none when writing a file", creds.Variables)
+				}
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := string(data)
+			for _, want := range tc.want {
+				if strings.Count(got, want) != 1 {
+					t.Errorf("file should contain %q exactly once:\n%s", want, got)
+				}
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if perm := info.Mode().Perm(); perm != 0o600 {
+				t.Errorf("file mode = %o, want 600", perm)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	awsInternal "github.com/bashhack/sesh/internal/aws"
+)
+
+// envOverrides maps the SESH_* variables that script the AWS provider onto
+// the flags whose defaults they set. They apply at flag-default time, so a
+// flag given on the command line still wins, and they take precedence over
+// a .sesh file, which only fills in flags no variable has set.
+//
+//	Variable           Flag           Value
+//	SESH_PROFILE       --profile      profile name; falls back to AWS_PROFILE
+//	SESH_AWS_REGION    --region       region for the STS call, e.g. eu-west-1
+//	SESH_STS_DURATION  --duration     session length, e.g. 1h or 12h
+//	SESH_NO_SUBSHELL   --no-subshell  any strconv.ParseBool true value
+var envOverrides = []struct {
+	env  string
+	flag string
+}{
+	{"SESH_PROFILE", "profile"},
+	{"SESH_AWS_REGION", "region"},
+	{"SESH_STS_DURATION", "duration"},
+	{"SESH_NO_SUBSHELL", "no-subshell"},
+}
+
+// envDefaults returns the flag defaults set by envOverrides, keyed by flag
+// name. Unset or empty variables are left out.
+func envDefaults() map[string]string {
+	defaults := make(map[string]string, len(envOverrides))
+	for _, o := range envOverrides {
+		if v := os.Getenv(o.env); v != "" {
+			defaults[o.flag] = v
+		}
+	}
+	return defaults
+}
+
+// EnvOverrides implements provider.EnvOverrider, so a .sesh file doesn't
+// replace a default that came from the environment.
+func (p *Provider) EnvOverrides() map[string]string {
+	return envDefaults()
+}
+
+// EnvSources implements provider.EnvSourcer. Besides the SESH_* overrides
+// it reports AWS_PROFILE when that is where --profile's default came from.
+func (p *Provider) EnvSources() map[string]string {
+	sources := make(map[string]string, len(envOverrides))
+	for _, o := range envOverrides {
+		if os.Getenv(o.env) != "" {
+			sources[o.flag] = o.env
+		}
+	}
+	if _, ok := sources["profile"]; !ok && os.Getenv("AWS_PROFILE") != "" {
+		sources["profile"] = "AWS_PROFILE"
+	}
+	return sources
+}
+
+// STS get-session-token accepts 15 minutes to 36 hours.
+const (
+	minSessionDuration = 15 * time.Minute
+	maxSessionDuration = 36 * time.Hour
+)
+
+// parseSessionDuration validates --duration (or SESH_STS_DURATION); empty
+// means STS's own default.
+func parseSessionDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < minSessionDuration || d > maxSessionDuration {
+		return 0, fmt.Errorf("--duration (or SESH_STS_DURATION) must be between 15m and 36h, got %q", s)
+	}
+	return d, nil
+}
+
+// parseEnvBool reads a boolean default such as SESH_NO_SUBSHELL. Values
+// that aren't booleans count as false, leaving the flag's usual default.
+func parseEnvBool(s string) bool {
+	v, err := strconv.ParseBool(s)
+	return err == nil && v
+}
+
+// sessionOptions returns the --region and --duration settings for
+// get-session-token.
+func (p *Provider) sessionOptions() awsInternal.SessionOptions {
+	return awsInternal.SessionOptions{Region: p.region, Duration: p.sessionDuration}
+}
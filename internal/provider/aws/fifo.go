@@ -0,0 +1,97 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// defaultFIFOTimeoutSeconds is how long --output-fifo waits for a reader.
+const defaultFIFOTimeoutSeconds = 30
+
+// fifoPollInterval is how often writeFIFO retries opening the FIFO while
+// waiting for a reader. It is a variable so we can swap it out in tests.
+var fifoPollInterval = 50 * time.Millisecond
+
+// currentUID returns the user sesh runs as, who must own an existing
+// FIFO. It is a variable so we can swap it out in tests.
+var currentUID = os.Getuid
+
+// renderCredentialsEnv renders session variables as shell export lines,
+// sorted by name, quoted the same way as the --no-subshell output.
+func renderCredentialsEnv(vars map[string]string) string {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "export %s='%s'\n", k, strings.ReplaceAll(vars[k], "'", "'\\''"))
+	}
+	return b.String()
+}
+
+// writeFIFO writes content to the named pipe at path, creating it with 0600
+// perms if absent (and removing it afterwards). It waits up to timeout for
+// a reader to open the other end. Anything other than a FIFO at path is
+// refused so secrets never land in a regular file, and so is an existing
+// FIFO that another user owns or can open.
+func writeFIFO(path, content string, timeout time.Duration) (err error) {
+	info, statErr := os.Lstat(path)
+	switch {
+	case errors.Is(statErr, fs.ErrNotExist):
+		if err := syscall.Mkfifo(path, 0o600); err != nil {
+			return fmt.Errorf("create FIFO %s: %w", path, err)
+		}
+		defer func() {
+			if rmErr := os.Remove(path); rmErr != nil && err == nil {
+				err = fmt.Errorf("remove FIFO %s: %w", path, rmErr)
+			}
+		}()
+	case statErr != nil:
+		return fmt.Errorf("stat %s: %w", path, statErr)
+	case info.Mode()&fs.ModeNamedPipe == 0:
+		return fmt.Errorf("%s exists and is not a FIFO", path)
+	case info.Mode().Perm()&0o077 != 0:
+		return fmt.Errorf("refusing to write %s: permissions %04o allow access by other users", path, info.Mode().Perm())
+	default:
+		if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != currentUID() {
+			return fmt.Errorf("refusing to write %s: it is owned by another user", path)
+		}
+	}
+
+	// A non-blocking open for writing fails with ENXIO until a reader has
+	// the FIFO open, which lets us give up after the timeout instead of
+	// blocking forever.
+	deadline := time.Now().Add(timeout)
+	var f *os.File
+	for {
+		f, err = os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0) //nolint:gosec // path is the user's explicit --output-fifo
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.ENXIO) {
+			return fmt.Errorf("open FIFO %s: %w", path, err)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for a reader on %s", timeout, path)
+		}
+		time.Sleep(fifoPollInterval)
+	}
+
+	if _, err := f.WriteString(content); err != nil {
+		_ = f.Close() //nolint:errcheck // the write error is the one worth reporting
+		return fmt.Errorf("write FIFO %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close FIFO %s: %w", path, err)
+	}
+	return nil
+}
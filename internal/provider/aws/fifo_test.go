@@ -0,0 +1,155 @@
+package aws
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRenderCredentialsEnv(t *testing.T) {
+	got := renderCredentialsEnv(map[string]string{
+		"AWS_SESSION_TOKEN": "tok'en",
+		"AWS_ACCESS_KEY_ID": "AKIA123",
+	})
+	want := "export AWS_ACCESS_KEY_ID='AKIA123'\n" +
+		"export AWS_SESSION_TOKEN='tok'\\''en'\n"
+	if got != want {
+		t.Errorf("renderCredentialsEnv() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteFIFO(t *testing.T) {
+	origPoll := fifoPollInterval
+	fifoPollInterval = time.Millisecond
+	defer func() { fifoPollInterval = origPoll }()
+
+	const content = "export AWS_ACCESS_KEY_ID='AKIA123'\n"
+
+	tests := map[string]struct {
+		precreate   bool
+		wantRemoved bool
+	}{
+		"creates and removes a missing FIFO": {wantRemoved: true},
+		"leaves an existing FIFO in place":   {precreate: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "creds.fifo")
+			if tc.precreate {
+				if err := syscall.Mkfifo(path, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			type result struct {
+				data []byte
+				perm fs.FileMode
+				err  error
+			}
+			done := make(chan result, 1)
+			go func() {
+				// Wait for writeFIFO to create the pipe before opening it.
+				for {
+					info, err := os.Stat(path)
+					if err == nil {
+						f, err := os.Open(path) //nolint:gosec // test path
+						if err != nil {
+							done <- result{err: err}
+							return
+						}
+						data, err := io.ReadAll(f)
+						_ = f.Close() //nolint:errcheck // test reader
+						done <- result{data: data, perm: info.Mode().Perm(), err: err}
+						return
+					}
+					time.Sleep(time.Millisecond)
+				}
+			}()
+
+			if err := writeFIFO(path, content, 5*time.Second); err != nil {
+				t.Fatalf("writeFIFO() error = %v", err)
+			}
+
+			res := <-done
+			if res.err != nil {
+				t.Fatalf("reader error: %v", res.err)
+			}
+			if string(res.data) != content {
+				t.Errorf("reader got %q, want %q", res.data, content)
+			}
+			if res.perm != 0o600 {
+				t.Errorf("FIFO mode = %o, want 600", res.perm)
+			}
+
+			_, statErr := os.Stat(path)
+			if removed := errors.Is(statErr, fs.ErrNotExist); removed != tc.wantRemoved {
+				t.Errorf("FIFO removed = %v, want %v", removed, tc.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestWriteFIFO_Errors(t *testing.T) {
+	origPoll := fifoPollInterval
+	fifoPollInterval = time.Millisecond
+	defer func() { fifoPollInterval = origPoll }()
+
+	tests := map[string]struct {
+		prepare    func(t *testing.T, path string)
+		wantErrMsg string
+	}{
+		"regular file is refused": {
+			prepare: func(t *testing.T, path string) {
+				if err := os.WriteFile(path, nil, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErrMsg: "is not a FIFO",
+		},
+		"group-readable FIFO is refused": {
+			prepare: func(t *testing.T, path string) {
+				if err := syscall.Mkfifo(path, 0o600); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(path, 0o640); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErrMsg: "allow access by other users",
+		},
+		"FIFO owned by another user is refused": {
+			prepare: func(t *testing.T, path string) {
+				if err := syscall.Mkfifo(path, 0o600); err != nil {
+					t.Fatal(err)
+				}
+				orig := currentUID
+				currentUID = func() int { return os.Getuid() + 1 }
+				t.Cleanup(func() { currentUID = orig })
+			},
+			wantErrMsg: "owned by another user",
+		},
+		"no reader times out": {
+			prepare:    func(t *testing.T, path string) {},
+			wantErrMsg: "waiting for a reader",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "creds.fifo")
+			tc.prepare(t, path)
+
+			err := writeFIFO(path, "secret", 20*time.Millisecond)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+				t.Fatalf("writeFIFO() error = %v, want to contain %q", err, tc.wantErrMsg)
+			}
+		})
+	}
+}
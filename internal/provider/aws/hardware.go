@@ -0,0 +1,132 @@
+package aws
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+
+	awsInternal "github.com/bashhack/sesh/internal/aws"
+	"github.com/bashhack/sesh/internal/keychain"
+	"github.com/bashhack/sesh/internal/prompt"
+	"github.com/bashhack/sesh/internal/provider"
+	"github.com/bashhack/sesh/internal/secure"
+)
+
+// Package-level seams for the hardware-token prompt. Tests save + restore
+// these via defer.
+var (
+	readMFACode = func() ([]byte, error) {
+		return term.ReadPassword(int(os.Stdin.Fd()))
+	}
+	stdinIsTerminal = func() bool {
+		return term.IsTerminal(int(os.Stdin.Fd()))
+	}
+	// saveTerminal records the terminal state so it can be put back when
+	// a timed-out prompt abandons ReadPassword with echo still off.
+	saveTerminal = func() (restore func()) {
+		fd := int(os.Stdin.Fd())
+		state, err := term.GetState(fd)
+		if err != nil {
+			return func() {}
+		}
+		return func() { _ = term.Restore(fd, state) } //nolint:errcheck // best-effort on the way out
+	}
+)
+
+// promptTimeout bounds the hardware MFA code prompt; zero waits forever.
+var promptTimeout time.Duration
+
+// SetPromptTimeout makes the hardware MFA code prompt give up after d
+// (--prompt-timeout), so an unattended run fails instead of hanging. It is
+// meant to be set once at startup, like SetDebugOutput.
+func SetPromptTimeout(d time.Duration) {
+	promptTimeout = d
+}
+
+// hasTOTPSecret reports whether a TOTP secret is stored for the profile.
+// Profiles with only an MFA serial belong to hardware-token users, who type
+// the code themselves.
+func (p *Provider) hasTOTPSecret() (bool, error) {
+	if err := p.EnsureUser(); err != nil {
+		return false, err
+	}
+	keyName, err := buildServiceKey(p.secretPrefix(), p.profile)
+	if err != nil {
+		return false, fmt.Errorf("failed to build service key: %w", err)
+	}
+
+	secret, err := p.keychain.GetSecret(p.User, keyName)
+	if err != nil {
+		if errors.Is(err, keychain.ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read TOTP secret from keychain: %w", err)
+	}
+	secure.SecureZeroBytes(secret)
+	return true, nil
+}
+
+// sessionTokenFromHardware prompts for a code from a hardware MFA token and
+// submits it to STS once. There is nothing to retry with: the next code
+// only exists on the device.
+func (p *Provider) sessionTokenFromHardware(serial string) (awsInternal.Credentials, error) {
+	if !stdinIsTerminal() {
+		return awsInternal.Credentials{}, fmt.Errorf("no TOTP secret stored for AWS %s and stdin is not a terminal to prompt for a hardware MFA code", formatProfile(p.profile))
+	}
+
+	fmt.Fprintf(os.Stderr, "🔐 Enter MFA code for %s: ", serial)
+	restore := saveTerminal()
+	code, err := prompt.WithTimeout(promptTimeout, readMFACode)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		if errors.Is(err, prompt.ErrTimeout) {
+			restore()
+		}
+		return awsInternal.Credentials{}, fmt.Errorf("failed to read MFA code: %w", err)
+	}
+	defer secure.SecureZeroBytes(code)
+
+	code = bytes.TrimSpace(code)
+	if len(code) == 0 {
+		return awsInternal.Credentials{}, fmt.Errorf("no MFA code entered")
+	}
+
+	awsCreds, err := p.aws.GetSessionToken(p.profile, serial, code, p.sessionOptions())
+	if err != nil {
+		return awsInternal.Credentials{}, fmt.Errorf("failed to get session token: %w", err)
+	}
+	return awsCreds, nil
+}
+
+// validateHardwareRequest handles ValidateRequest for a profile with no
+// stored TOTP secret. A stored MFA serial means the profile uses a hardware
+// token, which is allowed when there is a terminal to prompt on. Clipboard
+// mode still needs a stored secret and fails in GetTOTPCodes.
+func (p *Provider) validateHardwareRequest(totpKey, mfaKey string) error {
+	profileDesc := p.profile
+	if profileDesc == "" {
+		profileDesc = "default"
+	}
+	notSetup := provider.NotSetupError("no AWS entry found for profile '%s'. Run 'sesh --service aws --setup' first", profileDesc)
+
+	serial, err := p.keychain.GetSecret(p.User, mfaKey)
+	if err != nil {
+		if errors.Is(err, keychain.ErrNotFound) {
+			return provider.KeychainUserHint(notSetup, p.keychain.ListEntries, totpKey, p.User)
+		}
+		return fmt.Errorf("failed to read MFA serial from keychain: %w", err)
+	}
+	secure.SecureZeroBytes(serial)
+
+	if p.copySerial {
+		return nil
+	}
+	if !stdinIsTerminal() {
+		return fmt.Errorf("profile '%s' has an MFA serial but no TOTP secret; a hardware MFA code can only be entered from a terminal", profileDesc)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bashhack/sesh/internal/atomicfile"
+)
+
+// Output formats for --format.
+const (
+	formatEnv    = "env"
+	formatINI    = "ini"
+	formatBase64 = "base64"
+)
+
+// defaultINIProfile returns the credentials-file section name used when
+// --ini-profile is not given. The "-sesh" suffix keeps session credentials
+// from clobbering a static profile of the same name.
+func defaultINIProfile(profile string) string {
+	if profile == "" {
+		profile = "default"
+	}
+	return profile + "-sesh"
+}
+
+// renderCredentialsINI renders an ~/.aws/credentials section for the given
+// session variables, including the trailing newline.
+func renderCredentialsINI(section string, vars map[string]string, expiry time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s]\n", section)
+	fmt.Fprintf(&b, "aws_access_key_id = %s\n", vars["AWS_ACCESS_KEY_ID"])
+	fmt.Fprintf(&b, "aws_secret_access_key = %s\n", vars["AWS_SECRET_ACCESS_KEY"])
+	fmt.Fprintf(&b, "aws_session_token = %s\n", vars["AWS_SESSION_TOKEN"])
+	fmt.Fprintf(&b, "x_security_session_expires = %s\n", expiry.UTC().Format(time.RFC3339))
+	return b.String()
+}
+
+// mergeINISection returns content with the named section replaced by block,
+// or with block appended if the section is absent. Everything outside the
+// target section — other profiles, comments, blank lines — is preserved.
+func mergeINISection(content, section, block string) string {
+	lines := strings.SplitAfter(content, "\n")
+	header := "[" + section + "]"
+
+	var out strings.Builder
+	replaced := false
+	inTarget := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			inTarget = trimmed == header
+			if inTarget {
+				out.WriteString(block)
+				replaced = true
+				continue
+			}
+		}
+		if inTarget {
+			continue
+		}
+		out.WriteString(line)
+	}
+
+	if !replaced {
+		existing := out.String()
+		if existing != "" && !strings.HasSuffix(existing, "\n") {
+			out.WriteString("\n")
+		}
+		if existing != "" {
+			out.WriteString("\n")
+		}
+		out.WriteString(block)
+	}
+	return out.String()
+}
+
+// writeINISection merges block into the credentials file at path, replacing
+// only the named section. The file is written with 0600 perms.
+func writeINISection(path, section, block string) error {
+	existing, err := os.ReadFile(path) //nolint:gosec // path is the user's explicit --output-file
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+
+	return atomicfile.WriteString(path, mergeINISection(string(existing), section, block))
+}
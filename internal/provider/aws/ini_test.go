@@ -0,0 +1,276 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultINIProfile(t *testing.T) {
+	tests := map[string]struct {
+		profile string
+		want    string
+	}{
+		"empty profile": {profile: "", want: "default-sesh"},
+		"named profile": {profile: "work", want: "work-sesh"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := defaultINIProfile(tc.profile); got != tc.want {
+				t.Errorf("defaultINIProfile(%q) = %q, want %q", tc.profile, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRenderCredentialsINI(t *testing.T) {
+	vars := map[string]string{
+		"AWS_ACCESS_KEY_ID":     "AKIA123",
+		"AWS_SECRET_ACCESS_KEY": "secret",
+		"AWS_SESSION_TOKEN":     "token",
+	}
+	expiry := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	got := renderCredentialsINI("work-sesh", vars, expiry)
+	want := "[work-sesh]\n" +
+		"aws_access_key_id = AKIA123\n" +
+		"aws_secret_access_key = secret\n" +
+		"aws_session_token = token\n" +
+		"x_security_session_expires = 2026-01-02T03:04:05Z\n"
+	if got != want {
+		t.Errorf("renderCredentialsINI() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMergeINISection(t *testing.T) {
+	block := "[work-sesh]\naws_access_key_id = NEW\n"
+
+	tests := map[string]struct {
+		content string
+		want    string
+	}{
+		"empty file": {
+			content: "",
+			want:    block,
+		},
+		"append after other profiles": {
+			content: "[default]\naws_access_key_id = STATIC\n",
+			want:    "[default]\naws_access_key_id = STATIC\n\n" + block,
+		},
+		"append to file without trailing newline": {
+			content: "[default]\naws_access_key_id = STATIC",
+			want:    "[default]\naws_access_key_id = STATIC\n\n" + block,
+		},
+		"replace existing section only": {
+			content: "[default]\naws_access_key_id = STATIC\n\n" +
+				"[work-sesh]\naws_access_key_id = OLD\naws_session_token = OLD\n" +
+				"[other]\naws_access_key_id = OTHER\n",
+			want: "[default]\naws_access_key_id = STATIC\n\n" +
+				block +
+				"[other]\naws_access_key_id = OTHER\n",
+		},
+		"replace last section": {
+			content: "# comment\n[work-sesh]\naws_access_key_id = OLD\n",
+			want:    "# comment\n" + block,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := mergeINISection(tc.content, "work-sesh", block); got != tc.want {
+				t.Errorf("mergeINISection() =\n%q\nwant\n%q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriteINISection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(path, []byte("[default]\naws_access_key_id = STATIC\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeINISection(path, "default-sesh", "[default-sesh]\naws_access_key_id = ONE\n"); err != nil {
+		t.Fatalf("first write: %v", err)
+	}
+	if err := writeINISection(path, "default-sesh", "[default-sesh]\naws_access_key_id = TWO\n"); err != nil {
+		t.Fatalf("second write: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	if !strings.Contains(got, "aws_access_key_id = STATIC") {
+		t.Error("static profile was not preserved")
+	}
+	if strings.Contains(got, "ONE") || !strings.Contains(got, "aws_access_key_id = TWO") {
+		t.Errorf("target section not replaced: %q", got)
+	}
+	if n := strings.Count(got, "[default-sesh]"); n != 1 {
+		t.Errorf("found %d [default-sesh] headers, want 1", n)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("file mode = %o, want 600", perm)
+	}
+}
+
+func TestWriteINISection_CreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	block := "[work-sesh]\naws_access_key_id = NEW\n"
+
+	if err := writeINISection(path, "work-sesh", block); err != nil {
+		t.Fatalf("writeINISection: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != block {
+		t.Errorf("file = %q, want %q", string(data), block)
+	}
+}
+
+func TestProvider_ValidateRequest_Format(t *testing.T) {
+	tests := map[string]struct {
+		format       string
+		iniProfile   string
+		outputFile   string
+		appendOutput bool
+		wantErrMsg   string
+	}{
+		"unknown format": {
+			format:     "yaml",
+			wantErrMsg: `--format must be env, ini or base64, got "yaml"`,
+		},
+		"output-file without ini": {
+			format:     formatEnv,
+			outputFile: "/tmp/credentials",
+			wantErrMsg: "--output-file requires --format ini, or --format env with --append",
+		},
+		"output-file with base64": {
+			format:       formatBase64,
+			outputFile:   "/tmp/credentials",
+			appendOutput: true,
+			wantErrMsg:   "--output-file requires --format ini, or --format env with --append",
+		},
+		"append without output-file": {
+			format:       formatINI,
+			appendOutput: true,
+			wantErrMsg:   "--append requires --output-file",
+		},
+		"ini-profile without ini": {
+			format:     formatEnv,
+			iniProfile: "work",
+			wantErrMsg: "--ini-profile requires --format ini",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &Provider{format: tc.format, iniProfile: tc.iniProfile, outputFile: tc.outputFile, appendOutput: tc.appendOutput}
+			err := p.ValidateRequest()
+			if err == nil || err.Error() != tc.wantErrMsg {
+				t.Errorf("ValidateRequest() error = %v, want %q", err, tc.wantErrMsg)
+			}
+		})
+	}
+}
+
+func TestProvider_ShouldUseSubshell_INI(t *testing.T) {
+	p := &Provider{format: formatINI}
+	if p.ShouldUseSubshell() {
+		t.Error("ShouldUseSubshell() = true, want false for --format ini")
+	}
+}
+
+func TestProvider_iniCredentials_Stdout(t *testing.T) {
+	p := &Provider{profile: "work", format: formatINI}
+	envVars := map[string]string{
+		"AWS_ACCESS_KEY_ID":     "ASIAEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "secret-access-key",
+		"AWS_SESSION_TOKEN":     "session-token",
+	}
+
+	creds, err := p.iniCredentials(envVars, time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC), "profile (work)")
+	if err != nil {
+		t.Fatalf("iniCredentials() error = %v", err)
+	}
+	if !strings.HasPrefix(creds.Output, "[work-sesh]\n") || !strings.Contains(creds.Output, "secret-access-key") {
+		t.Errorf("Output = %q, want the [work-sesh] block", creds.Output)
+	}
+	for _, secret := range envVars {
+		if strings.Contains(creds.DisplayInfo, secret) {
+			t.Errorf("DisplayInfo %q leaks %q to stderr", creds.DisplayInfo, secret)
+		}
+	}
+}
+
+func TestWriteINISection_Symlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles-credentials")
+	if err := os.WriteFile(target, []byte("[default]\naws_access_key_id = STATIC\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "credentials")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeINISection(link, "default-sesh", "[default-sesh]\naws_access_key_id = NEW\n"); err != nil {
+		t.Fatalf("writeINISection: %v", err)
+	}
+
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Error("symlink was replaced by a regular file")
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "aws_access_key_id = NEW") || !strings.Contains(string(data), "STATIC") {
+		t.Errorf("target = %q, want both sections", string(data))
+	}
+}
+
+func TestWriteINISection_IgnoresStaleTemp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	// A leftover world-readable temp file from an older version must not
+	// lend its mode to the new file
+	if err := os.WriteFile(path+".tmp", []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeINISection(path, "work-sesh", "[work-sesh]\naws_access_key_id = NEW\n"); err != nil {
+		t.Fatalf("writeINISection: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("file mode = %o, want 600", perm)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("directory holds %d files, want the credentials file and the stale temp only", len(entries))
+	}
+}
@@ -2,10 +2,13 @@
 package aws
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,6 +21,7 @@ import (
 	"github.com/bashhack/sesh/internal/secure"
 	"github.com/bashhack/sesh/internal/setup"
 	"github.com/bashhack/sesh/internal/subshell"
+	"github.com/bashhack/sesh/internal/theme"
 	internalTotp "github.com/bashhack/sesh/internal/totp"
 )
 
@@ -30,9 +34,34 @@ type Provider struct {
 	provider.Clock
 	provider.KeyUser
 
-	profile    string
-	keyName    string
-	noSubshell bool
+	// KeyPrefix and SerialPrefix are the keychain namespaces the TOTP
+	// secrets and MFA serials are stored under, constants.AWSServicePrefix
+	// and constants.AWSServiceMFAPrefix when empty. Setting them keeps a
+	// second set of entries, e.g. a test's or another tenant's, apart
+	// from the usual ones. They must start with "sesh-" to be listed.
+	KeyPrefix    string
+	SerialPrefix string
+
+	profile      string
+	region       string
+	duration     string // --duration as given; sessionDuration once validated
+	format       string
+	iniProfile   string
+	outputFile   string
+	appendOutput bool
+	promptFormat string
+	outputFifo   string
+	fifoTimeout  int
+	noSubshell   bool
+	copySerial   bool
+	allowReused  bool
+	renameTo     string // --rename-profile old=new
+	force        bool
+	whoami       bool
+	details      bool
+	rawSerials   bool // --include-serial-entries, a --debug-only --list view
+
+	sessionDuration time.Duration
 }
 
 var _ provider.ServiceProvider = (*Provider)(nil)
@@ -47,10 +76,24 @@ func NewProvider(
 		aws:      aws,
 		keychain: kc,
 		totp:     totp,
-		keyName:  constants.AWSServicePrefix,
 	}
 }
 
+// secretPrefix returns the keychain namespace for TOTP secrets.
+func (p *Provider) secretPrefix() string {
+	return cmp.Or(p.KeyPrefix, constants.AWSServicePrefix)
+}
+
+// serialPrefix returns the keychain namespace for MFA serials.
+func (p *Provider) serialPrefix() string {
+	return cmp.Or(p.SerialPrefix, constants.AWSServiceMFAPrefix)
+}
+
+// KeyNamespaces implements provider.KeyNamespacer.
+func (p *Provider) KeyNamespaces() []string {
+	return []string{p.secretPrefix(), p.serialPrefix()}
+}
+
 // Name returns the provider name.
 func (p *Provider) Name() string {
 	return "aws"
@@ -63,36 +106,76 @@ func (p *Provider) Description() string {
 
 // SetupFlags adds provider-specific flags to the given FlagSet
 func (p *Provider) SetupFlags(fs provider.FlagSet) error {
-	fs.StringVar(&p.profile, "profile", os.Getenv("AWS_PROFILE"), "AWS CLI profile to use")
-	fs.BoolVar(&p.noSubshell, "no-subshell", false, "Print environment variables instead of launching subshell")
+	envs := envDefaults()
+	fs.StringVar(&p.profile, "profile", cmp.Or(envs["profile"], os.Getenv("AWS_PROFILE")), "AWS CLI profile to use")
+	fs.StringVar(&p.region, "region", envs["region"], "AWS region for the STS call (default: the profile's region)")
+	fs.StringVar(&p.duration, "duration", envs["duration"], "Session length, 15m to 36h (default: STS's 12h)")
+	fs.BoolVar(&p.noSubshell, "no-subshell", parseEnvBool(envs["no-subshell"]), "Print environment variables instead of launching subshell")
+	fs.BoolVar(&p.copySerial, "copy-serial", false, "Copy the MFA device ARN to the clipboard")
+	fs.BoolVar(&p.allowReused, "allow-reused-code", false, "Submit the current code once, skipping the next/future-window retries")
+	fs.StringVar(&p.format, "format", formatEnv, "Output format: env, ini or base64")
+	fs.StringVar(&p.iniProfile, "ini-profile", "", "Section name for --format ini (default: <profile>-sesh)")
+	fs.StringVar(&p.outputFile, "output-file", "", "Merge the --format ini section into this credentials file")
+	fs.BoolVar(&p.appendOutput, "append", false, "With --output-file, accumulate profiles in one file (env: <PROFILE>_-prefixed variables)")
+	fs.StringVar(&p.promptFormat, "prompt-format", subshell.DefaultPromptFormat, "Subshell prompt prefix; supports {provider}, {profile}, {expires}")
+	fs.StringVar(&p.outputFifo, "output-fifo", "", "Write credentials in the chosen --format to this named pipe instead of a subshell")
+	fs.IntVar(&p.fifoTimeout, "timeout", defaultFIFOTimeoutSeconds, "Seconds --output-fifo waits for a reader")
+	fs.StringVar(&p.renameTo, "rename-profile", "", "Move a profile's stored secret, serial and metadata: old=new")
+	fs.BoolVar(&p.force, "force", false, "With --rename-profile, overwrite entries the new profile already has")
+	fs.BoolVar(&p.whoami, "whoami", false, "Print the ARN and account behind the profile's credentials, without MFA")
+	fs.BoolVar(&p.details, "details", false, "With --list, show whether each profile's MFA serial is stored or auto-detected")
+	// Deliberately left out of GetFlagInfo: it is a debugging aid, not
+	// part of the documented provider surface.
+	fs.BoolVar(&p.rawSerials, "include-serial-entries", false, "With --list --debug, also show the raw sesh-aws-serial keychain entries")
 
 	defaultKeyUser, err := env.GetCurrentUser()
 	if err != nil {
 		return fmt.Errorf("failed to get current user: %w", err)
 	}
-	p.User = defaultKeyUser
+	fs.StringVar(&p.User, "keychain-user", defaultKeyUser, "Keychain account the secret is stored under (default: current user)")
 	return nil
 }
 
 // GetSetupHandler returns a setup handler for AWS
 func (p *Provider) GetSetupHandler() any {
-	return setup.NewAWSSetupHandler(p.keychain)
+	h := setup.NewAWSSetupHandler(p.keychain)
+	h.KeyPrefix, h.SerialPrefix = p.secretPrefix(), p.serialPrefix()
+	return h
 }
 
 // GetTOTPCodes retrieves TOTP codes without performing AWS authentication
 func (p *Provider) GetTOTPCodes() (currentCode, nextCode string, secondsLeft int64, err error) {
-	if err := p.EnsureUser(); err != nil {
+	codes, err := p.totpCodes()
+	if err != nil {
 		return "", "", 0, err
 	}
+	return codes.current, codes.next, codes.secondsLeft, nil
+}
 
-	keyName, err := buildServiceKey(p.keyName, p.profile)
+// totpWindowCodes are the codes GetTOTPCodes generates, together with the
+// entry's key and stored time offset so retries can reuse them without
+// reading the metadata index again.
+type totpWindowCodes struct {
+	current     string
+	next        string
+	secondsLeft int64
+	keyName     string
+	offset      time.Duration
+}
+
+func (p *Provider) totpCodes() (totpWindowCodes, error) {
+	if err := p.EnsureUser(); err != nil {
+		return totpWindowCodes{}, err
+	}
+
+	keyName, err := buildServiceKey(p.secretPrefix(), p.profile)
 	if err != nil {
-		return "", "", 0, fmt.Errorf("failed to build service key: %w", err)
+		return totpWindowCodes{}, fmt.Errorf("failed to build service key: %w", err)
 	}
 
 	secretBytes, err := p.keychain.GetSecret(p.User, keyName)
 	if err != nil {
-		return "", "", 0, fmt.Errorf("failed to retrieve TOTP secret for AWS %s: %w", formatProfile(p.profile), err)
+		return totpWindowCodes{}, fmt.Errorf("failed to retrieve TOTP secret for AWS %s: %w", formatProfile(p.profile), err)
 	}
 
 	secretCopy := make([]byte, len(secretBytes))
@@ -101,27 +184,36 @@ func (p *Provider) GetTOTPCodes() (currentCode, nextCode string, secondsLeft int
 
 	secure.SecureZeroBytes(secretBytes)
 
-	fmt.Fprintf(os.Stderr, "🔑 Retrieved secret from keychain\n")
+	debugNote("🔑 Retrieved secret from keychain")
 
 	// Check if secret looks valid (base32 encoded)
 	secretLen := len(secretCopy)
 	if secretLen < 16 || secretLen > 64 {
-		fmt.Fprintf(os.Stderr, "⚠️ Warning: TOTP secret has unusual length: %d characters\n", secretLen)
+		fmt.Fprintf(os.Stderr, theme.Warning()+" TOTP secret has unusual length: %d characters\n", secretLen)
 	}
 
-	currentCode, nextCode, err = p.totp.GenerateConsecutiveCodesBytes(secretCopy)
+	codes := totpWindowCodes{keyName: keyName, offset: p.timeOffset(keyName)}
+	if codes.offset != 0 {
+		codes.current, codes.next, err = p.totp.GenerateConsecutiveCodesForTimeBytes(secretCopy, p.TimeNow().Add(codes.offset))
+	} else {
+		codes.current, codes.next, err = p.totp.GenerateConsecutiveCodesBytes(secretCopy)
+	}
 	if err != nil {
-		return "", "", 0, fmt.Errorf("could not generate TOTP codes: %w", err)
+		return totpWindowCodes{}, fmt.Errorf("could not generate TOTP codes: %w", err)
 	}
 
-	secondsLeft = p.SecondsLeftInWindow()
+	codes.secondsLeft = secondsLeftInWindow(p.TimeNow().Add(codes.offset))
 
-	return currentCode, nextCode, secondsLeft, nil
+	return codes, nil
 }
 
 // GetClipboardValue implements the ServiceProvider interface for clipboard mode
 // It generates only TOTP codes without AWS authentication to avoid the double-use of TOTP codes
 func (p *Provider) GetClipboardValue() (provider.Credentials, error) {
+	if p.copySerial {
+		return p.getSerialClipboardValue()
+	}
+
 	currentCode, nextCode, secondsLeft, err := p.GetTOTPCodes()
 	if err != nil {
 		return provider.Credentials{}, err
@@ -131,12 +223,49 @@ func (p *Provider) GetClipboardValue() (provider.Credentials, error) {
 
 	profileStr := formatProfile(p.profile)
 
-	return provider.CreateClipboardCredentials(p.Name(), currentCode, nextCode, secondsLeft,
-		"AWS MFA code", profileStr), nil
+	creds := provider.CreateClipboardCredentials(p.Name(), currentCode, nextCode, secondsLeft,
+		"AWS MFA code", profileStr)
+	if secondsLeft < clipNextCodeSeconds {
+		// The current code would likely expire before it is pasted and
+		// submitted, so hand over the one for the next window instead.
+		creds.CopyValue = nextCode
+		creds.Expiry = creds.Expiry.Add(30 * time.Second)
+		creds.DisplayInfo += fmt.Sprintf("\n⏭️  Copied the next window's code (%ds left in the current one)", secondsLeft)
+	}
+	return creds, nil
+}
+
+// clipNextCodeSeconds is how close to the end of a window --clip copies the
+// next code rather than the current one.
+const clipNextCodeSeconds = 5
+
+// getSerialClipboardValue returns the resolved MFA device ARN for --copy-serial.
+func (p *Provider) getSerialClipboardValue() (provider.Credentials, error) {
+	serialBytes, err := p.GetMFASerialBytes()
+	if err != nil {
+		return provider.Credentials{}, err
+	}
+	serial := string(serialBytes)
+	secure.SecureZeroBytes(serialBytes)
+
+	return provider.Credentials{
+		Provider:             p.Name(),
+		Variables:            map[string]string{},
+		DisplayInfo:          provider.FormatRegularDisplayInfo("MFA device ARN", formatProfile(p.profile)) + "\n" + serial,
+		CopyValue:            serial,
+		ClipboardDescription: "MFA device ARN",
+	}, nil
 }
 
 // GetCredentials retrieves AWS credentials using TOTP
 func (p *Provider) GetCredentials() (provider.Credentials, error) {
+	if p.renameTo != "" {
+		return p.renameProfile()
+	}
+	if p.whoami {
+		return p.callerIdentity()
+	}
+
 	serialBytes, err := p.GetMFASerialBytes()
 	if err != nil {
 		return provider.Credentials{}, err
@@ -147,151 +276,461 @@ func (p *Provider) GetCredentials() (provider.Credentials, error) {
 
 	fmt.Fprintf(os.Stderr, "🔍 Using MFA serial: %s\n", serial)
 
-	currentCode, nextCode, secondsLeft, err := p.GetTOTPCodes()
+	hasSecret, err := p.hasTOTPSecret()
 	if err != nil {
 		return provider.Credentials{}, err
 	}
 
-	code := currentCode
+	var awsCreds awsInternal.Credentials
+	if hasSecret {
+		awsCreds, err = p.sessionTokenFromSecret(serial)
+	} else {
+		awsCreds, err = p.sessionTokenFromHardware(serial)
+	}
+	if err != nil {
+		return provider.Credentials{}, err
+	}
+
+	defer awsCreds.ZeroSecrets()
+
+	expiryTime, parseErr := time.Parse(time.RFC3339, awsCreds.Expiration)
+	if parseErr != nil {
+		expiryTime = p.TimeNow().Add(12 * time.Hour) // Default to 12h if we can't parse
+	}
+	issuedAt, parseErr := time.Parse(time.RFC3339, awsCreds.IssuedAt)
+	if parseErr != nil {
+		issuedAt = p.TimeNow() // STS didn't say; it was just now
+	}
+
+	envVars := map[string]string{
+		"AWS_ACCESS_KEY_ID":     awsCreds.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY": awsCreds.SecretAccessKey,
+		"AWS_SESSION_TOKEN":     awsCreds.SessionToken,
+	}
+
+	profileStr := formatProfile(p.profile)
+
+	var creds provider.Credentials
+	switch {
+	case p.format == formatINI:
+		creds, err = p.iniCredentials(envVars, expiryTime, profileStr)
+	case p.format == formatBase64:
+		creds, err = p.base64Credentials(awsCreds, expiryTime, profileStr)
+	case p.outputFifo != "":
+		creds, err = p.fifoCredentials(renderCredentialsEnv(envVars), expiryTime, profileStr)
+	case p.outputFile != "":
+		creds, err = p.dotenvCredentials(envVars, expiryTime, profileStr)
+	default:
+		creds = provider.Credentials{
+			Provider:         p.Name(),
+			Expiry:           expiryTime,
+			Variables:        envVars,
+			DisplayInfo:      provider.FormatRegularDisplayInfo("AWS credentials", profileStr),
+			MFAAuthenticated: true, // If we got this far, AWS STS accepted our MFA code
+		}
+	}
+	if err != nil {
+		return provider.Credentials{}, err
+	}
+	creds.IssuedAt = issuedAt
+	return creds, nil
+}
+
+// fifoCredentials writes rendered credentials to the --output-fifo pipe and
+// returns credentials that only carry a confirmation, so nothing is exported
+// into a subshell or echoed to the terminal.
+func (p *Provider) fifoCredentials(content string, expiry time.Time, profileStr string) (provider.Credentials, error) {
+	if err := writeFIFO(p.outputFifo, content, time.Duration(p.fifoTimeout)*time.Second); err != nil {
+		return provider.Credentials{}, fmt.Errorf("failed to write credentials FIFO: %w", err)
+	}
+	return provider.Credentials{
+		Provider:         p.Name(),
+		Expiry:           expiry,
+		Variables:        map[string]string{},
+		DisplayInfo:      fmt.Sprintf("%s\n📝 Wrote credentials to FIFO %s", provider.FormatRegularDisplayInfo("AWS credentials", profileStr), p.outputFifo),
+		MFAAuthenticated: true,
+	}, nil
+}
+
+// sessionTokenFromSecret calls STS with a code generated from the stored
+// TOTP secret. A rejected code, or one generated right at the end of its
+// window, is retried with the next window's code and, failing that, the
+// window after next.
+func (p *Provider) sessionTokenFromSecret(serial string) (awsInternal.Credentials, error) {
+	codes, err := p.totpCodes()
+	if err != nil {
+		return awsInternal.Credentials{}, err
+	}
+	secondsLeft, keyName, offset := codes.secondsLeft, codes.keyName, codes.offset
 
+	code := codes.current
+
+	if p.allowReused {
+		fmt.Fprint(os.Stderr, theme.Warning()+" --allow-reused-code: submitting the current code only, without retries\n")
+	}
+
+	debugf("submitting current window code; secondsLeft=%d", secondsLeft)
 	codeBytes := []byte(code)
-	awsCreds, err := p.aws.GetSessionToken(p.profile, serial, codeBytes)
+	awsCreds, err := p.aws.GetSessionToken(p.profile, serial, codeBytes, p.sessionOptions())
 	secure.SecureZeroBytes(codeBytes)
 
-	// Check if this is an "invalid MFA one time pass code" error, which could indicate a recently used code
-	if err != nil {
-		errStr := err.Error()
-		isInvalidMFA := strings.Contains(errStr, "MultiFactorAuthentication failed with invalid MFA one time pass code")
+	switch {
+	case err == nil:
+		debugf("current code accepted")
+	case p.allowReused:
+		debugf("current code failed; --allow-reused-code set, not retrying")
+	default:
+		// Check if this is an "invalid MFA one time pass code" error, which could indicate a recently used code
+		isInvalidMFA := isInvalidMFAError(err)
 
 		// If it's an invalid MFA code or if we're close to time boundary, try the next code
-		if isInvalidMFA || secondsLeft < 5 {
-			if isInvalidMFA {
-				fmt.Fprintf(os.Stderr, "⚠️ AWS rejected the current time window's code (it may have been used recently)\n")
-			} else {
-				fmt.Fprintf(os.Stderr, "⚠️ Current code failed - time window nearly expired\n")
+		if !isInvalidMFA && secondsLeft >= 5 {
+			debugf("current code failed with a non-MFA error; secondsLeft=%d >= 5, not retrying", secondsLeft)
+			break
+		}
+		if isInvalidMFA {
+			debugf("current code rejected as recently used; secondsLeft=%d; trying next window", secondsLeft)
+			fmt.Fprint(os.Stderr, theme.Warning()+" AWS rejected the current time window's code (it may have been used recently)\n")
+		} else {
+			debugf("current code failed; secondsLeft=%d < 5, window nearly expired; trying next window", secondsLeft)
+			fmt.Fprint(os.Stderr, theme.Warning()+" Current code failed - time window nearly expired\n")
+		}
+
+		// Try with the next time window's code
+		fmt.Fprintf(os.Stderr, "🔑 Trying with next time window's code\n")
+		code = codes.next
+		codeBytes = []byte(code)
+		awsCreds, err = p.aws.GetSessionToken(p.profile, serial, codeBytes, p.sessionOptions())
+		secure.SecureZeroBytes(codeBytes)
+		if err == nil {
+			debugf("next window code accepted")
+			break
+		}
+
+		// Re-evaluate whether the second attempt also failed with an invalid MFA error
+		secondInvalidMFA := isInvalidMFAError(err)
+
+		// If STILL failing with invalid MFA and we're not close to boundary,
+		// we may need to wait for the next time window
+		freshSecondsLeft := secondsLeftInWindow(p.TimeNow().Add(offset))
+		switch {
+		case !secondInvalidMFA:
+			debugf("next window code failed with a non-MFA error; not retrying")
+		case freshSecondsLeft <= 10:
+			debugf("next window code rejected; secondsLeft=%d <= 10, the window rolls over soon; not retrying", freshSecondsLeft)
+		default:
+			debugf("next window code rejected; secondsLeft=%d > 10; generating +60s future code", freshSecondsLeft)
+			fmt.Fprint(os.Stderr, theme.Warning()+" Both current and next codes were rejected - may need to wait for next time window\n")
+
+			secretBytes, fetchErr := p.keychain.GetSecret(p.User, keyName)
+			if fetchErr != nil {
+				return awsInternal.Credentials{}, fmt.Errorf("failed to retrieve TOTP secret for AWS %s: %w", formatProfile(p.profile), fetchErr)
 			}
 
-			// Try with the next time window's code
-			fmt.Fprintf(os.Stderr, "🔑 Trying with next time window's code\n")
-			code = nextCode
+			secretCopy := make([]byte, len(secretBytes))
+			copy(secretCopy, secretBytes)
+			defer secure.SecureZeroBytes(secretCopy)
+
+			secure.SecureZeroBytes(secretBytes)
+
+			// Generate a code for the window after next, in case AWS is far ahead of our clock
+			futureCode, gErr := p.totp.GenerateForTimeBytes(secretCopy, p.TimeNow().Add(offset+60*time.Second))
+			if gErr != nil {
+				debugf("could not generate the future code (%v); not retrying", gErr)
+				break
+			}
+			fmt.Fprintf(os.Stderr, "🔑 Trying with future time window's code\n")
+			code = futureCode
 			codeBytes = []byte(code)
-			awsCreds, err = p.aws.GetSessionToken(p.profile, serial, codeBytes)
+			awsCreds, err = p.aws.GetSessionToken(p.profile, serial, codeBytes, p.sessionOptions())
 			secure.SecureZeroBytes(codeBytes)
-
-			// Re-evaluate whether the second attempt also failed with an invalid MFA error
-			secondInvalidMFA := err != nil &&
-				strings.Contains(err.Error(), "MultiFactorAuthentication failed with invalid MFA one time pass code")
-
-			// If STILL failing with invalid MFA and we're not close to boundary,
-			// we may need to wait for the next time window
-			freshSecondsLeft := p.SecondsLeftInWindow()
-			if secondInvalidMFA && freshSecondsLeft > 10 {
-				fmt.Fprintf(os.Stderr, "⚠️ Both current and next codes were rejected - may need to wait for next time window\n")
-
-				keyName, kErr := buildServiceKey(p.keyName, p.profile)
-				if kErr != nil {
-					return provider.Credentials{}, fmt.Errorf("failed to build service key: %w", kErr)
-				}
-
-				secretBytes, fetchErr := p.keychain.GetSecret(p.User, keyName)
-				if fetchErr != nil {
-					return provider.Credentials{}, fmt.Errorf("failed to retrieve TOTP secret for AWS %s: %w", formatProfile(p.profile), fetchErr)
-				}
-
-				secretCopy := make([]byte, len(secretBytes))
-				copy(secretCopy, secretBytes)
-				defer secure.SecureZeroBytes(secretCopy)
-
-				secure.SecureZeroBytes(secretBytes)
-
-				// Generate a code for the window after next, in case AWS is far ahead of our clock
-				futureCode, gErr := p.totp.GenerateForTimeBytes(secretCopy, p.TimeNow().Add(60*time.Second))
-				if gErr == nil {
-					fmt.Fprintf(os.Stderr, "🔑 Trying with future time window's code\n")
-					code = futureCode
-					codeBytes = []byte(code)
-					awsCreds, err = p.aws.GetSessionToken(p.profile, serial, codeBytes)
-					secure.SecureZeroBytes(codeBytes)
-				}
+			if err == nil {
+				debugf("future window code accepted")
+			} else {
+				debugf("future window code failed; giving up")
 			}
 		}
 	}
 
 	if err != nil {
 		// Check if this looks like a "code already used" error
-		if strings.Contains(err.Error(), "MultiFactorAuthentication failed with invalid MFA one time pass code") {
+		if isInvalidMFAError(err) {
 			// Add more context to the error message
-			return provider.Credentials{}, fmt.Errorf("failed to get session token (this may be because the TOTP code was recently used; try waiting for the next time window): %w", err)
+			return awsInternal.Credentials{}, fmt.Errorf("failed to get session token (this may be because the TOTP code was recently used; try waiting for the next time window): %w", err)
 		}
-		return provider.Credentials{}, fmt.Errorf("failed to get session token: %w", err)
+		return awsInternal.Credentials{}, fmt.Errorf("failed to get session token: %w", err)
 	}
 
-	defer awsCreds.ZeroSecrets()
+	return awsCreds, nil
+}
 
-	expiryTime, err := time.Parse(time.RFC3339, awsCreds.Expiration)
-	if err != nil {
-		expiryTime = p.TimeNow().Add(12 * time.Hour) // Default to 12h if we can't parse
+// isInvalidMFAError reports whether STS rejected the submitted code itself,
+// typically because it was already used in this window.
+func isInvalidMFAError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "MultiFactorAuthentication failed with invalid MFA one time pass code")
+}
+
+// iniCredentials renders session credentials as an ~/.aws/credentials
+// section. With --output-file the section is merged into that file and only
+// a confirmation is displayed; otherwise the block is printed to stdout, so
+// it can be redirected and --mask-output applies to it.
+func (p *Provider) iniCredentials(envVars map[string]string, expiry time.Time, profileStr string) (provider.Credentials, error) {
+	section := p.iniProfile
+	if section == "" {
+		section = defaultINIProfile(p.profile)
 	}
+	block := renderCredentialsINI(section, envVars, expiry)
 
-	envVars := map[string]string{
-		"AWS_ACCESS_KEY_ID":     awsCreds.AccessKeyID,
-		"AWS_SECRET_ACCESS_KEY": awsCreds.SecretAccessKey,
-		"AWS_SESSION_TOKEN":     awsCreds.SessionToken,
+	if p.outputFifo != "" {
+		return p.fifoCredentials(block, expiry, profileStr)
 	}
 
-	profileStr := formatProfile(p.profile)
+	creds := provider.Credentials{
+		Provider:         p.Name(),
+		Expiry:           expiry,
+		Variables:        map[string]string{},
+		MFAAuthenticated: true,
+	}
+
+	if p.outputFile == "" {
+		creds.Output = strings.TrimSuffix(block, "\n")
+		creds.DisplayInfo = provider.FormatRegularDisplayInfo("AWS credentials", profileStr) + fmt.Sprintf(" ([%s] section)", section)
+		return creds, nil
+	}
+
+	if err := writeINISection(p.outputFile, section, block); err != nil {
+		return provider.Credentials{}, fmt.Errorf("failed to write credentials file: %w", err)
+	}
+	creds.DisplayInfo = fmt.Sprintf("%s\n📝 Wrote [%s] to %s",
+		provider.FormatRegularDisplayInfo("AWS credentials", profileStr), section, p.outputFile)
+	return creds, nil
+}
+
+// dotenvCredentials merges the session variables into the --output-file
+// dotenv file under the profile's prefix (--append), so one file can hold
+// several profiles' credentials.
+func (p *Provider) dotenvCredentials(envVars map[string]string, expiry time.Time, profileStr string) (provider.Credentials, error) {
+	prefix := dotenvPrefix(p.profile)
+	if err := appendDotenv(p.outputFile, renderCredentialsDotenv(prefix, envVars, expiry)); err != nil {
+		return provider.Credentials{}, fmt.Errorf("failed to write credentials file: %w", err)
+	}
+	return provider.Credentials{
+		Provider:         p.Name(),
+		Expiry:           expiry,
+		Variables:        map[string]string{},
+		DisplayInfo:      fmt.Sprintf("%s\n📝 Wrote %s* variables to %s", provider.FormatRegularDisplayInfo("AWS credentials", profileStr), prefix, p.outputFile),
+		MFAAuthenticated: true,
+	}, nil
+}
+
+// base64Credentials renders --format base64: the credential object as
+// base64-encoded JSON on one line, printed to stdout (or written to
+// --output-fifo) so it survives transports that mangle shell quoting.
+func (p *Provider) base64Credentials(awsCreds awsInternal.Credentials, expiry time.Time, profileStr string) (provider.Credentials, error) {
+	encoded, err := awsInternal.EncodeCredentials(awsCreds)
+	if err != nil {
+		return provider.Credentials{}, err
+	}
+
+	if p.outputFifo != "" {
+		return p.fifoCredentials(encoded+"\n", expiry, profileStr)
+	}
 
 	return provider.Credentials{
 		Provider:         p.Name(),
-		Expiry:           expiryTime,
-		Variables:        envVars,
-		DisplayInfo:      provider.FormatRegularDisplayInfo("AWS credentials", profileStr),
-		MFAAuthenticated: true, // If we got this far, AWS STS accepted our MFA code
+		Expiry:           expiry,
+		Variables:        map[string]string{},
+		Output:           encoded,
+		DisplayInfo:      provider.FormatRegularDisplayInfo("AWS credentials", profileStr) + " (base64 JSON; decode with 'sesh --decode')",
+		MFAAuthenticated: true,
 	}, nil
 }
 
 // ListEntries returns all AWS entries in the keychain
 func (p *Provider) ListEntries() ([]provider.ProviderEntry, error) {
-	allEntries, err := p.keychain.ListEntries(constants.AWSServicePrefix)
+	if p.rawSerials && debugOutput == nil {
+		return nil, fmt.Errorf("--include-serial-entries is a debug view and requires --debug")
+	}
+
+	// The service type excludes the paired sesh-aws-serial/ MFA entries,
+	// which are implementation details.
+	allEntries, err := p.keychain.List(keychain.EntryFilter{ServiceType: p.secretPrefix()})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list AWS entries: %w", err)
 	}
 
-	result := make([]provider.ProviderEntry, 0, len(allEntries))
-	for _, entry := range allEntries {
-		// Skip MFA serial entries - we don't want to show these to users
-		// as they're implementation details and paired with the main entries
-		if strings.HasPrefix(entry.Service, constants.AWSServiceMFAPrefix) {
-			continue
+	// The backend returns entries in no particular order; sort by profile
+	// (default first) so --list output is stable across runs.
+	sort.Slice(allEntries, func(i, j int) bool {
+		pi, pj := parseServiceKey(allEntries[i].Service, p.secretPrefix()), parseServiceKey(allEntries[j].Service, p.secretPrefix())
+		if (pi == "default") != (pj == "default") {
+			return pi == "default"
+		}
+		if pi != pj {
+			return pi < pj
+		}
+		if allEntries[i].Service != allEntries[j].Service {
+			return allEntries[i].Service < allEntries[j].Service
 		}
+		return allEntries[i].Account < allEntries[j].Account
+	})
 
+	result := make([]provider.ProviderEntry, 0, len(allEntries))
+	for _, entry := range allEntries {
 		serviceName := entry.Service
-		profile := parseServiceKey(serviceName)
+		profile := parseServiceKey(serviceName, p.secretPrefix())
 
 		name := fmt.Sprintf("AWS (%s)", profile)
 		description := fmt.Sprintf("AWS MFA for %s", formatProfile(profile))
 
 		id := fmt.Sprintf("%s:%s", serviceName, entry.Account)
 
+		var details string
+		if p.details {
+			details, err = p.serialDetails(entry.Account, profile)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		result = append(result, provider.ProviderEntry{
 			Name:        name,
 			Description: description,
 			ID:          id,
+			Type:        p.Name(),
+			Profile:     profile,
+			Account:     entry.Account,
+			Details:     details,
+			UpdatedAt:   entry.UpdatedAt,
 		})
 	}
 
+	if p.rawSerials {
+		serials, err := p.rawSerialEntries()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, serials...)
+	}
+
+	return result, nil
+}
+
+// rawSerialEntries returns the sesh-aws-serial keychain entries that
+// ListEntries normally hides, with their raw service keys and accounts,
+// for diagnosing orphaned serials. Entries are marked "[debug]" so the
+// view can't be mistaken for the regular listing.
+func (p *Provider) rawSerialEntries() ([]provider.ProviderEntry, error) {
+	serials, err := p.keychain.List(keychain.EntryFilter{ServiceType: p.serialPrefix()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list AWS MFA serial entries: %w", err)
+	}
+	sort.Slice(serials, func(i, j int) bool {
+		if serials[i].Service != serials[j].Service {
+			return serials[i].Service < serials[j].Service
+		}
+		return serials[i].Account < serials[j].Account
+	})
+
+	result := make([]provider.ProviderEntry, 0, len(serials))
+	for _, entry := range serials {
+		// An unparseable key is exactly what this view is for, so it is
+		// listed with an empty profile rather than skipped.
+		var profile string
+		if segments, err := keyformat.Parse(entry.Service, p.serialPrefix()); err == nil && len(segments) > 0 {
+			profile = segments[0]
+		}
+		result = append(result, provider.ProviderEntry{
+			Name:        fmt.Sprintf("[debug] serial (%s)", profile),
+			Description: fmt.Sprintf("raw keychain entry service=%s account=%s", entry.Service, entry.Account),
+			ID:          fmt.Sprintf("%s:%s", entry.Service, entry.Account),
+			Type:        p.Name(),
+			EntryType:   "serial",
+			Profile:     profile,
+			Account:     entry.Account,
+			UpdatedAt:   entry.UpdatedAt,
+		})
+	}
 	return result, nil
 }
 
+// serialDetails reports whether profile's MFA serial is stored in the
+// keychain or will be auto-detected on each run, for --list --details.
+// It costs one extra keychain read per entry, so it is off by default.
+func (p *Provider) serialDetails(account, profile string) (string, error) {
+	mfaKey, err := buildServiceKey(p.serialPrefix(), profile)
+	if err != nil {
+		return "", fmt.Errorf("failed to build MFA service key: %w", err)
+	}
+	serial, err := p.keychain.GetSecret(account, mfaKey)
+	if err != nil {
+		if errors.Is(err, keychain.ErrNotFound) {
+			return "serial: auto-detect", nil
+		}
+		return "", fmt.Errorf("failed to read MFA serial for AWS %s: %w", formatProfile(profile), err)
+	}
+	secure.SecureZeroBytes(serial)
+	return "serial: stored", nil
+}
+
+// awsConfigPath returns the AWS CLI config location, honoring
+// AWS_CONFIG_FILE like the CLI does.
+func awsConfigPath() (string, error) {
+	if path := os.Getenv("AWS_CONFIG_FILE"); path != "" {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".aws", "config"), nil
+}
+
+// awsConfigProfile is one profile section of the AWS CLI config.
+type awsConfigProfile struct {
+	name      string
+	mfaSerial string
+}
+
+// parseAWSConfig returns the profile sections of an AWS CLI config in file
+// order. "[default]" is reported as the profile "default"; other sections
+// such as "[sso-session x]" are skipped.
+func parseAWSConfig(data string) []awsConfigProfile {
+	var profiles []awsConfigProfile
+	var current *awsConfigProfile
+
+	for line := range strings.SplitSeq(data, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section := strings.TrimSpace(line[1 : len(line)-1])
+			current = nil
+			name, isProfile := strings.CutPrefix(section, "profile ")
+			if section == "default" || isProfile {
+				profiles = append(profiles, awsConfigProfile{name: strings.TrimSpace(name)})
+				current = &profiles[len(profiles)-1]
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.TrimSpace(key) == "mfa_serial" {
+			current.mfaSerial = strings.TrimSpace(value)
+		}
+	}
+
+	return profiles
+}
+
 // getAWSProfiles reads AWS profiles from ~/.aws/config
 func (p *Provider) getAWSProfiles() ([]string, error) {
-	homeDir, err := os.UserHomeDir()
+	configPath, err := awsConfigPath()
 	if err != nil {
 		return nil, err
 	}
 
-	configPath := filepath.Join(homeDir, ".aws", "config")
-	data, err := os.ReadFile(configPath) //nolint:gosec // path is constructed from os.UserHomeDir() + hardcoded suffix
+	data, err := os.ReadFile(configPath) //nolint:gosec // path is the AWS CLI config location
 	if err != nil {
 		return nil, err
 	}
@@ -299,21 +738,44 @@ func (p *Provider) getAWSProfiles() ([]string, error) {
 	var profiles []string
 	profiles = append(profiles, "default") // Always include default
 
-	for line := range strings.SplitSeq(string(data), "\n") {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "[profile ") && strings.HasSuffix(line, "]") {
-			profile := strings.TrimPrefix(line, "[profile ")
-			profile = strings.TrimSuffix(profile, "]")
-			profiles = append(profiles, strings.TrimSpace(profile))
+	for _, profile := range parseAWSConfig(string(data)) {
+		if profile.name != "default" {
+			profiles = append(profiles, profile.name)
 		}
 	}
 
 	return profiles, nil
 }
 
+// configMFASerial returns the mfa_serial the AWS CLI config sets for
+// profile ("" meaning default), or "" when there is no config file or the
+// profile doesn't set one.
+func configMFASerial(profile string) (string, error) {
+	configPath, err := awsConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(configPath) //nolint:gosec // path is the AWS CLI config location
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	name := cmp.Or(profile, "default")
+	for _, p := range parseAWSConfig(string(data)) {
+		if p.name == name && p.mfaSerial != "" {
+			return p.mfaSerial, nil
+		}
+	}
+	return "", nil
+}
+
 // DeleteEntry deletes an AWS entry from the keychain
 func (p *Provider) DeleteEntry(id string) error {
-	service, account, err := provider.ParseEntryID(id)
+	service, account, err := provider.ParseEntryIDFor(id, p.secretPrefix(), p.serialPrefix())
 	if err != nil {
 		return err
 	}
@@ -323,9 +785,9 @@ func (p *Provider) DeleteEntry(id string) error {
 	}
 
 	// If this was an AWS entry, also delete the corresponding serial entry
-	segments, parseErr := keyformat.Parse(service, constants.AWSServicePrefix)
+	segments, parseErr := keyformat.Parse(service, p.secretPrefix())
 	if parseErr == nil && len(segments) > 0 {
-		serialService, buildErr := keyformat.Build(constants.AWSServiceMFAPrefix, segments...)
+		serialService, buildErr := keyformat.Build(p.serialPrefix(), segments...)
 		if buildErr == nil {
 			if err := p.keychain.DeleteEntry(account, serialService); err != nil {
 				// Log but don't fail if serial entry deletion fails
@@ -348,7 +810,7 @@ func (p *Provider) GetTOTPKeyInfo() (string, string, error) {
 		return "", "", err
 	}
 
-	keyName, err := buildServiceKey(p.keyName, p.profile)
+	keyName, err := buildServiceKey(p.secretPrefix(), p.profile)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to build service key: %w", err)
 	}
@@ -364,7 +826,7 @@ func (p *Provider) GetMFASerialBytes() ([]byte, error) {
 
 	var serialService string
 	var err error
-	serialService, err = buildServiceKey(constants.AWSServiceMFAPrefix, p.profile)
+	serialService, err = buildServiceKey(p.serialPrefix(), p.profile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build MFA service key: %w", err)
 	}
@@ -382,6 +844,15 @@ func (p *Provider) GetMFASerialBytes() ([]byte, error) {
 		return nil, fmt.Errorf("failed to read MFA serial from keychain: %w", err)
 	}
 
+	// The profile's mfa_serial in the AWS CLI config saves an IAM call
+	configSerial, err := configMFASerial(p.profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mfa_serial from AWS config: %w", err)
+	}
+	if configSerial != "" {
+		return []byte(configSerial), nil
+	}
+
 	serial, autoErr := p.aws.GetFirstMFADevice(p.profile)
 	if autoErr != nil {
 		return nil, fmt.Errorf("failed to detect MFA device: %w", autoErr)
@@ -392,27 +863,83 @@ func (p *Provider) GetMFASerialBytes() ([]byte, error) {
 
 // NewSubshellConfig creates a subshell configuration for AWS credentials
 func (p *Provider) NewSubshellConfig(creds *provider.Credentials) any {
+	customizer := awsInternal.NewCustomizer()
+	if p.promptFormat != "" && p.promptFormat != subshell.DefaultPromptFormat {
+		customizer = awsInternal.NewCustomizerWithPrompt(p.promptFormat, subshell.PromptVars{
+			Provider: p.Name(),
+			Profile:  p.profile,
+		})
+	}
+
 	return subshell.Config{
 		ServiceName:     p.Name(),
 		Variables:       creds.Variables,
 		Expiry:          creds.Expiry,
-		ShellCustomizer: awsInternal.NewCustomizer(),
+		ShellCustomizer: customizer,
 	}
 }
 
+// NormalizeFlags implements provider.FlagNormalizer: an explicit
+// --profile default becomes "", like an omitted one.
+func (p *Provider) NormalizeFlags() {
+	p.profile = normalizeProfile(p.profile)
+}
+
 // ValidateRequest performs early validation before any AWS operations.
 func (p *Provider) ValidateRequest() error {
+	switch p.format {
+	case "", formatEnv, formatINI, formatBase64:
+	default:
+		return fmt.Errorf("--format must be env, ini or base64, got %q", p.format)
+	}
+	if p.format != formatINI && p.iniProfile != "" {
+		return fmt.Errorf("--ini-profile requires --format ini")
+	}
+	// An ini file is always merged by section; an env file only makes
+	// sense prefixed per profile, which is what --append does.
+	envAppend := (p.format == "" || p.format == formatEnv) && p.appendOutput
+	if p.outputFile != "" && p.format != formatINI && !envAppend {
+		return fmt.Errorf("--output-file requires --format ini, or --format env with --append")
+	}
+	if p.appendOutput && p.outputFile == "" {
+		return fmt.Errorf("--append requires --output-file")
+	}
+	if p.outputFifo != "" && p.outputFile != "" {
+		return fmt.Errorf("--output-fifo and --output-file cannot be used together")
+	}
+	if p.outputFifo != "" && p.fifoTimeout <= 0 {
+		return fmt.Errorf("--timeout must be a positive number of seconds, got %d", p.fifoTimeout)
+	}
+	d, err := parseSessionDuration(p.duration)
+	if err != nil {
+		return err
+	}
+	p.sessionDuration = d
+	if p.whoami {
+		if p.renameTo != "" {
+			return fmt.Errorf("--whoami and --rename-profile cannot be used together")
+		}
+		// Only the profile's own credentials are used, so nothing needs
+		// to be in the keychain
+		return nil
+	}
+	if p.renameTo != "" {
+		// The old profile's entries are checked by the rename itself.
+		_, _, err := parseRenameProfile(p.renameTo)
+		return err
+	}
+
 	if err := p.EnsureUser(); err != nil {
 		return err
 	}
 
 	// Check if we have required keychain entries for this profile
 	// This prevents slow AWS API calls when no entry exists
-	totpKey, err := buildServiceKey(p.keyName, p.profile)
+	totpKey, err := buildServiceKey(p.secretPrefix(), p.profile)
 	if err != nil {
 		return fmt.Errorf("failed to build service key: %w", err)
 	}
-	mfaKey, err := buildServiceKey(constants.AWSServiceMFAPrefix, p.profile)
+	mfaKey, err := buildServiceKey(p.serialPrefix(), p.profile)
 	if err != nil {
 		return fmt.Errorf("failed to build MFA service key: %w", err)
 	}
@@ -422,11 +949,7 @@ func (p *Provider) ValidateRequest() error {
 		if !errors.Is(err, keychain.ErrNotFound) {
 			return fmt.Errorf("failed to read TOTP secret from keychain: %w", err)
 		}
-		profileDesc := p.profile
-		if profileDesc == "" {
-			profileDesc = "default"
-		}
-		return fmt.Errorf("no AWS entry found for profile '%s'. Run 'sesh --service aws --setup' first", profileDesc)
+		return p.validateHardwareRequest(totpKey, mfaKey)
 	}
 	secure.SecureZeroBytes(totpSecret)
 
@@ -436,8 +959,11 @@ func (p *Provider) ValidateRequest() error {
 		if !errors.Is(err, keychain.ErrNotFound) {
 			return fmt.Errorf("failed to read MFA serial from keychain: %w", err)
 		}
-		// Not found is not fatal — we can try to auto-detect, but warn the user
-		fmt.Fprintf(os.Stderr, "⚠️  MFA serial not found in keychain for profile '%s', will attempt auto-detection\n", p.profile)
+		// Not found is not fatal — the AWS config or auto-detection can
+		// supply it, but warn the user when only auto-detection is left
+		if serial, _ := configMFASerial(p.profile); serial == "" {
+			fmt.Fprintf(os.Stderr, theme.Warning()+" MFA serial not found in keychain for %s, will attempt auto-detection\n", formatProfile(p.profile))
+		}
 	} else {
 		secure.SecureZeroBytes(mfaSecret)
 	}
@@ -460,12 +986,147 @@ func (p *Provider) GetFlagInfo() []provider.FlagInfo {
 			Description: "Print environment variables instead of launching subshell",
 			Required:    false,
 		},
+		{
+			Name:        "copy-serial",
+			Type:        "bool",
+			Description: "Copy the MFA device ARN to the clipboard",
+			Required:    false,
+		},
+		{
+			Name:        "allow-reused-code",
+			Type:        "bool",
+			Description: "Submit the current code once, skipping the next/future-window retries",
+			Required:    false,
+		},
+		{
+			Name:        "format",
+			Type:        "string",
+			Description: "Output format: env (default), ini (~/.aws/credentials section) or base64 (single-line JSON for transport)",
+			Required:    false,
+		},
+		{
+			Name:        "ini-profile",
+			Type:        "string",
+			Description: "Section name for --format ini (default: <profile>-sesh)",
+			Required:    false,
+		},
+		{
+			Name:        "output-file",
+			Type:        "string",
+			Description: "Merge the --format ini section into this file (written 0600)",
+			Required:    false,
+		},
+		{
+			Name:        "append",
+			Type:        "bool",
+			Description: "With --output-file, accumulate profiles in one file; --format env writes <PROFILE>_-prefixed variables",
+			Required:    false,
+		},
+		{
+			Name:        "prompt-format",
+			Type:        "string",
+			Description: "Subshell prompt prefix (default \"(sesh:{provider}) \"); placeholders: {provider}, {profile}, {expires}",
+			Required:    false,
+		},
+		{
+			Name:        "output-fifo",
+			Type:        "string",
+			Description: "Write credentials in the chosen --format to this named pipe (created 0600 if absent)",
+			Required:    false,
+		},
+		{
+			Name:        "timeout",
+			Type:        "int",
+			Description: "Seconds --output-fifo waits for a reader (default 30)",
+			Required:    false,
+		},
+		{
+			Name:        "rename-profile",
+			Type:        "string",
+			Description: "Move a profile's stored secret, serial and metadata to a new profile name: old=new",
+			Required:    false,
+		},
+		{
+			Name:        "force",
+			Type:        "bool",
+			Description: "With --rename-profile, overwrite entries the new profile already has",
+			Required:    false,
+		},
+		{
+			Name:        "whoami",
+			Type:        "bool",
+			Description: "Print the ARN and account behind the profile's credentials, without MFA or a session token",
+			Required:    false,
+		},
+		{
+			Name:        "details",
+			Type:        "bool",
+			Description: "With --list, show whether each profile's MFA serial is stored or auto-detected",
+			Required:    false,
+		},
+		{
+			Name:        "region",
+			Type:        "string",
+			Description: "AWS region for the STS call (default: the profile's region)",
+			Required:    false,
+		},
+		{
+			Name:        "duration",
+			Type:        "string",
+			Description: "Session length, 15m to 36h (default: STS's 12h)",
+			Required:    false,
+		},
+		{
+			Name:        "keychain-user",
+			Type:        "string",
+			Description: "Keychain account the secret is stored under (default: current user)",
+			Required:    false,
+		},
 	}
 }
 
-// ShouldUseSubshell returns whether to use subshell mode
+// ShouldUseSubshell returns whether to use subshell mode. INI output
+// implies printing, since a subshell has nothing to render it into, and
+// --rename-profile and --whoami fetch no credentials at all.
 func (p *Provider) ShouldUseSubshell() bool {
-	return !p.noSubshell
+	return !p.noSubshell && p.format != formatINI && p.format != formatBase64 && p.outputFifo == "" && p.outputFile == "" && p.renameTo == "" && !p.whoami
+}
+
+// SuppressActionFraming drops the "Generating credentials" framing for
+// --rename-profile and --whoami, which don't fetch credentials.
+func (p *Provider) SuppressActionFraming() bool {
+	return p.renameTo != "" || p.whoami
+}
+
+// SessionStatus reports whether the current environment holds an AWS session
+// started by sesh. It only inspects the environment — SESH_SERVICE and
+// SESH_EXPIRY set by the subshell, and AWS_SESSION_TOKEN — and never calls
+// AWS or the keychain.
+func (p *Provider) SessionStatus() (bool, time.Time, error) {
+	if os.Getenv("AWS_SESSION_TOKEN") == "" {
+		return false, time.Time{}, nil
+	}
+	if svc := os.Getenv("SESH_SERVICE"); svc != "" && svc != p.Name() {
+		return false, time.Time{}, nil
+	}
+
+	raw := os.Getenv("SESH_EXPIRY")
+	if raw == "" {
+		// Token present but no sesh expiry stamp (e.g. exported with
+		// --no-subshell): active, lifetime unknown.
+		return true, time.Time{}, nil
+	}
+	secs, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("invalid SESH_EXPIRY %q: %w", raw, err)
+	}
+	expiry := time.Unix(secs, 0)
+	return p.TimeNow().Before(expiry), expiry, nil
+}
+
+// ShouldCopyToClipboard reports whether --copy-serial selected clipboard mode.
+func (p *Provider) ShouldCopyToClipboard() bool {
+	return p.copySerial
 }
 
 // buildServiceKey creates a service key for the keychain using keyformat.Build.
@@ -477,6 +1138,33 @@ func buildServiceKey(prefix, profile string) (string, error) {
 	return keyformat.Build(prefix, profile)
 }
 
+// timeOffset returns the --time-offset stored with the entry at keyName at
+// setup, or zero if it has none. Like the TOTP provider's params, it is
+// best-effort: unreadable metadata means no offset rather than a failure.
+func (p *Provider) timeOffset(keyName string) time.Duration {
+	description, err := p.entryDescription(keyName)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(internalTotp.ParseParams(description).TimeOffset) * time.Second
+}
+
+// secondsLeftInWindow returns the seconds left in the 30-second AWS TOTP
+// window containing t.
+func secondsLeftInWindow(t time.Time) int64 {
+	return 30 - t.Unix()%30
+}
+
+// normalizeProfile maps an explicit "default" profile to "", the value an
+// omitted --profile has, so both resolve to the same keys, messages and AWS
+// CLI invocations.
+func normalizeProfile(profile string) string {
+	if profile == "default" {
+		return ""
+	}
+	return profile
+}
+
 // formatProfile returns a formatted profile description
 // Returns "profile (default)" or "profile (name)"
 func formatProfile(profile string) string {
@@ -487,10 +1175,10 @@ func formatProfile(profile string) string {
 	return fmt.Sprintf("profile (%s)", name)
 }
 
-// parseServiceKey extracts the profile from a service key using keyformat.Parse.
-// For "sesh-aws/default" returns "default".
-func parseServiceKey(serviceKey string) string {
-	segments, err := keyformat.Parse(serviceKey, constants.AWSServicePrefix)
+// parseServiceKey extracts the profile from a service key under prefix using
+// keyformat.Parse. For "sesh-aws/default" returns "default".
+func parseServiceKey(serviceKey, prefix string) string {
+	segments, err := keyformat.Parse(serviceKey, prefix)
 	if err != nil || len(segments) == 0 {
 		return ""
 	}
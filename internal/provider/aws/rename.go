@@ -0,0 +1,187 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bashhack/sesh/internal/keychain"
+	"github.com/bashhack/sesh/internal/provider"
+	"github.com/bashhack/sesh/internal/secure"
+	"github.com/bashhack/sesh/internal/theme"
+)
+
+// parseRenameProfile splits a --rename-profile value of the form old=new.
+func parseRenameProfile(value string) (oldProfile, newProfile string, err error) {
+	oldProfile, newProfile, ok := strings.Cut(value, "=")
+	oldProfile, newProfile = strings.TrimSpace(oldProfile), strings.TrimSpace(newProfile)
+	if !ok || oldProfile == "" || newProfile == "" {
+		return "", "", fmt.Errorf("--rename-profile must be old=new, got %q", value)
+	}
+	if oldProfile == newProfile {
+		return "", "", fmt.Errorf("--rename-profile: old and new profile are both %q", oldProfile)
+	}
+	return oldProfile, newProfile, nil
+}
+
+// profileKeys are the keychain service keys sesh stores for one profile.
+type profileKeys struct {
+	secret string
+	serial string
+}
+
+func (p *Provider) keysForProfile(profile string) (profileKeys, error) {
+	secret, err := buildServiceKey(p.secretPrefix(), profile)
+	if err != nil {
+		return profileKeys{}, fmt.Errorf("failed to build service key: %w", err)
+	}
+	serial, err := buildServiceKey(p.serialPrefix(), profile)
+	if err != nil {
+		return profileKeys{}, fmt.Errorf("failed to build MFA service key: %w", err)
+	}
+	return profileKeys{secret: secret, serial: serial}, nil
+}
+
+// renameProfile moves the TOTP secret, MFA serial and listing description
+// stored for one AWS profile to another, for when a profile is renamed in
+// ~/.aws/config. Every write under the new name happens before anything
+// under the old name is deleted, so a failure part way through never loses
+// the entry. The new profile's entries are only overwritten with --force.
+func (p *Provider) renameProfile() (provider.Credentials, error) {
+	oldProfile, newProfile, err := parseRenameProfile(p.renameTo)
+	if err != nil {
+		return provider.Credentials{}, err
+	}
+	if err := p.EnsureUser(); err != nil {
+		return provider.Credentials{}, err
+	}
+
+	oldKeys, err := p.keysForProfile(oldProfile)
+	if err != nil {
+		return provider.Credentials{}, err
+	}
+	newKeys, err := p.keysForProfile(newProfile)
+	if err != nil {
+		return provider.Credentials{}, err
+	}
+
+	secret, err := p.keychain.GetSecret(p.User, oldKeys.secret)
+	if err != nil {
+		if errors.Is(err, keychain.ErrNotFound) {
+			return provider.Credentials{}, fmt.Errorf("no AWS entry found for profile '%s'", oldProfile)
+		}
+		return provider.Credentials{}, fmt.Errorf("failed to read TOTP secret for AWS %s: %w", formatProfile(oldProfile), err)
+	}
+	defer secure.SecureZeroBytes(secret)
+
+	// Older entries may have no stored serial (it was auto-detected), so
+	// a missing one is simply not moved.
+	serial, err := p.keychain.GetSecret(p.User, oldKeys.serial)
+	if err != nil && !errors.Is(err, keychain.ErrNotFound) {
+		return provider.Credentials{}, fmt.Errorf("failed to read MFA serial from keychain: %w", err)
+	}
+	defer secure.SecureZeroBytes(serial)
+
+	if !p.force {
+		for _, key := range []string{newKeys.secret, newKeys.serial} {
+			existing, err := p.keychain.GetSecret(p.User, key)
+			if err == nil {
+				secure.SecureZeroBytes(existing)
+				return provider.Credentials{}, fmt.Errorf("AWS %s already has sesh entries; pass --force to overwrite them", formatProfile(newProfile))
+			}
+			if !errors.Is(err, keychain.ErrNotFound) {
+				return provider.Credentials{}, fmt.Errorf("failed to check for existing entry %s: %w", key, err)
+			}
+		}
+	}
+
+	description, err := p.entryDescription(oldKeys.secret)
+	if err != nil {
+		return provider.Credentials{}, err
+	}
+	if description == fmt.Sprintf("AWS MFA for profile %s", oldProfile) {
+		description = fmt.Sprintf("AWS MFA for profile %s", newProfile)
+	}
+
+	// Serial before secret, as in setup: a half-written rename then has
+	// no secret under the new name and isn't mistaken for a complete one.
+	if serial != nil {
+		if err := p.keychain.SetSecret(p.User, newKeys.serial, serial); err != nil {
+			return provider.Credentials{}, fmt.Errorf("failed to store MFA serial for AWS %s (old entries untouched): %w", formatProfile(newProfile), err)
+		}
+	}
+	if err := p.keychain.SetSecret(p.User, newKeys.secret, secret); err != nil {
+		return provider.Credentials{}, fmt.Errorf("failed to store TOTP secret for AWS %s (old entries untouched): %w", formatProfile(newProfile), err)
+	}
+	if description != "" {
+		if err := p.keychain.SetDescription(newKeys.secret, p.User, description); err != nil {
+			return provider.Credentials{}, fmt.Errorf("failed to store description for AWS %s (old entries untouched): %w", formatProfile(newProfile), err)
+		}
+	}
+
+	if p.force {
+		if err := p.clearStaleTarget(newKeys, serial == nil, description == ""); err != nil {
+			return provider.Credentials{}, fmt.Errorf("failed to clear stale entries for AWS %s (old entries untouched): %w", formatProfile(newProfile), err)
+		}
+	}
+
+	if err := p.keychain.DeleteEntry(p.User, oldKeys.secret); err != nil {
+		return provider.Credentials{}, fmt.Errorf("renamed to %s but failed to remove the old entry %s: %w", newProfile, oldKeys.secret, err)
+	}
+	if serial != nil {
+		if err := p.keychain.DeleteEntry(p.User, oldKeys.serial); err != nil {
+			return provider.Credentials{}, fmt.Errorf("renamed to %s but failed to remove the old entry %s: %w", newProfile, oldKeys.serial, err)
+		}
+	}
+
+	return provider.Credentials{
+		Provider:    p.Name(),
+		Variables:   map[string]string{},
+		DisplayInfo: fmt.Sprintf(theme.OK()+" Renamed AWS profile %s to %s", oldProfile, newProfile),
+	}, nil
+}
+
+// entryDescription returns the listing description stored for service
+// under the provider's keychain account, or "" if it has none.
+// clearStaleTarget removes what a forced rename didn't overwrite: the
+// target's serial when the source had none, and its description when
+// the source had none, so nothing from the replaced profile survives.
+func (p *Provider) clearStaleTarget(keys profileKeys, noSerial, noDescription bool) error {
+	if noSerial {
+		existing, err := p.keychain.GetSecret(p.User, keys.serial)
+		switch {
+		case err == nil:
+			secure.SecureZeroBytes(existing)
+			if err := p.keychain.DeleteEntry(p.User, keys.serial); err != nil {
+				return err
+			}
+		case !errors.Is(err, keychain.ErrNotFound):
+			return err
+		}
+	}
+	if noDescription {
+		stale, err := p.entryDescription(keys.secret)
+		if err != nil {
+			return err
+		}
+		if stale != "" {
+			return p.keychain.SetDescription(keys.secret, p.User, "")
+		}
+	}
+	return nil
+}
+
+func (p *Provider) entryDescription(service string) (string, error) {
+	entries, err := p.keychain.List(keychain.EntryFilter{ServicePrefix: service, Account: p.User})
+	if err != nil {
+		return "", fmt.Errorf("failed to read entry metadata: %w", err)
+	}
+	for _, e := range entries {
+		// The filter is a prefix match; "sesh-aws/work" also finds
+		// "sesh-aws/work-old".
+		if e.Service == service {
+			return e.Description, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,165 @@
+package aws
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/bashhack/sesh/internal/keychain"
+	keychainMocks "github.com/bashhack/sesh/internal/keychain/mocks"
+)
+
+// renameKeychain is a map-backed keychain for --rename-profile tests.
+type renameKeychain struct {
+	secrets      map[string]string // service -> secret
+	descriptions map[string]string // service -> description
+}
+
+func (k *renameKeychain) mock() *keychainMocks.MockProvider {
+	return &keychainMocks.MockProvider{
+		GetSecretFunc: func(_, service string) ([]byte, error) {
+			s, ok := k.secrets[service]
+			if !ok {
+				return nil, keychain.ErrNotFound
+			}
+			return []byte(s), nil
+		},
+		SetSecretFunc: func(_, service string, secret []byte) error {
+			k.secrets[service] = string(secret)
+			return nil
+		},
+		SetDescriptionFunc: func(service, _, description string) error {
+			k.descriptions[service] = description
+			return nil
+		},
+		DeleteEntryFunc: func(_, service string) error {
+			delete(k.secrets, service)
+			delete(k.descriptions, service)
+			return nil
+		},
+		ListFunc: func(filter keychain.EntryFilter) ([]keychain.KeychainEntryMeta, error) {
+			var out []keychain.KeychainEntryMeta
+			for service := range k.secrets {
+				if strings.HasPrefix(service, filter.ServicePrefix) {
+					out = append(out, keychain.KeychainEntryMeta{Service: service, Account: "testuser", Description: k.descriptions[service]})
+				}
+			}
+			return out, nil
+		},
+	}
+}
+
+func TestProvider_RenameProfile(t *testing.T) {
+	const serial = "arn:aws:iam::123456789012:mfa/me"
+
+	tests := map[string]struct {
+		rename       string
+		force        bool
+		secrets      map[string]string
+		descriptions map[string]string
+		wantSecrets  map[string]string
+		wantDescs    map[string]string
+		wantErrMsg   string
+	}{
+		"moves secret, serial and metadata": {
+			rename: "work=work-prod",
+			secrets: map[string]string{
+				"sesh-aws/work":              "SECRET",
+				"sesh-aws-serial/work":       serial,
+				"sesh-aws/work-old":          "OTHER",
+				"sesh-aws-serial/work-older": serial,
+			},
+			descriptions: map[string]string{"sesh-aws/work": "AWS MFA for profile work", "sesh-aws/work-old": "AWS MFA for profile work-old"},
+			wantSecrets: map[string]string{
+				"sesh-aws/work-prod":         "SECRET",
+				"sesh-aws-serial/work-prod":  serial,
+				"sesh-aws/work-old":          "OTHER",
+				"sesh-aws-serial/work-older": serial,
+			},
+			wantDescs: map[string]string{"sesh-aws/work-prod": "AWS MFA for profile work-prod", "sesh-aws/work-old": "AWS MFA for profile work-old"},
+		},
+		"custom description is kept": {
+			rename:       "default=personal",
+			secrets:      map[string]string{"sesh-aws/default": "SECRET", "sesh-aws-serial/default": serial},
+			descriptions: map[string]string{"sesh-aws/default": "AWS MFA"},
+			wantSecrets:  map[string]string{"sesh-aws/personal": "SECRET", "sesh-aws-serial/personal": serial},
+			wantDescs:    map[string]string{"sesh-aws/personal": "AWS MFA"},
+		},
+		"missing old profile": {
+			rename:     "ghost=work",
+			secrets:    map[string]string{},
+			wantErrMsg: "no AWS entry found for profile 'ghost'",
+		},
+		"new profile exists": {
+			rename:     "work=prod",
+			secrets:    map[string]string{"sesh-aws/work": "SECRET", "sesh-aws-serial/work": serial, "sesh-aws-serial/prod": "arn:other"},
+			wantErrMsg: "pass --force",
+		},
+		"new profile exists with force": {
+			rename:      "work=prod",
+			force:       true,
+			secrets:     map[string]string{"sesh-aws/work": "SECRET", "sesh-aws-serial/work": serial, "sesh-aws/prod": "STALE", "sesh-aws-serial/prod": "arn:other"},
+			wantSecrets: map[string]string{"sesh-aws/prod": "SECRET", "sesh-aws-serial/prod": serial},
+			wantDescs:   map[string]string{},
+		},
+		"force with no old serial": {
+			rename:       "work=prod",
+			force:        true,
+			secrets:      map[string]string{"sesh-aws/work": "SECRET", "sesh-aws/prod": "STALE", "sesh-aws-serial/prod": "arn:other"},
+			descriptions: map[string]string{"sesh-aws/prod": "AWS MFA for profile prod"},
+			wantSecrets:  map[string]string{"sesh-aws/prod": "SECRET"},
+			wantDescs:    map[string]string{"sesh-aws/prod": ""},
+		},
+		"malformed value": {
+			rename:     "work",
+			wantErrMsg: "must be old=new",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			kc := &renameKeychain{secrets: map[string]string{}, descriptions: map[string]string{}}
+			maps.Copy(kc.secrets, tc.secrets)
+			maps.Copy(kc.descriptions, tc.descriptions)
+
+			p := &Provider{keychain: kc.mock(), renameTo: tc.rename, force: tc.force}
+			p.User = "testuser"
+
+			creds, err := p.GetCredentials()
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("GetCredentials() error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				if !maps.Equal(kc.secrets, tc.secrets) {
+					t.Errorf("keychain changed on error: %v", kc.secrets)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetCredentials() error = %v", err)
+			}
+			if !strings.Contains(creds.DisplayInfo, "Renamed AWS profile") {
+				t.Errorf("DisplayInfo = %q", creds.DisplayInfo)
+			}
+			if !maps.Equal(kc.secrets, tc.wantSecrets) {
+				t.Errorf("secrets = %v, want %v", kc.secrets, tc.wantSecrets)
+			}
+			if !maps.Equal(kc.descriptions, tc.wantDescs) {
+				t.Errorf("descriptions = %v, want %v", kc.descriptions, tc.wantDescs)
+			}
+		})
+	}
+}
+
+func TestProvider_RenameProfile_SkipsSubshell(t *testing.T) {
+	p := &Provider{renameTo: "a=b"}
+	if p.ShouldUseSubshell() {
+		t.Error("--rename-profile should not launch a subshell")
+	}
+	if !p.SuppressActionFraming() {
+		t.Error("--rename-profile should suppress the credential framing")
+	}
+	if err := p.ValidateRequest(); err != nil {
+		t.Errorf("ValidateRequest() error = %v", err)
+	}
+}
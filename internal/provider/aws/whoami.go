@@ -0,0 +1,31 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bashhack/sesh/internal/provider"
+)
+
+// callerIdentity implements --whoami: it asks STS who the profile's own
+// credentials belong to, without generating a code or starting a session,
+// so a user can check which account and user a profile points at before
+// running setup or after editing ~/.aws/config.
+func (p *Provider) callerIdentity() (provider.Credentials, error) {
+	identity, err := p.aws.GetCallerIdentity(p.profile)
+	if err != nil {
+		return provider.Credentials{}, fmt.Errorf("failed to get caller identity for AWS %s: %w", formatProfile(p.profile), err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Arn:     %s\n", identity.Arn)
+	fmt.Fprintf(&b, "Account: %s\n", identity.Account)
+	fmt.Fprintf(&b, "UserId:  %s", identity.UserID)
+
+	return provider.Credentials{
+		Provider:    p.Name(),
+		Variables:   map[string]string{},
+		DisplayInfo: fmt.Sprintf("🪪 Caller identity for AWS %s", formatProfile(p.profile)),
+		Output:      b.String(),
+	}, nil
+}
@@ -0,0 +1,99 @@
+package aws
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	awsInternal "github.com/bashhack/sesh/internal/aws"
+	awsMocks "github.com/bashhack/sesh/internal/aws/mocks"
+	keychainMocks "github.com/bashhack/sesh/internal/keychain/mocks"
+	"github.com/bashhack/sesh/internal/provider"
+	totpMocks "github.com/bashhack/sesh/internal/totp/mocks"
+)
+
+func TestProvider_Whoami(t *testing.T) {
+	identity := awsInternal.CallerIdentity{
+		UserID:  "AIDAEXAMPLE",
+		Account: "123456789012",
+		Arn:     "arn:aws:iam::123456789012:user/alice",
+	}
+
+	tests := map[string]struct {
+		profile     string
+		renameTo    string
+		identityErr error
+		wantProfile string
+		wantOutput  string
+		wantInfo    string
+		wantErr     string
+	}{
+		"named profile": {
+			profile:     "prod",
+			wantProfile: "prod",
+			wantOutput:  "Arn:     arn:aws:iam::123456789012:user/alice\nAccount: 123456789012\nUserId:  AIDAEXAMPLE",
+			wantInfo:    "Caller identity for AWS profile (prod)",
+		},
+		"default profile": {
+			wantInfo: "Caller identity for AWS profile (default)",
+		},
+		"sts fails": {
+			profile:     "prod",
+			identityErr: errors.New("Unable to locate credentials"),
+			wantErr:     "failed to get caller identity for AWS profile (prod): Unable to locate credentials",
+		},
+		"with rename-profile": {
+			renameTo: "old=new",
+			wantErr:  "--whoami and --rename-profile cannot be used together",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var gotProfile string
+			mockAWS := &awsMocks.MockProvider{
+				GetCallerIdentityFunc: func(profile string) (awsInternal.CallerIdentity, error) {
+					gotProfile = profile
+					return identity, tc.identityErr
+				},
+				GetSessionTokenFunc: func(string, string, []byte, awsInternal.SessionOptions) (awsInternal.Credentials, error) {
+					t.Fatal("--whoami must not request a session token")
+					return awsInternal.Credentials{}, nil
+				},
+			}
+			// No keychain funcs: --whoami must not read any entries
+			p := NewProvider(mockAWS, &keychainMocks.MockProvider{}, &totpMocks.MockProvider{})
+			p.profile = tc.profile
+			p.renameTo = tc.renameTo
+			p.whoami = true
+
+			creds, err := func() (provider.Credentials, error) {
+				if err := p.ValidateRequest(); err != nil {
+					return provider.Credentials{}, err
+				}
+				return p.GetCredentials()
+			}()
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if gotProfile != tc.wantProfile {
+				t.Errorf("GetCallerIdentity profile = %q, want %q", gotProfile, tc.wantProfile)
+			}
+			if tc.wantOutput != "" && creds.Output != tc.wantOutput {
+				t.Errorf("Output =\n%s\nwant\n%s", creds.Output, tc.wantOutput)
+			}
+			if !strings.Contains(creds.DisplayInfo, tc.wantInfo) {
+				t.Errorf("DisplayInfo = %q, want it to contain %q", creds.DisplayInfo, tc.wantInfo)
+			}
+			if p.ShouldUseSubshell() {
+				t.Error("ShouldUseSubshell() = true, want false with --whoami")
+			}
+		})
+	}
+}
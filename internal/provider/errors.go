@@ -0,0 +1,52 @@
+package provider
+
+import "fmt"
+
+// ErrorCode is a stable, machine-readable identifier for a class of failure.
+// Codes are part of the CLI's scripting contract; don't rename them.
+type ErrorCode string
+
+// Known error codes.
+const (
+	CodeNotSetup        ErrorCode = "not_setup"
+	CodeUnknownProvider ErrorCode = "unknown_provider"
+	CodeNotSupported    ErrorCode = "not_supported"
+	CodeUsage           ErrorCode = "usage"
+)
+
+// Error is a failure that carries an ErrorCode alongside its human-readable
+// message. Compare with errors.Is against the sentinels below; two Errors
+// match when their codes match, regardless of message.
+type Error struct {
+	Code ErrorCode
+	Msg  string
+}
+
+// Error returns the human-readable message.
+func (e *Error) Error() string { return e.Msg }
+
+// Is reports whether target is an *Error with the same code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Sentinels for errors.Is checks.
+var (
+	ErrNotSetup        = &Error{Code: CodeNotSetup, Msg: "not set up"}
+	ErrUnknownProvider = &Error{Code: CodeUnknownProvider, Msg: "unknown provider"}
+	ErrNotSupported    = &Error{Code: CodeNotSupported, Msg: "not supported"}
+	ErrUsage           = &Error{Code: CodeUsage, Msg: "invalid command line"}
+)
+
+// NotSetupError returns an ErrNotSetup-class error with a formatted message.
+// Providers use it when the requested entry has no stored secret yet.
+func NotSetupError(format string, args ...any) error {
+	return &Error{Code: CodeNotSetup, Msg: fmt.Sprintf(format, args...)}
+}
+
+// UsageError returns an ErrUsage-class error with a formatted message, for
+// a command line that doesn't parse.
+func UsageError(format string, args ...any) error {
+	return &Error{Code: CodeUsage, Msg: fmt.Sprintf(format, args...)}
+}
@@ -2,11 +2,15 @@
 package provider
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/bashhack/sesh/internal/env"
+	"github.com/bashhack/sesh/internal/keychain"
+	"github.com/bashhack/sesh/internal/keyformat"
 )
 
 // FlagSet defines the interface for registering flags
@@ -67,10 +71,10 @@ type ServiceProvider interface {
 
 // FlagInfo describes a provider-specific flag
 type FlagInfo struct {
-	Name        string
-	Type        string // "string", "bool", etc.
-	Description string
-	Required    bool
+	Name        string `json:"name"`
+	Type        string `json:"type"` // "string", "bool", etc.
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
 }
 
 // SubshellDecider is an optional interface that providers can implement
@@ -79,6 +83,27 @@ type SubshellDecider interface {
 	ShouldUseSubshell() bool
 }
 
+// SessionStatusProvider is an optional interface for providers whose
+// credentials form a time-limited session. SessionStatus reports whether a
+// session is currently active, and when it expires, without fetching new
+// credentials. A zero expiry means the session's lifetime is unknown.
+type SessionStatusProvider interface {
+	SessionStatus() (active bool, expiry time.Time, err error)
+}
+
+// ClipboardDecider is an optional interface that providers can implement
+// when one of their own flags implies clipboard mode (as if --clip were set).
+type ClipboardDecider interface {
+	ShouldCopyToClipboard() bool
+}
+
+// LoginURLProvider is an optional interface for providers that can name
+// the web login page for the selected entry, so --open can launch it in
+// the browser next to the copied code.
+type LoginURLProvider interface {
+	LoginURL() (string, error)
+}
+
 // QuietProvider is an optional interface for providers that should not
 // print the app's generic "Generating credentials… / Credentials acquired
 // in Xs" framing. Useful for providers whose actions aren't a single
@@ -89,6 +114,34 @@ type QuietProvider interface {
 	SuppressActionFraming() bool
 }
 
+// EnvOverrider is an optional interface for providers whose flag defaults
+// can come from environment variables. EnvOverrides returns the flags so
+// set, keyed by name, so the app can keep a .sesh file from replacing them.
+type EnvOverrider interface {
+	EnvOverrides() map[string]string
+}
+
+// EnvSourcer is an optional interface for providers that can name the
+// environment variable behind each flag default it sets, for
+// --print-config. Keys are flag names.
+type EnvSourcer interface {
+	EnvSources() map[string]string
+}
+
+// KeyNamespacer is an optional interface for providers that can name the
+// keychain namespaces (e.g. "sesh-totp") their secrets are stored under,
+// so the app can check those items against the listing index.
+type KeyNamespacer interface {
+	KeyNamespaces() []string
+}
+
+// FlagNormalizer is an optional interface for providers that tidy their
+// flag values once parsing is done. The app calls NormalizeFlags right
+// after parsing, before any other provider method reads the flags.
+type FlagNormalizer interface {
+	NormalizeFlags()
+}
+
 // SubshellProvider is an optional interface that providers can implement
 // if they support launching a customized subshell environment
 type SubshellProvider interface {
@@ -99,9 +152,19 @@ type SubshellProvider interface {
 
 // ProviderEntry represents an entry for a specific provider
 type ProviderEntry struct {
-	Name        string // Entry name (e.g. AWS Profile or GCP Project)
-	Description string // Human-readable description
-	ID          string // Internal identifier
+	Name        string `json:"name"`                   // Entry name (e.g. AWS Profile or GCP Project)
+	Description string `json:"description"`            // Human-readable description
+	ID          string `json:"id"`                     // Internal identifier
+	Type        string `json:"type"`                   // Provider the entry belongs to (aws, totp, password)
+	Profile     string `json:"profile,omitempty"`      // AWS profile or TOTP profile, if any
+	ServiceName string `json:"service_name,omitempty"` // TOTP or password service name
+	Username    string `json:"username,omitempty"`     // Password entry username, if any
+	Issuer      string `json:"issuer,omitempty"`       // Friendly issuer name shown in place of a terse TOTP service name
+	Account     string `json:"account,omitempty"`      // Keychain account the secret is stored under, if known
+	Details     string `json:"details,omitempty"`      // Extra provider-specific detail, e.g. AWS --details serial state
+	EntryType   string `json:"entry_type,omitempty"`   // Kind of password entry (password, api_key, ...), if any
+
+	UpdatedAt time.Time `json:"updated_at,omitzero"` // When the entry was last stored, if known
 }
 
 // Clock provides testable time. Embed in provider structs and override Now in tests.
@@ -141,24 +204,93 @@ func (k *KeyUser) EnsureUser() error {
 	return nil
 }
 
+// ErrInvalidEntryID is wrapped by every entry ID validation error, so
+// callers can point the user at where valid IDs come from.
+var ErrInvalidEntryID = errors.New("invalid entry ID format")
+
 // ParseEntryID splits an entry ID of the form "service:account" into its parts.
+// Both parts must be non-empty.
 func ParseEntryID(id string) (service, account string, err error) {
 	parts := strings.SplitN(id, ":", 2)
-	if len(parts) != 2 {
-		return "", "", fmt.Errorf("invalid entry ID format: expected 'service:account', got %q", id)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("%w: expected 'service:account', got %q", ErrInvalidEntryID, id)
 	}
 	return parts[0], parts[1], nil
 }
 
+// ParseEntryIDFor is ParseEntryID for a provider that owns the given
+// service key namespaces: an ID whose service key is outside all of them
+// belongs to another provider and is rejected before anything is deleted.
+func ParseEntryIDFor(id string, namespaces ...string) (service, account string, err error) {
+	service, account, err = ParseEntryID(id)
+	if err != nil {
+		return "", "", err
+	}
+	for _, ns := range namespaces {
+		if _, err := keyformat.Parse(service, ns); err == nil {
+			return service, account, nil
+		}
+	}
+	return "", "", fmt.Errorf("%w: service key %q is not under %s", ErrInvalidEntryID, service, strings.Join(namespaces, " or "))
+}
+
+// CheckNotBlank rejects a flag value that is only whitespace, which would
+// otherwise become a keychain key no one can type back. An empty value
+// passes; callers report a missing required flag themselves.
+func CheckNotBlank(flag, value string) error {
+	if value != "" && strings.TrimSpace(value) == "" {
+		return UsageError("%s must not be blank", flag)
+	}
+	return nil
+}
+
+// KeychainUserHint adds a --keychain-user suggestion to notFound when
+// service is stored, but only under other keychain accounts: a secret saved
+// under an old username otherwise looks just like a missing one.
+// listEntries is the keychain's ListEntries. The hint is only a diagnostic,
+// so if listing fails notFound is returned unchanged.
+func KeychainUserHint(notFound error, listEntries func(prefix string) ([]keychain.KeychainEntry, error), service, account string) error {
+	entries, err := listEntries(service)
+	if err != nil {
+		return notFound
+	}
+	var others []string
+	for _, e := range entries {
+		// ListEntries matches by prefix; only the exact service counts
+		if e.Service == service && e.Account != account && !slices.Contains(others, e.Account) {
+			others = append(others, e.Account)
+		}
+	}
+
+	var hint string
+	switch len(others) {
+	case 0:
+		return notFound
+	case 1:
+		hint = fmt.Sprintf("Found it under keychain account '%s' instead; pass --keychain-user %s to use it", others[0], others[0])
+	default:
+		slices.Sort(others)
+		hint = fmt.Sprintf("Found it under keychain accounts '%s'; pass --keychain-user with one of them", strings.Join(others, "', '"))
+	}
+
+	var e *Error
+	if errors.As(notFound, &e) {
+		return &Error{Code: e.Code, Msg: e.Msg + ". " + hint}
+	}
+	return fmt.Errorf("%w. %s", notFound, hint)
+}
+
 // Credentials represents generic credentials returned by a provider
 type Credentials struct {
 	Provider             string            // Provider name
 	Expiry               time.Time         // When these credentials expire
+	IssuedAt             time.Time         // When these credentials were issued; lets callers plan a refresh ahead of Expiry
 	Variables            map[string]string // Environment variables to set
 	DisplayInfo          string            // Human-readable display information
 	CopyValue            string            // Value to copy to clipboard; must be non-empty when returned by GetClipboardValue
 	ClipboardDescription string            // Short label for CopyValue (e.g. "TOTP code", "password"); used in CLI output
 	MFAAuthenticated     bool              // Whether these credentials were authenticated with MFA
+	Output               string            // Printed to stdout as is, for output meant to be captured (e.g. AWS --format base64)
 }
 
 // FormatClipboardDisplayInfo creates the standard clipboard-mode display format
@@ -1,8 +1,11 @@
 package provider
 
 import (
+	"errors"
 	"testing"
 	"time"
+
+	"github.com/bashhack/sesh/internal/keychain"
 )
 
 func TestClock_TimeNow(t *testing.T) {
@@ -128,6 +131,14 @@ func TestParseEntryID(t *testing.T) {
 			id:      "invalid",
 			wantErr: true,
 		},
+		"empty service": {
+			id:      ":account",
+			wantErr: true,
+		},
+		"empty account": {
+			id:      "service:",
+			wantErr: true,
+		},
 	}
 
 	for name, tc := range tests {
@@ -186,3 +197,105 @@ func TestCreateClipboardCredentials(t *testing.T) {
 		t.Errorf("Variables should be empty, got %v", creds.Variables)
 	}
 }
+
+func TestParseEntryIDFor(t *testing.T) {
+	tests := map[string]struct {
+		id         string
+		namespaces []string
+		wantErr    bool
+	}{
+		"in namespace":         {id: "sesh-totp/github:alice", namespaces: []string{"sesh-totp"}},
+		"in second namespace":  {id: "sesh-aws-serial/dev:alice", namespaces: []string{"sesh-aws", "sesh-aws-serial"}},
+		"other namespace":      {id: "sesh-aws/dev:alice", namespaces: []string{"sesh-totp"}, wantErr: true},
+		"shared prefix only":   {id: "sesh-aws-serial/dev:alice", namespaces: []string{"sesh-aws"}, wantErr: true},
+		"malformed":            {id: "sesh-totp/github", namespaces: []string{"sesh-totp"}, wantErr: true},
+		"empty segment in key": {id: "sesh-totp/:alice", namespaces: []string{"sesh-totp"}, wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := ParseEntryIDFor(tc.id, tc.namespaces...)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseEntryIDFor() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidEntryID) {
+				t.Errorf("error %v does not wrap ErrInvalidEntryID", err)
+			}
+		})
+	}
+}
+
+func TestCheckNotBlank(t *testing.T) {
+	tests := map[string]struct {
+		value   string
+		wantErr bool
+	}{
+		"name":            {value: "github"},
+		"empty is left":   {value: ""},
+		"padded name":     {value: " github "},
+		"spaces":          {value: "   ", wantErr: true},
+		"tabs and spaces": {value: "\t \n", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := CheckNotBlank("--service-name", tc.value)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("CheckNotBlank(%q) error = %v, wantErr %v", tc.value, err, tc.wantErr)
+			}
+			if tc.wantErr && !errors.Is(err, ErrUsage) {
+				t.Errorf("CheckNotBlank(%q) error = %v, want a usage error", tc.value, err)
+			}
+		})
+	}
+}
+
+func TestKeychainUserHint(t *testing.T) {
+	notFound := NotSetupError("no TOTP entry found for service 'github'")
+
+	tests := map[string]struct {
+		entries []keychain.KeychainEntry
+		listErr error
+		want    string
+	}{
+		"stored under another account": {
+			entries: []keychain.KeychainEntry{{Service: "sesh-totp/github", Account: "olduser"}},
+			want:    "no TOTP entry found for service 'github'. Found it under keychain account 'olduser' instead; pass --keychain-user olduser to use it",
+		},
+		"stored under several accounts": {
+			entries: []keychain.KeychainEntry{
+				{Service: "sesh-totp/github", Account: "work"},
+				{Service: "sesh-totp/github", Account: "olduser"},
+				{Service: "sesh-totp/github", Account: "work"},
+			},
+			want: "no TOTP entry found for service 'github'. Found it under keychain accounts 'olduser', 'work'; pass --keychain-user with one of them",
+		},
+		"prefix matches are ignored": {
+			entries: []keychain.KeychainEntry{{Service: "sesh-totp/github/work", Account: "olduser"}},
+			want:    "no TOTP entry found for service 'github'",
+		},
+		"stored nowhere": {
+			want: "no TOTP entry found for service 'github'",
+		},
+		"listing fails": {
+			listErr: errors.New("keychain locked"),
+			want:    "no TOTP entry found for service 'github'",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			listEntries := func(prefix string) ([]keychain.KeychainEntry, error) {
+				if prefix != "sesh-totp/github" {
+					t.Errorf("ListEntries(%q), want the exact service key", prefix)
+				}
+				return tc.entries, tc.listErr
+			}
+			err := KeychainUserHint(notFound, listEntries, "sesh-totp/github", "alice")
+			if err.Error() != tc.want {
+				t.Errorf("KeychainUserHint() = %q, want %q", err.Error(), tc.want)
+			}
+			if !errors.Is(err, ErrNotSetup) {
+				t.Errorf("KeychainUserHint() = %v, want it to stay a not-set-up error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bashhack/sesh/internal/atomicfile"
+	"github.com/bashhack/sesh/internal/keychain"
+	"github.com/bashhack/sesh/internal/password"
+	"github.com/bashhack/sesh/internal/provider"
+	"github.com/bashhack/sesh/internal/secure"
+)
+
+// backupKeyService is the password entry holding the passphrase that
+// unattended backups are encrypted with, for runs without --passphrase-stdin.
+const backupKeyService = "sesh-backup"
+
+// backupEntries writes an encrypted export to p.file for scheduled runs.
+// Unlike --action export it never prompts: the passphrase comes from stdin
+// or the sesh-backup entry. The file is replaced atomically, so a failed
+// run leaves the previous backup intact, and with --max-age a backup newer
+// than that is left alone.
+func (p *Provider) backupEntries(mgr *password.Manager) (provider.Credentials, error) {
+	maxAge, err := p.parsedMaxAge()
+	if err != nil {
+		return provider.Credentials{}, err
+	}
+
+	if maxAge > 0 {
+		if age, ok := backupAge(p.file); ok && age < maxAge {
+			return provider.Credentials{
+				Provider:    p.Name(),
+				DisplayInfo: fmt.Sprintf("Backup %s is %s old, newer than --max-age %s; skipped", p.file, age.Round(time.Second), maxAge),
+			}, nil
+		}
+	}
+
+	pw, err := p.backupPassphrase(mgr)
+	if err != nil {
+		return provider.Credentials{}, err
+	}
+	defer secure.SecureZeroBytes(pw)
+
+	var count int
+	err = atomicfile.Write(p.file, func(f *os.File) error {
+		var exportErr error
+		count, exportErr = mgr.ExportEncrypted(f, password.ExportOptions{EntryType: password.EntryType(p.entryType)}, pw)
+		return exportErr
+	})
+	if err != nil {
+		return provider.Credentials{}, fmt.Errorf("write backup: %w", err)
+	}
+
+	return provider.Credentials{
+		Provider:    p.Name(),
+		DisplayInfo: fmt.Sprintf("Backed up %d entries to %s", count, p.file),
+	}, nil
+}
+
+// parsedMaxAge returns --max-age as a duration; zero means always back up.
+func (p *Provider) parsedMaxAge() (time.Duration, error) {
+	if p.maxAge == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(p.maxAge)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("--max-age must be a non-negative duration such as 24h, got %q", p.maxAge)
+	}
+	return d, nil
+}
+
+// backupPassphrase reads the backup passphrase from the first line of
+// stdin with --passphrase-stdin, otherwise from the sesh-backup entry.
+func (p *Provider) backupPassphrase(mgr *password.Manager) ([]byte, error) {
+	if p.passphraseStdin {
+		line, err := bufio.NewReader(p.stdin).ReadBytes('\n')
+		if err != nil && len(line) == 0 {
+			return nil, fmt.Errorf("read passphrase from stdin: %w", err)
+		}
+		pw := trimLineEnding(line)
+		if len(pw) == 0 {
+			return nil, fmt.Errorf("passphrase cannot be empty")
+		}
+		return pw, nil
+	}
+
+	pw, err := mgr.GetPassword(backupKeyService, "", password.EntryTypePassword)
+	if err != nil {
+		if errors.Is(err, keychain.ErrNotFound) {
+			return nil, fmt.Errorf("no backup passphrase: pass --passphrase-stdin, or store one with 'sesh --service password --action store --service-name %s'", backupKeyService)
+		}
+		return nil, err
+	}
+	return pw, nil
+}
+
+// trimLineEnding strips a trailing "\n" or "\r\n" in place.
+func trimLineEnding(line []byte) []byte {
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+	}
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	return line
+}
+
+// backupAge returns how long ago the file at path was last written, and
+// false if it doesn't exist or can't be read.
+func backupAge(path string) (time.Duration, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	return now().Sub(info.ModTime()), true
+}
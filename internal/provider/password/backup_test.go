@@ -0,0 +1,173 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bashhack/sesh/internal/keychain"
+	"github.com/bashhack/sesh/internal/keychain/mocks"
+	"github.com/bashhack/sesh/internal/password"
+)
+
+func TestBackupEntries_MaxAge(t *testing.T) {
+	fixedNow := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	origNow := now
+	t.Cleanup(func() { now = origNow })
+	now = func() time.Time { return fixedNow }
+
+	tests := map[string]struct {
+		backupAge   time.Duration // 0 means no backup exists yet
+		maxAge      string
+		wantWritten bool
+		wantInfo    string
+	}{
+		"no backup yet": {
+			maxAge:      "24h",
+			wantWritten: true,
+			wantInfo:    "Backed up 1 entries",
+		},
+		"recent backup is kept": {
+			backupAge: 2 * time.Hour,
+			maxAge:    "24h",
+			wantInfo:  "is 2h0m0s old, newer than --max-age 24h0m0s; skipped",
+		},
+		"stale backup is replaced": {
+			backupAge:   25 * time.Hour,
+			maxAge:      "24h",
+			wantWritten: true,
+			wantInfo:    "Backed up 1 entries",
+		},
+		"without --max-age it always runs": {
+			backupAge:   time.Minute,
+			wantWritten: true,
+			wantInfo:    "Backed up 1 entries",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "sesh.enc")
+			if tc.backupAge != 0 {
+				if err := os.WriteFile(path, []byte("previous"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				modTime := fixedNow.Add(-tc.backupAge)
+				if err := os.Chtimes(path, modTime, modTime); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mock := &mocks.MockProvider{
+				ListEntriesFunc: func(string) ([]keychain.KeychainEntry, error) {
+					return []keychain.KeychainEntry{{Service: "sesh-password/password/github", Account: "testuser"}}, nil
+				},
+				GetSecretFunc: func(string, string) ([]byte, error) {
+					return []byte("plaintext-secret"), nil
+				},
+			}
+			p, _ := newTestProvider(mock)
+			p.action = "backup"
+			p.file = path
+			p.maxAge = tc.maxAge
+			p.passphraseStdin = true
+			p.stdin = strings.NewReader("backup-passphrase\n")
+
+			if err := p.ValidateRequest(); err != nil {
+				t.Fatalf("ValidateRequest() error = %v", err)
+			}
+			creds, err := p.GetCredentials()
+			if err != nil {
+				t.Fatalf("GetCredentials() error = %v", err)
+			}
+			if !strings.Contains(creds.DisplayInfo, tc.wantInfo) {
+				t.Errorf("DisplayInfo = %q, want to contain %q", creds.DisplayInfo, tc.wantInfo)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if written := string(data) != "previous"; written != tc.wantWritten {
+				t.Fatalf("backup written = %v, want %v", written, tc.wantWritten)
+			}
+			if !tc.wantWritten {
+				return
+			}
+			if strings.Contains(string(data), "plaintext-secret") {
+				t.Fatal("backup leaked a plaintext secret")
+			}
+
+			mgr := password.NewManager(&mocks.MockProvider{}, "testuser")
+			result, err := mgr.ImportEncrypted(strings.NewReader(string(data)), password.ImportOptions{OnConflict: password.ConflictOverwrite}, []byte("backup-passphrase"))
+			if err != nil {
+				t.Fatalf("backup does not decrypt with the passphrase: %v", err)
+			}
+			if result.Imported != 1 {
+				t.Errorf("restored %d entries, want 1", result.Imported)
+			}
+		})
+	}
+}
+
+func TestBackupEntries_Passphrase(t *testing.T) {
+	tests := map[string]struct {
+		stdin      string
+		fromStdin  bool
+		storedKey  string // "" means no sesh-backup entry
+		wantErrMsg string
+	}{
+		"from the sesh-backup entry": {
+			storedKey: "stored-passphrase",
+		},
+		"from stdin": {
+			fromStdin: true,
+			stdin:     "stdin-passphrase\r\n",
+		},
+		"empty stdin": {
+			fromStdin:  true,
+			stdin:      "\n",
+			wantErrMsg: "passphrase cannot be empty",
+		},
+		"no passphrase anywhere": {
+			wantErrMsg: "no backup passphrase",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock := &mocks.MockProvider{
+				ListEntriesFunc: func(string) ([]keychain.KeychainEntry, error) { return nil, nil },
+				GetSecretFunc: func(_, service string) ([]byte, error) {
+					if strings.Contains(service, backupKeyService) && tc.storedKey != "" {
+						return []byte(tc.storedKey), nil
+					}
+					return nil, keychain.ErrNotFound
+				},
+			}
+			p, _ := newTestProvider(mock)
+			p.passphraseStdin = tc.fromStdin
+			p.stdin = strings.NewReader(tc.stdin)
+
+			pw, err := p.backupPassphrase(password.NewManager(mock, "testuser"))
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("backupPassphrase() error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("backupPassphrase() error = %v", err)
+			}
+			want := tc.storedKey
+			if tc.fromStdin {
+				want = strings.TrimRight(tc.stdin, "\r\n")
+			}
+			if string(pw) != want {
+				t.Errorf("passphrase = %q, want %q", pw, want)
+			}
+		})
+	}
+}
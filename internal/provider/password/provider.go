@@ -9,17 +9,20 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"unicode/utf8"
 
 	"golang.org/x/term"
 
+	"github.com/bashhack/sesh/internal/constants"
 	"github.com/bashhack/sesh/internal/env"
 	"github.com/bashhack/sesh/internal/keychain"
 	"github.com/bashhack/sesh/internal/password"
 	"github.com/bashhack/sesh/internal/provider"
 	"github.com/bashhack/sesh/internal/qrcode"
 	"github.com/bashhack/sesh/internal/secure"
+	"github.com/bashhack/sesh/internal/theme"
 	"github.com/bashhack/sesh/internal/totp"
 )
 
@@ -33,7 +36,7 @@ type Provider struct {
 	sortBy     string
 	username   string
 	entryType  string
-	action     string // "store", "get", "search", "generate", "export", "import", "totp-store", "totp-generate"
+	action     string // "store", "get", "search", "generate", "export", "import", "backup", "totp-store", "totp-generate"
 	file       string // file path for export/import
 	onConflict string // import conflict strategy: "skip", "overwrite"
 	provider.KeyUser
@@ -42,9 +45,13 @@ type Provider struct {
 	pwLength  int // password generation length
 	limit     int
 	offset    int
-	force     bool // skip confirmation
-	noSymbols bool // password generation: exclude symbols
-	show      bool // show password instead of clipboard
+	force     bool   // skip confirmation
+	noSymbols bool   // password generation: exclude symbols
+	show      bool   // show password instead of clipboard
+	copyField string // what --clip copies: "password", "username" or "both"
+
+	passphraseStdin bool   // backup: read the passphrase from stdin
+	maxAge          string // backup: skip when the existing backup is newer
 }
 
 var _ provider.ServiceProvider = (*Provider)(nil)
@@ -59,6 +66,7 @@ var (
 		return term.IsTerminal(int(os.Stdin.Fd()))
 	}
 	scanQRCodeFull = qrcode.ScanQRCodeFull
+	now            = time.Now
 )
 
 // NewProvider creates a new password manager provider.
@@ -74,6 +82,9 @@ func (p *Provider) Name() string         { return "password" }
 func (p *Provider) Description() string  { return "Secure password manager" }
 func (p *Provider) GetSetupHandler() any { return nil }
 
+// KeyNamespaces implements provider.KeyNamespacer.
+func (p *Provider) KeyNamespaces() []string { return []string{constants.PasswordServicePrefix} }
+
 // SuppressActionFraming opts out of the app's generic
 // "Generating credentials… / Credentials acquired in Xs" wrapper. The
 // password provider dispatches many sub-actions (store/search/export/
@@ -82,7 +93,7 @@ func (p *Provider) GetSetupHandler() any { return nil }
 func (p *Provider) SuppressActionFraming() bool { return true }
 
 func (p *Provider) SetupFlags(fs provider.FlagSet) error {
-	fs.StringVar(&p.action, "action", "", "Action to perform (store, get, generate, search, export, import, totp-store, totp-generate)")
+	fs.StringVar(&p.action, "action", "", "Action to perform (store, get, generate, search, export, import, backup, totp-store, totp-generate)")
 	fs.StringVar(&p.service, "service-name", "", "Service name")
 	fs.StringVar(&p.username, "username", "", "Username for the service")
 	fs.StringVar(&p.entryType, "entry-type", "", "Entry type filter (password, api_key, totp, secure_note); empty shows all")
@@ -97,6 +108,9 @@ func (p *Provider) SetupFlags(fs provider.FlagSet) error {
 	fs.IntVar(&p.pwLength, "length", 24, "Generated password length")
 	fs.IntVar(&p.limit, "limit", 0, "Limit number of results (0 = no limit)")
 	fs.IntVar(&p.offset, "offset", 0, "Skip first N results")
+	fs.StringVar(&p.copyField, "copy-field", copyFieldPassword, "What --clip copies: password, username, or both (username:password)")
+	fs.BoolVar(&p.passphraseStdin, "passphrase-stdin", false, "For backup, read the encryption passphrase from the first line of stdin")
+	fs.StringVar(&p.maxAge, "max-age", "", "For backup, skip if the existing backup is newer than this (e.g. 24h)")
 
 	defaultUser, err := env.GetCurrentUser()
 	if err != nil {
@@ -108,7 +122,7 @@ func (p *Provider) SetupFlags(fs provider.FlagSet) error {
 
 func (p *Provider) GetFlagInfo() []provider.FlagInfo {
 	return []provider.FlagInfo{
-		{Name: "action", Type: "string", Description: "Action: store, get, generate, search, export, import, totp-store, totp-generate"},
+		{Name: "action", Type: "string", Description: "Action: store, get, generate, search, export, import, backup, totp-store, totp-generate"},
 		{Name: "service-name", Type: "string", Description: "Service name"},
 		{Name: "username", Type: "string", Description: "Username for the service"},
 		{Name: "entry-type", Type: "string", Description: "Entry type (password, api_key, totp, secure_note)"},
@@ -123,10 +137,26 @@ func (p *Provider) GetFlagInfo() []provider.FlagInfo {
 		{Name: "length", Type: "int", Description: "Generated password length (default 24)"},
 		{Name: "limit", Type: "int", Description: "Limit number of results (0 = no limit)"},
 		{Name: "offset", Type: "int", Description: "Skip first N results"},
+		{Name: "copy-field", Type: "string", Description: "What --clip copies: password, username, or both (username:password)"},
+		{Name: "passphrase-stdin", Type: "bool", Description: "For backup, read the encryption passphrase from the first line of stdin"},
+		{Name: "max-age", Type: "string", Description: "For backup, skip if the existing backup is newer than this (e.g. 24h)"},
 	}
 }
 
 func (p *Provider) ValidateRequest() error {
+	if err := provider.CheckNotBlank("--service-name", p.service); err != nil {
+		return err
+	}
+	if err := provider.CheckNotBlank("--username", p.username); err != nil {
+		return err
+	}
+
+	switch p.copyField {
+	case "", copyFieldPassword, copyFieldUsername, copyFieldBoth:
+	default:
+		return fmt.Errorf("--copy-field must be password, username, or both, got %q", p.copyField)
+	}
+
 	switch p.action {
 	case "store":
 		if p.service == "" {
@@ -162,10 +192,17 @@ func (p *Provider) ValidateRequest() error {
 		if p.action == "import" && p.onConflict != "" && p.onConflict != "skip" && p.onConflict != "overwrite" {
 			return fmt.Errorf("--on-conflict must be skip or overwrite, got %q", p.onConflict)
 		}
+	case "backup":
+		if p.file == "" {
+			return fmt.Errorf("--file is required for backup action")
+		}
+		if _, err := p.parsedMaxAge(); err != nil {
+			return err
+		}
 	case "":
 		// Default action handled by GetCredentials
 	default:
-		return fmt.Errorf("unknown action: %q (use store, get, search, generate, export, import, totp-store, totp-generate)", p.action)
+		return fmt.Errorf("unknown action: %q (use store, get, search, generate, export, import, backup, totp-store, totp-generate)", p.action)
 	}
 	return nil
 }
@@ -187,16 +224,26 @@ func (p *Provider) GetCredentials() (provider.Credentials, error) {
 		return p.exportEntries(mgr)
 	case "import":
 		return p.importEntries(mgr)
+	case "backup":
+		return p.backupEntries(mgr)
 	case "totp-store":
 		return p.storeTOTP(mgr)
 	case "totp-generate":
 		return p.generateTOTP(mgr)
 	default:
-		return provider.Credentials{}, fmt.Errorf("specify --action (store, get, search, generate, export, import, totp-store, totp-generate) or use --list, --delete")
+		return provider.Credentials{}, fmt.Errorf("specify --action (store, get, search, generate, export, import, backup, totp-store, totp-generate) or use --list, --delete")
 	}
 }
 
-// GetClipboardValue retrieves a password and prepares it for clipboard.
+// Values for --copy-field.
+const (
+	copyFieldPassword = "password"
+	copyFieldUsername = "username"
+	copyFieldBoth     = "both"
+)
+
+// GetClipboardValue retrieves the field chosen by --copy-field (the
+// password by default) and prepares it for the clipboard.
 func (p *Provider) GetClipboardValue() (provider.Credentials, error) {
 	if p.service == "" {
 		return provider.Credentials{}, fmt.Errorf("--service-name is required")
@@ -205,15 +252,39 @@ func (p *Provider) GetClipboardValue() (provider.Credentials, error) {
 	mgr := password.NewManager(p.keychain, p.User)
 	et := p.effectiveEntryType()
 
-	secretBytes, err := mgr.GetPassword(p.service, p.username, et)
+	username := p.username
+	if p.copyField == copyFieldUsername || p.copyField == copyFieldBoth {
+		var err error
+		if username, err = p.resolveUsername(mgr, et); err != nil {
+			return provider.Credentials{}, err
+		}
+	}
+
+	desc := p.service
+	if username != "" {
+		desc = fmt.Sprintf("%s (%s)", p.service, username)
+	}
+
+	if p.copyField == copyFieldUsername {
+		return provider.Credentials{
+			Provider:             p.Name(),
+			CopyValue:            username,
+			ClipboardDescription: fmt.Sprintf("username for %s", p.service),
+		}, nil
+	}
+
+	secretBytes, err := mgr.GetPassword(p.service, username, et)
 	if err != nil {
 		return provider.Credentials{}, err
 	}
 	defer secure.SecureZeroBytes(secretBytes)
 
-	desc := p.service
-	if p.username != "" {
-		desc = fmt.Sprintf("%s (%s)", p.service, p.username)
+	if p.copyField == copyFieldBoth {
+		return provider.Credentials{
+			Provider:             p.Name(),
+			CopyValue:            username + ":" + string(secretBytes),
+			ClipboardDescription: fmt.Sprintf("username:%s for %s", et, desc),
+		}, nil
 	}
 
 	return provider.Credentials{
@@ -223,8 +294,37 @@ func (p *Provider) GetClipboardValue() (provider.Credentials, error) {
 	}, nil
 }
 
+// resolveUsername returns --username, or the username of the only entry
+// stored for the service when none was given.
+func (p *Provider) resolveUsername(mgr *password.Manager, et password.EntryType) (string, error) {
+	if p.username != "" {
+		return p.username, nil
+	}
+
+	entries, err := mgr.ListEntriesFiltered(password.ListFilter{Service: p.service, EntryType: et})
+	if err != nil {
+		return "", err
+	}
+	switch len(entries) {
+	case 0:
+		return "", fmt.Errorf("no %s entry found for %s", et, p.service)
+	case 1:
+		if entries[0].Username == "" {
+			return "", fmt.Errorf("the %s entry for %s has no username", et, p.service)
+		}
+		return entries[0].Username, nil
+	default:
+		return "", fmt.Errorf("%d %s entries found for %s; pass --username to pick one", len(entries), et, p.service)
+	}
+}
+
 // ListEntries returns all password manager entries.
 func (p *Provider) ListEntries() ([]provider.ProviderEntry, error) {
+	// Aggregate listings (--status --all, --dump-redacted) never run
+	// SetupFlags, which is what normally fills in the user
+	if err := p.EnsureUser(); err != nil {
+		return nil, err
+	}
 	mgr := password.NewManager(p.keychain, p.User)
 
 	filter := password.ListFilter{
@@ -238,6 +338,7 @@ func (p *Provider) ListEntries() ([]provider.ProviderEntry, error) {
 	if err != nil {
 		return nil, err
 	}
+	entries = groupByService(entries)
 
 	result := make([]provider.ProviderEntry, 0, len(entries))
 	for i := range entries {
@@ -248,15 +349,47 @@ func (p *Provider) ListEntries() ([]provider.ProviderEntry, error) {
 		}
 		result = append(result, provider.ProviderEntry{
 			Name:        name,
-			Description: fmt.Sprintf("[%s] %s", e.Type, e.Description),
+			Description: fmt.Sprintf("[%s] %s", e.Type.String(), e.Description),
 			ID:          e.ID,
+			Type:        p.Name(),
+			ServiceName: e.Service,
+			Username:    e.Username,
+			EntryType:   string(e.Type),
+			UpdatedAt:   e.UpdatedAt,
 		})
 	}
 	return result, nil
 }
 
+// groupByService moves each entry up to join the first entry for the same
+// service, keeping the order otherwise, so a service's usernames are listed
+// together whatever --sort says.
+func groupByService(entries []password.Entry) []password.Entry {
+	grouped := make([]password.Entry, 0, len(entries))
+	placed := make(map[string]bool, len(entries))
+	for i := range entries {
+		service := entries[i].Service
+		if placed[service] {
+			continue
+		}
+		placed[service] = true
+		for j := i; j < len(entries); j++ {
+			if entries[j].Service == service {
+				grouped = append(grouped, entries[j])
+			}
+		}
+	}
+	return grouped
+}
+
 // DeleteEntry deletes a password entry by ID, with confirmation unless --force.
 func (p *Provider) DeleteEntry(id string) error {
+	// Validate before asking, so a typo doesn't get a confirmation prompt
+	service, account, err := provider.ParseEntryIDFor(id, constants.PasswordServicePrefix)
+	if err != nil {
+		return err
+	}
+
 	if !p.force {
 		fmt.Fprintf(os.Stderr, "Delete entry %q? [y/N]: ", id)
 		answer, err := bufio.NewReader(p.stdin).ReadString('\n')
@@ -268,10 +401,6 @@ func (p *Provider) DeleteEntry(id string) error {
 		}
 	}
 
-	service, account, err := provider.ParseEntryID(id)
-	if err != nil {
-		return err
-	}
 	return p.keychain.DeleteEntry(account, service)
 }
 
@@ -358,7 +487,7 @@ func (p *Provider) storePassword(mgr *password.Manager) (provider.Credentials, e
 
 	return provider.Credentials{
 		Provider:    p.Name(),
-		DisplayInfo: fmt.Sprintf("✅ Stored %s for %s", et, p.service),
+		DisplayInfo: fmt.Sprintf(theme.OK()+" Stored %s for %s", et, p.service),
 	}, nil
 }
 
@@ -418,7 +547,7 @@ func (p *Provider) generatePassword(mgr *password.Manager) (provider.Credentials
 		// explicitly-interactive `generate` invocation.
 		return provider.Credentials{
 			Provider:    p.Name(),
-			DisplayInfo: fmt.Sprintf("✅ Generated and stored %s for %s\n%s", et, desc, string(generated)),
+			DisplayInfo: fmt.Sprintf(theme.OK()+" Generated and stored %s for %s\n%s", et, desc, string(generated)),
 		}, nil
 	}
 
@@ -426,7 +555,7 @@ func (p *Provider) generatePassword(mgr *password.Manager) (provider.Credentials
 		Provider:             p.Name(),
 		CopyValue:            string(generated),
 		ClipboardDescription: fmt.Sprintf("generated password for %s", desc),
-		DisplayInfo:          fmt.Sprintf("✅ Generated and stored %s for %s\n💡 Use --show to display it or --clip to copy", et, desc),
+		DisplayInfo:          fmt.Sprintf(theme.OK()+" Generated and stored %s for %s\n💡 Use --show to display it or --clip to copy", et, desc),
 	}, nil
 }
 
@@ -516,7 +645,7 @@ func (p *Provider) searchPasswords(mgr *password.Manager) (provider.Credentials,
 		}
 		// Highlight matching portion in service name
 		highlighted := highlightMatch(name, q)
-		fmt.Fprintf(&sb, "  %-30s [%s] %s\n", highlighted, e.Type, e.Description)
+		fmt.Fprintf(&sb, "  %-30s [%s] %s\n", highlighted, e.Type.String(), e.Description)
 	}
 
 	return provider.Credentials{
@@ -561,7 +690,7 @@ func (p *Provider) storeTOTP(mgr *password.Manager) (provider.Credentials, error
 		if p.username == "" && info.Account != "" {
 			p.username = info.Account
 		}
-		fmt.Fprintf(os.Stderr, "✅ QR code scanned successfully\n")
+		fmt.Fprint(os.Stderr, theme.OK()+" QR code scanned successfully\n")
 		if info.Issuer != "" {
 			fmt.Fprintf(os.Stderr, "   Issuer: %s\n", info.Issuer)
 		}
@@ -588,7 +717,7 @@ func (p *Provider) storeTOTP(mgr *password.Manager) (provider.Credentials, error
 		return provider.Credentials{}, err
 	}
 
-	display := fmt.Sprintf("✅ Stored TOTP secret for %s", p.service)
+	display := fmt.Sprintf(theme.OK()+" Stored TOTP secret for %s", p.service)
 	if !params.IsDefault() {
 		display += fmt.Sprintf(" (algorithm=%s, digits=%d, period=%ds)",
 			params.Algorithm, params.Digits, params.Period)
@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"flag"
+	"maps"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bashhack/sesh/internal/keychain"
 	"github.com/bashhack/sesh/internal/keychain/mocks"
 	"github.com/bashhack/sesh/internal/password"
+	"github.com/bashhack/sesh/internal/provider"
 	"github.com/bashhack/sesh/internal/qrcode"
 )
 
@@ -23,10 +27,14 @@ func TestName(t *testing.T) {
 
 func TestValidateRequest(t *testing.T) {
 	tests := map[string]struct {
-		action  string
-		service string
-		query   string
-		wantErr bool
+		action    string
+		service   string
+		username  string
+		query     string
+		copyField string
+		file      string
+		maxAge    string
+		wantErr   bool
 	}{
 		"store without service": {
 			action: "store", service: "", wantErr: true,
@@ -40,6 +48,12 @@ func TestValidateRequest(t *testing.T) {
 		"get with service": {
 			action: "get", service: "github", wantErr: false,
 		},
+		"whitespace service": {
+			action: "store", service: "  ", wantErr: true,
+		},
+		"whitespace username": {
+			action: "store", service: "github", username: "\t", wantErr: true,
+		},
 		"search without query": {
 			action: "search", query: "", wantErr: true,
 		},
@@ -58,14 +72,30 @@ func TestValidateRequest(t *testing.T) {
 		"empty action": {
 			action: "", wantErr: false,
 		},
+		"unknown copy field": {
+			action: "get", service: "github", copyField: "email", wantErr: true,
+		},
+		"backup without file": {
+			action: "backup", wantErr: true,
+		},
+		"backup with file": {
+			action: "backup", file: "sesh.enc", maxAge: "24h", wantErr: false,
+		},
+		"backup with bad max-age": {
+			action: "backup", file: "sesh.enc", maxAge: "daily", wantErr: true,
+		},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			p := &Provider{
-				action:  tc.action,
-				service: tc.service,
-				query:   tc.query,
+				action:    tc.action,
+				service:   tc.service,
+				username:  tc.username,
+				query:     tc.query,
+				copyField: tc.copyField,
+				file:      tc.file,
+				maxAge:    tc.maxAge,
 			}
 			err := p.ValidateRequest()
 			if (err != nil) != tc.wantErr {
@@ -133,6 +163,72 @@ func TestListEntriesWithFilters(t *testing.T) {
 	}
 }
 
+func TestListEntries_GroupsUsernames(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock := &mocks.MockProvider{
+		ListEntriesFunc: func(string) ([]keychain.KeychainEntry, error) {
+			return []keychain.KeychainEntry{
+				{Service: "sesh-password/password/github/alice", Account: "me", CreatedAt: created},
+				{Service: "sesh-password/password/gitlab/alice", Account: "me", CreatedAt: created.Add(time.Hour)},
+				{Service: "sesh-password/password/github/work", Account: "me", CreatedAt: created.Add(2 * time.Hour)},
+			}, nil
+		},
+	}
+
+	// Created-at order would put gitlab between the two github accounts
+	p := &Provider{keychain: mock, sortBy: "created_at"}
+	p.User = "me"
+
+	entries, err := p.ListEntries()
+	if err != nil {
+		t.Fatalf("ListEntries: %v", err)
+	}
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.ServiceName+"/"+e.Username)
+	}
+	want := []string{"github/alice", "github/work", "gitlab/alice"}
+	if !slices.Equal(got, want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+}
+
+func TestListEntries_TypeLabels(t *testing.T) {
+	mock := &mocks.MockProvider{
+		ListEntriesFunc: func(string) ([]keychain.KeychainEntry, error) {
+			return []keychain.KeychainEntry{
+				{Service: "sesh-password/password/github/user1", Account: "alice", Description: "gh"},
+				{Service: "sesh-password/api_key/stripe", Account: "alice", Description: "live key"},
+				{Service: "sesh-password/totp/aws", Account: "alice", Description: "mfa"},
+				{Service: "sesh-password/secure_note/recovery", Account: "alice", Description: "codes"},
+			}, nil
+		},
+	}
+
+	p := &Provider{keychain: mock, sortBy: "service"}
+	p.User = "alice"
+
+	entries, err := p.ListEntries()
+	if err != nil {
+		t.Fatalf("ListEntries: %v", err)
+	}
+
+	got := make(map[string]string, len(entries))
+	for _, e := range entries {
+		got[e.Name] = e.Description
+	}
+	want := map[string]string{
+		"aws":            "[TOTP] mfa",
+		"github (user1)": "[Password] gh",
+		"recovery":       "[Secure Note] codes",
+		"stripe":         "[API Key] live key",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("listing = %v, want %v", got, want)
+	}
+}
+
 func TestHighlightMatch(t *testing.T) {
 	tests := map[string]struct {
 		text     string
@@ -266,6 +362,15 @@ func TestDeleteEntry_InvalidID(t *testing.T) {
 	}
 }
 
+func TestDeleteEntry_OtherProviderID(t *testing.T) {
+	// Without --force the ID is still checked before the confirmation prompt
+	p := &Provider{keychain: &mocks.MockProvider{}, stdin: strings.NewReader("")}
+	err := p.DeleteEntry("sesh-totp/github:alice")
+	if !errors.Is(err, provider.ErrInvalidEntryID) {
+		t.Fatalf("DeleteEntry() error = %v, want ErrInvalidEntryID", err)
+	}
+}
+
 // stubReadPassword overrides the package-level readPassword seam.
 func stubReadPassword(t *testing.T, value string) {
 	t.Helper()
@@ -341,8 +446,8 @@ func TestStorePassword_HappyPath(t *testing.T) {
 	if !strings.Contains(storedKey, "github") {
 		t.Errorf("stored service key = %q, want contains github", storedKey)
 	}
-	if !strings.Contains(creds.DisplayInfo, "Stored password for github") {
-		t.Errorf("DisplayInfo = %q, want contains 'Stored password for github'", creds.DisplayInfo)
+	if !strings.Contains(creds.DisplayInfo, "Stored Password for github") {
+		t.Errorf("DisplayInfo = %q, want contains 'Stored Password for github'", creds.DisplayInfo)
 	}
 }
 
@@ -440,7 +545,7 @@ func TestGeneratePassword_ShowEchoesPassword(t *testing.T) {
 	if creds.CopyValue != "" {
 		t.Errorf("CopyValue = %q, want empty when --show is set", creds.CopyValue)
 	}
-	if !strings.Contains(creds.DisplayInfo, "Generated and stored password for github") {
+	if !strings.Contains(creds.DisplayInfo, "Generated and stored Password for github") {
 		t.Errorf("DisplayInfo = %q, missing status line", creds.DisplayInfo)
 	}
 }
@@ -553,6 +658,103 @@ func TestGetPassword_JSONFormat(t *testing.T) {
 	}
 }
 
+func TestGetClipboardValue_CopyField(t *testing.T) {
+	tests := map[string]struct {
+		copyField  string
+		username   string
+		entries    []keychain.KeychainEntry
+		wantValue  string
+		wantDesc   string
+		wantSecret bool
+		wantErrMsg string
+	}{
+		"password by default": {
+			username:   "alice",
+			wantValue:  "s3cret",
+			wantDesc:   "Password for github (alice)",
+			wantSecret: true,
+		},
+		"password": {
+			copyField:  "password",
+			username:   "alice",
+			wantValue:  "s3cret",
+			wantDesc:   "Password for github (alice)",
+			wantSecret: true,
+		},
+		"username from --username": {
+			copyField: "username",
+			username:  "alice",
+			wantValue: "alice",
+			wantDesc:  "username for github",
+		},
+		"username from the only entry": {
+			copyField: "username",
+			entries:   []keychain.KeychainEntry{{Service: "sesh-password/password/github/alice", Account: "testuser"}},
+			wantValue: "alice",
+			wantDesc:  "username for github",
+		},
+		"both": {
+			copyField:  "both",
+			entries:    []keychain.KeychainEntry{{Service: "sesh-password/password/github/alice", Account: "testuser"}},
+			wantValue:  "alice:s3cret",
+			wantDesc:   "username:Password for github (alice)",
+			wantSecret: true,
+		},
+		"username with several entries": {
+			copyField: "username",
+			entries: []keychain.KeychainEntry{
+				{Service: "sesh-password/password/github/alice", Account: "testuser"},
+				{Service: "sesh-password/password/github/bob", Account: "testuser"},
+			},
+			wantErrMsg: "pass --username",
+		},
+		"username with no entry": {
+			copyField:  "username",
+			wantErrMsg: "no Password entry found",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var readSecret bool
+			mock := &mocks.MockProvider{
+				GetSecretFunc: func(_, _ string) ([]byte, error) {
+					readSecret = true
+					return []byte("s3cret"), nil
+				},
+				ListEntriesFunc: func(_ string) ([]keychain.KeychainEntry, error) {
+					return tc.entries, nil
+				},
+			}
+
+			p, _ := newTestProvider(mock)
+			p.service = "github"
+			p.username = tc.username
+			p.copyField = tc.copyField
+
+			creds, err := p.GetClipboardValue()
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("GetClipboardValue() error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetClipboardValue() error = %v", err)
+			}
+			if creds.CopyValue != tc.wantValue {
+				t.Errorf("CopyValue = %q, want %q", creds.CopyValue, tc.wantValue)
+			}
+			if creds.ClipboardDescription != tc.wantDesc {
+				t.Errorf("ClipboardDescription = %q, want %q", creds.ClipboardDescription, tc.wantDesc)
+			}
+			if readSecret != tc.wantSecret {
+				t.Errorf("secret read = %v, want %v", readSecret, tc.wantSecret)
+			}
+		})
+	}
+}
+
 func TestSearchPasswords_NoMatches(t *testing.T) {
 	mock := &mocks.MockProvider{
 		ListEntriesFunc: func(_ string) ([]keychain.KeychainEntry, error) {
@@ -1002,7 +1204,7 @@ func TestGetFlagInfo(t *testing.T) {
 		names[f.Name] = true
 	}
 
-	for _, expected := range []string{"action", "service-name", "username", "entry-type", "query", "sort", "format", "show", "force", "limit", "offset"} {
+	for _, expected := range []string{"action", "service-name", "username", "entry-type", "query", "sort", "format", "show", "force", "limit", "offset", "copy-field"} {
 		if !names[expected] {
 			t.Errorf("missing flag %q in GetFlagInfo", expected)
 		}
@@ -3,6 +3,7 @@ package provider
 import (
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
 )
 
@@ -41,12 +42,65 @@ func (r *Registry) GetProvider(name string) (ServiceProvider, error) {
 
 	p, ok := r.providers[name]
 	if !ok {
-		return nil, fmt.Errorf("provider %q not found", name)
+		msg := fmt.Sprintf("provider %q not found", name)
+		if s := r.suggest(name); s != "" {
+			msg += fmt.Sprintf("; did you mean '%s'?", s)
+		}
+		return nil, &Error{Code: CodeUnknownProvider, Msg: msg}
 	}
 
 	return p, nil
 }
 
+// Suggest returns the registered provider name closest to name, or "" when
+// nothing is close enough to be a plausible typo.
+func (r *Registry) Suggest(name string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.suggest(name)
+}
+
+// suggest is Suggest without locking; callers must hold r.mu.
+func (r *Registry) suggest(name string) string {
+	name = strings.ToLower(name)
+	best, bestDist := "", 0
+	for candidate := range r.providers {
+		d := levenshtein(name, candidate)
+		// Allow at most two edits, and never more than half the input, so
+		// short unrelated names like "gcp" don't match "aws".
+		if d > 2 || d*2 > len(name)+1 {
+			continue
+		}
+		if best == "" || d < bestDist || (d == bestDist && candidate < best) {
+			best, bestDist = candidate, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b: the number of
+// single-byte insertions, deletions and substitutions turning one into the
+// other.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
 // ListProviders returns all registered providers sorted by name.
 func (r *Registry) ListProviders() []ServiceProvider {
 	r.mu.RLock()
@@ -129,6 +129,67 @@ func TestRegistry_GetProvider(t *testing.T) {
 	}
 }
 
+func TestRegistry_Suggest(t *testing.T) {
+	tests := map[string]struct {
+		lookup string
+		want   string
+	}{
+		"transposed letters":   {lookup: "asw", want: "aws"},
+		"extra letter":         {lookup: "totpp", want: "totp"},
+		"missing letter":       {lookup: "pasword", want: "password"},
+		"wrong case":           {lookup: "AWS", want: "aws"},
+		"substituted letter":   {lookup: "totb", want: "totp"},
+		"unrelated short name": {lookup: "gcp"},
+		"unrelated long name":  {lookup: "kubernetes"},
+		"empty name":           {lookup: ""},
+	}
+
+	registry := NewRegistry()
+	for _, n := range []string{"aws", "password", "totp"} {
+		registry.RegisterProvider(&mockProvider{name: n, description: n + " provider"})
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := registry.Suggest(tc.lookup); got != tc.want {
+				t.Errorf("Suggest(%q) = %q, want %q", tc.lookup, got, tc.want)
+			}
+
+			_, err := registry.GetProvider(tc.lookup)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			hasHint := strings.Contains(err.Error(), "did you mean")
+			if hasHint != (tc.want != "") {
+				t.Errorf("GetProvider(%q) error = %q, suggestion expected: %v", tc.lookup, err, tc.want != "")
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := map[string]struct {
+		a, b string
+		want int
+	}{
+		"identical":                {a: "aws", b: "aws", want: 0},
+		"empty both":               {a: "", b: "", want: 0},
+		"empty one":                {a: "", b: "totp", want: 4},
+		"substitution":             {a: "totb", b: "totp", want: 1},
+		"insertion":                {a: "pasword", b: "password", want: 1},
+		"transposition counts two": {a: "asw", b: "aws", want: 2},
+		"classic":                  {a: "kitten", b: "sitting", want: 3},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := levenshtein(tc.a, tc.b); got != tc.want {
+				t.Errorf("levenshtein(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+			}
+		})
+	}
+}
+
 func TestRegistry_RegisterProvider_PanicsOnNil(t *testing.T) {
 	registry := NewRegistry()
 
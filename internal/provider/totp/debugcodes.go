@@ -0,0 +1,86 @@
+package totp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/bashhack/sesh/internal/provider"
+	internalTotp "github.com/bashhack/sesh/internal/totp"
+)
+
+// debugCodeWindows is how many windows --debug-codes prints on either side
+// of the current one.
+const debugCodeWindows = 3
+
+// stdoutIsTerminal is a variable so we can swap it out in tests
+var stdoutIsTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// checkDebugCodes validates --debug-codes. The codes go to stdout, so
+// without --force they are only written to a terminal, never into a pipe
+// or log file.
+func (p *Provider) checkDebugCodes() error {
+	if !p.debugCodes {
+		return nil
+	}
+	if !p.force && !stdoutIsTerminal() {
+		return provider.UsageError("--debug-codes: not printing codes to a non-terminal; add --force to print them anyway")
+	}
+	return nil
+}
+
+// windowCodes implements --debug-codes: the codes for the windows around
+// now, one line each with the window's UTC start time and its offset from
+// the current window, so a user can tell a service's support which window
+// a working code fell into.
+func (p *Provider) windowCodes(secret []byte, params internalTotp.Params, label string) (provider.Credentials, error) {
+	// GenerateForTimeBytes only knows the default parameters; the time
+	// offset is applied to the clock below instead
+	defaults := params
+	defaults.TimeOffset = 0
+	if p.algorithm != "" {
+		defaults.Algorithm, _ = internalTotp.ParseAlgorithm(p.algorithm)
+	}
+	if defaults.Algorithm == "SHA1" {
+		defaults.Algorithm = ""
+	}
+	if !defaults.IsDefault() {
+		return provider.Credentials{}, fmt.Errorf("--debug-codes only supports SHA1, 6-digit, 30-second codes")
+	}
+
+	const period = 30
+	now := params.Shift(p.TimeNow())
+	current := now.Unix() - now.Unix()%period
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-20s  %-6s  %s\n", "window start (UTC)", "code", "offset")
+	for i := -debugCodeWindows; i <= debugCodeWindows; i++ {
+		start := time.Unix(current+int64(i*period), 0).UTC()
+		code, err := p.totp.GenerateForTimeBytes(secret, start)
+		if err != nil {
+			return provider.Credentials{}, fmt.Errorf("could not generate TOTP code for %s: %w", start.Format(time.RFC3339), err)
+		}
+		fmt.Fprintf(&b, "%-20s  %-6s  %+d", start.Format(time.RFC3339), code, i)
+		if i == 0 {
+			b.WriteString(" (current)")
+		}
+		if i < debugCodeWindows {
+			b.WriteString("\n")
+		}
+	}
+
+	info := fmt.Sprintf("🕒 TOTP codes for %s around %s", label, now.UTC().Format(time.RFC3339))
+	if params.TimeOffset != 0 {
+		info += fmt.Sprintf(" (clock shifted %+ds by the stored time offset)", params.TimeOffset)
+	}
+	return provider.Credentials{
+		Provider:    p.Name(),
+		DisplayInfo: info,
+		Output:      b.String(),
+	}, nil
+}
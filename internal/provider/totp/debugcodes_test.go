@@ -0,0 +1,157 @@
+package totp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bashhack/sesh/internal/keychain"
+	keychainMocks "github.com/bashhack/sesh/internal/keychain/mocks"
+	"github.com/bashhack/sesh/internal/provider"
+	"github.com/bashhack/sesh/internal/testutil"
+	totpMocks "github.com/bashhack/sesh/internal/totp/mocks"
+)
+
+func TestProvider_GetCredentials_DebugCodes(t *testing.T) {
+	// 12:00:47 is 17 seconds into the window starting at 12:00:30
+	now := time.Date(2025, 6, 1, 12, 0, 47, 0, time.UTC)
+
+	tests := map[string]struct {
+		description string
+		algorithm   string
+		wantOutput  string
+		wantInfo    string
+		wantErr     string
+	}{
+		"windows around now": {
+			wantOutput: "window start (UTC)    code    offset\n" +
+				"2025-06-01T11:59:00Z  115900  -3\n" +
+				"2025-06-01T11:59:30Z  115930  -2\n" +
+				"2025-06-01T12:00:00Z  120000  -1\n" +
+				"2025-06-01T12:00:30Z  120030  +0 (current)\n" +
+				"2025-06-01T12:01:00Z  120100  +1\n" +
+				"2025-06-01T12:01:30Z  120130  +2\n" +
+				"2025-06-01T12:02:00Z  120200  +3",
+			wantInfo: "TOTP codes for github around 2025-06-01T12:00:47Z",
+		},
+		"stored time offset shifts the windows": {
+			description: `{"time_offset":-20}`,
+			wantOutput: "window start (UTC)    code    offset\n" +
+				"2025-06-01T11:58:30Z  115830  -3\n" +
+				"2025-06-01T11:59:00Z  115900  -2\n" +
+				"2025-06-01T11:59:30Z  115930  -1\n" +
+				"2025-06-01T12:00:00Z  120000  +0 (current)\n" +
+				"2025-06-01T12:00:30Z  120030  +1\n" +
+				"2025-06-01T12:01:00Z  120100  +2\n" +
+				"2025-06-01T12:01:30Z  120130  +3",
+			wantInfo: "(clock shifted -20s by the stored time offset)",
+		},
+		"sha1 override is allowed": {
+			algorithm: "sha1",
+			wantInfo:  "TOTP codes for github",
+		},
+		"non-default params are refused": {
+			description: `{"digits":8}`,
+			wantErr:     "--debug-codes only supports SHA1, 6-digit, 30-second codes",
+		},
+		"algorithm override is refused": {
+			algorithm: "sha256",
+			wantErr:   "--debug-codes only supports SHA1, 6-digit, 30-second codes",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer testutil.DiscardStderr(t)()
+
+			kc := &keychainMocks.MockProvider{
+				GetSecretFunc: func(_, _ string) ([]byte, error) { return []byte("JBSWY3DPEHPK3PXP"), nil },
+				ListEntriesFunc: func(_ string) ([]keychain.KeychainEntry, error) {
+					return []keychain.KeychainEntry{{Service: "sesh-totp/github", Account: "alice", Description: tc.description}}, nil
+				},
+			}
+			// Each code is the window's start time, so the output shows
+			// exactly which times were asked for
+			mockTOTP := &totpMocks.MockProvider{
+				GenerateForTimeBytesFunc: func(_ []byte, at time.Time) (string, error) {
+					return at.Format("150405"), nil
+				},
+			}
+
+			p := NewProvider(kc, mockTOTP)
+			p.Now = func() time.Time { return now }
+			p.User = "alice"
+			p.serviceName = "github"
+			p.algorithm = tc.algorithm
+			p.debugCodes = true
+
+			creds, err := p.GetCredentials()
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("GetCredentials() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetCredentials() error = %v", err)
+			}
+			if tc.wantOutput != "" && creds.Output != tc.wantOutput {
+				t.Errorf("Output =\n%s\nwant\n%s", creds.Output, tc.wantOutput)
+			}
+			if !strings.Contains(creds.DisplayInfo, tc.wantInfo) {
+				t.Errorf("DisplayInfo = %q, want it to contain %q", creds.DisplayInfo, tc.wantInfo)
+			}
+			if creds.CopyValue != "" {
+				t.Errorf("CopyValue = %q, want none", creds.CopyValue)
+			}
+		})
+	}
+}
+
+func TestProvider_DebugCodesGating(t *testing.T) {
+	tests := map[string]struct {
+		terminal bool
+		force    bool
+		wantErr  bool
+	}{
+		"terminal":              {terminal: true},
+		"redirected":            {wantErr: true},
+		"redirected with force": {force: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			orig := stdoutIsTerminal
+			defer func() { stdoutIsTerminal = orig }()
+			stdoutIsTerminal = func() bool { return tc.terminal }
+
+			kc := &keychainMocks.MockProvider{
+				GetSecretFunc: func(_, _ string) ([]byte, error) { return []byte("JBSWY3DPEHPK3PXP"), nil },
+			}
+			p := NewProvider(kc, &totpMocks.MockProvider{})
+			p.User = "alice"
+			p.serviceName = "github"
+			p.debugCodes = true
+			p.force = tc.force
+
+			err := p.ValidateRequest()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ValidateRequest() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr && !errors.Is(err, provider.ErrUsage) {
+				t.Errorf("ValidateRequest() error = %v, want a usage error", err)
+			}
+		})
+	}
+}
+
+func TestProvider_GetClipboardValue_DebugCodes(t *testing.T) {
+	p := NewProvider(&keychainMocks.MockProvider{}, &totpMocks.MockProvider{})
+	p.serviceName = "github"
+	p.debugCodes = true
+
+	if _, err := p.GetClipboardValue(); err == nil {
+		t.Error("GetClipboardValue() with --debug-codes should fail")
+	}
+}
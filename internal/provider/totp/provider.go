@@ -2,9 +2,12 @@
 package totp
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/bashhack/sesh/internal/constants"
@@ -14,6 +17,7 @@ import (
 	"github.com/bashhack/sesh/internal/provider"
 	"github.com/bashhack/sesh/internal/secure"
 	"github.com/bashhack/sesh/internal/setup"
+	"github.com/bashhack/sesh/internal/theme"
 	internalTotp "github.com/bashhack/sesh/internal/totp"
 )
 
@@ -25,8 +29,25 @@ type Provider struct {
 	provider.Clock
 	provider.KeyUser
 
+	// KeyPrefix is the keychain namespace secrets are stored under,
+	// constants.TOTPServicePrefix when empty. Setting it keeps a second
+	// set of entries, e.g. a test's or another tenant's, apart from the
+	// usual ones. It must start with "sesh-" to be listed.
+	KeyPrefix string
+
 	serviceName string
 	profile     string
+	algorithm   string
+
+	// keychainService names a keychain item another tool created, read
+	// as is instead of through the sesh-totp/ naming scheme
+	keychainService string
+
+	// debugCodes prints the codes for the windows around now instead of
+	// the usual pair, set by --debug-codes; force lets it print to a
+	// non-terminal
+	debugCodes bool
+	force      bool
 }
 
 var _ provider.ServiceProvider = (*Provider)(nil)
@@ -42,6 +63,16 @@ func NewProvider(
 	}
 }
 
+// prefix returns the keychain namespace for secrets.
+func (p *Provider) prefix() string {
+	return cmp.Or(p.KeyPrefix, constants.TOTPServicePrefix)
+}
+
+// KeyNamespaces implements provider.KeyNamespacer.
+func (p *Provider) KeyNamespaces() []string {
+	return []string{p.prefix()}
+}
+
 // Name returns the provider name.
 func (p *Provider) Name() string {
 	return "totp"
@@ -56,62 +87,78 @@ func (p *Provider) Description() string {
 func (p *Provider) SetupFlags(fs provider.FlagSet) error {
 	fs.StringVar(&p.serviceName, "service-name", "", "Name of the service to authenticate with")
 	fs.StringVar(&p.profile, "profile", "", "Profile name for the service (for multiple accounts)")
+	fs.StringVar(&p.algorithm, "algorithm", "", "HMAC algorithm (sha1, sha256, sha512); overrides the stored one")
+	fs.StringVar(&p.keychainService, "keychain-service", "", "Read the secret from this keychain item's service name instead of a sesh entry")
+	fs.BoolVar(&p.debugCodes, "debug-codes", false, "Print the codes for the windows around now with their UTC start times, to diagnose clock drift")
+	fs.BoolVar(&p.force, "force", false, "With --debug-codes or --setup --show-uri, print even when stdout isn't a terminal")
 
 	defaultKeyUser, err := env.GetCurrentUser()
 	if err != nil {
 		return fmt.Errorf("failed to get current user: %w", err)
 	}
-	p.User = defaultKeyUser
+	fs.StringVar(&p.User, "keychain-user", defaultKeyUser, "Keychain account the secret is stored under (default: current user)")
 	return nil
 }
 
 // GetSetupHandler returns a setup handler for TOTP.
 func (p *Provider) GetSetupHandler() any {
-	return setup.NewTOTPSetupHandler(p.keychain)
+	h := setup.NewTOTPSetupHandler(p.keychain)
+	h.KeyPrefix = p.prefix()
+	return h
 }
 
 // GetCredentials generates a TOTP code.
 func (p *Provider) GetCredentials() (provider.Credentials, error) {
 	creds, err := p.generateTOTP()
-	if err != nil {
+	if err != nil || p.debugCodes {
 		return creds, err
 	}
 
 	// Suggest clipboard mode when called directly
 	cmd := fmt.Sprintf("sesh --service totp --service-name %q", p.serviceName)
+	if p.keychainService != "" {
+		cmd = fmt.Sprintf("sesh --service totp --keychain-service %q", p.keychainService)
+	}
 	if p.profile != "" {
 		cmd += fmt.Sprintf(" --profile %q", p.profile)
 	}
-	fmt.Fprintf(os.Stderr, "⚠️  TOTP codes are typically used with clipboard mode for easy copying.\n💡 Recommended: %s --clip\n\n", cmd)
+	fmt.Fprintf(os.Stderr, theme.Warning()+" TOTP codes are typically used with clipboard mode for easy copying.\n💡 Recommended: %s --clip\n\n", cmd)
 
 	return creds, nil
 }
 
 // GetClipboardValue implements the ServiceProvider interface for clipboard mode.
 func (p *Provider) GetClipboardValue() (provider.Credentials, error) {
+	if p.debugCodes {
+		return provider.Credentials{}, errors.New("--debug-codes prints its codes and can't be combined with --clip")
+	}
 	return p.generateTOTP()
 }
 
 // generateTOTP is the shared implementation for both GetCredentials and GetClipboardValue.
 func (p *Provider) generateTOTP() (provider.Credentials, error) {
+	if p.keychainService != "" {
+		return p.generateFromKeychainService()
+	}
 	if p.serviceName == "" {
 		return provider.Credentials{}, fmt.Errorf("service name is required, use --service-name flag")
 	}
+	service, profile := p.target()
 
 	if err := p.EnsureUser(); err != nil {
 		return provider.Credentials{}, err
 	}
 
-	serviceKey, err := buildServiceKey(p.serviceName, p.profile)
+	serviceKey, err := buildServiceKey(p.prefix(), service, profile)
 	if err != nil {
 		return provider.Credentials{}, fmt.Errorf("failed to build service key: %w", err)
 	}
 
-	fmt.Fprintf(os.Stderr, "🔑 Retrieving TOTP secret for %s\n", p.serviceName)
+	fmt.Fprintf(os.Stderr, "🔑 Retrieving TOTP secret for %s\n", service)
 
 	secretBytes, err := p.keychain.GetSecret(p.User, serviceKey)
 	if err != nil {
-		return provider.Credentials{}, fmt.Errorf("failed to retrieve TOTP secret for %s: %w", p.serviceName, err)
+		return provider.Credentials{}, fmt.Errorf("failed to retrieve TOTP secret for %s: %w", service, err)
 	}
 
 	secretCopy := make([]byte, len(secretBytes))
@@ -122,8 +169,53 @@ func (p *Provider) generateTOTP() (provider.Credentials, error) {
 
 	// Check for stored TOTP params (algorithm, digits, period) via the entry description
 	params := p.loadTOTPParams(serviceKey)
+	return p.codesFor(secretCopy, params, displayName(service, profile, params.Issuer))
+}
+
+// generateFromKeychainService generates codes from the keychain item
+// --keychain-service names, for secrets another tool stored. sesh keeps
+// no params for such an item, so the defaults apply unless --algorithm
+// says otherwise.
+func (p *Provider) generateFromKeychainService() (provider.Credentials, error) {
+	if err := p.EnsureUser(); err != nil {
+		return provider.Credentials{}, err
+	}
 
-	currentCode, nextCode, err := p.totp.GenerateConsecutiveCodesBytesWithParams(secretCopy, params)
+	fmt.Fprintf(os.Stderr, "🔑 Retrieving TOTP secret from keychain item %s\n", p.keychainService)
+
+	secretBytes, err := p.keychain.GetSecret(p.User, p.keychainService)
+	if err != nil {
+		return provider.Credentials{}, fmt.Errorf("failed to retrieve TOTP secret from keychain item %q: %w", p.keychainService, err)
+	}
+	defer secure.SecureZeroBytes(secretBytes)
+
+	// The item wasn't written by sesh setup, so check it holds a base32
+	// secret before generating anything from it
+	secret, err := internalTotp.ValidateAndNormalizeSecret(string(secretBytes))
+	if err != nil {
+		return provider.Credentials{}, fmt.Errorf("keychain item %q does not hold a valid TOTP secret: %w", p.keychainService, err)
+	}
+	secretCopy := []byte(secret)
+	defer secure.SecureZeroBytes(secretCopy)
+
+	return p.codesFor(secretCopy, internalTotp.Params{}, p.keychainService)
+}
+
+// codesFor generates the current and next codes for secret, with
+// --algorithm overriding params, and labels them for output.
+func (p *Provider) codesFor(secret []byte, params internalTotp.Params, label string) (provider.Credentials, error) {
+	if p.debugCodes {
+		return p.windowCodes(secret, params, label)
+	}
+	if p.algorithm != "" {
+		var err error
+		params.Algorithm, err = internalTotp.ParseAlgorithm(p.algorithm)
+		if err != nil {
+			return provider.Credentials{}, err
+		}
+	}
+
+	currentCode, nextCode, err := p.totp.GenerateConsecutiveCodesBytesWithParams(secret, params)
 	if err != nil {
 		return provider.Credentials{}, fmt.Errorf("could not generate TOTP codes: %w", err)
 	}
@@ -132,15 +224,10 @@ func (p *Provider) generateTOTP() (provider.Credentials, error) {
 	if params.Period > 0 {
 		period = int64(params.Period)
 	}
-	secondsLeft := period - (p.TimeNow().Unix() % period)
-
-	serviceDesc := p.serviceName
-	if p.profile != "" {
-		serviceDesc = fmt.Sprintf("%s (%s)", p.serviceName, p.profile)
-	}
+	secondsLeft := period - (params.Shift(p.TimeNow()).Unix() % period)
 
 	return provider.CreateClipboardCredentials(p.Name(), currentCode, nextCode, secondsLeft,
-		"TOTP code", serviceDesc), nil
+		"TOTP code", label), nil
 }
 
 // loadTOTPParams reads stored TOTP params (algorithm, digits, period) from the entry description.
@@ -148,43 +235,96 @@ func (p *Provider) generateTOTP() (provider.Credentials, error) {
 // the metadata lookup to the same (service, account) as the secret was read
 // under, so a prefix sibling or cross-user entry can't spoof the params.
 func (p *Provider) loadTOTPParams(serviceKey string) internalTotp.Params {
-	entries, err := p.keychain.ListEntries(serviceKey)
-	if err != nil || len(entries) == 0 {
+	entries, err := p.keychain.List(keychain.EntryFilter{ServicePrefix: serviceKey, Account: p.User})
+	if err != nil {
 		return internalTotp.Params{}
 	}
-	if entries[0].Service != serviceKey || entries[0].Account != p.User {
-		return internalTotp.Params{}
+	for _, e := range entries {
+		if e.Service == serviceKey {
+			return internalTotp.ParseParams(e.Description)
+		}
 	}
-	return internalTotp.ParseParams(entries[0].Description)
+	return internalTotp.Params{}
+}
+
+// knownLoginURLs maps common service names, lowercased, to their login
+// pages, for --open on entries set up without --url.
+var knownLoginURLs = map[string]string{
+	"atlassian": "https://id.atlassian.com/login",
+	"aws":       "https://console.aws.amazon.com/",
+	"bitbucket": "https://bitbucket.org/account/signin/",
+	"dropbox":   "https://www.dropbox.com/login",
+	"github":    "https://github.com/login",
+	"gitlab":    "https://gitlab.com/users/sign_in",
+	"google":    "https://accounts.google.com/",
+	"microsoft": "https://login.microsoftonline.com/",
+	"npm":       "https://www.npmjs.com/login",
+	"slack":     "https://slack.com/signin",
+}
+
+// LoginURL implements provider.LoginURLProvider. It returns the URL stored
+// with --url at setup, falling back to a known login page for the service
+// name or issuer.
+func (p *Provider) LoginURL() (string, error) {
+	if p.keychainService != "" {
+		return "", errors.New("--open needs a sesh entry; a --keychain-service item has no login URL")
+	}
+	service, profile := p.target()
+	if err := p.EnsureUser(); err != nil {
+		return "", err
+	}
+	serviceKey, err := buildServiceKey(p.prefix(), service, profile)
+	if err != nil {
+		return "", fmt.Errorf("failed to build service key: %w", err)
+	}
+
+	params := p.loadTOTPParams(serviceKey)
+	if params.URL != "" {
+		return params.URL, nil
+	}
+	for _, name := range []string{service, params.Issuer} {
+		if u, ok := knownLoginURLs[strings.ToLower(name)]; ok {
+			return u, nil
+		}
+	}
+	return "", fmt.Errorf("no login URL known for %s; store one with 'sesh --service totp --setup --url URL'", service)
 }
 
 // ListEntries returns all TOTP entries in the keychain.
 func (p *Provider) ListEntries() ([]provider.ProviderEntry, error) {
-	entries, err := p.keychain.ListEntries(constants.TOTPServicePrefix)
+	entries, err := p.keychain.List(keychain.EntryFilter{ServiceType: p.prefix()})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list TOTP entries: %w", err)
 	}
 
-	result := make([]provider.ProviderEntry, 0, len(entries))
-	for _, entry := range entries {
-		if !strings.HasPrefix(entry.Service, constants.TOTPServicePrefix+"/") {
-			continue
+	// Sort by service key, then account, so --list output is stable.
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Service != entries[j].Service {
+			return entries[i].Service < entries[j].Service
 		}
+		return entries[i].Account < entries[j].Account
+	})
 
-		serviceName, profile := parseServiceKey(entry.Service)
+	result := make([]provider.ProviderEntry, 0, len(entries))
+	for _, entry := range entries {
+		serviceName, profile := parseServiceKey(entry.Service, p.prefix())
+		issuer := internalTotp.ParseParams(entry.Description).Issuer
 
-		displayName := serviceName
 		description := fmt.Sprintf("TOTP for %s", serviceName)
-
 		if profile != "" {
-			displayName = fmt.Sprintf("%s (%s)", serviceName, profile)
 			description = fmt.Sprintf("TOTP for %s profile %s", serviceName, profile)
 		}
 
 		result = append(result, provider.ProviderEntry{
-			Name:        displayName,
+			Name:        displayName(serviceName, profile, issuer),
 			Description: description,
 			ID:          fmt.Sprintf("%s:%s", entry.Service, entry.Account),
+			Type:        p.Name(),
+			Profile:     profile,
+			ServiceName: serviceName,
+			Issuer:      issuer,
+			Account:     entry.Account,
+			UpdatedAt:   entry.UpdatedAt,
 		})
 	}
 
@@ -193,7 +333,7 @@ func (p *Provider) ListEntries() ([]provider.ProviderEntry, error) {
 
 // DeleteEntry deletes a TOTP entry from the keychain.
 func (p *Provider) DeleteEntry(id string) error {
-	service, account, err := provider.ParseEntryID(id)
+	service, account, err := provider.ParseEntryIDFor(id, p.prefix())
 	if err != nil {
 		return err
 	}
@@ -205,17 +345,70 @@ func (p *Provider) DeleteEntry(id string) error {
 	return nil
 }
 
+// missingServiceNameError reports a missing --service-name along with the
+// names already stored, in the form --service-name accepts. A failed
+// listing just leaves the names out.
+func (p *Provider) missingServiceNameError() error {
+	const msg = "--service-name is required for TOTP provider"
+	entries, err := p.ListEntries()
+	if err != nil || len(entries) == 0 {
+		return errors.New(msg)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		name := strings.ReplaceAll(e.ServiceName, ":", `\:`)
+		if e.Profile != "" {
+			name += ":" + e.Profile
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return fmt.Errorf("%s; available: %s", msg, strings.Join(names, ", "))
+}
+
 // ValidateRequest performs early validation before any TOTP operations.
 func (p *Provider) ValidateRequest() error {
-	if p.serviceName == "" {
-		return fmt.Errorf("--service-name is required for TOTP provider")
+	if p.keychainService != "" && (p.serviceName != "" || p.profile != "") {
+		return errors.New("--keychain-service cannot be combined with --service-name or --profile")
+	}
+	if p.serviceName == "" && p.keychainService == "" {
+		return p.missingServiceNameError()
+	}
+	service, profile := p.target()
+	if err := provider.CheckNotBlank("--service-name", service); err != nil {
+		return err
+	}
+	if err := provider.CheckNotBlank("--profile", profile); err != nil {
+		return err
+	}
+	if p.algorithm != "" {
+		if _, err := internalTotp.ParseAlgorithm(p.algorithm); err != nil {
+			return err
+		}
+	}
+	if err := p.checkDebugCodes(); err != nil {
+		return err
 	}
 
 	if err := p.EnsureUser(); err != nil {
 		return err
 	}
 
-	keyName, err := buildServiceKey(p.serviceName, p.profile)
+	if p.keychainService != "" {
+		secret, err := p.keychain.GetSecret(p.User, p.keychainService)
+		if err != nil {
+			if !errors.Is(err, keychain.ErrNotFound) {
+				return fmt.Errorf("failed to read TOTP secret from keychain: %w", err)
+			}
+			notFound := provider.NotSetupError("no keychain item found for service '%s' and account '%s'; pass --keychain-user for a different account", p.keychainService, p.User)
+			return provider.KeychainUserHint(notFound, p.keychain.ListEntries, p.keychainService, p.User)
+		}
+		secure.SecureZeroBytes(secret)
+		return nil
+	}
+
+	keyName, err := buildServiceKey(p.prefix(), service, profile)
 	if err != nil {
 		return fmt.Errorf("failed to build service key: %w", err)
 	}
@@ -225,10 +418,11 @@ func (p *Provider) ValidateRequest() error {
 		if !errors.Is(err, keychain.ErrNotFound) {
 			return fmt.Errorf("failed to read TOTP secret from keychain: %w", err)
 		}
-		if p.profile != "" {
-			return fmt.Errorf("no TOTP entry found for service '%s' with profile '%s'. Run 'sesh --service totp --setup' first", p.serviceName, p.profile)
+		notFound := provider.NotSetupError("no TOTP entry found for service '%s'. Run 'sesh --service totp --setup' first", service)
+		if profile != "" {
+			notFound = provider.NotSetupError("no TOTP entry found for service '%s' with profile '%s'. Run 'sesh --service totp --setup' first", service, profile)
 		}
-		return fmt.Errorf("no TOTP entry found for service '%s'. Run 'sesh --service totp --setup' first", p.serviceName)
+		return provider.KeychainUserHint(notFound, p.keychain.ListEntries, keyName, p.User)
 	}
 	secure.SecureZeroBytes(secret)
 
@@ -250,23 +444,97 @@ func (p *Provider) GetFlagInfo() []provider.FlagInfo {
 			Description: "Profile name for the service (for multiple accounts)",
 			Required:    false,
 		},
+		{
+			Name:        "algorithm",
+			Type:        "string",
+			Description: "HMAC algorithm (sha1, sha256, sha512); overrides the stored one",
+			Required:    false,
+		},
+		{
+			Name:        "keychain-user",
+			Type:        "string",
+			Description: "Keychain account the secret is stored under (default: current user)",
+			Required:    false,
+		},
+		{
+			Name:        "keychain-service",
+			Type:        "string",
+			Description: "Read the secret from this keychain item's service name instead of a sesh entry",
+			Required:    false,
+		},
+		{
+			Name:        "debug-codes",
+			Type:        "bool",
+			Description: "Print the codes for the windows around now with their UTC start times, to diagnose clock drift",
+			Required:    false,
+		},
+		{
+			Name:        "force",
+			Type:        "bool",
+			Description: "With --debug-codes, print the codes even when stdout isn't a terminal",
+			Required:    false,
+		},
+	}
+}
+
+// target returns the service and profile to look up. Without --profile,
+// --service-name accepts "service:profile" shorthand, split on the first
+// colon; write "\:" for a colon that is part of the service name. With
+// --profile given, --service-name is taken as is.
+func (p *Provider) target() (service, profile string) {
+	if p.profile != "" {
+		return p.serviceName, p.profile
+	}
+	return splitServiceProfile(p.serviceName)
+}
+
+// splitServiceProfile splits "github:work" into ("github", "work") and
+// unescapes "\:" to a literal colon. A name with no unescaped colon, or
+// with nothing on one side of it, is a plain service name.
+func splitServiceProfile(name string) (service, profile string) {
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '\\':
+			i++ // skip the escaped character
+		case ':':
+			if i > 0 && i < len(name)-1 {
+				return unescapeColons(name[:i]), unescapeColons(name[i+1:])
+			}
+		}
+	}
+	return unescapeColons(name), ""
+}
+
+// unescapeColons turns each "\:" into ":".
+func unescapeColons(s string) string {
+	return strings.ReplaceAll(s, `\:`, ":")
+}
+
+// displayName labels an entry for output: the issuer if one was stored,
+// since service names are often terse, otherwise the service name, with
+// the profile in parentheses. E.g. "GitHub (personal)".
+func displayName(service, profile, issuer string) string {
+	name := cmp.Or(issuer, service)
+	if profile == "" {
+		return name
 	}
+	return fmt.Sprintf("%s (%s)", name, profile)
 }
 
-// buildServiceKey creates a service key using keyformat.Build.
+// buildServiceKey creates a service key under prefix using keyformat.Build.
 // Format: sesh-totp/{service} or sesh-totp/{service}/{profile}
-func buildServiceKey(service, profile string) (string, error) {
+func buildServiceKey(prefix, service, profile string) (string, error) {
 	if profile == "" {
-		return keyformat.Build(constants.TOTPServicePrefix, service)
+		return keyformat.Build(prefix, service)
 	}
-	return keyformat.Build(constants.TOTPServicePrefix, service, profile)
+	return keyformat.Build(prefix, service, profile)
 }
 
 // parseServiceKey extracts service name and profile from a service key.
 // For "sesh-totp/github" returns ("github", "").
 // For "sesh-totp/github/work" returns ("github", "work").
-func parseServiceKey(serviceKey string) (serviceName, profile string) {
-	segments, err := keyformat.Parse(serviceKey, constants.TOTPServicePrefix)
+func parseServiceKey(serviceKey, prefix string) (serviceName, profile string) {
+	segments, err := keyformat.Parse(serviceKey, prefix)
 	if err != nil || len(segments) == 0 {
 		return serviceKey, ""
 	}
@@ -13,6 +13,7 @@ import (
 	"github.com/bashhack/sesh/internal/provider"
 	"github.com/bashhack/sesh/internal/setup"
 	"github.com/bashhack/sesh/internal/testutil"
+	internalTotp "github.com/bashhack/sesh/internal/totp"
 	totpMocks "github.com/bashhack/sesh/internal/totp/mocks"
 )
 
@@ -66,12 +67,59 @@ func TestProvider_SetupFlags(t *testing.T) {
 	}
 }
 
+func TestProvider_KeychainUserFlag(t *testing.T) {
+	tests := map[string]struct {
+		args        []string
+		wantAccount string
+	}{
+		"custom account": {
+			args:        []string{"--service-name", "github", "--keychain-user", "svc-bot"},
+			wantAccount: "svc-bot",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer testutil.DiscardStderr(t)()
+
+			var gotAccount string
+			mockKeychain := &keychainMocks.MockProvider{
+				GetSecretFunc: func(account, _ string) ([]byte, error) {
+					gotAccount = account
+					return []byte("MYSECRET"), nil
+				},
+			}
+			mockTOTP := &totpMocks.MockProvider{
+				GenerateConsecutiveCodesBytesFunc: func(_ []byte) (string, string, error) {
+					return "123456", "654321", nil
+				},
+			}
+			p := NewProvider(mockKeychain, mockTOTP)
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			if err := p.SetupFlags(fs); err != nil {
+				t.Fatalf("SetupFlags() error = %v", err)
+			}
+			if err := fs.Parse(tc.args); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			if _, err := p.GetCredentials(); err != nil {
+				t.Fatalf("GetCredentials() error = %v", err)
+			}
+			if gotAccount != tc.wantAccount {
+				t.Errorf("GetSecret account = %q, want %q", gotAccount, tc.wantAccount)
+			}
+		})
+	}
+}
+
 func TestProvider_GetFlagInfo(t *testing.T) {
 	p := &Provider{}
 	flags := p.GetFlagInfo()
 
-	if len(flags) != 2 {
-		t.Fatalf("GetFlagInfo() returned %d flags, want 2", len(flags))
+	if len(flags) != 7 {
+		t.Fatalf("GetFlagInfo() returned %d flags, want 7", len(flags))
 	}
 
 	if flags[0].Name != "service-name" {
@@ -87,6 +135,14 @@ func TestProvider_GetFlagInfo(t *testing.T) {
 	if flags[1].Required {
 		t.Error("profile flag should not be required")
 	}
+
+	if flags[2].Name != "algorithm" {
+		t.Errorf("flag[2].Name = %v, want 'algorithm'", flags[2].Name)
+	}
+
+	if flags[4].Name != "keychain-service" {
+		t.Errorf("flag[4].Name = %v, want 'keychain-service'", flags[4].Name)
+	}
 }
 
 func TestProvider_GetSetupHandler(t *testing.T) {
@@ -109,11 +165,13 @@ func TestProvider_GetSetupHandler(t *testing.T) {
 
 func TestProvider_ValidateRequest(t *testing.T) {
 	tests := map[string]struct {
-		setupKeychain func(*keychainMocks.MockProvider)
-		serviceName   string
-		profile       string
-		wantErrMsg    string
-		wantErr       bool
+		setupKeychain   func(*keychainMocks.MockProvider)
+		serviceName     string
+		profile         string
+		algorithm       string
+		keychainService string
+		wantErrMsg      string
+		wantErr         bool
 	}{
 		"valid request": {
 			serviceName: "github",
@@ -138,6 +196,50 @@ func TestProvider_ValidateRequest(t *testing.T) {
 				}
 			},
 		},
+		"profile shorthand in service name": {
+			serviceName: "github:work",
+			setupKeychain: func(m *keychainMocks.MockProvider) {
+				m.GetSecretFunc = func(account, service string) ([]byte, error) {
+					if service == "sesh-totp/github/work" {
+						return []byte("secret"), nil
+					}
+					return nil, fmt.Errorf("unexpected service: %s", service)
+				}
+			},
+		},
+		"escaped colon is part of the service name": {
+			serviceName: `corp\:vpn`,
+			setupKeychain: func(m *keychainMocks.MockProvider) {
+				m.GetSecretFunc = func(account, service string) ([]byte, error) {
+					if service == "sesh-totp/corp:vpn" {
+						return []byte("secret"), nil
+					}
+					return nil, fmt.Errorf("unexpected service: %s", service)
+				}
+			},
+		},
+		"explicit profile disables the shorthand": {
+			serviceName: "corp:vpn",
+			profile:     "work",
+			setupKeychain: func(m *keychainMocks.MockProvider) {
+				m.GetSecretFunc = func(account, service string) ([]byte, error) {
+					if service == "sesh-totp/corp:vpn/work" {
+						return []byte("secret"), nil
+					}
+					return nil, fmt.Errorf("unexpected service: %s", service)
+				}
+			},
+		},
+		"shorthand names the profile when missing": {
+			serviceName: "gitlab:work",
+			setupKeychain: func(m *keychainMocks.MockProvider) {
+				m.GetSecretFunc = func(account, service string) ([]byte, error) {
+					return nil, keychain.ErrNotFound
+				}
+			},
+			wantErr:    true,
+			wantErrMsg: "no TOTP entry found for service 'gitlab' with profile 'work'. Run 'sesh --service totp --setup' first",
+		},
 		"no TOTP secret for service": {
 			serviceName: "gitlab",
 			setupKeychain: func(m *keychainMocks.MockProvider) {
@@ -159,6 +261,19 @@ func TestProvider_ValidateRequest(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "no TOTP entry found for service 'gitlab' with profile 'work'. Run 'sesh --service totp --setup' first",
 		},
+		"entry under another keychain account": {
+			serviceName: "gitlab",
+			setupKeychain: func(m *keychainMocks.MockProvider) {
+				m.GetSecretFunc = func(account, service string) ([]byte, error) {
+					return nil, keychain.ErrNotFound
+				}
+				m.ListEntriesFunc = func(prefix string) ([]keychain.KeychainEntry, error) {
+					return []keychain.KeychainEntry{{Service: "sesh-totp/gitlab", Account: "olduser"}}, nil
+				}
+			},
+			wantErr:    true,
+			wantErrMsg: "no TOTP entry found for service 'gitlab'. Run 'sesh --service totp --setup' first. Found it under keychain account 'olduser' instead; pass --keychain-user olduser to use it",
+		},
 		"keychain error surfaces without fallback message": {
 			serviceName: "github",
 			setupKeychain: func(m *keychainMocks.MockProvider) {
@@ -169,6 +284,18 @@ func TestProvider_ValidateRequest(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "failed to read TOTP secret from keychain: keychain locked",
 		},
+		"unsupported algorithm": {
+			serviceName: "github",
+			algorithm:   "md5",
+			setupKeychain: func(m *keychainMocks.MockProvider) {
+				m.GetSecretFunc = func(account, service string) ([]byte, error) {
+					t.Error("GetSecret should not be called with an unsupported algorithm")
+					return nil, errors.New("should not be called")
+				}
+			},
+			wantErr:    true,
+			wantErrMsg: `unsupported TOTP algorithm "md5" (use sha1, sha256 or sha512)`,
+		},
 		"empty service name": {
 			serviceName: "",
 			setupKeychain: func(m *keychainMocks.MockProvider) {
@@ -180,6 +307,76 @@ func TestProvider_ValidateRequest(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "--service-name is required for TOTP provider",
 		},
+		"empty service name lists stored names": {
+			setupKeychain: func(m *keychainMocks.MockProvider) {
+				m.ListEntriesFunc = func(prefix string) ([]keychain.KeychainEntry, error) {
+					return []keychain.KeychainEntry{
+						{Service: "sesh-totp/github", Account: "testuser"},
+						{Service: "sesh-totp/github/work", Account: "testuser"},
+						{Service: "sesh-totp/github", Account: "otheruser"},
+						{Service: "sesh-totp/a:b", Account: "testuser"},
+					}, nil
+				}
+			},
+			wantErr:    true,
+			wantErrMsg: `--service-name is required for TOTP provider; available: a\:b, github, github:work`,
+		},
+		"keychain service found": {
+			keychainService: "com.example.authenticator",
+			setupKeychain: func(m *keychainMocks.MockProvider) {
+				m.GetSecretFunc = func(account, service string) ([]byte, error) {
+					if account == "testuser" && service == "com.example.authenticator" {
+						return []byte("secret"), nil
+					}
+					return nil, fmt.Errorf("unexpected call: %s, %s", account, service)
+				}
+			},
+		},
+		"keychain service not found": {
+			keychainService: "com.example.authenticator",
+			setupKeychain: func(m *keychainMocks.MockProvider) {
+				m.GetSecretFunc = func(account, service string) ([]byte, error) {
+					return nil, keychain.ErrNotFound
+				}
+			},
+			wantErr:    true,
+			wantErrMsg: "no keychain item found for service 'com.example.authenticator' and account 'testuser'; pass --keychain-user for a different account",
+		},
+		"keychain service with service name": {
+			keychainService: "com.example.authenticator",
+			serviceName:     "github",
+			setupKeychain:   func(m *keychainMocks.MockProvider) {},
+			wantErr:         true,
+			wantErrMsg:      "--keychain-service cannot be combined with --service-name or --profile",
+		},
+		"empty service name with failed listing": {
+			setupKeychain: func(m *keychainMocks.MockProvider) {
+				m.ListEntriesFunc = func(prefix string) ([]keychain.KeychainEntry, error) {
+					return nil, errors.New("keychain locked")
+				}
+			},
+			wantErr:    true,
+			wantErrMsg: "--service-name is required for TOTP provider",
+		},
+		"whitespace service name": {
+			serviceName:   "  \t",
+			setupKeychain: noKeychainReads(t),
+			wantErr:       true,
+			wantErrMsg:    "--service-name must not be blank",
+		},
+		"whitespace profile": {
+			serviceName:   "github",
+			profile:       " ",
+			setupKeychain: noKeychainReads(t),
+			wantErr:       true,
+			wantErrMsg:    "--profile must not be blank",
+		},
+		"whitespace profile in shorthand": {
+			serviceName:   "github: ",
+			setupKeychain: noKeychainReads(t),
+			wantErr:       true,
+			wantErrMsg:    "--profile must not be blank",
+		},
 	}
 
 	for name, tc := range tests {
@@ -188,10 +385,12 @@ func TestProvider_ValidateRequest(t *testing.T) {
 			tc.setupKeychain(mockKeychain)
 
 			p := &Provider{
-				keychain:    mockKeychain,
-				serviceName: tc.serviceName,
-				profile:     tc.profile,
-				KeyUser:     provider.KeyUser{User: "testuser"},
+				keychain:        mockKeychain,
+				serviceName:     tc.serviceName,
+				profile:         tc.profile,
+				algorithm:       tc.algorithm,
+				keychainService: tc.keychainService,
+				KeyUser:         provider.KeyUser{User: "testuser"},
 			}
 
 			err := p.ValidateRequest()
@@ -370,6 +569,146 @@ func TestProvider_GetCredentials(t *testing.T) {
 	}
 }
 
+func TestProvider_GetCredentials_AlgorithmOverride(t *testing.T) {
+	tests := map[string]struct {
+		description   string
+		algorithm     string
+		wantAlgorithm string
+		wantDigits    int
+	}{
+		"stored algorithm without override": {
+			description:   `{"algorithm":"SHA256","digits":8}`,
+			wantAlgorithm: "SHA256",
+			wantDigits:    8,
+		},
+		"flag overrides stored algorithm": {
+			description:   `{"algorithm":"SHA256","digits":8}`,
+			algorithm:     "sha512",
+			wantAlgorithm: "SHA512",
+			wantDigits:    8,
+		},
+		"flag fills in missing metadata": {
+			algorithm:     "sha256",
+			wantAlgorithm: "SHA256",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer testutil.DiscardStderr(t)()
+
+			mockKeychain := &keychainMocks.MockProvider{
+				GetSecretFunc: func(_, _ string) ([]byte, error) { return []byte("MYSECRET"), nil },
+				ListEntriesFunc: func(_ string) ([]keychain.KeychainEntry, error) {
+					if tc.description == "" {
+						return nil, nil
+					}
+					return []keychain.KeychainEntry{{Service: "sesh-totp/github", Account: "testuser", Description: tc.description}}, nil
+				},
+			}
+			var gotParams internalTotp.Params
+			mockTOTP := &totpMocks.MockProvider{
+				GenerateConsecutiveCodesBytesWithParamsFunc: func(_ []byte, params internalTotp.Params) (string, string, error) {
+					gotParams = params
+					return "12345678", "87654321", nil
+				},
+			}
+
+			p := &Provider{
+				keychain:    mockKeychain,
+				totp:        mockTOTP,
+				serviceName: "github",
+				algorithm:   tc.algorithm,
+				KeyUser:     provider.KeyUser{User: "testuser"},
+			}
+
+			if _, err := p.GetCredentials(); err != nil {
+				t.Fatalf("GetCredentials() error = %v", err)
+			}
+			if gotParams.Algorithm != tc.wantAlgorithm || gotParams.Digits != tc.wantDigits {
+				t.Errorf("params = %+v, want Algorithm %q Digits %d", gotParams, tc.wantAlgorithm, tc.wantDigits)
+			}
+		})
+	}
+}
+
+func TestProvider_GetClipboardValue_KeychainService(t *testing.T) {
+	tests := map[string]struct {
+		stored     string
+		getErr     error
+		wantSecret string
+		wantErr    string
+	}{
+		"raw service lookup": {
+			stored:     "JBSWY3DPEHPK3PXP",
+			wantSecret: "JBSWY3DPEHPK3PXP",
+		},
+		"secret from another tool is normalized": {
+			stored:     "jbsw y3dp ehpk 3pxp",
+			wantSecret: "JBSWY3DPEHPK3PXP",
+		},
+		"value that isn't base32": {
+			stored:  "hunter2!",
+			wantErr: `keychain item "com.example.authenticator" does not hold a valid TOTP secret`,
+		},
+		"item missing": {
+			getErr:  keychain.ErrNotFound,
+			wantErr: `failed to retrieve TOTP secret from keychain item "com.example.authenticator"`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer testutil.DiscardStderr(t)()
+
+			var gotAccount, gotService string
+			mockKeychain := &keychainMocks.MockProvider{
+				GetSecretFunc: func(account, service string) ([]byte, error) {
+					gotAccount, gotService = account, service
+					if tc.getErr != nil {
+						return nil, tc.getErr
+					}
+					return []byte(tc.stored), nil
+				},
+			}
+			var gotSecret string
+			mockTOTP := &totpMocks.MockProvider{
+				GenerateConsecutiveCodesBytesWithParamsFunc: func(secret []byte, _ internalTotp.Params) (string, string, error) {
+					gotSecret = string(secret)
+					return "123456", "654321", nil
+				},
+			}
+
+			p := &Provider{
+				keychain:        mockKeychain,
+				totp:            mockTOTP,
+				keychainService: "com.example.authenticator",
+				KeyUser:         provider.KeyUser{User: "testuser"},
+			}
+
+			creds, err := p.GetClipboardValue()
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("GetClipboardValue() error = %v, want containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetClipboardValue() error = %v", err)
+			}
+			if gotAccount != "testuser" || gotService != "com.example.authenticator" {
+				t.Errorf("GetSecret(%q, %q), want (testuser, com.example.authenticator)", gotAccount, gotService)
+			}
+			if gotSecret != tc.wantSecret {
+				t.Errorf("secret = %q, want %q", gotSecret, tc.wantSecret)
+			}
+			if creds.CopyValue != "123456" {
+				t.Errorf("CopyValue = %q, want 123456", creds.CopyValue)
+			}
+		})
+	}
+}
+
 func TestProvider_GetClipboardValue(t *testing.T) {
 	tests := map[string]struct {
 		setupKeychain func(*keychainMocks.MockProvider)
@@ -481,14 +820,14 @@ func TestProvider_ListEntries(t *testing.T) {
 			},
 			wantCount: 3,
 			checkEntries: func(t *testing.T, entries []provider.ProviderEntry) {
-				if entries[0].Name != "github" {
-					t.Errorf("entries[0].Name = %v, want 'github'", entries[0].Name)
+				if entries[1].Name != "github" {
+					t.Errorf("entries[1].Name = %v, want 'github'", entries[1].Name)
 				}
-				if entries[0].Description != "TOTP for github" {
-					t.Errorf("entries[0].Description = %v, want 'TOTP for github'", entries[0].Description)
+				if entries[1].Description != "TOTP for github" {
+					t.Errorf("entries[1].Description = %v, want 'TOTP for github'", entries[1].Description)
 				}
-				if entries[0].ID != "sesh-totp/github:testuser" {
-					t.Errorf("entries[0].ID = %v, want 'sesh-totp/github:testuser'", entries[0].ID)
+				if entries[1].ID != "sesh-totp/github:testuser" {
+					t.Errorf("entries[1].ID = %v, want 'sesh-totp/github:testuser'", entries[1].ID)
 				}
 			},
 		},
@@ -503,11 +842,61 @@ func TestProvider_ListEntries(t *testing.T) {
 			},
 			wantCount: 2,
 			checkEntries: func(t *testing.T, entries []provider.ProviderEntry) {
-				if entries[0].Name != "github (work)" {
-					t.Errorf("entries[0].Name = %v, want 'github (work)'", entries[0].Name)
+				if entries[1].Name != "github (work)" {
+					t.Errorf("entries[1].Name = %v, want 'github (work)'", entries[1].Name)
+				}
+				if entries[1].Description != "TOTP for github profile work" {
+					t.Errorf("entries[1].Description = %v, want 'TOTP for github profile work'", entries[1].Description)
+				}
+			},
+		},
+		"stored issuer is shown as the name": {
+			setupKeychain: func(m *keychainMocks.MockProvider) {
+				m.ListEntriesFunc = func(prefix string) ([]keychain.KeychainEntry, error) {
+					return []keychain.KeychainEntry{
+						{Service: "sesh-totp/gh/personal", Account: "testuser", Description: `{"issuer":"GitHub"}`},
+						{Service: "sesh-totp/gl", Account: "testuser", Description: "TOTP for gl"},
+					}, nil
+				}
+			},
+			wantCount: 2,
+			checkEntries: func(t *testing.T, entries []provider.ProviderEntry) {
+				if entries[0].Name != "GitHub (personal)" {
+					t.Errorf("entries[0].Name = %v, want 'GitHub (personal)'", entries[0].Name)
+				}
+				if entries[0].Issuer != "GitHub" || entries[0].ServiceName != "gh" {
+					t.Errorf("entries[0] issuer/service = %q/%q, want GitHub/gh", entries[0].Issuer, entries[0].ServiceName)
 				}
-				if entries[0].Description != "TOTP for github profile work" {
-					t.Errorf("entries[0].Description = %v, want 'TOTP for github profile work'", entries[0].Description)
+				if entries[1].Name != "gl" || entries[1].Issuer != "" {
+					t.Errorf("entries[1] name/issuer = %q/%q, want gl and no issuer", entries[1].Name, entries[1].Issuer)
+				}
+			},
+		},
+		"shuffled input is sorted by service then account": {
+			setupKeychain: func(m *keychainMocks.MockProvider) {
+				m.ListEntriesFunc = func(prefix string) ([]keychain.KeychainEntry, error) {
+					return []keychain.KeychainEntry{
+						{Service: "sesh-totp/gitlab", Account: "bob"},
+						{Service: "sesh-totp/github/work", Account: "alice"},
+						{Service: "sesh-totp/github", Account: "bob"},
+						{Service: "sesh-totp/github", Account: "alice"},
+						{Service: "sesh-totp/bitbucket", Account: "alice"},
+					}, nil
+				}
+			},
+			wantCount: 5,
+			checkEntries: func(t *testing.T, entries []provider.ProviderEntry) {
+				want := []string{
+					"sesh-totp/bitbucket:alice",
+					"sesh-totp/github:alice",
+					"sesh-totp/github:bob",
+					"sesh-totp/github/work:alice",
+					"sesh-totp/gitlab:bob",
+				}
+				for i, id := range want {
+					if entries[i].ID != id {
+						t.Errorf("entries[%d].ID = %v, want %v", i, entries[i].ID, id)
+					}
 				}
 			},
 		},
@@ -584,6 +973,28 @@ func TestProvider_DeleteEntry(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "invalid entry ID format: expected 'service:account', got \"invalid-id\"",
 		},
+		"empty service": {
+			entryID: ":testuser",
+			setupKeychain: func(m *keychainMocks.MockProvider) {
+				m.DeleteEntryFunc = func(account, service string) error {
+					t.Error("DeleteEntry should not be called with invalid ID")
+					return nil
+				}
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid entry ID format: expected 'service:account', got \":testuser\"",
+		},
+		"AWS entry ID": {
+			entryID: "sesh-aws/default:testuser",
+			setupKeychain: func(m *keychainMocks.MockProvider) {
+				m.DeleteEntryFunc = func(account, service string) error {
+					t.Error("DeleteEntry should not be called for another provider's entry")
+					return nil
+				}
+			},
+			wantErr:    true,
+			wantErrMsg: "invalid entry ID format: service key \"sesh-aws/default\" is not under sesh-totp",
+		},
 		"keychain error": {
 			entryID: "sesh-totp/gitlab:testuser",
 			setupKeychain: func(m *keychainMocks.MockProvider) {
@@ -638,7 +1049,7 @@ func TestBuildServiceKey(t *testing.T) {
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			got, err := buildServiceKey(tc.service, tc.profile)
+			got, err := buildServiceKey("sesh-totp", tc.service, tc.profile)
 			if tc.wantErr && err == nil {
 				t.Error("buildServiceKey() expected error but got nil")
 			}
@@ -652,6 +1063,31 @@ func TestBuildServiceKey(t *testing.T) {
 	}
 }
 
+func TestSplitServiceProfile(t *testing.T) {
+	tests := map[string]struct {
+		name        string
+		wantService string
+		wantProfile string
+	}{
+		"plain name":            {name: "github", wantService: "github"},
+		"shorthand":             {name: "github:work", wantService: "github", wantProfile: "work"},
+		"splits on first colon": {name: "github:work:eu", wantService: "github", wantProfile: "work:eu"},
+		"escaped colon":         {name: `corp\:vpn`, wantService: "corp:vpn"},
+		"escaped then split":    {name: `corp\:vpn:work`, wantService: "corp:vpn", wantProfile: "work"},
+		"trailing colon":        {name: "github:", wantService: "github:"},
+		"leading colon":         {name: ":work", wantService: ":work"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			service, profile := splitServiceProfile(tc.name)
+			if service != tc.wantService || profile != tc.wantProfile {
+				t.Errorf("splitServiceProfile(%q) = (%q, %q), want (%q, %q)", tc.name, service, profile, tc.wantService, tc.wantProfile)
+			}
+		})
+	}
+}
+
 func TestParseServiceKey(t *testing.T) {
 	tests := map[string]struct {
 		serviceKey  string
@@ -682,7 +1118,7 @@ func TestParseServiceKey(t *testing.T) {
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			service, profile := parseServiceKey(tc.serviceKey)
+			service, profile := parseServiceKey(tc.serviceKey, "sesh-totp")
 			if service != tc.wantService {
 				t.Errorf("parseServiceKey() service = %v, want %v", service, tc.wantService)
 			}
@@ -692,3 +1128,161 @@ func TestParseServiceKey(t *testing.T) {
 		})
 	}
 }
+
+func TestProvider_CustomKeyPrefix(t *testing.T) {
+	defer testutil.DiscardStderr(t)()
+
+	store := map[string]string{
+		"sesh-test-totp/github/work": "JBSWY3DPEHPK3PXP",
+		"sesh-totp/github/work":      "GEZDGNBVGY3TQOJQ",
+	}
+	kc := &keychainMocks.MockProvider{
+		GetSecretFunc: func(_, service string) ([]byte, error) {
+			secret, ok := store[service]
+			if !ok {
+				return nil, keychain.ErrNotFound
+			}
+			return []byte(secret), nil
+		},
+		ListFunc: func(filter keychain.EntryFilter) ([]keychain.KeychainEntryMeta, error) {
+			var out []keychain.KeychainEntryMeta
+			for service := range store {
+				namespace, _, _ := strings.Cut(service, "/")
+				if namespace == filter.ServiceType {
+					out = append(out, keychain.KeychainEntryMeta{Service: service, Account: "alice", ServiceType: namespace})
+				}
+			}
+			return out, nil
+		},
+		DeleteEntryFunc: func(_, service string) error {
+			delete(store, service)
+			return nil
+		},
+	}
+	var gotSecret string
+	mockTOTP := &totpMocks.MockProvider{
+		GenerateConsecutiveCodesBytesWithParamsFunc: func(secret []byte, _ internalTotp.Params) (string, string, error) {
+			gotSecret = string(secret)
+			return "123456", "654321", nil
+		},
+	}
+
+	p := NewProvider(kc, mockTOTP)
+	p.KeyPrefix = "sesh-test-totp"
+	p.User = "alice"
+	p.serviceName, p.profile = "github", "work"
+
+	if h := p.GetSetupHandler().(*setup.TOTPSetupHandler); h.KeyPrefix != "sesh-test-totp" {
+		t.Errorf("setup handler KeyPrefix = %q, want sesh-test-totp", h.KeyPrefix)
+	}
+
+	if err := p.ValidateRequest(); err != nil {
+		t.Fatalf("ValidateRequest() error = %v", err)
+	}
+	if _, err := p.GetClipboardValue(); err != nil {
+		t.Fatalf("GetClipboardValue() error = %v", err)
+	}
+	if gotSecret != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("generated from %q, want the sesh-test-totp secret", gotSecret)
+	}
+
+	entries, err := p.ListEntries()
+	if err != nil {
+		t.Fatalf("ListEntries() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].ID != "sesh-test-totp/github/work:alice" || entries[0].ServiceName != "github" || entries[0].Profile != "work" {
+		t.Fatalf("ListEntries() = %+v, want only the sesh-test-totp entry", entries)
+	}
+
+	if err := p.DeleteEntry("sesh-totp/github/work:alice"); err == nil {
+		t.Error("DeleteEntry() accepted a key outside the custom prefix")
+	}
+	if err := p.DeleteEntry(entries[0].ID); err != nil {
+		t.Fatalf("DeleteEntry() error = %v", err)
+	}
+	if _, ok := store["sesh-test-totp/github/work"]; ok {
+		t.Error("custom-prefix entry still stored after DeleteEntry")
+	}
+	if _, ok := store["sesh-totp/github/work"]; !ok {
+		t.Error("default-prefix entry was deleted")
+	}
+}
+
+func TestProvider_LoginURL(t *testing.T) {
+	tests := map[string]struct {
+		serviceName     string
+		keychainService string
+		description     string
+		want            string
+		wantErr         string
+	}{
+		"stored url wins": {
+			serviceName: "github",
+			description: `{"url":"https://github.example.com/login"}`,
+			want:        "https://github.example.com/login",
+		},
+		"known service name": {
+			serviceName: "GitHub",
+			description: "TOTP for GitHub",
+			want:        "https://github.com/login",
+		},
+		"known issuer": {
+			serviceName: "work-gh",
+			description: `{"issuer":"GitHub"}`,
+			want:        "https://github.com/login",
+		},
+		"profile entry": {
+			serviceName: "gitlab:work",
+			want:        "https://gitlab.com/users/sign_in",
+		},
+		"unknown service": {
+			serviceName: "intranet",
+			wantErr:     "no login URL known for intranet",
+		},
+		"keychain service has none": {
+			keychainService: "com.example.otp",
+			wantErr:         "--keychain-service item has no login URL",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			kc := &keychainMocks.MockProvider{
+				ListFunc: func(filter keychain.EntryFilter) ([]keychain.KeychainEntryMeta, error) {
+					return []keychain.KeychainEntryMeta{
+						{Service: filter.ServicePrefix, Account: filter.Account, Description: tc.description},
+					}, nil
+				},
+			}
+			p := NewProvider(kc, &totpMocks.MockProvider{})
+			p.User = "alice"
+			p.serviceName = tc.serviceName
+			p.keychainService = tc.keychainService
+
+			got, err := p.LoginURL()
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("LoginURL() error = %v, want containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoginURL() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("LoginURL() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// noKeychainReads fails the test if the keychain is read, for requests
+// that should be rejected before any keychain operation.
+func noKeychainReads(t *testing.T) func(*keychainMocks.MockProvider) {
+	return func(m *keychainMocks.MockProvider) {
+		m.GetSecretFunc = func(_, service string) ([]byte, error) {
+			t.Errorf("keychain read for %q", service)
+			return nil, keychain.ErrNotFound
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"image"
 	"image/png"
+	"io"
 	"net/url"
 	"os"
 	"os/exec"
@@ -14,6 +15,8 @@ import (
 
 	"github.com/makiuchi-d/gozxing"
 	"github.com/makiuchi-d/gozxing/qrcode"
+
+	"github.com/bashhack/sesh/internal/theme"
 )
 
 var (
@@ -53,18 +56,38 @@ func DecodeQRCodeFromImage(img image.Image) (string, error) {
 // DecodeQRCodeFromImageFull extracts full TOTP info from a QR code image,
 // including algorithm, digits, and period.
 func DecodeQRCodeFromImageFull(img image.Image) (TOTPInfo, error) {
+	text, err := decodeText(img)
+	if err != nil {
+		return TOTPInfo{}, fmt.Errorf("%w\nMake sure the QR code is clearly visible in the screenshot", err)
+	}
+
+	return ExtractTOTPFullInfo(text)
+}
+
+// DecodeImage decodes the QR code in a PNG read from r and returns its
+// text (for an authenticator QR code, the otpauth:// URI). It needs no
+// screen capture, so it works on any platform and headless.
+func DecodeImage(r io.Reader) (string, error) {
+	img, err := png.Decode(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode PNG image: %w", err)
+	}
+	return decodeText(img)
+}
+
+// decodeText returns the text encoded in the QR code in img.
+func decodeText(img image.Image) (string, error) {
 	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
 	if err != nil {
-		return TOTPInfo{}, fmt.Errorf("failed to process image for QR reading: %w", err)
+		return "", fmt.Errorf("failed to process image for QR reading: %w", err)
 	}
 
 	reader := qrcode.NewQRCodeReader()
 	result, err := reader.Decode(bmp, nil)
 	if err != nil {
-		return TOTPInfo{}, fmt.Errorf("failed to decode QR code: %w\nMake sure the QR code is clearly visible in the screenshot", err)
+		return "", fmt.Errorf("failed to decode QR code: %w", err)
 	}
-
-	return ExtractTOTPFullInfo(result.GetText())
+	return result.GetText(), nil
 }
 
 // ScanQRCodeFull captures a QR code from screen and returns full TOTP info.
@@ -94,7 +117,7 @@ func ScanQRCodeFull() (TOTPInfo, error) {
 		return TOTPInfo{}, fmt.Errorf("screenshot capture was canceled or failed")
 	}
 
-	fmt.Println("✅ Screenshot captured, processing QR code...")
+	fmt.Println(theme.OK() + " Screenshot captured, processing QR code...")
 
 	file, err := os.Open(filepath.Clean(tempFile))
 	if err != nil {
@@ -400,6 +400,65 @@ func TestGenerateValidQRCode(t *testing.T) {
 	}
 }
 
+func TestDecodeImage(t *testing.T) {
+	const uri = "otpauth://totp/Example:alice@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Example&algorithm=SHA256&digits=8&period=60"
+
+	bitMatrix, err := qrcode.NewQRCodeWriter().Encode(uri, gozxing.BarcodeFormat_QR_CODE, 250, 250, nil)
+	if err != nil {
+		t.Fatalf("Failed to encode QR code: %v", err)
+	}
+	var qrPNG bytes.Buffer
+	if err := png.Encode(&qrPNG, bitMatrix); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+
+	tests := map[string]struct {
+		input      []byte
+		want       string
+		wantErrMsg string
+	}{
+		"otpauth QR code": {
+			input: qrPNG.Bytes(),
+			want:  uri,
+		},
+		"not a PNG": {
+			input:      []byte("not an image"),
+			wantErrMsg: "failed to decode PNG image",
+		},
+		"PNG without a QR code": {
+			input:      encodePNG(t, createCheckerboardImage(100, 100)),
+			wantErrMsg: "failed to decode QR code",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := DecodeImage(bytes.NewReader(tc.input))
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("DecodeImage() error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeImage() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("DecodeImage() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
 func TestDecodeNonTOTPQRCode(t *testing.T) {
 	tests := map[string]struct {
 		data    string
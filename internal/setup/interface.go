@@ -2,6 +2,7 @@ package setup
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/bashhack/sesh/internal/keychain"
 )
@@ -12,13 +13,114 @@ type SetupHandler interface {
 	Setup() error
 }
 
+// Options tunes a setup run. The zero value is the interactive default.
+type Options struct {
+	// NoMetadata stores secrets without writing the listing metadata index,
+	// so the entry won't appear in -list until it is re-indexed.
+	NoMetadata bool
+
+	// SecretEnv names an environment variable holding the TOTP secret.
+	// When set, TOTP setup reads the secret from it instead of prompting.
+	SecretEnv string
+
+	// Resume offers to continue an interrupted AWS setup from its saved
+	// scratch state instead of starting over.
+	Resume bool
+
+	// VerifyWithService ends TOTP setup by checking a code the service
+	// currently shows against the stored secret, without asking first.
+	VerifyWithService bool
+
+	// ExistingDevice skips the AWS console walkthrough for users whose
+	// virtual MFA device is already assigned; setup only captures the
+	// secret and serial.
+	ExistingDevice bool
+
+	// ProfileFromARN is an MFA device ARN whose account ID is matched
+	// against ~/.aws/config to pick the AWS profile instead of asking.
+	ProfileFromARN string
+
+	// Algorithm overrides the TOTP hash algorithm ("SHA1", "SHA256",
+	// "SHA512") parsed from an otpauth URI, or sets it for manual entry.
+	Algorithm string
+
+	// Issuer is a friendly name (e.g. "GitHub") stored with a TOTP entry
+	// and shown in listings instead of the service name. It overrides an
+	// issuer read from an otpauth URI.
+	Issuer string
+
+	// URL is the login page stored with a TOTP entry, which --open
+	// launches in the browser after copying the code.
+	URL string
+
+	// StrictSecret rejects a captured TOTP secret that would otherwise be
+	// normalized (whitespace, lowercase, wrong padding) instead of fixing it.
+	StrictSecret bool
+
+	// KeychainUser stores the entry under this keychain account instead
+	// of the current OS user; generation must pass the same --keychain-user.
+	KeychainUser string
+
+	// QRImage is a PNG file (or "-" for stdin) holding the TOTP QR code,
+	// decoded instead of prompting for manual entry or a screen capture.
+	QRImage string
+
+	// CopyFirstCode puts the first verification code on the clipboard so it
+	// can be pasted into the service, cleared after ClipTimeout.
+	CopyFirstCode bool
+
+	// ClipTwo puts both verification codes on the clipboard, space
+	// separated and in order, for services such as the AWS console that
+	// ask for two consecutive codes.
+	ClipTwo bool
+
+	// ClipTimeout is how long a copied code stays on the clipboard. Zero
+	// means the package default.
+	ClipTimeout time.Duration
+
+	// TimeOffset is stored with the entry and added to the local clock, in
+	// seconds, whenever its codes are generated, for a clock that is
+	// persistently fast or slow.
+	TimeOffset int
+
+	// QRRetries is how many screen captures QR setup attempts before
+	// offering manual entry. Zero means DefaultQRRetries; SkipQRCapture
+	// goes straight to manual entry.
+	QRRetries int
+
+	// PromptTimeout bounds each wait for AWS console confirmation; zero
+	// waits forever.
+	PromptTimeout time.Duration
+
+	// NoConsoleWait is for users who have already finished the AWS console
+	// step: AWS setup shows the codes without pausing for confirmation and
+	// lists MFA devices once, failing instead of offering retries.
+	NoConsoleWait bool
+
+	// ShowURI ends a successful setup by printing the otpauth:// URI for
+	// the stored secret, so it can be backed up. It is only printed to a
+	// terminal unless Force is also set.
+	ShowURI bool
+	Force   bool
+
+	// PrintEnvExample ends a successful AWS setup by printing the commands
+	// that put the profile's session where the AWS CLI and SDKs find it.
+	PrintEnvExample bool
+}
+
+// Configurable is implemented by handlers that honor Options. The setup
+// service passes the run's Options to Configure before calling Setup.
+type Configurable interface {
+	Configure(opts Options)
+}
+
 // SetupService is the main service for setting up credentials
 type SetupService interface {
 	// RegisterHandler registers a setup handler for a service
 	RegisterHandler(handler SetupHandler)
 
 	// SetupService initiates the setup process for a specific service
-	SetupService(serviceName string) error
+	SetupService(serviceName string, opts Options) error
 
 	// GetAvailableServices returns a list of services that can be set up
 	GetAvailableServices() []string
@@ -44,12 +146,16 @@ func (s *setupServiceImpl) RegisterHandler(handler SetupHandler) {
 }
 
 // SetupService initiates the setup process for a specific service
-func (s *setupServiceImpl) SetupService(serviceName string) error {
+func (s *setupServiceImpl) SetupService(serviceName string, opts Options) error {
 	handler, exists := s.handlers[serviceName]
 	if !exists {
 		return fmt.Errorf("no setup handler registered for service: %s", serviceName)
 	}
 
+	if c, ok := handler.(Configurable); ok {
+		c.Configure(opts)
+	}
+
 	return handler.Setup()
 }
 
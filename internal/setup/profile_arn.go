@@ -0,0 +1,175 @@
+package setup
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bashhack/sesh/internal/theme"
+)
+
+// awsConfigPath returns the AWS CLI config location, honoring
+// AWS_CONFIG_FILE like the CLI does. It is a variable so we can swap it out
+// in tests.
+var awsConfigPath = func() (string, error) {
+	if path := os.Getenv("AWS_CONFIG_FILE"); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".aws", "config"), nil
+}
+
+// validateMFAARN checks that arn looks like an IAM MFA device ARN.
+func validateMFAARN(arn string) error {
+	if !strings.HasPrefix(arn, "arn:aws:iam::") || !strings.Contains(arn, ":mfa/") {
+		return fmt.Errorf("invalid MFA ARN %q (format: arn:aws:iam::ACCOUNT_ID:mfa/USERNAME)", arn)
+	}
+	return nil
+}
+
+// arnAccountID returns the 12-digit account ID field of an ARN, or "" if it
+// has none.
+func arnAccountID(arn string) string {
+	fields := strings.SplitN(arn, ":", 6)
+	if len(fields) < 6 || !isAccountID(fields[4]) {
+		return ""
+	}
+	return fields[4]
+}
+
+// checkMFAAccount rejects an MFA ARN from a different account than the
+// identity setup verified, which usually means an ARN copied from another
+// account. It passes when either account can't be read from its ARN.
+func checkMFAAccount(mfaArn, identityArn string) error {
+	mfaAccount, identityAccount := arnAccountID(mfaArn), arnAccountID(identityArn)
+	if mfaAccount == "" || identityAccount == "" || mfaAccount == identityAccount {
+		return nil
+	}
+	return fmt.Errorf("that MFA ARN belongs to account %s but you're authenticated as account %s", mfaAccount, identityAccount)
+}
+
+func isAccountID(s string) bool {
+	if len(s) != 12 {
+		return false
+	}
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
+
+// profilesForAccount returns, in file order, the profiles in an AWS CLI
+// config whose account can be determined to be account: from
+// sso_account_id, or the account field of role_arn or mfa_serial. The
+// default profile is returned as "default".
+func profilesForAccount(config, account string) []string {
+	var matches []string
+	current := ""
+	matched := false
+
+	for line := range strings.SplitSeq(config, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section := strings.TrimSpace(line[1 : len(line)-1])
+			switch {
+			case section == "default":
+				current = "default"
+			case strings.HasPrefix(section, "profile "):
+				current = strings.TrimSpace(strings.TrimPrefix(section, "profile "))
+			default:
+				// sso-session, services, etc. aren't profiles.
+				current = ""
+			}
+			matched = false
+			continue
+		}
+		if current == "" || matched {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		var lineAccount string
+		switch strings.TrimSpace(key) {
+		case "sso_account_id":
+			lineAccount = value
+		case "role_arn", "mfa_serial":
+			lineAccount = arnAccountID(value)
+		}
+		if lineAccount == account {
+			matches = append(matches, current)
+			matched = true
+		}
+	}
+	return matches
+}
+
+// profileFromARN picks the AWS profile for --profile-from-arn by matching
+// the ARN's account against ~/.aws/config. It returns ok=false when there is
+// no usable match, so the caller falls back to prompting. The default
+// profile is returned as "", the way setup stores it.
+func (h *AWSSetupHandler) profileFromARN(arn string) (profile string, ok bool, err error) {
+	account := arnAccountID(arn)
+	if account == "" {
+		return "", false, fmt.Errorf("MFA ARN %q has no account ID", arn)
+	}
+
+	path, err := awsConfigPath()
+	if err != nil {
+		return "", false, err
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // the user's AWS CLI config
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Printf(theme.Info()+" No AWS config at %s to match account %s against\n", path, account)
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	matches := profilesForAccount(string(data), account)
+	switch len(matches) {
+	case 0:
+		fmt.Printf(theme.Info()+" No profile in %s matches account %s\n", path, account)
+		return "", false, nil
+	case 1:
+		fmt.Printf(theme.OK()+" Account %s matches AWS profile '%s'\n", account, matches[0])
+		return setupProfileName(matches[0]), true, nil
+	}
+
+	fmt.Printf("Account %s matches several AWS profiles:\n", account)
+	for i, m := range matches {
+		fmt.Printf("%d: %s\n", i+1, m)
+	}
+	fmt.Printf("Select a profile (1-%d), or press Enter to type one: ", len(matches))
+
+	choice, err := readLine(h.reader)
+	if err != nil {
+		return "", false, err
+	}
+	if choice == "" {
+		return "", false, nil
+	}
+	n, convErr := strconv.Atoi(choice)
+	if convErr != nil || n < 1 || n > len(matches) {
+		return "", false, fmt.Errorf("invalid choice %q, please select 1-%d", choice, len(matches))
+	}
+	return setupProfileName(matches[n-1]), true, nil
+}
+
+// setupProfileName maps an AWS CLI profile name to the name setup stores:
+// the default profile is stored under "".
+func setupProfileName(name string) string {
+	if name == "default" {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,196 @@
+package setup
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bashhack/sesh/internal/testutil"
+)
+
+const sampleAWSConfig = `[default]
+region = us-east-1
+mfa_serial = arn:aws:iam::111111111111:mfa/alice
+
+[profile work]
+sso_account_id = 222222222222
+sso_role_name = Admin
+
+[profile work-admin]
+role_arn = arn:aws:iam::222222222222:role/Admin
+source_profile = default
+
+[sso-session corp]
+sso_account_id = 222222222222
+
+[profile dev]
+mfa_serial = arn:aws:iam::444444444444:mfa/alice
+
+[profile unknown]
+region = eu-west-1
+`
+
+func TestProfilesForAccount(t *testing.T) {
+	tests := map[string]struct {
+		account string
+		want    []string
+	}{
+		"mfa_serial on default":    {account: "111111111111", want: []string{"default"}},
+		"sso and role_arn matches": {account: "222222222222", want: []string{"work", "work-admin"}},
+		"no match":                 {account: "333333333333", want: nil},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := profilesForAccount(sampleAWSConfig, tc.account)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("profilesForAccount(%q) = %v, want %v", tc.account, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAWSSetupHandler_profileFromARN(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(configPath, []byte(sampleAWSConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	origPath := awsConfigPath
+	defer func() { awsConfigPath = origPath }()
+	awsConfigPath = func() (string, error) { return configPath, nil }
+
+	tests := map[string]struct {
+		arn         string
+		input       string
+		wantProfile string
+		wantOK      bool
+		wantErrMsg  string
+	}{
+		"single match selects profile": {
+			arn:         "arn:aws:iam::444444444444:mfa/alice",
+			wantProfile: "dev",
+			wantOK:      true,
+		},
+		"no match falls back to prompting": {
+			arn:    "arn:aws:iam::333333333333:mfa/bob",
+			wantOK: false,
+		},
+		"default profile maps to empty": {
+			arn:         "arn:aws:iam::111111111111:mfa/alice",
+			wantProfile: "",
+			wantOK:      true,
+		},
+		"several matches prompt for choice": {
+			arn:         "arn:aws:iam::222222222222:mfa/alice",
+			input:       "2\n",
+			wantProfile: "work-admin",
+			wantOK:      true,
+		},
+		"several matches, enter falls back to typing": {
+			arn:    "arn:aws:iam::222222222222:mfa/alice",
+			input:  "\n",
+			wantOK: false,
+		},
+		"several matches, out-of-range choice": {
+			arn:        "arn:aws:iam::222222222222:mfa/alice",
+			input:      "9\n",
+			wantErrMsg: "invalid choice",
+		},
+		"ARN without account ID": {
+			arn:        "arn:aws:iam::not-an-account:mfa/alice",
+			wantErrMsg: "no account ID",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &AWSSetupHandler{reader: bufio.NewReader(strings.NewReader(tc.input))}
+
+			var (
+				profile string
+				ok      bool
+				err     error
+			)
+			_ = testutil.CaptureStdout(func() {
+				profile, ok, err = h.profileFromARN(tc.arn)
+			})
+
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("profileFromARN() error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("profileFromARN() error = %v", err)
+			}
+			if ok != tc.wantOK || profile != tc.wantProfile {
+				t.Errorf("profileFromARN() = (%q, %v), want (%q, %v)", profile, ok, tc.wantProfile, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateMFAARN(t *testing.T) {
+	tests := map[string]struct {
+		arn     string
+		wantErr bool
+	}{
+		"valid":        {arn: "arn:aws:iam::123456789012:mfa/alice"},
+		"role ARN":     {arn: "arn:aws:iam::123456789012:role/Admin", wantErr: true},
+		"not an ARN":   {arn: "alice", wantErr: true},
+		"wrong prefix": {arn: "arn:aws:sts::123456789012:mfa/alice", wantErr: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := validateMFAARN(tc.arn); (err != nil) != tc.wantErr {
+				t.Errorf("validateMFAARN(%q) error = %v, wantErr %v", tc.arn, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckMFAAccount(t *testing.T) {
+	tests := map[string]struct {
+		mfaArn      string
+		identityArn string
+		wantErrMsg  string
+	}{
+		"same account": {
+			mfaArn:      "arn:aws:iam::123456789012:mfa/alice",
+			identityArn: "arn:aws:iam::123456789012:user/alice",
+		},
+		"same account, assumed role": {
+			mfaArn:      "arn:aws:iam::123456789012:mfa/alice",
+			identityArn: "arn:aws:sts::123456789012:assumed-role/Admin/alice",
+		},
+		"different account": {
+			mfaArn:      "arn:aws:iam::210987654321:mfa/alice",
+			identityArn: "arn:aws:iam::123456789012:user/alice",
+			wantErrMsg:  "that MFA ARN belongs to account 210987654321 but you're authenticated as account 123456789012",
+		},
+		"identity without an account is not checked": {
+			mfaArn:      "arn:aws:iam::123456789012:mfa/alice",
+			identityArn: "not-an-arn",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := checkMFAAccount(tc.mfaArn, tc.identityArn)
+			if tc.wantErrMsg == "" {
+				if err != nil {
+					t.Errorf("checkMFAAccount() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantErrMsg {
+				t.Errorf("checkMFAAccount() error = %v, want %q", err, tc.wantErrMsg)
+			}
+		})
+	}
+}
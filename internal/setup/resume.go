@@ -0,0 +1,195 @@
+package setup
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/bashhack/sesh/internal/database"
+	"github.com/bashhack/sesh/internal/keychain"
+	"github.com/bashhack/sesh/internal/secure"
+	"github.com/bashhack/sesh/internal/theme"
+)
+
+// setupStateKeyService is the keychain service holding the random key that
+// encrypts the setup scratch file. It lives outside the provider prefixes so
+// it never shows up in --list.
+const setupStateKeyService = "sesh-setup-state-key"
+
+// setupStateKeyLength is the AES-256 key length used by database.Encrypt.
+const setupStateKeyLength = 32
+
+// Checkpoints recorded in the scratch state, in the order they are reached.
+const (
+	stageSecretCaptured = "secret_captured"
+	stageConsoleDone    = "console_done"
+)
+
+// setupState is the scratch state written at AWS setup checkpoints so an
+// interrupted run can continue with --setup --resume.
+type setupState struct {
+	Service string `json:"service"`
+	Profile string `json:"profile"`
+	Secret  string `json:"secret"`
+	Stage   string `json:"stage"`
+}
+
+// setupStatePath returns the scratch file location. It is a variable so we
+// can swap it out in tests.
+var setupStatePath = func() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "sesh", "setup-state.enc"), nil
+}
+
+// stateKey returns the scratch-file key from the keychain, generating and
+// storing one if create is set and none exists yet. The key is stored
+// hex-encoded for the same reason as the database key (see
+// database.KeychainSource). The caller must zero the returned slice.
+func stateKey(kc keychain.Provider, user string, create bool) ([]byte, error) {
+	stored, err := kc.GetSecret(user, setupStateKeyService)
+	if err == nil {
+		defer secure.SecureZeroBytes(stored)
+		if len(stored) != hex.EncodedLen(setupStateKeyLength) {
+			return nil, fmt.Errorf("invalid setup state key: got %d bytes, want %d hex chars", len(stored), hex.EncodedLen(setupStateKeyLength))
+		}
+		key := make([]byte, setupStateKeyLength)
+		if _, err := hex.Decode(key, stored); err != nil {
+			secure.SecureZeroBytes(key)
+			return nil, fmt.Errorf("decode setup state key: %w", err)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, keychain.ErrNotFound) || !create {
+		return nil, fmt.Errorf("get setup state key: %w", err)
+	}
+
+	key, err := database.GenerateEncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	encoded := make([]byte, hex.EncodedLen(len(key)))
+	hex.Encode(encoded, key)
+	defer secure.SecureZeroBytes(encoded)
+	if err := kc.SetSecret(user, setupStateKeyService, encoded); err != nil {
+		secure.SecureZeroBytes(key)
+		return nil, fmt.Errorf("store setup state key: %w", err)
+	}
+	return key, nil
+}
+
+// saveSetupState encrypts state and writes it to the scratch file (0600),
+// replacing any previous checkpoint.
+func saveSetupState(kc keychain.Provider, user string, state setupState) error {
+	path, err := setupStatePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create %s: %w", filepath.Dir(path), err)
+	}
+
+	key, err := stateKey(kc, user, true)
+	if err != nil {
+		return err
+	}
+	defer secure.SecureZeroBytes(key)
+
+	plaintext, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encode setup state: %w", err)
+	}
+	defer secure.SecureZeroBytes(plaintext)
+
+	ciphertext, err := database.Encrypt(key, plaintext)
+	if err != nil {
+		return fmt.Errorf("encrypt setup state: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, ciphertext, 0o600); err != nil {
+		return fmt.Errorf("write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		if rmErr := os.Remove(tmp); rmErr != nil && !os.IsNotExist(rmErr) {
+			return fmt.Errorf("replace %s: %w (cleanup of %s also failed: %v)", path, err, tmp, rmErr)
+		}
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadSetupState reads and decrypts the scratch file. It returns (nil, nil)
+// when there is nothing to resume.
+func loadSetupState(kc keychain.Provider, user string) (*setupState, error) {
+	path, err := setupStatePath()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := os.ReadFile(path) //nolint:gosec // fixed path under the user's home directory
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	key, err := stateKey(kc, user, false)
+	if err != nil {
+		return nil, err
+	}
+	defer secure.SecureZeroBytes(key)
+
+	plaintext, err := database.Decrypt(key, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt setup state: %w", err)
+	}
+	defer secure.SecureZeroBytes(plaintext)
+
+	var state setupState
+	if err := json.Unmarshal(plaintext, &state); err != nil {
+		return nil, fmt.Errorf("decode setup state: %w", err)
+	}
+	return &state, nil
+}
+
+// clearSetupState removes the scratch file and its key. A missing file is
+// not an error.
+func clearSetupState(kc keychain.Provider, user string) error {
+	path, err := setupStatePath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// The key is only created alongside the file; nothing to clear.
+			return nil
+		}
+		return fmt.Errorf("remove %s: %w", path, err)
+	}
+	if err := kc.DeleteEntry(user, setupStateKeyService); err != nil && !errors.Is(err, keychain.ErrNotFound) {
+		return fmt.Errorf("delete setup state key: %w", err)
+	}
+	return nil
+}
+
+// checkpoint saves setup progress, warning rather than failing: a setup that
+// can't be resumed is still better than no setup.
+func checkpoint(kc keychain.Provider, user string, state setupState) {
+	if err := saveSetupState(kc, user, state); err != nil {
+		fmt.Printf(theme.Warning()+" Warning: could not save setup progress (--resume won't be available): %v\n", err)
+	}
+}
+
+// discardSetupState clears scratch state, warning if that fails.
+func discardSetupState(kc keychain.Provider, user string) {
+	if err := clearSetupState(kc, user); err != nil {
+		fmt.Printf(theme.Warning()+" Warning: could not remove setup scratch state: %v\n", err)
+	}
+}
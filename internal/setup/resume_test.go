@@ -0,0 +1,235 @@
+package setup
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bashhack/sesh/internal/keychain"
+	"github.com/bashhack/sesh/internal/keychain/mocks"
+	"github.com/bashhack/sesh/internal/testutil"
+)
+
+// memKeychain returns a mock keychain backed by a map, keyed by service.
+func memKeychain() (*mocks.MockProvider, map[string]string) {
+	store := map[string]string{}
+	return &mocks.MockProvider{
+		GetSecretFunc: func(_, service string) ([]byte, error) {
+			v, ok := store[service]
+			if !ok {
+				return nil, keychain.ErrNotFound
+			}
+			return []byte(v), nil
+		},
+		SetSecretFunc: func(_, service string, secret []byte) error {
+			store[service] = string(secret)
+			return nil
+		},
+		GetSecretStringFunc: func(_, service string) (string, error) {
+			return store[service], nil
+		},
+		SetSecretStringFunc: func(_, service, secret string) error {
+			store[service] = secret
+			return nil
+		},
+		DeleteEntryFunc: func(_, service string) error {
+			delete(store, service)
+			return nil
+		},
+	}, store
+}
+
+// useTempSetupState points setupStatePath at a temp dir for the test.
+func useTempSetupState(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sesh", "setup-state.enc")
+	orig := setupStatePath
+	setupStatePath = func() (string, error) { return path, nil }
+	t.Cleanup(func() { setupStatePath = orig })
+	return path
+}
+
+func TestSetupState_RoundTrip(t *testing.T) {
+	path := useTempSetupState(t)
+	kc, store := memKeychain()
+
+	want := setupState{Service: "aws", Profile: "work", Secret: "JBSWY3DPEHPK3PXP", Stage: stageSecretCaptured}
+	if err := saveSetupState(kc, "testuser", want); err != nil {
+		t.Fatalf("saveSetupState: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("file mode = %o, want 600", perm)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), want.Secret) || strings.Contains(string(data), "work") {
+		t.Error("scratch file contains plaintext state")
+	}
+	if _, ok := store[setupStateKeyService]; !ok {
+		t.Error("state key was not stored in the keychain")
+	}
+
+	got, err := loadSetupState(kc, "testuser")
+	if err != nil {
+		t.Fatalf("loadSetupState: %v", err)
+	}
+	if got == nil || *got != want {
+		t.Errorf("loadSetupState() = %+v, want %+v", got, want)
+	}
+
+	if err := clearSetupState(kc, "testuser"); err != nil {
+		t.Fatalf("clearSetupState: %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("scratch file still present after clear: %v", err)
+	}
+	if _, ok := store[setupStateKeyService]; ok {
+		t.Error("state key still present after clear")
+	}
+}
+
+func TestLoadSetupState(t *testing.T) {
+	tests := map[string]struct {
+		prepare    func(t *testing.T, path string, kc *mocks.MockProvider)
+		wantNil    bool
+		wantErrMsg string
+	}{
+		"no scratch file": {
+			prepare: func(t *testing.T, path string, kc *mocks.MockProvider) {},
+			wantNil: true,
+		},
+		"key missing from keychain": {
+			prepare: func(t *testing.T, path string, kc *mocks.MockProvider) {
+				if err := saveSetupState(kc, "testuser", setupState{Service: "aws"}); err != nil {
+					t.Fatal(err)
+				}
+				if err := kc.DeleteEntry("testuser", setupStateKeyService); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErrMsg: "get setup state key",
+		},
+		"corrupted scratch file": {
+			prepare: func(t *testing.T, path string, kc *mocks.MockProvider) {
+				if err := saveSetupState(kc, "testuser", setupState{Service: "aws"}); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte("garbage that is long enough to hold a nonce"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErrMsg: "decrypt setup state",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := useTempSetupState(t)
+			kc, _ := memKeychain()
+			tc.prepare(t, path, kc)
+
+			got, err := loadSetupState(kc, "testuser")
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantNil && got != nil {
+				t.Errorf("loadSetupState() = %+v, want nil", got)
+			}
+		})
+	}
+}
+
+func TestAWSSetupHandler_Setup_Resume(t *testing.T) {
+	origLookPath := execLookPath
+	defer func() { execLookPath = origLookPath }()
+	origRunCommand := runCommand
+	defer func() { runCommand = origRunCommand }()
+	origGetUser := getCurrentUser
+	defer func() { getCurrentUser = origGetUser }()
+
+	const mfaArn = "arn:aws:iam::123456789012:mfa/work"
+	execLookPath = func(string) (string, error) { return "/usr/local/bin/aws", nil }
+	getCurrentUser = func() (string, error) { return "testuser", nil }
+	runCommand = func(name string, args ...string) ([]byte, error) {
+		switch args[0] {
+		case "sts":
+			return []byte("arn:aws:iam::123456789012:user/test\n"), nil
+		case "iam":
+			return []byte(mfaArn + "\n"), nil
+		}
+		return nil, errors.New("unexpected command")
+	}
+
+	tests := map[string]struct {
+		input       string
+		wantSecret  string
+		wantErr     bool
+		wantCleared bool
+	}{
+		"accept resume skips to device selection": {
+			// Y to resume, then pick device 1.
+			input:       "y\n1\n",
+			wantSecret:  "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			wantCleared: true,
+		},
+		"decline resume clears state and starts fresh": {
+			// n to resume, then the fresh run hits EOF at the profile prompt.
+			input:       "n\n",
+			wantErr:     true,
+			wantCleared: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := useTempSetupState(t)
+			kc, store := memKeychain()
+			state := setupState{Service: "aws", Profile: "work", Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Stage: stageConsoleDone}
+			if err := saveSetupState(kc, "testuser", state); err != nil {
+				t.Fatal(err)
+			}
+
+			handler := &AWSSetupHandler{
+				reader:           bufio.NewReader(strings.NewReader(tc.input)),
+				keychainProvider: kc,
+			}
+			handler.Configure(Options{Resume: true})
+
+			var err error
+			_ = testutil.CaptureStdout(func() {
+				err = handler.Setup()
+			})
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Setup() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantSecret != "" {
+				if got := store["sesh-aws/work"]; got != tc.wantSecret {
+					t.Errorf("stored secret = %q, want %q", got, tc.wantSecret)
+				}
+				if got := store["sesh-aws-serial/work"]; got != mfaArn {
+					t.Errorf("stored serial = %q, want %q", got, mfaArn)
+				}
+			}
+			if _, statErr := os.Stat(path); tc.wantCleared && !errors.Is(statErr, os.ErrNotExist) {
+				t.Errorf("scratch file should be cleared, stat err = %v", statErr)
+			}
+		})
+	}
+}
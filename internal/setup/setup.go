@@ -3,22 +3,30 @@ package setup
 
 import (
 	"bufio"
+	"cmp"
+	"encoding/base32"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
+	"unicode"
 
 	"golang.org/x/term"
 
+	"github.com/bashhack/sesh/internal/clipboard"
 	"github.com/bashhack/sesh/internal/constants"
 	"github.com/bashhack/sesh/internal/env"
 	"github.com/bashhack/sesh/internal/keychain"
 	"github.com/bashhack/sesh/internal/keyformat"
+	"github.com/bashhack/sesh/internal/prompt"
 	"github.com/bashhack/sesh/internal/qrcode"
 	"github.com/bashhack/sesh/internal/secure"
+	"github.com/bashhack/sesh/internal/theme"
 	"github.com/bashhack/sesh/internal/totp"
 )
 
@@ -31,6 +39,11 @@ var runCommand = func(name string, args ...string) ([]byte, error) {
 // readPassword is a variable so we can swap it out in tests
 var readPassword = term.ReadPassword
 
+// stdoutIsTerminal is a variable so we can swap it out in tests
+var stdoutIsTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
 // scanQRCodeFull returns full TOTP info (including algorithm, digits, period)
 var scanQRCodeFull = qrcode.ScanQRCodeFull
 
@@ -40,15 +53,133 @@ var timeSleep = time.Sleep
 // validateAndNormalizeSecret is a variable so we can swap it out in tests
 var validateAndNormalizeSecret = totp.ValidateAndNormalizeSecret
 
+// validateSecretStrict is a variable so we can swap it out in tests
+var validateSecretStrict = totp.ValidateSecretStrict
+
 // generateConsecutiveCodes is a variable so we can swap it out in tests
 var generateConsecutiveCodes = totp.GenerateConsecutiveCodes
 
+// clipboardCopy is a variable so we can swap it out in tests
+var clipboardCopy = clipboard.CopyWithAutoClear
+
+// defaultClipTimeout matches the CLI's --clip-timeout default.
+const defaultClipTimeout = 30 * time.Second
+
+// DefaultQRRetries is how many QR screen captures setup attempts when
+// Options.QRRetries is zero, and the CLI's --qr-retries default.
+const DefaultQRRetries = 2
+
+// SkipQRCapture is the Options.QRRetries value for --qr-retries 0: no
+// screen capture at all, straight to manual entry.
+const SkipQRCapture = -1
+
+// qrAttempts returns how many QR screen captures to attempt.
+func (o Options) qrAttempts() int {
+	switch {
+	case o.QRRetries < 0:
+		return 0
+	case o.QRRetries == 0:
+		return DefaultQRRetries
+	}
+	return o.QRRetries
+}
+
+// copySetupCodes copies setup verification codes to the clipboard when
+// --copy-first-code or --clip-two was given. A failed copy only warns,
+// since the codes are also printed.
+func copySetupCodes(opts Options, firstCode, secondCode string) {
+	if !opts.CopyFirstCode && !opts.ClipTwo {
+		return
+	}
+	timeout := opts.ClipTimeout
+	if timeout <= 0 {
+		timeout = defaultClipTimeout
+	}
+	text, what := firstCode, "First code"
+	if opts.ClipTwo {
+		text, what = firstCode+" "+secondCode, "Both codes"
+	}
+	if err := clipboardCopy(text, timeout); err != nil {
+		fmt.Printf(theme.Warning()+" Could not copy the codes to the clipboard: %v\n", err)
+		return
+	}
+	fmt.Printf("📋 %s copied to clipboard (clears in %s)\n", what, timeout)
+}
+
+// showURI prints the otpauth URI for --show-uri. The URI holds the secret
+// in the clear, so without --force it is only written to a terminal, never
+// into a pipe or log file.
+func showURI(opts Options, uri string) {
+	if !opts.ShowURI {
+		return
+	}
+	if !opts.Force && !stdoutIsTerminal() {
+		fmt.Println(theme.Warning() + " --show-uri: not printing the secret to a non-terminal; add --force to print it anyway")
+		return
+	}
+	fmt.Println("🔗 otpauth URI for your backup (it contains the secret; store it somewhere safe):")
+	fmt.Printf("   %s\n", uri)
+}
+
+// printEnvExample prints, for --print-env-example, how to use a freshly set
+// up AWS profile outside a sesh subshell: write a session into
+// ~/.aws/credentials, then select the section it lands in. The commands
+// carry the profile and any --keychain-user so they work as pasted.
+func printEnvExample(opts Options, profile string) {
+	if !opts.PrintEnvExample {
+		return
+	}
+	cmd := "sesh --service aws"
+	if profile != "" {
+		cmd += " --profile " + profile
+	}
+	if opts.KeychainUser != "" {
+		cmd += " --keychain-user " + opts.KeychainUser
+	}
+	section := cmp.Or(profile, "default") + "-sesh"
+
+	fmt.Printf(`
+📋 To use this profile from the AWS CLI and SDKs, write a session into
+~/.aws/credentials (re-run it when the session expires):
+
+  %s --format ini --output-file ~/.aws/credentials
+
+Then select the [%s] section it writes:
+
+  export AWS_PROFILE=%s
+`, cmd, section, section)
+}
+
 // getCurrentUser is a variable so we can swap it out in tests
 var getCurrentUser = env.GetCurrentUser
 
+// keychainAccount is the account setup stores entries under: the
+// --keychain-user override if given, otherwise the current OS user.
+func keychainAccount(opts Options) (string, error) {
+	if opts.KeychainUser != "" {
+		return opts.KeychainUser, nil
+	}
+	return getCurrentUser()
+}
+
 // execLookPath is a variable so we can swap it out in tests
 var execLookPath = exec.LookPath
 
+// matchTOTPWindow is a variable so we can swap it out in tests
+var matchTOTPWindow = totp.MatchWindow
+
+// timeNow is a variable so we can swap it out in tests
+var timeNow = time.Now
+
+// setupCodes returns the two consecutive codes shown during setup, shifted
+// by --time-offset so they are the codes the service expects.
+func setupCodes(secret string, opts Options) (current, next string, err error) {
+	if opts.TimeOffset == 0 {
+		return generateConsecutiveCodes(secret)
+	}
+	return totp.GenerateConsecutiveCodesForTime(secret, totp.Params{TimeOffset: opts.TimeOffset}.Shift(timeNow()))
+}
+
 // readLine reads a line of input, returning the trimmed string or an error.
 func readLine(r *bufio.Reader) (string, error) {
 	line, err := r.ReadString('\n')
@@ -58,6 +189,49 @@ func readLine(r *bufio.Reader) (string, error) {
 	return strings.TrimSpace(line), nil
 }
 
+// errSecretNotTTY is returned when a secret can't be read without echo
+// because stdin isn't a terminal, and nothing was piped in either.
+var errSecretNotTTY = errors.New("stdin is not a terminal and has no more input; pipe the secret in or run interactively (TOTP setup also accepts --secret-env)")
+
+// readSecret reads a secret without echoing it. When stdin isn't a terminal
+// (ReadPassword fails with ENOTTY), it falls back to reading a line from r,
+// so secrets can be piped in.
+func readSecret(r *bufio.Reader) ([]byte, error) {
+	secret, err := readPassword(syscall.Stdin)
+	if err == nil || !errors.Is(err, syscall.ENOTTY) {
+		return secret, err
+	}
+	if r == nil {
+		return nil, errSecretNotTTY
+	}
+
+	line, readErr := r.ReadString('\n')
+	if readErr != nil && line == "" {
+		return nil, errSecretNotTTY
+	}
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
+
+// pastedSecret trims a pasted secret and strips any whitespace inside it,
+// which shows up when a secret is copied wrapped across lines or in the
+// space-separated groups some issuers display. Authenticator secrets are
+// base32 and never contain whitespace, so none of it is meaningful.
+func pastedSecret(secret []byte) string {
+	trimmed := strings.TrimSpace(string(secret))
+	removed := 0
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			removed++
+			return -1
+		}
+		return r
+	}, trimmed)
+	if removed > 0 {
+		fmt.Printf(theme.Warning()+" Removed %d whitespace characters from pasted secret\n", removed)
+	}
+	return cleaned
+}
+
 // waitForEnter blocks until the user presses Enter.
 func waitForEnter(r *bufio.Reader) error {
 	_, err := r.ReadString('\n')
@@ -67,12 +241,97 @@ func waitForEnter(r *bufio.Reader) error {
 	return nil
 }
 
+// waitForConsole waits for Enter after an AWS console step, giving up
+// after --prompt-timeout so an unattended setup doesn't hang.
+func (h *AWSSetupHandler) waitForConsole() error {
+	_, err := prompt.WithTimeout(h.opts.PromptTimeout, func() (struct{}, error) {
+		return struct{}{}, waitForEnter(h.reader)
+	})
+	return err
+}
+
+// minRecommendedSecretBytes is the RFC 4226 recommended minimum shared
+// secret length (160 bits).
+const minRecommendedSecretBytes = 20
+
+// validateCapturedSecret validates and normalizes a freshly captured TOTP
+// secret. Secrets shorter than the recommended 160 bits only produce a
+// warning, since the issuer - not the user - controls the seed. With strict
+// set, a secret that would need normalizing is rejected instead.
+func validateCapturedSecret(secret string, strict bool) (string, error) {
+	validate := validateAndNormalizeSecret
+	if strict {
+		validate = validateSecretStrict
+	}
+	normalized, err := validate(secret)
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+
+	if n := decodedSecretLen(normalized); n > 0 && n < minRecommendedSecretBytes {
+		fmt.Printf(theme.Warning()+" Warning: this secret is only %d bits; the issuer may have given a weak seed (recommended minimum is %d bits)\n",
+			n*8, minRecommendedSecretBytes*8)
+	}
+
+	return normalized, nil
+}
+
+// secretFromEnv reads a TOTP secret from the named environment variable,
+// for CI and secret-injection setups. The value is never echoed.
+func secretFromEnv(name string) (string, error) {
+	secret := strings.TrimSpace(os.Getenv(name))
+	if secret == "" {
+		return "", fmt.Errorf("environment variable %s is empty or unset", name)
+	}
+	fmt.Printf(theme.OK()+" Read TOTP secret from $%s\n", name)
+	return secret, nil
+}
+
+// decodedSecretLen returns the decoded byte length of a normalized base32
+// secret, or 0 if it cannot be decoded.
+func decodedSecretLen(secret string) int {
+	decoded, err := base32.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return 0
+	}
+	n := len(decoded)
+	secure.SecureZeroBytes(decoded)
+	return n
+}
+
+// storeSecret writes secret under service. With NoMetadata it skips the
+// listing index when the backend supports that; otherwise it is a plain
+// SetSecretString.
+func storeSecret(kc keychain.Provider, opts Options, user, service, secret string) error {
+	if opts.NoMetadata {
+		if us, ok := kc.(keychain.UnindexedStore); ok {
+			secretBytes := []byte(secret)
+			defer secure.SecureZeroBytes(secretBytes)
+			return us.SetSecretUnindexed(user, service, secretBytes)
+		}
+	}
+	return kc.SetSecretString(user, service, secret)
+}
+
+// printNoMetadataNote tells the user why a --no-metadata entry is missing
+// from -list.
+func printNoMetadataNote() {
+	fmt.Println(theme.Info() + " Skipped metadata (--no-metadata): this entry won't appear in --list until it is re-indexed by running setup again without --no-metadata.")
+}
+
 // AWS Setup Handler
 
 // AWSSetupHandler implements SetupHandler for AWS
 type AWSSetupHandler struct {
 	keychainProvider keychain.Provider
 	reader           *bufio.Reader
+	opts             Options
+
+	// KeyPrefix and SerialPrefix are the keychain namespaces to store the
+	// TOTP secret and MFA serial under; empty means the defaults from
+	// constants. They match the AWS provider's own.
+	KeyPrefix    string
+	SerialPrefix string
 }
 
 // NewAWSSetupHandler creates a new AWS setup handler
@@ -88,6 +347,11 @@ func (h *AWSSetupHandler) ServiceName() string {
 	return "aws"
 }
 
+// Configure implements Configurable
+func (h *AWSSetupHandler) Configure(opts Options) {
+	h.opts = opts
+}
+
 // Helper to create service names with proper profile handling
 func (h *AWSSetupHandler) createServiceName(prefix, profile string) (string, error) {
 	if profile == "" {
@@ -120,7 +384,7 @@ func (h *AWSSetupHandler) verifyAWSCredentials(profile string) (string, error) {
 
 	userArn := strings.TrimSpace(string(output))
 
-	fmt.Printf("✅ Found AWS identity: %s\n", userArn)
+	fmt.Printf(theme.OK()+" Found AWS identity: %s\n", userArn)
 
 	return userArn, nil
 }
@@ -140,14 +404,14 @@ func (h *AWSSetupHandler) captureMFASecret(choice string) (string, error) {
 ❗ DO NOT COMPLETE THE AWS SETUP YET - we'll do that together`)
 
 		fmt.Print("\n📋 Paste the secret key below and press Enter:\n→ ")
-		secret, err := readPassword(syscall.Stdin)
+		secret, err := readSecret(h.reader)
 		if err != nil {
 			return "", fmt.Errorf("failed to read secret: %w", err)
 		}
 		fmt.Println("✓") // Visual confirmation that input was received
 
 		defer secure.SecureZeroBytes(secret)
-		secretStr = strings.TrimSpace(string(secret))
+		secretStr = pastedSecret(secret)
 
 	case "2": // QR code capture flow with retry
 		fmt.Println(`
@@ -175,7 +439,7 @@ func (h *AWSSetupHandler) captureMFASecret(choice string) (string, error) {
 
 // captureAWSQRCodeWithFallback attempts AWS QR capture with retry and manual fallback
 func (h *AWSSetupHandler) captureAWSQRCodeWithFallback() (string, error) {
-	return captureQRWithRetry(h.reader, h.captureAWSManualEntry)
+	return captureQRWithRetry(h.reader, h.opts.qrAttempts(), h.captureAWSManualEntry)
 }
 
 // captureAWSManualEntry handles manual AWS MFA secret entry
@@ -187,14 +451,14 @@ func (h *AWSSetupHandler) captureAWSManualEntry() (string, error) {
 ❗ DO NOT COMPLETE THE AWS SETUP YET - we'll do that together`)
 
 	fmt.Print("\n📋 Paste the secret key below and press Enter:\n→ ")
-	secret, err := readPassword(syscall.Stdin)
+	secret, err := readSecret(h.reader)
 	if err != nil {
 		return "", fmt.Errorf("failed to read secret: %w", err)
 	}
 	fmt.Println("✓") // Visual confirmation that input was received
 
 	defer secure.SecureZeroBytes(secret)
-	return strings.TrimSpace(string(secret)), nil
+	return pastedSecret(secret), nil
 }
 
 // setupMFAConsole generates TOTP codes and guides the user through AWS console setup
@@ -202,12 +466,13 @@ func (h *AWSSetupHandler) captureAWSManualEntry() (string, error) {
 // Returns any error that occurred during code generation
 func (h *AWSSetupHandler) setupMFAConsole(secretStr string) error {
 	// At the time of writing, AWS requires two codes during setup
-	firstCode, secondCode, err := totp.GenerateConsecutiveCodes(secretStr)
+	firstCode, secondCode, err := setupCodes(secretStr, h.opts)
 	if err != nil {
 		return fmt.Errorf("failed to generate TOTP codes: %w", err)
 	}
+	copySetupCodes(h.opts, firstCode, secondCode)
 
-	fmt.Printf(`✅ Generated TOTP codes for AWS setup
+	fmt.Printf(theme.OK()+` Generated TOTP codes for AWS setup
 First code: %s
 Second code: %s
 
@@ -215,9 +480,14 @@ IMPORTANT - FOLLOW THESE STEPS:
 1. Enter these codes in the AWS Console
 2. Click the "Add MFA" button to complete setup
 3. Wait for confirmation in the AWS console that setup is complete
-
-Press Enter ONLY AFTER you see "MFA device was successfully assigned" in AWS console...`, firstCode, secondCode)
-	if err := waitForEnter(h.reader); err != nil {
+`, firstCode, secondCode)
+	if h.opts.NoConsoleWait {
+		fmt.Println("\n⏩ --no-console-wait: not waiting for console confirmation")
+		return nil
+	}
+	fmt.Print(`
+Press Enter ONLY AFTER you see "MFA device was successfully assigned" in AWS console...`)
+	if err := h.waitForConsole(); err != nil {
 		return err
 	}
 
@@ -229,6 +499,9 @@ Press Enter ONLY AFTER you see "MFA device was successfully assigned" in AWS con
 // If no devices are found, it provides retry and manual entry options
 // Returns the MFA device ARN and any error that occurred
 func (h *AWSSetupHandler) selectMFADevice(profile string) (string, error) {
+	if h.opts.NoConsoleWait {
+		return h.selectListedMFADevice(profile)
+	}
 
 	mfaOutput, err := h.runAWSCommand(profile, "iam", "list-mfa-devices", "--query", "MFADevices[].SerialNumber", "--output", "text")
 	var mfaArn string
@@ -239,9 +512,9 @@ func (h *AWSSetupHandler) selectMFADevice(profile string) (string, error) {
 
 mfaDeviceLoop:
 	for {
-		if err == nil && strings.TrimSpace(string(mfaOutput)) != "" {
+		mfaDevices := parseMFADevices(mfaOutput)
+		if err == nil && len(mfaDevices) > 0 {
 			// MFA devices were found, process them
-			mfaDevices := strings.Split(strings.TrimSpace(string(mfaOutput)), "\t")
 
 			// Always show the list of devices and let the user choose, even if there's only one.
 			// This handles cases where they already had an MFA device and the new one isn't
@@ -253,7 +526,7 @@ mfaDeviceLoop:
 
 		selectionPrompt:
 			fmt.Print("\nChoose the MFA device you just created (1-" + fmt.Sprintf("%d", len(mfaDevices)) +
-				"), 'r' to refresh the list, or 'm' to enter manually: ")
+				"), part of its name to search, 'r' to refresh the list, or 'm' to enter manually: ")
 			choice, err := readLine(h.reader)
 			if err != nil {
 				return "", err
@@ -264,14 +537,14 @@ mfaDeviceLoop:
 				// Refresh MFA devices list
 				fmt.Println("\n🔄 Refreshing MFA device list...")
 				mfaOutput, err = h.runAWSCommand(profile, "iam", "list-mfa-devices", "--query", "MFADevices[].SerialNumber", "--output", "text")
-				if err != nil || strings.TrimSpace(string(mfaOutput)) == "" {
+				mfaDevices = parseMFADevices(mfaOutput)
+				if err != nil || len(mfaDevices) == 0 {
 					fmt.Println("❗ No MFA devices found after refresh.")
 					// Continue to the retry options below
 					break
 				}
 
 				// Show updated list of devices and go back to selection prompt
-				mfaDevices = strings.Split(strings.TrimSpace(string(mfaOutput)), "\t")
 				fmt.Println("\nFound MFA device(s) after refresh:")
 				for i, device := range mfaDevices {
 					fmt.Printf("%d: %s\n", i+1, device)
@@ -288,16 +561,33 @@ mfaDeviceLoop:
 				break mfaDeviceLoop // Exit the entire loop when we've manually entered ARN
 
 			default:
-				// Try to parse as number
-				var index int
-				_, err := fmt.Sscanf(choice, "%d", &index)
+				// Anything that isn't a number narrows the list by name
+				index, err := strconv.Atoi(choice)
+				if err != nil && choice != "" {
+					matches := filterMFADevices(mfaDevices, choice)
+					switch len(matches) {
+					case 0:
+						fmt.Printf("\n"+theme.Error()+" No MFA device matches %q.\n", choice)
+					case 1:
+						mfaArn = matches[0]
+						fmt.Printf(theme.OK()+" Selected MFA device: %s\n", mfaArn)
+						break mfaDeviceLoop
+					default:
+						mfaDevices = matches
+						fmt.Printf("\nMFA device(s) matching %q:\n", choice)
+						for i, device := range mfaDevices {
+							fmt.Printf("%d: %s\n", i+1, device)
+						}
+					}
+					goto selectionPrompt
+				}
 				if err != nil || index < 1 || index > len(mfaDevices) {
-					fmt.Println("\n❌ Invalid choice. Please select a number from the list, 'r' to refresh, or 'm' for manual entry.")
+					fmt.Println("\n" + theme.Error() + " Invalid choice. Please select a number from the list, part of a device name, 'r' to refresh, or 'm' for manual entry.")
 					goto selectionPrompt
 				}
 
 				mfaArn = mfaDevices[index-1]
-				fmt.Printf("✅ Selected MFA device: %s\n", mfaArn)
+				fmt.Printf(theme.OK()+" Selected MFA device: %s\n", mfaArn)
 				// MFA device successfully selected
 				break mfaDeviceLoop // Exit the entire for loop with our selected device
 			}
@@ -349,7 +639,7 @@ Please complete these steps in the AWS Console:
 1. Make sure you've clicked "Add MFA" after entering the TOTP codes
 2. Confirm you see "MFA device was successfully assigned" message
 3. Press Enter when complete...`)
-			if waitErr := waitForEnter(h.reader); waitErr != nil {
+			if waitErr := h.waitForConsole(); waitErr != nil {
 				return "", waitErr
 			}
 
@@ -365,7 +655,7 @@ Please complete these steps in the AWS Console:
 			break mfaDeviceLoop // Exit the loop completely
 
 		default: // Invalid input
-			fmt.Println("\n❌ Invalid choice. Please select 1, 2, or 3.")
+			fmt.Println("\n" + theme.Error() + " Invalid choice. Please select 1, 2, or 3.")
 			// Stay in the loop and show the options again
 		}
 	}
@@ -373,6 +663,64 @@ Please complete these steps in the AWS Console:
 	return mfaArn, nil
 }
 
+// parseMFADevices splits `list-mfa-devices --output text` output into
+// device ARNs. The CLI separates them with tabs, but pagination and output
+// settings can add newlines, spaces or a trailing newline; ARNs never
+// contain whitespace, so any run of it separates two devices.
+func parseMFADevices(out []byte) []string {
+	return strings.Fields(string(out))
+}
+
+// filterMFADevices returns the devices whose ARN contains term, ignoring
+// case, so typing "laptop" finds ".../mfa/laptop".
+func filterMFADevices(devices []string, term string) []string {
+	term = strings.ToLower(term)
+	var matches []string
+	for _, device := range devices {
+		if strings.Contains(strings.ToLower(device), term) {
+			matches = append(matches, device)
+		}
+	}
+	return matches
+}
+
+// selectListedMFADevice is selectMFADevice for --no-console-wait: it lists
+// the devices once and fails when there are none, rather than offering to
+// wait, refresh or enter the ARN by hand. A single device is used as is.
+func (h *AWSSetupHandler) selectListedMFADevice(profile string) (string, error) {
+	mfaOutput, err := h.runAWSCommand(profile, "iam", "list-mfa-devices", "--query", "MFADevices[].SerialNumber", "--output", "text")
+	if err != nil {
+		return "", fmt.Errorf("failed to list MFA devices: %w", err)
+	}
+	mfaDevices := parseMFADevices(mfaOutput)
+	if len(mfaDevices) == 0 {
+		return "", fmt.Errorf("no MFA devices found; finish assigning the device in the AWS console, or run setup without --no-console-wait")
+	}
+	if len(mfaDevices) == 1 {
+		fmt.Printf(theme.OK()+" Using MFA device: %s\n", mfaDevices[0])
+		return mfaDevices[0], nil
+	}
+
+	fmt.Println("\nFound MFA device(s):")
+	for i, device := range mfaDevices {
+		fmt.Printf("%d: %s\n", i+1, device)
+	}
+	for {
+		fmt.Printf("\nChoose the MFA device (1-%d): ", len(mfaDevices))
+		choice, err := readLine(h.reader)
+		if err != nil {
+			return "", err
+		}
+		var index int
+		if _, err := fmt.Sscanf(choice, "%d", &index); err != nil || index < 1 || index > len(mfaDevices) {
+			fmt.Println("\n" + theme.Error() + " Invalid choice. Please select a number from the list.")
+			continue
+		}
+		fmt.Printf(theme.OK()+" Selected MFA device: %s\n", mfaDevices[index-1])
+		return mfaDevices[index-1], nil
+	}
+}
+
 // promptForMFAARN prompts the user to enter an MFA ARN manually
 // It validates the ARN format and ensures it's not empty
 // Returns the validated MFA ARN string and any error that occurred
@@ -391,8 +739,7 @@ func (h *AWSSetupHandler) promptForMFAARN() (string, error) {
 			continue
 		}
 
-		// Basic validation could be added here
-		if !strings.HasPrefix(mfaArn, "arn:aws:iam::") || !strings.Contains(mfaArn, ":mfa/") {
+		if validateMFAARN(mfaArn) != nil {
 			fmt.Println("\u274c Invalid ARN format. Please enter a valid MFA ARN.")
 			continue
 		}
@@ -430,10 +777,58 @@ Enter your choice (1-2): `)
 	return choice, nil
 }
 
+// captureExistingDeviceSecret captures the secret of a virtual MFA device
+// that is already assigned in AWS (--existing-device), without the console
+// instructions for creating one.
+func (h *AWSSetupHandler) captureExistingDeviceSecret() (string, error) {
+	fmt.Print(`
+📱 Using an existing virtual MFA device
+
+How would you like to provide its secret?
+1: Enter the secret key manually
+2: Capture QR code from screen
+Enter your choice (1-2): `)
+
+	choice, err := readLine(h.reader)
+	if err != nil {
+		return "", err
+	}
+
+	var secretStr string
+	switch choice {
+	case "1":
+		secretStr, err = h.readExistingDeviceSecret()
+	case "2":
+		secretStr, err = captureQRWithRetry(h.reader, h.opts.qrAttempts(), h.readExistingDeviceSecret)
+	default:
+		return "", fmt.Errorf("invalid choice, please select 1 or 2")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if len(secretStr) < 16 {
+		return "", fmt.Errorf("secret key seems too short (got %d chars). Please double-check and try again", len(secretStr))
+	}
+	return secretStr, nil
+}
+
+// readExistingDeviceSecret reads a pasted secret key without echoing it.
+func (h *AWSSetupHandler) readExistingDeviceSecret() (string, error) {
+	fmt.Print("\n📋 Paste the secret key below and press Enter:\n→ ")
+	secret, err := readSecret(h.reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	fmt.Println("✓") // Visual confirmation that input was received
+
+	defer secure.SecureZeroBytes(secret)
+	return pastedSecret(secret), nil
+}
+
 // showSetupCompletionMessage displays the final success message with usage instructions
 func (h *AWSSetupHandler) showSetupCompletionMessage(profile string) {
-	fmt.Println(`
-✅ Setup complete! You can now use 'sesh' to generate AWS temporary credentials.
+	fmt.Println("\n" + theme.OK() + ` Setup complete! You can now use 'sesh' to generate AWS temporary credentials.
 
 🚀 Next steps:
 1. Run 'sesh -service aws' to generate a temporary session token
@@ -457,6 +852,7 @@ To use this setup, run without the --profile flag
 //  4. Guides the user through setting up a virtual MFA device in AWS Console
 //  5. Captures the MFA secret (either manually or via QR code)
 //  6. Generates TOTP codes and helps with AWS Console MFA setup
+//     (steps 4 and 6 are skipped with --existing-device)
 //  7. Helps identify and select the newly created MFA device, with retry and refresh options
 //  8. Stores the MFA secret and serial number securely in system keychain
 //  9. Provides instructions for using the setup with the sesh command
@@ -468,133 +864,271 @@ To use this setup, run without the --profile flag
 // Returns an error if any step in the setup process fails. If successful,
 // the user will be able to generate temporary AWS credentials with MFA protection
 // using the 'sesh' command.
-func (h *AWSSetupHandler) Setup() error {
+func (h *AWSSetupHandler) Setup() (err error) {
 	fmt.Println("🔐 Setting up AWS credentials...")
 
-	_, err := execLookPath("aws")
-	if err != nil {
+	if _, err = execLookPath("aws"); err != nil {
 		return fmt.Errorf("AWS CLI not found. Please install it first: https://aws.amazon.com/cli/")
 	}
 
-	fmt.Println("✅ AWS CLI is installed")
+	fmt.Println(theme.OK() + " AWS CLI is installed")
 
-	fmt.Print("Enter AWS CLI profile name (leave empty for default): ")
-	profile, err := readLine(h.reader)
-	if err != nil {
-		return err
+	if h.opts.ProfileFromARN != "" {
+		if err = validateMFAARN(h.opts.ProfileFromARN); err != nil {
+			return err
+		}
 	}
 
-	// Check if entry already exists
-	user, err := getCurrentUser()
+	user, err := keychainAccount(h.opts)
 	if err != nil {
 		return fmt.Errorf("failed to get current user: %w", err)
 	}
 
-	serviceName, err := h.createServiceName(constants.AWSServicePrefix, profile)
-	if err != nil {
-		return fmt.Errorf("failed to build service key: %w", err)
-	}
-	existingSecret, err := h.keychainProvider.GetSecretString(user, serviceName)
-	if err != nil && !errors.Is(err, keychain.ErrNotFound) {
-		return fmt.Errorf("failed to check existing entry: %w", err)
-	}
-
-	if existingSecret != "" {
-		// Entry exists, prompt for overwrite
-		profileDisplay := profile
-		if profileDisplay == "" {
-			profileDisplay = "default"
+	var resumed *setupState
+	if h.opts.Resume {
+		resumed, err = h.offerResume(user)
+		if err != nil {
+			return err
 		}
+	}
 
-		fmt.Printf("\n⚠️  An entry already exists for AWS profile '%s'\n", profileDisplay)
-		fmt.Print("\nOverwrite existing configuration? (y/N): ")
-
-		response, readErr := readLine(h.reader)
-		if readErr != nil {
-			return readErr
+	// Once a checkpoint has been written, tell the user how to pick up
+	// where this run stopped if a later step fails.
+	saved := false
+	defer func() {
+		if err != nil && saved {
+			fmt.Println("\n💾 Setup progress saved. Run 'sesh --service aws --setup --resume' to continue.")
 		}
-		response = strings.ToLower(response)
+	}()
 
-		if response != "y" && response != "yes" {
-			fmt.Println("\n❌ Setup cancelled")
-			return fmt.Errorf("setup cancelled by user")
+	var profile, secretStr string
+	if resumed != nil {
+		profile = resumed.Profile
+		secretStr = resumed.Secret
+		saved = true
+	} else {
+		profile, err = h.promptForProfileAndConfirm(user)
+		if err != nil {
+			return err
 		}
-		fmt.Println() // Add spacing before continuing
 	}
 
-	_, err = h.verifyAWSCredentials(profile)
+	identityArn, err := h.verifyAWSCredentials(profile)
 	if err != nil {
 		return err
 	}
 
-	choice, err := h.promptForMFASetupMethod()
-	if err != nil {
-		return err
-	}
+	if resumed == nil {
+		var captured string
+		if h.opts.ExistingDevice {
+			captured, err = h.captureExistingDeviceSecret()
+		} else {
+			choice, promptErr := h.promptForMFASetupMethod()
+			if promptErr != nil {
+				return promptErr
+			}
+			captured, err = h.captureMFASecret(choice)
+		}
+		if err != nil {
+			return err
+		}
 
-	secretStr, err := h.captureMFASecret(choice)
-	if err != nil {
-		return err
-	}
+		secretStr, err = validateCapturedSecret(captured, h.opts.StrictSecret)
+		if err != nil {
+			return err
+		}
 
-	// Validate and normalize the TOTP secret
-	normalizedSecret, err := validateAndNormalizeSecret(secretStr)
-	if err != nil {
-		return fmt.Errorf("invalid TOTP secret: %w", err)
+		// An existing device is already assigned, so there is no console
+		// step left to resume into.
+		stage := stageSecretCaptured
+		if h.opts.ExistingDevice {
+			stage = stageConsoleDone
+		}
+		checkpoint(h.keychainProvider, user, setupState{Service: "aws", Profile: profile, Secret: secretStr, Stage: stage})
+		saved = true
 	}
-	secretStr = normalizedSecret
 
-	err = h.setupMFAConsole(secretStr)
-	if err != nil {
-		return err
+	if !h.opts.ExistingDevice && (resumed == nil || resumed.Stage == stageSecretCaptured) {
+		err = h.setupMFAConsole(secretStr)
+		if err != nil {
+			return err
+		}
+		checkpoint(h.keychainProvider, user, setupState{Service: "aws", Profile: profile, Secret: secretStr, Stage: stageConsoleDone})
 	}
 
 	mfaArn, err := h.selectMFADevice(profile)
 	if err != nil {
 		return fmt.Errorf("failed to select MFA device: %w", err)
 	}
+	if err = checkMFAAccount(mfaArn, identityArn); err != nil {
+		return err
+	}
 
 	// Write MFA ARN first — if the main secret write fails afterward,
 	// we avoid leaving an "existing" setup that blocks future runs.
-	serialServiceName, err := h.createServiceName(constants.AWSServiceMFAPrefix, profile)
+	serialServiceName, err := h.createServiceName(cmp.Or(h.SerialPrefix, constants.AWSServiceMFAPrefix), profile)
 	if err != nil {
 		return fmt.Errorf("failed to build MFA serial key: %w", err)
 	}
-	err = h.keychainProvider.SetSecretString(user, serialServiceName, mfaArn)
+	err = storeSecret(h.keychainProvider, h.opts, user, serialServiceName, mfaArn)
 	if err != nil {
 		return fmt.Errorf("failed to store MFA serial in keychain: %w", err)
 	}
 
-	serviceName, err = h.createServiceName(constants.AWSServicePrefix, profile)
+	serviceName, err := h.createServiceName(cmp.Or(h.KeyPrefix, constants.AWSServicePrefix), profile)
 	if err != nil {
 		return fmt.Errorf("failed to build service key: %w", err)
 	}
-	err = h.keychainProvider.SetSecretString(user, serviceName, secretStr)
+	err = storeSecret(h.keychainProvider, h.opts, user, serviceName, secretStr)
 	if err != nil {
 		return fmt.Errorf("failed to store secret in keychain: %w", err)
 	}
 
-	description := "AWS MFA"
-	if profile != "" {
-		description = fmt.Sprintf("AWS MFA for profile %s", profile)
+	if h.opts.NoMetadata {
+		printNoMetadataNote()
+	} else {
+		description := "AWS MFA"
+		if profile != "" {
+			description = fmt.Sprintf("AWS MFA for profile %s", profile)
+		}
+		if h.opts.TimeOffset != 0 {
+			// The offset is load-bearing, read back by the provider on
+			// every run, so it replaces the cosmetic label.
+			description = totp.Params{TimeOffset: h.opts.TimeOffset}.MarshalDescription()
+		}
+
+		err = h.keychainProvider.SetDescription(serviceName, user, description)
+		if err != nil {
+			if h.opts.TimeOffset != 0 {
+				return fmt.Errorf("stored AWS secret but failed to persist --time-offset (codes would be generated without it): %w", err)
+			}
+			fmt.Println(theme.Warning() + " Warning: Failed to store description. This entry might not appear when listing available AWS profiles.")
+		}
 	}
 
-	err = h.keychainProvider.SetDescription(serviceName, user, description)
-	if err != nil {
-		fmt.Println("⚠️ Warning: Failed to store description. This entry might not appear when listing available AWS profiles.")
+	if saved {
+		discardSetupState(h.keychainProvider, user)
 	}
 
+	showURI(h.opts, totp.URI("AWS", cmp.Or(profile, "default"), secretStr, totp.Params{}))
 	h.showSetupCompletionMessage(profile)
+	printEnvExample(h.opts, profile)
 
 	return nil
 }
 
+// promptForProfileAndConfirm asks for the AWS CLI profile and, if an entry
+// already exists for it, confirms the overwrite.
+func (h *AWSSetupHandler) promptForProfileAndConfirm(user string) (string, error) {
+	profile, err := h.readProfile()
+	if err != nil {
+		return "", err
+	}
+
+	serviceName, err := h.createServiceName(cmp.Or(h.KeyPrefix, constants.AWSServicePrefix), profile)
+	if err != nil {
+		return "", fmt.Errorf("failed to build service key: %w", err)
+	}
+	existingSecret, err := h.keychainProvider.GetSecretString(user, serviceName)
+	if err != nil && !errors.Is(err, keychain.ErrNotFound) {
+		return "", fmt.Errorf("failed to check existing entry: %w", err)
+	}
+
+	if existingSecret != "" {
+		// Entry exists, prompt for overwrite
+		profileDisplay := profile
+		if profileDisplay == "" {
+			profileDisplay = "default"
+		}
+
+		fmt.Printf("\n"+theme.Warning()+" An entry already exists for AWS profile '%s'\n", profileDisplay)
+		fmt.Print("\nOverwrite existing configuration? (y/N): ")
+
+		response, readErr := readLine(h.reader)
+		if readErr != nil {
+			return "", readErr
+		}
+		response = strings.ToLower(response)
+
+		if response != "y" && response != "yes" {
+			discardSetupState(h.keychainProvider, user)
+			fmt.Println("\n" + theme.Error() + " Setup cancelled")
+			return "", fmt.Errorf("setup cancelled by user")
+		}
+		fmt.Println() // Add spacing before continuing
+	}
+
+	return profile, nil
+}
+
+// readProfile returns the AWS CLI profile to set up: derived from
+// --profile-from-arn when that finds a match, otherwise typed by the user.
+func (h *AWSSetupHandler) readProfile() (string, error) {
+	if h.opts.ProfileFromARN != "" {
+		profile, ok, err := h.profileFromARN(h.opts.ProfileFromARN)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return profile, nil
+		}
+	}
+
+	fmt.Print("Enter AWS CLI profile name (leave empty for default): ")
+	return readLine(h.reader)
+}
+
+// offerResume looks for scratch state from an interrupted AWS setup and asks
+// whether to continue from it. It returns nil to start fresh; declining
+// discards the saved state.
+func (h *AWSSetupHandler) offerResume(user string) (*setupState, error) {
+	state, err := loadSetupState(h.keychainProvider, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load saved setup progress: %w", err)
+	}
+	if state == nil || state.Service != "aws" {
+		fmt.Println(theme.Info() + " No interrupted AWS setup found; starting a fresh setup")
+		return nil, nil
+	}
+
+	profileDisplay := state.Profile
+	if profileDisplay == "" {
+		profileDisplay = "default"
+	}
+	step := "the secret was captured"
+	if state.Stage == stageConsoleDone {
+		step = "the AWS console step"
+	}
+	fmt.Printf("\n💾 Found an interrupted setup for AWS profile '%s' (stopped after %s)\n", profileDisplay, step)
+	fmt.Print("Continue where you left off? (Y/n): ")
+
+	response, err := readLine(h.reader)
+	if err != nil {
+		return nil, err
+	}
+	response = strings.ToLower(response)
+	if response == "n" || response == "no" {
+		discardSetupState(h.keychainProvider, user)
+		fmt.Println("Starting a fresh setup")
+		return nil, nil
+	}
+
+	fmt.Println()
+	return state, nil
+}
+
 // TOTP Setup Handler
 
 // TOTPSetupHandler implements SetupHandler for TOTP
 type TOTPSetupHandler struct {
 	keychainProvider keychain.Provider
 	reader           *bufio.Reader
+	opts             Options
+
+	// KeyPrefix is the keychain namespace to store the secret under;
+	// empty means constants.TOTPServicePrefix. It matches the TOTP
+	// provider's own.
+	KeyPrefix string
 }
 
 // NewTOTPSetupHandler creates a new TOTP setup handler
@@ -610,12 +1144,18 @@ func (h *TOTPSetupHandler) ServiceName() string {
 	return "totp"
 }
 
+// Configure implements Configurable
+func (h *TOTPSetupHandler) Configure(opts Options) {
+	h.opts = opts
+}
+
 // createTOTPServiceName creates a TOTP service name with proper profile handling
 func (h *TOTPSetupHandler) createTOTPServiceName(serviceName, profile string) (string, error) {
+	prefix := cmp.Or(h.KeyPrefix, constants.TOTPServicePrefix)
 	if profile == "" {
-		return keyformat.Build(constants.TOTPServicePrefix, serviceName)
+		return keyformat.Build(prefix, serviceName)
 	}
-	return keyformat.Build(constants.TOTPServicePrefix, serviceName, profile)
+	return keyformat.Build(prefix, serviceName, profile)
 }
 
 // promptForServiceName prompts the user to enter a service name and validates it
@@ -678,21 +1218,54 @@ func (h *TOTPSetupHandler) captureTOTPSecretFull(choice string) (qrcode.TOTPInfo
 		secret, err := h.captureManualEntry()
 		return qrcode.TOTPInfo{Secret: secret}, err
 	case "2": // QR code capture with retry + fallback — returns full params
-		return captureQRWithRetryFull(h.reader, h.captureManualEntry)
+		return captureQRWithRetryFull(h.reader, h.opts.qrAttempts(), h.captureManualEntry)
 	default:
 		return qrcode.TOTPInfo{}, fmt.Errorf("invalid choice, please select 1 or 2")
 	}
 }
 
+// secretFromQRImage decodes the TOTP QR code in the PNG at path, or piped
+// on stdin when path is "-". Unlike the screen capture this works on any
+// platform, including headless machines.
+func (h *TOTPSetupHandler) secretFromQRImage(path string) (qrcode.TOTPInfo, error) {
+	var r io.Reader = h.reader
+	if path != "-" {
+		f, err := os.Open(path) //nolint:gosec // path is the user's --qr-image
+		if err != nil {
+			return qrcode.TOTPInfo{}, fmt.Errorf("failed to open QR image: %w", err)
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: failed to close QR image: %v\n", err)
+			}
+		}()
+		r = f
+	}
+
+	text, err := qrcode.DecodeImage(r)
+	if err != nil {
+		return qrcode.TOTPInfo{}, fmt.Errorf("failed to read QR code from %s: %w", path, err)
+	}
+	info, err := qrcode.ExtractTOTPFullInfo(text)
+	if err != nil {
+		return qrcode.TOTPInfo{}, err
+	}
+	fmt.Println(theme.OK() + " QR code decoded from image")
+	if info.Issuer != "" {
+		fmt.Printf("   Issuer: %s\n", info.Issuer)
+	}
+	return info, nil
+}
+
 // captureQRCodeWithFallback attempts QR capture with retry and manual fallback
 func (h *TOTPSetupHandler) captureQRCodeWithFallback() (string, error) {
-	return captureQRWithRetry(h.reader, h.captureManualEntry)
+	return captureQRWithRetry(h.reader, h.opts.qrAttempts(), h.captureManualEntry)
 }
 
 // captureManualEntry handles manual secret entry with secure memory handling
 func (h *TOTPSetupHandler) captureManualEntry() (string, error) {
 	fmt.Print("\n📋 Enter or paste your TOTP secret key and press Enter:\n→ ")
-	secret, err := readPassword(syscall.Stdin)
+	secret, err := readSecret(h.reader)
 	if err != nil {
 		return "", fmt.Errorf("failed to read secret: %w", err)
 	}
@@ -701,7 +1274,7 @@ func (h *TOTPSetupHandler) captureManualEntry() (string, error) {
 	// Handle secret securely
 	secretBytes := secret
 	defer secure.SecureZeroBytes(secretBytes)
-	return strings.TrimSpace(string(secretBytes)), nil
+	return pastedSecret(secretBytes), nil
 }
 
 // showTOTPSetupCompletionMessage displays the final success message with usage instructions
@@ -710,7 +1283,7 @@ func (h *TOTPSetupHandler) showTOTPSetupCompletionMessage(serviceName, profile s
 	if profile != "" {
 		profileFlag = fmt.Sprintf(" --profile '%s'", profile)
 	}
-	fmt.Println("✅ Setup complete! Generate TOTP codes with:")
+	fmt.Println(theme.OK() + " Setup complete! Generate TOTP codes with:")
 	fmt.Printf("  sesh --service totp --service-name '%s'%s\n", serviceName, profileFlag)
 	fmt.Println("Copy to clipboard with:")
 	fmt.Printf("  sesh --service totp --service-name '%s'%s --clip\n", serviceName, profileFlag)
@@ -731,7 +1304,7 @@ func (h *TOTPSetupHandler) Setup() error {
 	}
 
 	// Check if entry already exists
-	user, err := getCurrentUser()
+	user, err := keychainAccount(h.opts)
 	if err != nil {
 		return fmt.Errorf("failed to get current user: %w", err)
 	}
@@ -747,7 +1320,7 @@ func (h *TOTPSetupHandler) Setup() error {
 
 	if existingSecret != "" {
 		// Entry exists, prompt for overwrite
-		fmt.Printf("\n⚠️  An entry already exists for service '%s'", serviceName)
+		fmt.Printf("\n"+theme.Warning()+" An entry already exists for service '%s'", serviceName)
 		if profile != "" {
 			fmt.Printf(" with profile '%s'", profile)
 		}
@@ -761,31 +1334,59 @@ func (h *TOTPSetupHandler) Setup() error {
 		response = strings.ToLower(response)
 
 		if response != "y" && response != "yes" {
-			fmt.Println("\n❌ Setup cancelled")
+			fmt.Println("\n" + theme.Error() + " Setup cancelled")
 			return fmt.Errorf("setup cancelled by user")
 		}
 		fmt.Println() // Add spacing before continuing
 	}
 
-	choice, err := h.promptForCaptureMethod()
-	if err != nil {
-		return err
+	var info qrcode.TOTPInfo
+	if h.opts.SecretEnv != "" {
+		secret, envErr := secretFromEnv(h.opts.SecretEnv)
+		if envErr != nil {
+			return envErr
+		}
+		info.Secret = secret
+	} else if h.opts.QRImage != "" {
+		info, err = h.secretFromQRImage(h.opts.QRImage)
+		if err != nil {
+			return err
+		}
+	} else {
+		choice, promptErr := h.promptForCaptureMethod()
+		if promptErr != nil {
+			return promptErr
+		}
+
+		info, err = h.captureTOTPSecretFull(choice)
+		if err != nil {
+			return err
+		}
 	}
 
-	info, err := h.captureTOTPSecretFull(choice)
-	if err != nil {
-		return err
+	if h.opts.Algorithm != "" {
+		if info.Algorithm != "" && info.Algorithm != h.opts.Algorithm {
+			fmt.Printf(theme.Warning()+" Using algorithm %s instead of %s from the QR code\n", h.opts.Algorithm, info.Algorithm)
+		}
+		info.Algorithm = h.opts.Algorithm
+		if info.Algorithm == "SHA1" {
+			// SHA1 is the default; keep it out of the stored params.
+			info.Algorithm = ""
+		}
+	}
+
+	if h.opts.Issuer != "" {
+		info.Issuer = h.opts.Issuer
 	}
 
-	// Validate and normalize the TOTP secret
-	normalizedSecret, err := validateAndNormalizeSecret(info.Secret)
+	normalizedSecret, err := validateCapturedSecret(info.Secret, h.opts.StrictSecret)
 	if err != nil {
-		return fmt.Errorf("invalid TOTP secret: %w", err)
+		return err
 	}
 	secretStr := normalizedSecret
 
 	// Generate two consecutive TOTP codes
-	firstCode, secondCode, err := generateConsecutiveCodes(secretStr)
+	firstCode, secondCode, err := setupCodes(secretStr, h.opts)
 	if err != nil {
 		return fmt.Errorf("failed to generate TOTP codes: %s", err)
 	}
@@ -796,32 +1397,40 @@ func (h *TOTPSetupHandler) Setup() error {
 		return fmt.Errorf("failed to build service key: %w", err)
 	}
 
-	// Store the secret using the keychain provider
-	err = h.keychainProvider.SetSecretString(user, serviceKey, secretStr)
-	if err != nil {
-		return fmt.Errorf("failed to store secret in keychain: %w", err)
-	}
-
 	// Build the description. For non-default QR params (algorithm, digits,
 	// period) this is load-bearing metadata — GenerateTOTPCode reads it
 	// back to reproduce the correct codes. For default params we fall
 	// back to a cosmetic human-readable label.
 	params := totp.Params{
-		Issuer:    info.Issuer,
-		Algorithm: info.Algorithm,
-		Digits:    info.Digits,
-		Period:    info.Period,
+		Issuer:     info.Issuer,
+		Algorithm:  info.Algorithm,
+		Digits:     info.Digits,
+		Period:     info.Period,
+		TimeOffset: h.opts.TimeOffset,
+		URL:        h.opts.URL,
+	}
+	if h.opts.NoMetadata && !params.IsDefault() {
+		return fmt.Errorf("--no-metadata cannot be used for this secret: its non-default parameters (algorithm, digits, period, time offset) are stored in metadata")
 	}
 	description := params.MarshalDescription()
-	paramsAreLoadBearing := description != ""
-	if !paramsAreLoadBearing {
+	// An issuer or URL alone is only for display
+	paramsAreLoadBearing := !params.IsDefault()
+	if description == "" {
 		description = fmt.Sprintf("TOTP for %s", serviceName)
 		if profile != "" {
 			description = fmt.Sprintf("TOTP for %s profile %s", serviceName, profile)
 		}
 	}
 
-	if err := h.keychainProvider.SetDescription(serviceKey, user, description); err != nil {
+	// Store the secret using the keychain provider
+	err = storeSecret(h.keychainProvider, h.opts, user, serviceKey, secretStr)
+	if err != nil {
+		return fmt.Errorf("failed to store secret in keychain: %w", err)
+	}
+
+	if h.opts.NoMetadata {
+		printNoMetadataNote()
+	} else if err := h.keychainProvider.SetDescription(serviceKey, user, description); err != nil {
 		if paramsAreLoadBearing {
 			// Fail closed: the entry would otherwise persist with the
 			// secret but no params, and every future code generation
@@ -829,25 +1438,93 @@ func (h *TOTPSetupHandler) Setup() error {
 			// codes for the issuer's expected configuration.
 			return fmt.Errorf("stored TOTP secret but failed to persist non-default params (subsequent codes would fall back to defaults): %w", err)
 		}
-		fmt.Println("⚠️ Warning: Failed to store description. This entry might not appear when listing available TOTP services.")
+		fmt.Println(theme.Warning() + " Warning: Failed to store description. This entry might not appear when listing available TOTP services.")
 	}
 
 	// Display the generated TOTP codes for setup verification
-	fmt.Println("✅ Generated TOTP codes for verification:")
+	fmt.Println(theme.OK() + " Generated TOTP codes for verification:")
 	fmt.Printf("   Current code: %s\n", firstCode)
 	fmt.Printf("   Next code: %s\n", secondCode)
 	fmt.Println("   (Use these codes if your service requires verification during setup)")
+	copySetupCodes(h.opts, firstCode, secondCode)
 	fmt.Println()
 
+	if h.shouldVerifyWithService() {
+		if err := h.verifyWithService(serviceName, secretStr, params); err != nil {
+			return err
+		}
+	}
+
+	showURI(h.opts, totp.URI(cmp.Or(info.Issuer, serviceName), cmp.Or(info.Account, profile, serviceName), secretStr, params))
 	h.showTOTPSetupCompletionMessage(serviceName, profile)
 
 	return nil
 }
 
+// shouldVerifyWithService reports whether to run the verify step: always
+// with --verify-with-service, otherwise by asking, except for
+// non-interactive --secret-env runs. Enter or y accepts; any other answer,
+// or unreadable input (e.g. stdin at EOF), skips the step.
+func (h *TOTPSetupHandler) shouldVerifyWithService() bool {
+	if h.opts.VerifyWithService {
+		return true
+	}
+	if h.opts.SecretEnv != "" {
+		return false
+	}
+	fmt.Print("Verify against a code your service shows now? (Y/n): ")
+	response, err := readLine(h.reader)
+	if err != nil {
+		fmt.Println()
+		return false
+	}
+	response = strings.ToLower(response)
+	return response == "" || response == "y" || response == "yes"
+}
+
+// verifySkewWindows is how many windows either side of now a code typed
+// during verification may come from and still count as a match.
+const verifySkewWindows = 1
+
+// verifyWithService asks for the code the service currently displays and
+// checks it against the stored secret, catching transcription errors before
+// first real use. Matches in an adjacent window are reported as clock skew.
+func (h *TOTPSetupHandler) verifyWithService(serviceName, secret string, params totp.Params) error {
+	fmt.Printf("Enter the code %s currently shows: ", serviceName)
+	code, err := readLine(h.reader)
+	if err != nil {
+		return err
+	}
+
+	offset, ok, err := matchTOTPWindow(secret, params, code, timeNow(), verifySkewWindows)
+	if err != nil {
+		return fmt.Errorf("failed to verify TOTP code: %w", err)
+	}
+	if !ok {
+		fmt.Println(theme.Error() + " Code did not match the current or adjacent windows")
+		return fmt.Errorf("TOTP verification failed: the secret was stored but may have been mistyped; re-run setup to replace it")
+	}
+
+	period := params.Period
+	if period <= 0 {
+		period = 30
+	}
+	switch {
+	case offset == 0:
+		fmt.Println(theme.OK() + " Code matches the current window")
+	case offset < 0:
+		fmt.Printf(theme.OK()+" Code matches the previous window — this machine's clock may be ~%ds ahead of %s\n", -offset*period, serviceName)
+	default:
+		fmt.Printf(theme.OK()+" Code matches the next window — this machine's clock may be ~%ds behind %s\n", offset*period, serviceName)
+	}
+	fmt.Println()
+	return nil
+}
+
 // captureQRWithRetry is a shared helper for QR code capture with retry logic.
 // Returns just the secret string (for backward compatibility).
-func captureQRWithRetry(reader *bufio.Reader, manualEntryFunc func() (string, error)) (string, error) {
-	info, err := captureQRWithRetryFull(reader, manualEntryFunc)
+func captureQRWithRetry(reader *bufio.Reader, maxRetries int, manualEntryFunc func() (string, error)) (string, error) {
+	info, err := captureQRWithRetryFull(reader, maxRetries, manualEntryFunc)
 	if err != nil {
 		return "", err
 	}
@@ -855,9 +1532,15 @@ func captureQRWithRetry(reader *bufio.Reader, manualEntryFunc func() (string, er
 }
 
 // captureQRWithRetryFull captures a QR code with retry logic and returns full TOTP info
-// (including algorithm, digits, period). Falls back to manual entry with default params.
-func captureQRWithRetryFull(reader *bufio.Reader, manualEntryFunc func() (string, error)) (qrcode.TOTPInfo, error) {
-	maxRetries := 2
+// (including algorithm, digits, period). It makes up to maxRetries captures,
+// then falls back to manual entry with default params; with maxRetries zero
+// it goes straight to manual entry.
+func captureQRWithRetryFull(reader *bufio.Reader, maxRetries int, manualEntryFunc func() (string, error)) (qrcode.TOTPInfo, error) {
+	if maxRetries <= 0 {
+		fmt.Println("Skipping QR capture (--qr-retries 0); enter the secret manually")
+		secret, err := manualEntryFunc()
+		return qrcode.TOTPInfo{Secret: secret}, err
+	}
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		fmt.Printf("📸 QR capture attempt %d/%d\n", attempt, maxRetries)
@@ -869,14 +1552,14 @@ func captureQRWithRetryFull(reader *bufio.Reader, manualEntryFunc func() (string
 
 		info, err := scanQRCodeFull()
 		if err == nil {
-			fmt.Println("✅ QR code successfully captured and decoded!")
+			fmt.Println(theme.OK() + " QR code successfully captured and decoded!")
 			if info.Issuer != "" {
 				fmt.Printf("   Issuer: %s\n", info.Issuer)
 			}
 			return info, nil
 		}
 
-		fmt.Printf("❌ QR capture failed: %v\n", err)
+		fmt.Printf(theme.Error()+" QR capture failed: %v\n", err)
 
 		if attempt < maxRetries {
 			fmt.Println("💡 Tips: Check screen brightness, QR code size, and cursor positioning")
@@ -894,7 +1577,11 @@ func captureQRWithRetryFull(reader *bufio.Reader, manualEntryFunc func() (string
 	}
 
 	// Final fallback after all retries
-	fmt.Println("\n❓ QR capture failed after multiple attempts.")
+	attempts := "1 attempt"
+	if maxRetries > 1 {
+		attempts = fmt.Sprintf("%d attempts", maxRetries)
+	}
+	fmt.Printf("\n❓ QR capture failed after %s.\n", attempts)
 	fmt.Print("Would you like to enter the secret manually instead? (y/n): ")
 	fallback, err := readLine(reader)
 	if err != nil {
@@ -906,5 +1593,5 @@ func captureQRWithRetryFull(reader *bufio.Reader, manualEntryFunc func() (string
 		return qrcode.TOTPInfo{Secret: secret}, err
 	}
 
-	return qrcode.TOTPInfo{}, fmt.Errorf("QR capture failed after %d attempts and user declined manual entry", maxRetries)
+	return qrcode.TOTPInfo{}, fmt.Errorf("QR capture failed after %s and user declined manual entry", attempts)
 }
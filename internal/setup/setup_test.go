@@ -2,18 +2,27 @@ package setup
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"image/png"
 	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"slices"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/makiuchi-d/gozxing"
+	gozxingQR "github.com/makiuchi-d/gozxing/qrcode"
+
 	"github.com/bashhack/sesh/internal/keychain/mocks"
+	"github.com/bashhack/sesh/internal/prompt"
 	"github.com/bashhack/sesh/internal/qrcode"
 	"github.com/bashhack/sesh/internal/testutil"
 	"github.com/bashhack/sesh/internal/totp"
@@ -96,6 +105,32 @@ func (h *mockSetupHandler) Setup() error {
 	return h.setupError
 }
 
+// configurableSetupHandler records the Options it was configured with.
+type configurableSetupHandler struct {
+	mockSetupHandler
+	opts Options
+}
+
+func (h *configurableSetupHandler) Configure(opts Options) {
+	h.opts = opts
+}
+
+func TestSetupService_PassesOptions(t *testing.T) {
+	handler := &configurableSetupHandler{mockSetupHandler: mockSetupHandler{name: "test-service"}}
+	service := NewSetupService(nil)
+	service.RegisterHandler(handler)
+
+	if err := service.SetupService("test-service", Options{NoMetadata: true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !handler.opts.NoMetadata {
+		t.Error("handler was not configured with NoMetadata")
+	}
+	if !handler.setupCalled {
+		t.Error("Setup was not called on handler")
+	}
+}
+
 func TestSetupService(t *testing.T) {
 	handler := &mockSetupHandler{
 		name: "test-service",
@@ -119,7 +154,7 @@ func TestSetupService(t *testing.T) {
 	}
 
 	// Test setup for registered service
-	err := service.SetupService("test-service")
+	err := service.SetupService("test-service", Options{})
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -128,7 +163,7 @@ func TestSetupService(t *testing.T) {
 	}
 
 	// Test setup for unregistered service
-	err = service.SetupService("unknown-service")
+	err = service.SetupService("unknown-service", Options{})
 	if err == nil {
 		t.Error("Expected error for unknown service, got nil")
 	}
@@ -512,9 +547,8 @@ func TestAWSSetupHandler_createServiceName(t *testing.T) {
 }
 
 func TestTOTPSetupHandler_createTOTPServiceName(t *testing.T) {
-	handler := &TOTPSetupHandler{}
-
 	tests := map[string]struct {
+		keyPrefix   string
 		serviceName string
 		profile     string
 		want        string
@@ -533,6 +567,12 @@ func TestTOTPSetupHandler_createTOTPServiceName(t *testing.T) {
 			serviceName: "my service",
 			want:        "sesh-totp/my service",
 		},
+		"custom key prefix": {
+			keyPrefix:   "sesh-test-totp",
+			serviceName: "github",
+			profile:     "work",
+			want:        "sesh-test-totp/github/work",
+		},
 		"empty service is rejected": {
 			serviceName: "",
 			wantErr:     true,
@@ -545,6 +585,7 @@ func TestTOTPSetupHandler_createTOTPServiceName(t *testing.T) {
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
+			handler := &TOTPSetupHandler{KeyPrefix: tc.keyPrefix}
 			got, err := handler.createTOTPServiceName(tc.serviceName, tc.profile)
 			if tc.wantErr {
 				if err == nil {
@@ -715,6 +756,7 @@ func TestTOTPSetupHandler_captureManualEntry(t *testing.T) {
 		secretInput string
 		wantSecret  string
 		wantErrMsg  string
+		wantWarning string
 		wantErr     bool
 	}{
 		"valid secret": {
@@ -729,6 +771,16 @@ func TestTOTPSetupHandler_captureManualEntry(t *testing.T) {
 			wantSecret:  "JBSWY3DPEHPK3PXP",
 			wantErr:     false,
 		},
+		"secret with internal spaces": {
+			secretInput: "JBSW Y3DP EHPK 3PXP",
+			wantSecret:  "JBSWY3DPEHPK3PXP",
+			wantWarning: "Removed 3 whitespace characters from pasted secret",
+		},
+		"secret wrapped across lines": {
+			secretInput: "JBSWY3DP\r\nEHPK\t3PXP\n",
+			wantSecret:  "JBSWY3DPEHPK3PXP",
+			wantWarning: "Removed 3 whitespace characters from pasted secret",
+		},
 		"read error": {
 			secretInput: "",
 			readError:   io.ErrUnexpectedEOF,
@@ -763,6 +815,13 @@ func TestTOTPSetupHandler_captureManualEntry(t *testing.T) {
 				t.Error("Expected prompt not displayed")
 			}
 
+			if tc.wantWarning != "" && !strings.Contains(output, tc.wantWarning) {
+				t.Errorf("output = %q, want warning %q", output, tc.wantWarning)
+			}
+			if tc.wantWarning == "" && strings.Contains(output, "whitespace") {
+				t.Errorf("unexpected whitespace warning in %q", output)
+			}
+
 			// Check secret
 			if secret != tc.wantSecret {
 				t.Errorf("captureManualEntry() secret = %v, want %v", secret, tc.wantSecret)
@@ -1137,6 +1196,55 @@ func TestAWSSetupHandler_showSetupCompletionMessage(t *testing.T) {
 	}
 }
 
+func TestPrintEnvExample(t *testing.T) {
+	tests := map[string]struct {
+		opts    Options
+		profile string
+		want    []string
+	}{
+		"named profile": {
+			opts:    Options{PrintEnvExample: true},
+			profile: "dev",
+			want: []string{
+				"  sesh --service aws --profile dev --format ini --output-file ~/.aws/credentials\n",
+				"[dev-sesh] section",
+				"  export AWS_PROFILE=dev-sesh\n",
+			},
+		},
+		"default profile": {
+			opts: Options{PrintEnvExample: true},
+			want: []string{
+				"  sesh --service aws --format ini --output-file ~/.aws/credentials\n",
+				"  export AWS_PROFILE=default-sesh\n",
+			},
+		},
+		"keychain user is carried over": {
+			opts:    Options{PrintEnvExample: true, KeychainUser: "ci"},
+			profile: "dev",
+			want:    []string{"  sesh --service aws --profile dev --keychain-user ci --format ini"},
+		},
+		"not requested": {
+			profile: "dev",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			output := testutil.CaptureStdout(func() {
+				printEnvExample(tc.opts, tc.profile)
+			})
+			if len(tc.want) == 0 && output != "" {
+				t.Errorf("printed %q without --print-env-example", output)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("output = %q, want it to contain %q", output, want)
+				}
+			}
+		})
+	}
+}
+
 // TestAWSSetupHandler_setupMFAConsole tests MFA console setup guidance
 func TestAWSSetupHandler_setupMFAConsole(t *testing.T) {
 	tests := map[string]struct {
@@ -1194,6 +1302,75 @@ func TestAWSSetupHandler_setupMFAConsole(t *testing.T) {
 	}
 }
 
+func TestValidateCapturedSecret(t *testing.T) {
+	tests := map[string]struct {
+		secret      string
+		wantErrMsg  string
+		wantWarning string
+		wantErr     bool
+		strict      bool
+	}{
+		"80-bit secret warns": {
+			secret:      "JBSWY3DPEHPK3PXP",
+			wantWarning: "this secret is only 80 bits",
+		},
+		"160-bit secret does not warn": {
+			secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		},
+		"256-bit secret does not warn": {
+			secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA",
+		},
+		"invalid secret fails": {
+			secret:     "not-base32!",
+			wantErr:    true,
+			wantErrMsg: "invalid TOTP secret",
+		},
+		"lowercase secret is normalized by default": {
+			secret: "gezdgnbvgy3tqojqgezdgnbvgy3tqojq",
+		},
+		"lowercase secret fails with strict": {
+			secret:     "gezdgnbvgy3tqojqgezdgnbvgy3tqojq",
+			strict:     true,
+			wantErr:    true,
+			wantErrMsg: "--strict requires an uppercase secret",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var got string
+			var err error
+			output := testutil.CaptureStdout(func() {
+				got, err = validateCapturedSecret(tc.secret, tc.strict)
+			})
+
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Errorf("error = %q, want to contain %q", err.Error(), tc.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got == "" {
+				t.Error("expected normalized secret, got empty string")
+			}
+
+			if tc.wantWarning != "" {
+				if !strings.Contains(output, tc.wantWarning) {
+					t.Errorf("output = %q, want to contain %q", output, tc.wantWarning)
+				}
+			} else if strings.Contains(output, "Warning") {
+				t.Errorf("unexpected warning in output: %q", output)
+			}
+		})
+	}
+}
+
 // TestCaptureQRWithRetry tests QR code capture with retry logic
 func TestCaptureQRWithRetry(t *testing.T) {
 	// Save originals and restore after test
@@ -1279,7 +1456,7 @@ func TestCaptureQRWithRetry(t *testing.T) {
 			var secret string
 			var err error
 			output := testutil.CaptureStdout(func() {
-				secret, err = captureQRWithRetry(reader, mockManualEntry)
+				secret, err = captureQRWithRetry(reader, DefaultQRRetries, mockManualEntry)
 			})
 
 			// Check scan was called expected number of times
@@ -1310,6 +1487,91 @@ func TestCaptureQRWithRetry(t *testing.T) {
 	}
 }
 
+func TestCaptureQRWithRetry_ConfiguredRetries(t *testing.T) {
+	origScanQRCodeFull := scanQRCodeFull
+	defer func() { scanQRCodeFull = origScanQRCodeFull }()
+
+	tests := map[string]struct {
+		retries       int // as given to --qr-retries
+		readerInput   string
+		wantScanCalls int
+		wantOutput    string
+	}{
+		"zero goes straight to manual entry": {
+			retries:       0,
+			wantScanCalls: 0,
+			wantOutput:    "Skipping QR capture (--qr-retries 0)",
+		},
+		"one attempt": {
+			retries:       1,
+			readerInput:   "\ny\n",
+			wantScanCalls: 1,
+			wantOutput:    "QR capture failed after 1 attempt.",
+		},
+		"three attempts": {
+			retries:       3,
+			readerInput:   "\n\n\n\n\ny\n",
+			wantScanCalls: 3,
+			wantOutput:    "QR capture failed after 3 attempts.",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			scanCalls := 0
+			scanQRCodeFull = func() (qrcode.TOTPInfo, error) {
+				scanCalls++
+				return qrcode.TOTPInfo{}, errors.New("scan failed")
+			}
+
+			// Mirror the CLI, which passes --qr-retries 0 as SkipQRCapture
+			opts := Options{QRRetries: tc.retries}
+			if tc.retries == 0 {
+				opts.QRRetries = SkipQRCapture
+			}
+
+			reader := bufio.NewReader(strings.NewReader(tc.readerInput))
+			var secret string
+			var err error
+			output := testutil.CaptureStdout(func() {
+				secret, err = captureQRWithRetry(reader, opts.qrAttempts(), func() (string, error) {
+					return "MANUAL_SECRET", nil
+				})
+			})
+			if err != nil {
+				t.Fatalf("captureQRWithRetry() error = %v", err)
+			}
+			if secret != "MANUAL_SECRET" {
+				t.Errorf("secret = %q, want the manual entry", secret)
+			}
+			if scanCalls != tc.wantScanCalls {
+				t.Errorf("scanQRCodeFull called %d times, want %d", scanCalls, tc.wantScanCalls)
+			}
+			if !strings.Contains(output, tc.wantOutput) {
+				t.Errorf("output = %q, want it to contain %q", output, tc.wantOutput)
+			}
+		})
+	}
+}
+
+func TestOptions_qrAttempts(t *testing.T) {
+	tests := map[string]struct {
+		retries int
+		want    int
+	}{
+		"zero value is the default": {retries: 0, want: DefaultQRRetries},
+		"skip":                      {retries: SkipQRCapture, want: 0},
+		"configured":                {retries: 5, want: 5},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := (Options{QRRetries: tc.retries}).qrAttempts(); got != tc.want {
+				t.Errorf("qrAttempts() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
 // TestTOTPSetupHandler_captureQRCodeWithFallback tests TOTP QR capture wrapper
 func TestTOTPSetupHandler_captureQRCodeWithFallback(t *testing.T) {
 	// Save originals and restore after test
@@ -1457,6 +1719,33 @@ func TestAWSSetupHandler_captureAWSQRCodeWithFallback(t *testing.T) {
 }
 
 // TestAWSSetupHandler_selectMFADevice tests MFA device selection
+func TestParseMFADevices(t *testing.T) {
+	const (
+		dev1 = "arn:aws:iam::123456789012:mfa/user1"
+		dev2 = "arn:aws:iam::123456789012:mfa/user2"
+	)
+	tests := map[string]struct {
+		out  string
+		want []string
+	}{
+		"tab separated":              {out: dev1 + "\t" + dev2, want: []string{dev1, dev2}},
+		"space separated":            {out: dev1 + "  " + dev2, want: []string{dev1, dev2}},
+		"newline separated":          {out: dev1 + "\n" + dev2 + "\n", want: []string{dev1, dev2}},
+		"single device with newline": {out: dev1 + "\n", want: []string{dev1}},
+		"mixed with trailing space":  {out: "\t" + dev1 + " \t\r\n" + dev2 + "  ", want: []string{dev1, dev2}},
+		"empty":                      {out: "", want: []string{}},
+		"whitespace only":            {out: " \t\n", want: []string{}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := parseMFADevices([]byte(tc.out)); !slices.Equal(got, tc.want) {
+				t.Errorf("parseMFADevices(%q) = %q, want %q", tc.out, got, tc.want)
+			}
+		})
+	}
+}
+
 func TestAWSSetupHandler_selectMFADevice(t *testing.T) {
 	// Save original runCommand and restore after test
 	origRunCommand := runCommand
@@ -1504,6 +1793,18 @@ func TestAWSSetupHandler_selectMFADevice(t *testing.T) {
 			wantDevice: "arn:aws:iam::123456789012:mfa/user2",
 			wantErr:    false,
 		},
+		"newline-separated devices with trailing whitespace": {
+			profile:    "default",
+			awsOutputs: []string{"arn:aws:iam::123456789012:mfa/user1\narn:aws:iam::123456789012:mfa/user2  \n"},
+			userInput:  "2\n",
+			wantDevice: "arn:aws:iam::123456789012:mfa/user2",
+		},
+		"whitespace-only output is no devices": {
+			profile:    "default",
+			awsOutputs: []string{" \t\n"},
+			userInput:  "3\narn:aws:iam::123456789012:mfa/manual\n",
+			wantDevice: "arn:aws:iam::123456789012:mfa/manual",
+		},
 		"manual entry": {
 			profile:    "default",
 			awsOutputs: []string{"arn:aws:iam::123456789012:mfa/user1"},
@@ -1536,6 +1837,24 @@ func TestAWSSetupHandler_selectMFADevice(t *testing.T) {
 			wantDevice: "arn:aws:iam::123456789012:mfa/user1",
 			wantErr:    false,
 		},
+		"search term matching one device selects it": {
+			profile:    "default",
+			awsOutputs: []string{"arn:aws:iam::123456789012:mfa/desktop\tarn:aws:iam::123456789012:mfa/laptop"},
+			userInput:  "LAPTOP\n",
+			wantDevice: "arn:aws:iam::123456789012:mfa/laptop",
+		},
+		"search term matching several devices re-lists them": {
+			profile:    "default",
+			awsOutputs: []string{"arn:aws:iam::123456789012:mfa/desktop\tarn:aws:iam::123456789012:mfa/work-laptop\tarn:aws:iam::123456789012:mfa/home-laptop"},
+			userInput:  "laptop\n2\n", // Numbers refer to the narrowed list
+			wantDevice: "arn:aws:iam::123456789012:mfa/home-laptop",
+		},
+		"search term matching nothing then valid": {
+			profile:    "default",
+			awsOutputs: []string{"arn:aws:iam::123456789012:mfa/desktop"},
+			userInput:  "phone\n1\n",
+			wantDevice: "arn:aws:iam::123456789012:mfa/desktop",
+		},
 		"out of range then valid": {
 			profile:    "default",
 			awsOutputs: []string{"arn:aws:iam::123456789012:mfa/user1"},
@@ -1636,7 +1955,7 @@ func TestAWSSetupHandler_selectMFADevice(t *testing.T) {
 			}
 
 			// Verify the prompts were shown
-			if len(tc.awsOutputs) > 0 && tc.awsOutputs[0] != "" {
+			if len(tc.awsOutputs) > 0 && strings.TrimSpace(tc.awsOutputs[0]) != "" {
 				if !strings.Contains(output, "Found MFA device(s):") {
 					t.Error("Expected 'Found MFA device(s):' prompt")
 				}
@@ -1957,6 +2276,135 @@ func TestTOTPSetupHandler_Setup_NonDefaultParamsFailClosed(t *testing.T) {
 	}
 }
 
+func TestTOTPSetupHandler_Setup_NoMetadata(t *testing.T) {
+	origScanQRCodeFull := scanQRCodeFull
+	defer func() { scanQRCodeFull = origScanQRCodeFull }()
+	origValidate := validateAndNormalizeSecret
+	defer func() { validateAndNormalizeSecret = origValidate }()
+	origGenerate := generateConsecutiveCodes
+	defer func() { generateConsecutiveCodes = origGenerate }()
+	origGetUser := getCurrentUser
+	defer func() { getCurrentUser = origGetUser }()
+
+	validateAndNormalizeSecret = func(s string) (string, error) { return s, nil }
+	generateConsecutiveCodes = func(s string) (string, string, error) {
+		return "123456", "654321", nil
+	}
+	getCurrentUser = func() (string, error) { return "testuser", nil }
+
+	tests := map[string]struct {
+		info       qrcode.TOTPInfo
+		wantErrMsg string
+		wantStored bool
+	}{
+		"default params store unindexed": {
+			info:       qrcode.TOTPInfo{Secret: "JBSWY3DPEHPK3PXP"},
+			wantStored: true,
+		},
+		"issuer only stores unindexed": {
+			info:       qrcode.TOTPInfo{Secret: "JBSWY3DPEHPK3PXP", Issuer: "GitHub"},
+			wantStored: true,
+		},
+		"non-default params are refused": {
+			info:       qrcode.TOTPInfo{Secret: "JBSWY3DPEHPK3PXP", Digits: 8},
+			wantErrMsg: "--no-metadata cannot be used",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			scanQRCodeFull = func() (qrcode.TOTPInfo, error) { return tc.info, nil }
+
+			var unindexedService string
+			mockKeychain := &mocks.MockProvider{
+				GetSecretStringFunc: func(_, _ string) (string, error) { return "", nil },
+				SetSecretStringFunc: func(_, _, _ string) error {
+					t.Error("SetSecretString should not be used with --no-metadata")
+					return nil
+				},
+				SetSecretUnindexedFunc: func(_, service string, _ []byte) error {
+					unindexedService = service
+					return nil
+				},
+				SetDescriptionFunc: func(_, _, _ string) error {
+					t.Error("SetDescription (metadata write) should not be called with --no-metadata")
+					return nil
+				},
+			}
+
+			handler := &TOTPSetupHandler{
+				reader:           bufio.NewReader(strings.NewReader("MyService\n\n2\n\n")),
+				keychainProvider: mockKeychain,
+			}
+			handler.Configure(Options{NoMetadata: true})
+
+			var err error
+			output := testutil.CaptureStdout(func() {
+				err = handler.Setup()
+			})
+
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				if unindexedService != "" {
+					t.Error("secret should not be stored when the request is refused")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Setup() unexpected error: %v", err)
+			}
+			if tc.wantStored && unindexedService != "sesh-totp/MyService" {
+				t.Errorf("unindexed store service = %q, want sesh-totp/MyService", unindexedService)
+			}
+			if !strings.Contains(output, "won't appear in --list") {
+				t.Error("expected a note that the entry won't be listed")
+			}
+		})
+	}
+}
+
+func TestStoreSecret(t *testing.T) {
+	tests := map[string]struct {
+		opts          Options
+		wantIndexed   bool
+		wantUnindexed bool
+	}{
+		"default writes indexed": {
+			opts:        Options{},
+			wantIndexed: true,
+		},
+		"no-metadata writes unindexed": {
+			opts:          Options{NoMetadata: true},
+			wantUnindexed: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var indexed, unindexed bool
+			kc := &mocks.MockProvider{
+				SetSecretStringFunc: func(_, _, _ string) error {
+					indexed = true
+					return nil
+				},
+				SetSecretUnindexedFunc: func(_, _ string, _ []byte) error {
+					unindexed = true
+					return nil
+				},
+			}
+
+			if err := storeSecret(kc, tc.opts, "user", "sesh-aws/default", "SECRET"); err != nil {
+				t.Fatalf("storeSecret: %v", err)
+			}
+			if indexed != tc.wantIndexed || unindexed != tc.wantUnindexed {
+				t.Errorf("indexed=%v unindexed=%v, want %v/%v", indexed, unindexed, tc.wantIndexed, tc.wantUnindexed)
+			}
+		})
+	}
+}
+
 func TestTOTPSetupHandler_Setup_QRMetadataPersisted(t *testing.T) {
 	// When a QR scan returns a non-default issuer/algorithm/digits/period,
 	// the description written to the keychain must be the JSON-encoded
@@ -2029,21 +2477,89 @@ func TestTOTPSetupHandler_Setup_QRMetadataPersisted(t *testing.T) {
 	}
 }
 
-func TestTOTPSetupHandler_Setup_Overwrite(t *testing.T) {
-	// Save original functions
-	origGetCurrentUser := getCurrentUser
-	origValidateAndNormalizeSecret := validateAndNormalizeSecret
-	origGenerateConsecutiveCodes := generateConsecutiveCodes
-	origReadPassword := readPassword
-	defer func() {
-		getCurrentUser = origGetCurrentUser
-		validateAndNormalizeSecret = origValidateAndNormalizeSecret
-		generateConsecutiveCodes = origGenerateConsecutiveCodes
-		readPassword = origReadPassword
-	}()
+func TestTOTPSetupHandler_Setup_AlgorithmOverride(t *testing.T) {
+	origScanQRCodeFull := scanQRCodeFull
+	defer func() { scanQRCodeFull = origScanQRCodeFull }()
+	origValidate := validateAndNormalizeSecret
+	defer func() { validateAndNormalizeSecret = origValidate }()
+	origGenerate := generateConsecutiveCodes
+	defer func() { generateConsecutiveCodes = origGenerate }()
+	origGetUser := getCurrentUser
+	defer func() { getCurrentUser = origGetUser }()
 
-	// Mock functions
-	getCurrentUser = func() (string, error) {
+	validateAndNormalizeSecret = func(s string) (string, error) { return s, nil }
+	generateConsecutiveCodes = func(s string) (string, string, error) {
+		return "123456", "654321", nil
+	}
+	getCurrentUser = func() (string, error) { return "testuser", nil }
+
+	tests := map[string]struct {
+		qrAlgorithm   string
+		algorithm     string
+		wantAlgorithm string
+		wantNotice    bool
+	}{
+		"QR algorithm kept without override": {qrAlgorithm: "SHA256", wantAlgorithm: "SHA256"},
+		"override replaces QR algorithm":     {qrAlgorithm: "SHA256", algorithm: "SHA512", wantAlgorithm: "SHA512", wantNotice: true},
+		"override on a QR without algorithm": {algorithm: "SHA256", wantAlgorithm: "SHA256"},
+		"sha1 override stays default":        {qrAlgorithm: "SHA512", algorithm: "SHA1", wantAlgorithm: "", wantNotice: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			scanQRCodeFull = func() (qrcode.TOTPInfo, error) {
+				return qrcode.TOTPInfo{Secret: "JBSWY3DPEHPK3PXP", Issuer: "ExampleCorp", Algorithm: tc.qrAlgorithm}, nil
+			}
+
+			var gotDescription string
+			mockKeychain := &mocks.MockProvider{
+				GetSecretStringFunc: func(_, _ string) (string, error) { return "", nil },
+				SetSecretStringFunc: func(_, _, _ string) error { return nil },
+				SetDescriptionFunc: func(_, _, description string) error {
+					gotDescription = description
+					return nil
+				},
+			}
+
+			handler := &TOTPSetupHandler{
+				reader:           bufio.NewReader(strings.NewReader("MyService\ndefault\n2\n\n")),
+				keychainProvider: mockKeychain,
+			}
+			handler.Configure(Options{Algorithm: tc.algorithm})
+
+			var setupErr error
+			output := testutil.CaptureStdout(func() {
+				setupErr = handler.Setup()
+			})
+			if setupErr != nil {
+				t.Fatalf("Setup: %v", setupErr)
+			}
+
+			if got := totp.ParseParams(gotDescription).Algorithm; got != tc.wantAlgorithm {
+				t.Errorf("stored Algorithm = %q, want %q (description %q)", got, tc.wantAlgorithm, gotDescription)
+			}
+			if got := strings.Contains(output, "from the QR code"); got != tc.wantNotice {
+				t.Errorf("override notice shown = %v, want %v", got, tc.wantNotice)
+			}
+		})
+	}
+}
+
+func TestTOTPSetupHandler_Setup_Overwrite(t *testing.T) {
+	// Save original functions
+	origGetCurrentUser := getCurrentUser
+	origValidateAndNormalizeSecret := validateAndNormalizeSecret
+	origGenerateConsecutiveCodes := generateConsecutiveCodes
+	origReadPassword := readPassword
+	defer func() {
+		getCurrentUser = origGetCurrentUser
+		validateAndNormalizeSecret = origValidateAndNormalizeSecret
+		generateConsecutiveCodes = origGenerateConsecutiveCodes
+		readPassword = origReadPassword
+	}()
+
+	// Mock functions
+	getCurrentUser = func() (string, error) {
 		return "testuser", nil
 	}
 
@@ -2185,3 +2701,1066 @@ func TestTOTPSetupHandler_Setup_Overwrite(t *testing.T) {
 		})
 	}
 }
+
+func TestTOTPSetupHandler_Setup_SecretEnv(t *testing.T) {
+	origGenerate := generateConsecutiveCodes
+	defer func() { generateConsecutiveCodes = origGenerate }()
+	origGetUser := getCurrentUser
+	defer func() { getCurrentUser = origGetUser }()
+
+	generateConsecutiveCodes = func(s string) (string, string, error) {
+		return "123456", "654321", nil
+	}
+	getCurrentUser = func() (string, error) { return "testuser", nil }
+
+	const envVar = "SESH_TEST_TOTP_SECRET"
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	tests := map[string]struct {
+		value      string
+		set        bool
+		wantErrMsg string
+	}{
+		"set": {
+			value: " " + secret + "\n",
+			set:   true,
+		},
+		"empty": {
+			value:      "",
+			set:        true,
+			wantErrMsg: "environment variable SESH_TEST_TOTP_SECRET is empty or unset",
+		},
+		"unset": {
+			wantErrMsg: "environment variable SESH_TEST_TOTP_SECRET is empty or unset",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tc.set {
+				t.Setenv(envVar, tc.value)
+			} else {
+				t.Setenv(envVar, "")
+				if err := os.Unsetenv(envVar); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var stored string
+			mockKeychain := &mocks.MockProvider{
+				GetSecretStringFunc: func(_, _ string) (string, error) { return "", nil },
+				SetSecretStringFunc: func(_, _, s string) error {
+					stored = s
+					return nil
+				},
+				SetDescriptionFunc: func(_, _, _ string) error { return nil },
+			}
+
+			// No capture-method choice in the input: the env path must not prompt for it.
+			handler := &TOTPSetupHandler{
+				reader:           bufio.NewReader(strings.NewReader("MyService\n\n")),
+				keychainProvider: mockKeychain,
+			}
+			handler.Configure(Options{SecretEnv: envVar})
+
+			var err error
+			output := testutil.CaptureStdout(func() {
+				err = handler.Setup()
+			})
+
+			if tc.wantErrMsg != "" {
+				if err == nil || err.Error() != tc.wantErrMsg {
+					t.Fatalf("error = %v, want %q", err, tc.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Setup() unexpected error: %v", err)
+			}
+			if stored != secret {
+				t.Errorf("stored secret = %q, want %q", stored, secret)
+			}
+			if strings.Contains(output, secret) {
+				t.Error("secret must not be echoed")
+			}
+		})
+	}
+}
+
+func TestTOTPSetupHandler_Setup_KeychainUser(t *testing.T) {
+	origGenerate := generateConsecutiveCodes
+	defer func() { generateConsecutiveCodes = origGenerate }()
+	origGetUser := getCurrentUser
+	defer func() { getCurrentUser = origGetUser }()
+
+	generateConsecutiveCodes = func(s string) (string, string, error) {
+		return "123456", "654321", nil
+	}
+	getCurrentUser = func() (string, error) { return "testuser", nil }
+
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	t.Setenv("SESH_TEST_TOTP_SECRET", secret)
+
+	tests := map[string]struct {
+		keychainUser string
+		wantAccount  string
+	}{
+		"defaults to the current user": {wantAccount: "testuser"},
+		"override account":             {keychainUser: "svc-bot", wantAccount: "svc-bot"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Keyed by account and service so a mismatched lookup misses.
+			store := map[string]string{}
+			var descAccount string
+			mockKeychain := &mocks.MockProvider{
+				GetSecretStringFunc: func(account, service string) (string, error) {
+					return store[account+"|"+service], nil
+				},
+				SetSecretStringFunc: func(account, service, s string) error {
+					store[account+"|"+service] = s
+					return nil
+				},
+				SetDescriptionFunc: func(_, account, _ string) error {
+					descAccount = account
+					return nil
+				},
+			}
+
+			handler := &TOTPSetupHandler{
+				reader:           bufio.NewReader(strings.NewReader("MyService\n\n")),
+				keychainProvider: mockKeychain,
+			}
+			handler.Configure(Options{SecretEnv: "SESH_TEST_TOTP_SECRET", KeychainUser: tc.keychainUser})
+
+			var err error
+			_ = testutil.CaptureStdout(func() {
+				err = handler.Setup()
+			})
+			if err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+
+			got, _ := mockKeychain.GetSecretString(tc.wantAccount, "sesh-totp/MyService")
+			if got != secret {
+				t.Errorf("secret under account %q = %q, want %q (store %v)", tc.wantAccount, got, secret, store)
+			}
+			if descAccount != tc.wantAccount {
+				t.Errorf("description account = %q, want %q", descAccount, tc.wantAccount)
+			}
+		})
+	}
+}
+
+func TestTOTPSetupHandler_shouldVerifyWithService(t *testing.T) {
+	tests := map[string]struct {
+		opts  Options
+		input string
+		want  bool
+	}{
+		"enter accepts":      {input: "\n", want: true},
+		"yes":                {input: "y\n", want: true},
+		"no":                 {input: "n\n"},
+		"other answer skips": {input: "later\n"},
+		"eof skips":          {input: ""},
+		"flag skips the prompt": {
+			opts: Options{VerifyWithService: true},
+			want: true,
+		},
+		"secret-env skips the step": {
+			opts:  Options{SecretEnv: "TOTP_SECRET"},
+			input: "\n",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &TOTPSetupHandler{reader: bufio.NewReader(strings.NewReader(tc.input)), opts: tc.opts}
+			var got bool
+			testutil.CaptureStdout(func() { got = h.shouldVerifyWithService() })
+			if got != tc.want {
+				t.Errorf("shouldVerifyWithService() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTOTPSetupHandler_Setup_VerifyWithService(t *testing.T) {
+	origGenerate := generateConsecutiveCodes
+	defer func() { generateConsecutiveCodes = origGenerate }()
+	origGetUser := getCurrentUser
+	defer func() { getCurrentUser = origGetUser }()
+	origMatch := matchTOTPWindow
+	defer func() { matchTOTPWindow = origMatch }()
+
+	generateConsecutiveCodes = func(s string) (string, string, error) {
+		return "123456", "654321", nil
+	}
+	getCurrentUser = func() (string, error) { return "testuser", nil }
+
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	t.Setenv("SESH_TEST_VERIFY_SECRET", secret)
+
+	tests := map[string]struct {
+		opts       Options
+		input      string
+		offset     int
+		matched    bool
+		wantCalled bool
+		wantErrMsg string
+		wantOutput string
+	}{
+		"flag verifies current window": {
+			opts:       Options{VerifyWithService: true, SecretEnv: "SESH_TEST_VERIFY_SECRET"},
+			input:      "MyService\n\n111111\n",
+			matched:    true,
+			wantCalled: true,
+			wantOutput: "Code matches the current window",
+		},
+		"adjacent window reports clock skew": {
+			opts:       Options{VerifyWithService: true, SecretEnv: "SESH_TEST_VERIFY_SECRET"},
+			input:      "MyService\n\n111111\n",
+			offset:     1,
+			matched:    true,
+			wantCalled: true,
+			wantOutput: "clock may be ~30s behind MyService",
+		},
+		"mismatch fails setup": {
+			opts:       Options{VerifyWithService: true, SecretEnv: "SESH_TEST_VERIFY_SECRET"},
+			input:      "MyService\n\n111111\n",
+			wantCalled: true,
+			wantErrMsg: "TOTP verification failed",
+		},
+		"secret-env without flag does not prompt": {
+			opts:  Options{SecretEnv: "SESH_TEST_VERIFY_SECRET"},
+			input: "MyService\n\n",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			called := false
+			matchTOTPWindow = func(s string, _ totp.Params, code string, _ time.Time, skew int) (int, bool, error) {
+				called = true
+				if s != secret || code != "111111" || skew != 1 {
+					t.Errorf("matchTOTPWindow(%q, %q, %d) unexpected args", s, code, skew)
+				}
+				return tc.offset, tc.matched, nil
+			}
+
+			mockKeychain := &mocks.MockProvider{
+				GetSecretStringFunc: func(_, _ string) (string, error) { return "", nil },
+				SetSecretStringFunc: func(_, _, _ string) error { return nil },
+				SetDescriptionFunc:  func(_, _, _ string) error { return nil },
+			}
+			handler := &TOTPSetupHandler{
+				reader:           bufio.NewReader(strings.NewReader(tc.input)),
+				keychainProvider: mockKeychain,
+			}
+			handler.Configure(tc.opts)
+
+			var err error
+			output := testutil.CaptureStdout(func() {
+				err = handler.Setup()
+			})
+
+			if called != tc.wantCalled {
+				t.Errorf("verification called = %v, want %v", called, tc.wantCalled)
+			}
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Setup() unexpected error: %v", err)
+			}
+			if !strings.Contains(output, tc.wantOutput) {
+				t.Errorf("output missing %q:\n%s", tc.wantOutput, output)
+			}
+		})
+	}
+}
+
+func TestAWSSetupHandler_Setup_ExistingDevice(t *testing.T) {
+	origLookPath := execLookPath
+	defer func() { execLookPath = origLookPath }()
+	origRunCommand := runCommand
+	defer func() { runCommand = origRunCommand }()
+	origGetUser := getCurrentUser
+	defer func() { getCurrentUser = origGetUser }()
+	origReadPassword := readPassword
+	defer func() { readPassword = origReadPassword }()
+
+	const (
+		mfaArn = "arn:aws:iam::123456789012:mfa/work"
+		secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	)
+	execLookPath = func(string) (string, error) { return "/usr/local/bin/aws", nil }
+	getCurrentUser = func() (string, error) { return "testuser", nil }
+	runCommand = func(name string, args ...string) ([]byte, error) {
+		switch args[0] {
+		case "sts":
+			return []byte("arn:aws:iam::123456789012:user/test\n"), nil
+		case "iam":
+			return []byte(mfaArn + "\n"), nil
+		}
+		return nil, errors.New("unexpected command")
+	}
+	readPassword = func(int) ([]byte, error) { return []byte(secret), nil }
+
+	tests := map[string]struct {
+		input         string
+		keyPrefix     string
+		serialPrefix  string
+		wantSecretKey string
+		wantSerialKey string
+		wantErrMsg    string
+	}{
+		"manual secret then device selection": {
+			// Profile "work", manual entry, then pick device 1.
+			input:         "work\n1\n1\n",
+			wantSecretKey: "sesh-aws/work",
+			wantSerialKey: "sesh-aws-serial/work",
+		},
+		"custom key prefixes": {
+			input:         "work\n1\n1\n",
+			keyPrefix:     "sesh-test-aws",
+			serialPrefix:  "sesh-test-aws-serial",
+			wantSecretKey: "sesh-test-aws/work",
+			wantSerialKey: "sesh-test-aws-serial/work",
+		},
+		"invalid capture choice": {
+			input:      "work\n3\n",
+			wantErrMsg: "invalid choice",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			useTempSetupState(t)
+			kc, store := memKeychain()
+
+			handler := &AWSSetupHandler{
+				reader:           bufio.NewReader(strings.NewReader(tc.input)),
+				keychainProvider: kc,
+				KeyPrefix:        tc.keyPrefix,
+				SerialPrefix:     tc.serialPrefix,
+			}
+			handler.Configure(Options{ExistingDevice: true})
+
+			var err error
+			output := testutil.CaptureStdout(func() {
+				err = handler.Setup()
+			})
+
+			for _, unwanted := range []string{"Assign MFA device", "Generated TOTP codes"} {
+				if strings.Contains(output, unwanted) {
+					t.Errorf("output contains console walkthrough %q:\n%s", unwanted, output)
+				}
+			}
+
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("Setup() error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+			if got := store[tc.wantSecretKey]; got != secret {
+				t.Errorf("stored secret at %s = %q, want %q", tc.wantSecretKey, got, secret)
+			}
+			if got := store[tc.wantSerialKey]; got != mfaArn {
+				t.Errorf("stored serial at %s = %q, want %q", tc.wantSerialKey, got, mfaArn)
+			}
+		})
+	}
+}
+
+func TestAWSSetupHandler_SetupMFAConsole_PromptTimeout(t *testing.T) {
+	origGenerate := generateConsecutiveCodes
+	defer func() { generateConsecutiveCodes = origGenerate }()
+	generateConsecutiveCodes = func(string) (string, string, error) { return "123456", "654321", nil }
+
+	tests := map[string]struct {
+		input   io.Reader
+		timeout time.Duration
+		wantErr error
+	}{
+		"unanswered confirmation times out": {
+			input:   blockedReader(t),
+			timeout: 10 * time.Millisecond,
+			wantErr: prompt.ErrTimeout,
+		},
+		"answered confirmation is within the timeout": {
+			input:   strings.NewReader("\n"),
+			timeout: time.Minute,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			handler := &AWSSetupHandler{reader: bufio.NewReader(tc.input)}
+			handler.Configure(Options{PromptTimeout: tc.timeout})
+
+			var err error
+			testutil.CaptureStdout(func() {
+				err = handler.setupMFAConsole("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+			})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("setupMFAConsole() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// blockedReader returns a reader with no input that never reaches EOF
+// until the test ends, like a terminal nobody is typing into.
+func blockedReader(t *testing.T) io.Reader {
+	t.Helper()
+	r, w := io.Pipe()
+	t.Cleanup(func() { _ = w.Close() })
+	return r
+}
+
+func TestAWSSetupHandler_Setup_NoConsoleWait(t *testing.T) {
+	origLookPath := execLookPath
+	defer func() { execLookPath = origLookPath }()
+	origRunCommand := runCommand
+	defer func() { runCommand = origRunCommand }()
+	origGetUser := getCurrentUser
+	defer func() { getCurrentUser = origGetUser }()
+	origReadPassword := readPassword
+	defer func() { readPassword = origReadPassword }()
+	origSleep := timeSleep
+	defer func() { timeSleep = origSleep }()
+
+	const (
+		secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+		first  = "arn:aws:iam::123456789012:mfa/work"
+		second = "arn:aws:iam::123456789012:mfa/backup"
+	)
+	execLookPath = func(string) (string, error) { return "/usr/local/bin/aws", nil }
+	getCurrentUser = func() (string, error) { return "testuser", nil }
+	readPassword = func(int) ([]byte, error) { return []byte(secret), nil }
+	timeSleep = func(time.Duration) { t.Error("--no-console-wait should not sleep and retry") }
+
+	tests := map[string]struct {
+		devices    string // list-mfa-devices output
+		input      string
+		wantSerial string
+		wantErrMsg string
+	}{
+		"single device is used without prompting": {
+			devices:    first + "\n",
+			input:      "work\n1\n",
+			wantSerial: first,
+		},
+		"several devices still ask which one": {
+			devices:    first + "\t" + second + "\n",
+			input:      "work\n1\n2\n",
+			wantSerial: second,
+		},
+		"no devices is an error, not a retry loop": {
+			devices:    "\n",
+			input:      "work\n1\n",
+			wantErrMsg: "no MFA devices found",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			useTempSetupState(t)
+			kc, store := memKeychain()
+			runCommand = func(name string, args ...string) ([]byte, error) {
+				switch args[0] {
+				case "sts":
+					return []byte("arn:aws:iam::123456789012:user/test\n"), nil
+				case "iam":
+					return []byte(tc.devices), nil
+				}
+				return nil, errors.New("unexpected command")
+			}
+
+			handler := &AWSSetupHandler{
+				reader:           bufio.NewReader(strings.NewReader(tc.input)),
+				keychainProvider: kc,
+			}
+			handler.Configure(Options{NoConsoleWait: true})
+
+			var err error
+			output := testutil.CaptureStdout(func() {
+				err = handler.Setup()
+			})
+
+			if !strings.Contains(output, "Generated TOTP codes") {
+				t.Errorf("output is missing the codes for the console:\n%s", output)
+			}
+			if strings.Contains(output, "Press Enter ONLY AFTER") {
+				t.Errorf("output still waits for console confirmation:\n%s", output)
+			}
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("Setup() error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+			if got := store["sesh-aws-serial/work"]; got != tc.wantSerial {
+				t.Errorf("stored serial = %q, want %q", got, tc.wantSerial)
+			}
+		})
+	}
+}
+
+func TestAWSSetupHandler_Setup_MFAAccountMismatch(t *testing.T) {
+	origLookPath := execLookPath
+	defer func() { execLookPath = origLookPath }()
+	origRunCommand := runCommand
+	defer func() { runCommand = origRunCommand }()
+	origGetUser := getCurrentUser
+	defer func() { getCurrentUser = origGetUser }()
+	origReadPassword := readPassword
+	defer func() { readPassword = origReadPassword }()
+
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	execLookPath = func(string) (string, error) { return "/usr/local/bin/aws", nil }
+	getCurrentUser = func() (string, error) { return "testuser", nil }
+	runCommand = func(name string, args ...string) ([]byte, error) {
+		switch args[0] {
+		case "sts":
+			return []byte("arn:aws:iam::123456789012:user/test\n"), nil
+		case "iam":
+			return []byte("arn:aws:iam::123456789012:mfa/test\n"), nil
+		}
+		return nil, errors.New("unexpected command")
+	}
+	readPassword = func(int) ([]byte, error) { return []byte(secret), nil }
+
+	tests := map[string]struct {
+		mfaArn     string
+		wantErrMsg string
+	}{
+		"matching account": {
+			mfaArn: "arn:aws:iam::123456789012:mfa/work",
+		},
+		"mismatched account": {
+			mfaArn:     "arn:aws:iam::210987654321:mfa/work",
+			wantErrMsg: "that MFA ARN belongs to account 210987654321 but you're authenticated as account 123456789012",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			useTempSetupState(t)
+			kc, store := memKeychain()
+
+			// Profile "work", manual secret, then enter the MFA ARN by hand.
+			handler := &AWSSetupHandler{
+				reader:           bufio.NewReader(strings.NewReader("work\n1\nm\n" + tc.mfaArn + "\n")),
+				keychainProvider: kc,
+			}
+			handler.Configure(Options{ExistingDevice: true})
+
+			var err error
+			testutil.CaptureStdout(func() {
+				err = handler.Setup()
+			})
+
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("Setup() error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				for key := range store {
+					if strings.HasPrefix(key, "sesh-aws") {
+						t.Errorf("stored %s despite the account mismatch", key)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+			if got := store["sesh-aws-serial/work"]; got != tc.mfaArn {
+				t.Errorf("stored serial = %q, want %q", got, tc.mfaArn)
+			}
+		})
+	}
+}
+
+func TestReadSecret(t *testing.T) {
+	origReadPassword := readPassword
+	defer func() { readPassword = origReadPassword }()
+
+	tests := map[string]struct {
+		readErr    error
+		password   string
+		input      string
+		want       string
+		wantErr    error
+		wantErrMsg string
+	}{
+		"terminal read": {
+			password: "JBSWY3DPEHPK3PXP",
+			want:     "JBSWY3DPEHPK3PXP",
+		},
+		"non-TTY falls back to piped line": {
+			readErr: syscall.ENOTTY,
+			input:   "JBSWY3DPEHPK3PXP\r\n",
+			want:    "JBSWY3DPEHPK3PXP",
+		},
+		"non-TTY without trailing newline": {
+			readErr: syscall.ENOTTY,
+			input:   "JBSWY3DPEHPK3PXP",
+			want:    "JBSWY3DPEHPK3PXP",
+		},
+		"non-TTY with no input": {
+			readErr: syscall.ENOTTY,
+			wantErr: errSecretNotTTY,
+		},
+		"other errors pass through": {
+			readErr:    io.ErrUnexpectedEOF,
+			input:      "JBSWY3DPEHPK3PXP\n",
+			wantErrMsg: io.ErrUnexpectedEOF.Error(),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			readPassword = func(int) ([]byte, error) {
+				if tc.readErr != nil {
+					return nil, tc.readErr
+				}
+				return []byte(tc.password), nil
+			}
+
+			got, err := readSecret(bufio.NewReader(strings.NewReader(tc.input)))
+			switch {
+			case tc.wantErr != nil:
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("readSecret() error = %v, want %v", err, tc.wantErr)
+				}
+				return
+			case tc.wantErrMsg != "":
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("readSecret() error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readSecret() error = %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("readSecret() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTOTPSetupHandler_captureManualEntry_NonTTY(t *testing.T) {
+	origReadPassword := readPassword
+	defer func() { readPassword = origReadPassword }()
+	readPassword = func(int) ([]byte, error) { return nil, syscall.ENOTTY }
+
+	tests := map[string]struct {
+		input      string
+		want       string
+		wantErrMsg string
+	}{
+		"piped secret is read": {
+			input: "JBSWY3DPEHPK3PXP\n",
+			want:  "JBSWY3DPEHPK3PXP",
+		},
+		"empty stdin gives an actionable error": {
+			wantErrMsg: "stdin is not a terminal",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := &TOTPSetupHandler{reader: bufio.NewReader(strings.NewReader(tc.input))}
+
+			var (
+				got string
+				err error
+			)
+			_ = testutil.CaptureStdout(func() {
+				got, err = h.captureManualEntry()
+			})
+
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("captureManualEntry() error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("captureManualEntry() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("captureManualEntry() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTOTPSetupHandler_Setup_ShowURI(t *testing.T) {
+	origGenerate := generateConsecutiveCodes
+	defer func() { generateConsecutiveCodes = origGenerate }()
+	origGetUser := getCurrentUser
+	defer func() { getCurrentUser = origGetUser }()
+	origTTY := stdoutIsTerminal
+	defer func() { stdoutIsTerminal = origTTY }()
+
+	generateConsecutiveCodes = func(s string) (string, string, error) {
+		return "123456", "654321", nil
+	}
+	getCurrentUser = func() (string, error) { return "testuser", nil }
+	t.Setenv("SESH_TEST_TOTP_SECRET", "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+
+	const uri = "otpauth://totp/MyService:work?issuer=MyService&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	tests := map[string]struct {
+		opts        Options
+		tty         bool
+		wantURI     bool
+		wantRefused bool
+	}{
+		"terminal":             {opts: Options{ShowURI: true}, tty: true, wantURI: true},
+		"pipe is refused":      {opts: Options{ShowURI: true}, wantRefused: true},
+		"pipe with --force":    {opts: Options{ShowURI: true, Force: true}, wantURI: true},
+		"flag not set":         {tty: true},
+		"force alone is inert": {opts: Options{Force: true}, tty: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			stdoutIsTerminal = func() bool { return tc.tty }
+
+			handler := &TOTPSetupHandler{
+				reader: bufio.NewReader(strings.NewReader("MyService\nwork\n\n")),
+				keychainProvider: &mocks.MockProvider{
+					SetSecretStringFunc: func(_, _, _ string) error { return nil },
+					SetDescriptionFunc:  func(_, _, _ string) error { return nil },
+				},
+			}
+			tc.opts.SecretEnv = "SESH_TEST_TOTP_SECRET"
+			handler.Configure(tc.opts)
+
+			var err error
+			output := testutil.CaptureStdout(func() {
+				err = handler.Setup()
+			})
+			if err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+			if got := strings.Contains(output, uri); got != tc.wantURI {
+				t.Errorf("URI printed = %v, want %v; output:\n%s", got, tc.wantURI, output)
+			}
+			if got := strings.Contains(output, "--show-uri: not printing the secret"); got != tc.wantRefused {
+				t.Errorf("refusal shown = %v, want %v; output:\n%s", got, tc.wantRefused, output)
+			}
+		})
+	}
+}
+
+func TestTOTPSetupHandler_Setup_Issuer(t *testing.T) {
+	origGenerate := generateConsecutiveCodes
+	defer func() { generateConsecutiveCodes = origGenerate }()
+	origGetUser := getCurrentUser
+	defer func() { getCurrentUser = origGetUser }()
+
+	generateConsecutiveCodes = func(s string) (string, string, error) {
+		return "123456", "654321", nil
+	}
+	getCurrentUser = func() (string, error) { return "testuser", nil }
+	t.Setenv("SESH_TEST_TOTP_SECRET", "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+
+	tests := map[string]struct {
+		issuer   string
+		url      string
+		descErr  error
+		wantDesc string
+	}{
+		"issuer stored in metadata": {
+			issuer:   "GitHub",
+			wantDesc: `{"issuer":"GitHub"}`,
+		},
+		"login url stored in metadata": {
+			issuer:   "GitHub",
+			url:      "https://github.com/login",
+			wantDesc: `{"issuer":"GitHub","url":"https://github.com/login"}`,
+		},
+		"login url alone is not load-bearing": {
+			url:      "https://github.com/login",
+			descErr:  errors.New("metadata write failed"),
+			wantDesc: `{"url":"https://github.com/login"}`,
+		},
+		"no issuer keeps the plain label": {
+			wantDesc: "TOTP for MyService",
+		},
+		"issuer only is not load-bearing": {
+			issuer:   "GitHub",
+			descErr:  errors.New("metadata write failed"),
+			wantDesc: `{"issuer":"GitHub"}`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var gotDesc string
+			handler := &TOTPSetupHandler{
+				reader: bufio.NewReader(strings.NewReader("MyService\n\n")),
+				keychainProvider: &mocks.MockProvider{
+					SetSecretStringFunc: func(_, _, _ string) error { return nil },
+					SetDescriptionFunc: func(_, _, desc string) error {
+						gotDesc = desc
+						return tc.descErr
+					},
+				},
+			}
+			handler.Configure(Options{SecretEnv: "SESH_TEST_TOTP_SECRET", Issuer: tc.issuer, URL: tc.url})
+
+			var err error
+			testutil.CaptureStdout(func() {
+				err = handler.Setup()
+			})
+			if err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+			if gotDesc != tc.wantDesc {
+				t.Errorf("description = %q, want %q", gotDesc, tc.wantDesc)
+			}
+		})
+	}
+}
+
+func TestTOTPSetupHandler_Setup_CopyFirstCode(t *testing.T) {
+	origGenerate := generateConsecutiveCodes
+	defer func() { generateConsecutiveCodes = origGenerate }()
+	origGetUser := getCurrentUser
+	defer func() { getCurrentUser = origGetUser }()
+	origCopy := clipboardCopy
+	defer func() { clipboardCopy = origCopy }()
+
+	generateConsecutiveCodes = func(s string) (string, string, error) {
+		return "123456", "654321", nil
+	}
+	getCurrentUser = func() (string, error) { return "testuser", nil }
+	t.Setenv("SESH_TEST_TOTP_SECRET", "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+
+	tests := map[string]struct {
+		opts        Options
+		wantCopied  string
+		wantTimeout time.Duration
+	}{
+		"flag set": {
+			opts:        Options{CopyFirstCode: true, ClipTimeout: 10 * time.Second},
+			wantCopied:  "123456",
+			wantTimeout: 10 * time.Second,
+		},
+		"default timeout": {
+			opts:        Options{CopyFirstCode: true},
+			wantCopied:  "123456",
+			wantTimeout: 30 * time.Second,
+		},
+		"clip two copies both codes in order": {
+			opts:        Options{ClipTwo: true},
+			wantCopied:  "123456 654321",
+			wantTimeout: 30 * time.Second,
+		},
+		"flag not set": {},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var copied string
+			var timeout time.Duration
+			clipboardCopy = func(text string, d time.Duration) error {
+				copied, timeout = text, d
+				return nil
+			}
+
+			handler := &TOTPSetupHandler{
+				reader: bufio.NewReader(strings.NewReader("MyService\n\n")),
+				keychainProvider: &mocks.MockProvider{
+					SetSecretStringFunc: func(_, _, _ string) error { return nil },
+					SetDescriptionFunc:  func(_, _, _ string) error { return nil },
+				},
+			}
+			tc.opts.SecretEnv = "SESH_TEST_TOTP_SECRET"
+			handler.Configure(tc.opts)
+
+			var err error
+			output := testutil.CaptureStdout(func() {
+				err = handler.Setup()
+			})
+			if err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+			if copied != tc.wantCopied {
+				t.Errorf("clipboard got %q, want %q", copied, tc.wantCopied)
+			}
+			if timeout != tc.wantTimeout {
+				t.Errorf("clipboard timeout = %s, want %s", timeout, tc.wantTimeout)
+			}
+			if gotNote := strings.Contains(output, "copied to clipboard (clears in"); gotNote != (tc.wantCopied != "") {
+				t.Errorf("copy note shown = %v, want %v; output:\n%s", gotNote, tc.wantCopied != "", output)
+			}
+		})
+	}
+}
+
+func TestTOTPSetupHandler_Setup_QRImage(t *testing.T) {
+	origGenerate := generateConsecutiveCodes
+	defer func() { generateConsecutiveCodes = origGenerate }()
+	origGetUser := getCurrentUser
+	defer func() { getCurrentUser = origGetUser }()
+	origScan := scanQRCodeFull
+	defer func() { scanQRCodeFull = origScan }()
+
+	generateConsecutiveCodes = func(s string) (string, string, error) {
+		return "123456", "654321", nil
+	}
+	getCurrentUser = func() (string, error) { return "testuser", nil }
+	scanQRCodeFull = func() (qrcode.TOTPInfo, error) {
+		t.Fatal("--qr-image must not fall back to screen capture")
+		return qrcode.TOTPInfo{}, nil
+	}
+
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	bitMatrix, err := gozxingQR.NewQRCodeWriter().Encode(
+		"otpauth://totp/Example:alice?secret="+secret+"&issuer=Example&digits=8",
+		gozxing.BarcodeFormat_QR_CODE, 250, 250, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var qrPNG bytes.Buffer
+	if err := png.Encode(&qrPNG, bitMatrix); err != nil {
+		t.Fatal(err)
+	}
+	qrFile := filepath.Join(t.TempDir(), "qr.png")
+	if err := os.WriteFile(qrFile, qrPNG.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		qrImage string
+		input   string
+	}{
+		"from a file": {
+			qrImage: qrFile,
+			input:   "MyService\n\nn\n",
+		},
+		"piped on stdin": {
+			qrImage: "-",
+			input:   "MyService\n\n" + qrPNG.String(),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var stored, description string
+			handler := &TOTPSetupHandler{
+				reader: bufio.NewReader(strings.NewReader(tc.input)),
+				keychainProvider: &mocks.MockProvider{
+					SetSecretStringFunc: func(_, _, s string) error {
+						stored = s
+						return nil
+					},
+					SetDescriptionFunc: func(_, _, d string) error {
+						description = d
+						return nil
+					},
+				},
+			}
+			handler.Configure(Options{QRImage: tc.qrImage})
+
+			var err error
+			output := testutil.CaptureStdout(func() {
+				err = handler.Setup()
+			})
+			if err != nil {
+				t.Fatalf("Setup() error = %v\noutput:\n%s", err, output)
+			}
+			if stored != secret {
+				t.Errorf("stored secret = %q, want %q", stored, secret)
+			}
+			// The QR code's non-default digits must survive into metadata.
+			if !strings.Contains(description, `"digits":8`) {
+				t.Errorf("description = %q, want the QR code's digits", description)
+			}
+			if !strings.Contains(output, "QR code decoded from image") {
+				t.Errorf("output missing decode confirmation:\n%s", output)
+			}
+		})
+	}
+}
+
+func TestTOTPSetupHandler_Setup_TimeOffset(t *testing.T) {
+	origGenerate := generateConsecutiveCodes
+	defer func() { generateConsecutiveCodes = origGenerate }()
+	origGetUser := getCurrentUser
+	defer func() { getCurrentUser = origGetUser }()
+	origNow := timeNow
+	defer func() { timeNow = origNow }()
+
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	now := time.Unix(1_699_999_995, 0)
+	generateConsecutiveCodes = func(s string) (string, string, error) {
+		return totp.GenerateConsecutiveCodesForTime(s, now)
+	}
+	getCurrentUser = func() (string, error) { return "testuser", nil }
+	timeNow = func() time.Time { return now }
+	t.Setenv("SESH_TEST_TOTP_SECRET", secret)
+
+	tests := map[string]struct {
+		offset   int
+		wantDesc string
+	}{
+		"no offset":      {wantDesc: "TOTP for MyService"},
+		"clock 60s slow": {offset: 60, wantDesc: `{"time_offset":60}`},
+		"clock 60s fast": {offset: -60, wantDesc: `{"time_offset":-60}`},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var description string
+			handler := &TOTPSetupHandler{
+				reader: bufio.NewReader(strings.NewReader("MyService\n\nn\n")),
+				keychainProvider: &mocks.MockProvider{
+					SetDescriptionFunc: func(_, _, d string) error {
+						description = d
+						return nil
+					},
+				},
+			}
+			handler.Configure(Options{SecretEnv: "SESH_TEST_TOTP_SECRET", TimeOffset: tc.offset})
+
+			var err error
+			output := testutil.CaptureStdout(func() {
+				err = handler.Setup()
+			})
+			if err != nil {
+				t.Fatalf("Setup() error = %v\noutput:\n%s", err, output)
+			}
+			if description != tc.wantDesc {
+				t.Errorf("description = %q, want %q", description, tc.wantDesc)
+			}
+
+			// The verification codes shown must be the shifted ones.
+			want, _, err := totp.GenerateConsecutiveCodesForTime(secret, now.Add(time.Duration(tc.offset)*time.Second))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(output, "Current code: "+want) {
+				t.Errorf("output missing the current code %s:\n%s", want, output)
+			}
+		})
+	}
+}
@@ -5,15 +5,73 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
+// FallbackShell is launched when $SHELL is unset or doesn't exist.
+const FallbackShell = "/bin/sh"
+
+// DefaultPromptFormat reproduces the built-in "(sesh:<provider>) " prefix.
+const DefaultPromptFormat = "(sesh:{provider}) "
+
+// PromptVars holds the values substituted into a prompt format.
+type PromptVars struct {
+	Provider string
+	Profile  string
+}
+
+// PromptShell is the shell a prompt is rendered for. zsh and bash decode
+// prompts differently, so substituted values are quoted per shell.
+type PromptShell int
+
+const (
+	// PromptBash renders for a bash PS1, which decodes backslash escapes
+	// before expanding it.
+	PromptBash PromptShell = iota
+	// PromptZsh renders for a zsh PROMPT under PROMPT_SUBST, where "%"
+	// starts a prompt escape.
+	PromptZsh
+)
+
+// RenderPrompt expands the {provider}, {profile} and {expires} placeholders
+// in format for shell. {expires} becomes a call to the sesh_remaining shell
+// helper so the remaining time is recomputed each time the prompt is drawn;
+// customizers that honor a rendered prompt must define that helper in their
+// init script. The provider and profile are quoted so the shell shows them
+// literally instead of expanding anything in them.
+func RenderPrompt(format string, vars PromptVars, shell PromptShell) string {
+	profile := vars.Profile
+	if profile == "" {
+		profile = "default"
+	}
+	return strings.NewReplacer(
+		"{provider}", quotePromptValue(vars.Provider, shell),
+		"{profile}", quotePromptValue(profile, shell),
+		"{expires}", "$(sesh_remaining)",
+	).Replace(format)
+}
+
+// quotePromptValue backslash-escapes the characters that would expand in
+// a prompt: "$", "`" and "\". bash then decodes the prompt's own backslash
+// escapes before expanding it, so each backslash is doubled again; zsh
+// needs "%" doubled instead.
+func quotePromptValue(s string, shell PromptShell) string {
+	s = strings.NewReplacer(`\`, `\\`, "$", `\$`, "`", "\\`").Replace(s)
+	if shell == PromptZsh {
+		return strings.ReplaceAll(s, "%", "%%")
+	}
+	return strings.ReplaceAll(s, `\`, `\\`)
+}
+
 // Config holds the parameters needed to launch an authenticated subshell.
 type Config struct {
 	Expiry          time.Time
 	ShellCustomizer ShellCustomizer
 	Variables       map[string]string
 	ServiceName     string
+	// Shell overrides $SHELL as the shell to launch when set.
+	Shell string
 }
 
 // ShellCustomizer provides shell-specific init scripts and prompt configuration.
@@ -40,29 +98,14 @@ func GetShellConfig(config Config) (*ShellConfig, error) {
 		return nil, fmt.Errorf("shell customizer is required")
 	}
 
-	env := os.Environ()
+	env := BuildEnv(config)
 
-	for key, value := range config.Variables {
-		env = FilterEnv(env, key)
-		env = append(env, fmt.Sprintf("%s=%s", key, value))
-	}
-
-	now := time.Now().Unix()
-	env = append(env, "SESH_ACTIVE=1",
-		fmt.Sprintf("SESH_SERVICE=%s", config.ServiceName),
-		"SESH_DISABLE_INTEGRATION=1",
-		fmt.Sprintf("SESH_START_TIME=%d", now),
-	)
-	if !config.Expiry.IsZero() {
-		env = append(env,
-			fmt.Sprintf("SESH_EXPIRY=%d", config.Expiry.Unix()),
-			fmt.Sprintf("SESH_TOTAL_DURATION=%d", config.Expiry.Unix()-now),
-		)
+	shell := config.Shell
+	if shell == "" {
+		shell = os.Getenv("SHELL")
 	}
-
-	shell := os.Getenv("SHELL")
 	if shell == "" {
-		shell = "/bin/sh"
+		shell = FallbackShell
 	}
 
 	var shellArgs []string
@@ -116,6 +159,32 @@ func GetShellConfig(config Config) (*ShellConfig, error) {
 	}, nil
 }
 
+// BuildEnv returns the current environment with the credential variables
+// and the SESH_* session markers applied. It is shared by the interactive
+// subshell and one-off commands run with credentials.
+func BuildEnv(config Config) []string {
+	env := os.Environ()
+
+	for key, value := range config.Variables {
+		env = FilterEnv(env, key)
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	now := time.Now().Unix()
+	env = append(env, "SESH_ACTIVE=1",
+		fmt.Sprintf("SESH_SERVICE=%s", config.ServiceName),
+		"SESH_DISABLE_INTEGRATION=1",
+		fmt.Sprintf("SESH_START_TIME=%d", now),
+	)
+	if !config.Expiry.IsZero() {
+		env = append(env,
+			fmt.Sprintf("SESH_EXPIRY=%d", config.Expiry.Unix()),
+			fmt.Sprintf("SESH_TOTAL_DURATION=%d", config.Expiry.Unix()-now),
+		)
+	}
+	return env
+}
+
 // SetupZshShell creates a temporary ZDOTDIR with a custom .zshrc for the subshell.
 func SetupZshShell(config Config, env []string) ([]string, string, error) {
 	// Create a temporary ZDOTDIR for zsh
@@ -478,3 +478,53 @@ func TestSetupFallbackShellCustomPrefix(t *testing.T) {
 		t.Error("Expected PS1 with custom prompt prefix 'myapp'")
 	}
 }
+
+func TestRenderPrompt(t *testing.T) {
+	tests := map[string]struct {
+		format string
+		vars   PromptVars
+		shell  PromptShell
+		want   string
+	}{
+		"default format": {
+			format: DefaultPromptFormat,
+			vars:   PromptVars{Provider: "aws", Profile: "dev"},
+			want:   "(sesh:aws) ",
+		},
+		"profile and expires": {
+			format: "(aws:{profile} {expires}) ",
+			vars:   PromptVars{Provider: "aws", Profile: "prod"},
+			want:   "(aws:prod $(sesh_remaining)) ",
+		},
+		"empty profile renders as default": {
+			format: "[{provider}/{profile}] ",
+			vars:   PromptVars{Provider: "aws"},
+			want:   "[aws/default] ",
+		},
+		"unknown placeholders are left alone": {
+			format: "{provider} {region} ",
+			vars:   PromptVars{Provider: "aws"},
+			want:   "aws {region} ",
+		},
+		"bash profile is quoted": {
+			format: "({profile} {expires}) ",
+			vars:   PromptVars{Provider: "aws", Profile: `$(touch x)` + "`id`" + `\u!%`},
+			shell:  PromptBash,
+			want:   `(\\$(touch x)\\` + "`id\\\\`" + `\\\\u!% $(sesh_remaining)) `,
+		},
+		"zsh profile is quoted": {
+			format: "({profile} {expires}) ",
+			vars:   PromptVars{Provider: "aws", Profile: `$(touch x)` + "`id`" + `\u!%`},
+			shell:  PromptZsh,
+			want:   `(\$(touch x)\` + "`id\\`" + `\\u!%% $(sesh_remaining)) `,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := RenderPrompt(tc.format, tc.vars, tc.shell); got != tc.want {
+				t.Errorf("RenderPrompt(%q) = %q, want %q", tc.format, got, tc.want)
+			}
+		})
+	}
+}
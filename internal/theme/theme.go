@@ -0,0 +1,86 @@
+// Package theme holds the status symbols sesh prints in front of success,
+// error, warning and info messages, so they can be swapped for plain ASCII or
+// Nerd Font glyphs.
+package theme
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Theme is a set of status symbols.
+type Theme struct {
+	Name    string
+	OK      string
+	Error   string
+	Warning string
+	Info    string
+}
+
+// The built-in themes. The emoji warning and info signs render one cell
+// wide in most terminals, so they carry an extra space to line up with the
+// others.
+var (
+	Emoji = Theme{Name: "emoji", OK: "✅", Error: "❌", Warning: "⚠️ ", Info: "ℹ️ "}
+	ASCII = Theme{Name: "ascii", OK: "[OK]", Error: "[ERROR]", Warning: "[WARN]", Info: "[INFO]"}
+	// Nerd uses Font Awesome glyphs from Nerd Fonts (nf-fa-check,
+	// nf-fa-times, nf-fa-warning, nf-fa-info_circle), which only render in
+	// a patched font.
+	Nerd = Theme{Name: "nerd", OK: "\uf00c", Error: "\uf00d", Warning: "\uf071", Info: "\uf05a"}
+)
+
+var themes = map[string]Theme{
+	Emoji.Name: Emoji,
+	ASCII.Name: ASCII,
+	Nerd.Name:  Nerd,
+}
+
+// current is the theme the package-level accessors read. It is set once
+// at startup, before any output.
+var current = Emoji
+
+// Parse returns the built-in theme called name.
+func Parse(name string) (Theme, error) {
+	t, ok := themes[name]
+	if !ok {
+		names := make([]string, 0, len(themes))
+		for n := range themes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return Theme{}, fmt.Errorf("unknown theme %q (valid: %s)", name, strings.Join(names, ", "))
+	}
+	return t, nil
+}
+
+// Select picks the theme for a run: name if one was given, otherwise emoji
+// on a terminal and ascii when output is redirected, where emoji tend to
+// end up as mojibake in logs.
+func Select(name string, terminal bool) (Theme, error) {
+	if name != "" {
+		return Parse(name)
+	}
+	if terminal {
+		return Emoji, nil
+	}
+	return ASCII, nil
+}
+
+// Set makes t the current theme.
+func Set(t Theme) { current = t }
+
+// Current returns the current theme.
+func Current() Theme { return current }
+
+// OK returns the current success symbol.
+func OK() string { return current.OK }
+
+// Error returns the current error symbol.
+func Error() string { return current.Error }
+
+// Warning returns the current warning symbol.
+func Warning() string { return current.Warning }
+
+// Info returns the current info symbol.
+func Info() string { return current.Info }
@@ -0,0 +1,72 @@
+package theme
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestThemes_RenderStatusLine(t *testing.T) {
+	tests := map[string]struct {
+		theme Theme
+		want  string
+	}{
+		"emoji": {
+			theme: Emoji,
+			want:  "✅ TOTP code copied | ❌ no entry found | ⚠️  clipboard unavailable | ℹ️  no metadata",
+		},
+		"ascii": {
+			theme: ASCII,
+			want:  "[OK] TOTP code copied | [ERROR] no entry found | [WARN] clipboard unavailable | [INFO] no metadata",
+		},
+		"nerd": {
+			theme: Nerd,
+			want:  "\uf00c TOTP code copied | \uf00d no entry found | \uf071 clipboard unavailable | \uf05a no metadata",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer Set(Current())
+			Set(tc.theme)
+
+			got := fmt.Sprintf("%s TOTP code copied | %s no entry found | %s clipboard unavailable | %s no metadata", OK(), Error(), Warning(), Info())
+			if got != tc.want {
+				t.Errorf("status line = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	tests := map[string]struct {
+		name     string
+		terminal bool
+		want     string
+		wantErr  string
+	}{
+		"default on a terminal is emoji":      {terminal: true, want: "emoji"},
+		"default when redirected is ascii":    {terminal: false, want: "ascii"},
+		"explicit emoji wins when redirected": {name: "emoji", terminal: false, want: "emoji"},
+		"explicit nerd":                       {name: "nerd", terminal: true, want: "nerd"},
+		"unknown theme":                       {name: "sparkles", terminal: true, wantErr: `unknown theme "sparkles" (valid: ascii, emoji, nerd)`},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Select(tc.name, tc.terminal)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Select() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			if got.Name != tc.want {
+				t.Errorf("Select() = %s, want %s", got.Name, tc.want)
+			}
+		})
+	}
+}
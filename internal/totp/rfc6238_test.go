@@ -0,0 +1,103 @@
+package totp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRFC6238_GenerateForTimeWithParams(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		t.Run(fmt.Sprintf("%s/%d", v.algorithm, v.unix), func(t *testing.T) {
+			params := Params{Algorithm: v.algorithm, Digits: 8}
+
+			got, err := GenerateForTimeWithParams(rfc6238Secret(v.algorithm), params, time.Unix(v.unix, 0).UTC())
+			if err != nil {
+				t.Fatalf("GenerateForTimeWithParams() error = %v", err)
+			}
+			if got != v.want {
+				t.Errorf("GenerateForTimeWithParams() = %q, want %q", got, v.want)
+			}
+		})
+	}
+}
+
+func TestRFC6238_GenerateConsecutiveCodesForTimeBytesWithParams(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		t.Run(fmt.Sprintf("%s/%d", v.algorithm, v.unix), func(t *testing.T) {
+			params := Params{Algorithm: v.algorithm, Digits: 8}
+			secret := rfc6238Secret(v.algorithm)
+			at := time.Unix(v.unix, 0).UTC()
+
+			current, next, err := GenerateConsecutiveCodesForTimeBytesWithParams([]byte(secret), params, at)
+			if err != nil {
+				t.Fatalf("GenerateConsecutiveCodesForTimeBytesWithParams() error = %v", err)
+			}
+			if current != v.want {
+				t.Errorf("current = %q, want %q", current, v.want)
+			}
+
+			wantNext, err := GenerateForTimeWithParams(secret, params, at.Add(30*time.Second))
+			if err != nil {
+				t.Fatalf("GenerateForTimeWithParams() error = %v", err)
+			}
+			if next != wantNext {
+				t.Errorf("next = %q, want %q", next, wantNext)
+			}
+		})
+	}
+}
+
+// The default (SHA1, 6 digits) paths truncate the same HOTP value, so their
+// codes are the last six digits of the RFC's eight-digit answers.
+func TestRFC6238_DefaultSHA1(t *testing.T) {
+	secret := rfc6238Secret("SHA1")
+
+	for _, v := range rfc6238Vectors {
+		if v.algorithm != "SHA1" {
+			continue
+		}
+		t.Run(fmt.Sprintf("%d", v.unix), func(t *testing.T) {
+			at := time.Unix(v.unix, 0).UTC()
+			want := v.want[2:]
+
+			got, err := GenerateForTime(secret, at)
+			if err != nil {
+				t.Fatalf("GenerateForTime() error = %v", err)
+			}
+			if got != want {
+				t.Errorf("GenerateForTime() = %q, want %q", got, want)
+			}
+
+			got, err = GenerateForTimeBytes([]byte(secret), at)
+			if err != nil {
+				t.Fatalf("GenerateForTimeBytes() error = %v", err)
+			}
+			if got != want {
+				t.Errorf("GenerateForTimeBytes() = %q, want %q", got, want)
+			}
+
+			current, _, err := GenerateConsecutiveCodesForTimeBytes([]byte(secret), at)
+			if err != nil {
+				t.Fatalf("GenerateConsecutiveCodesForTimeBytes() error = %v", err)
+			}
+			if current != want {
+				t.Errorf("GenerateConsecutiveCodesForTimeBytes() current = %q, want %q", current, want)
+			}
+		})
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	results := SelfTest()
+
+	// Two paths per vector, plus two default paths per SHA1 vector.
+	if want := 2*len(rfc6238Vectors) + 2*6; len(results) != want {
+		t.Fatalf("SelfTest() ran %d checks, want %d", len(results), want)
+	}
+	for _, r := range results {
+		if !r.Passed() {
+			t.Errorf("%s: got %q (err %v), want %q", r.Name, r.Got, r.Err, r.Want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
@@ -20,17 +21,43 @@ type Params struct {
 	Algorithm string `json:"algorithm,omitempty"` // "SHA1", "SHA256", "SHA512"
 	Digits    int    `json:"digits,omitempty"`    // 6 or 8
 	Period    int    `json:"period,omitempty"`    // seconds
+
+	// TimeOffset is added to the local clock, in seconds, before generating
+	// a code, for machines whose clock is persistently fast or slow.
+	TimeOffset int `json:"time_offset,omitempty"`
+
+	// URL is the service's login page, opened by --open. Like Issuer it
+	// is only for display and doesn't affect the codes.
+	URL string `json:"url,omitempty"`
 }
 
 // IsDefault returns true if all params are zero/default values.
 func (p Params) IsDefault() bool {
-	return p.Algorithm == "" && p.Digits == 0 && p.Period == 0
+	return p.Algorithm == "" && p.Digits == 0 && p.Period == 0 && p.TimeOffset == 0
+}
+
+// Shift returns t adjusted by the params' TimeOffset.
+func (p Params) Shift(t time.Time) time.Time {
+	return t.Add(time.Duration(p.TimeOffset) * time.Second)
+}
+
+// MaxTimeOffsetSeconds caps TimeOffset. Drift this large means the clock
+// needs fixing, not compensating, and a typo like 6000 for 60 is caught.
+const MaxTimeOffsetSeconds = 3600
+
+// ValidateTimeOffset checks that a TimeOffset is within MaxTimeOffsetSeconds
+// either way.
+func ValidateTimeOffset(seconds int) error {
+	if seconds > MaxTimeOffsetSeconds || seconds < -MaxTimeOffsetSeconds {
+		return fmt.Errorf("time offset %ds exceeds the maximum of ±%ds", seconds, MaxTimeOffsetSeconds)
+	}
+	return nil
 }
 
 // MarshalDescription returns the JSON-encoded params for storage in the entry
 // description, or "" if all values are default.
 func (p Params) MarshalDescription() string {
-	if p.IsDefault() && p.Issuer == "" {
+	if p.IsDefault() && p.Issuer == "" && p.URL == "" {
 		return ""
 	}
 	b, err := json.Marshal(p)
@@ -53,6 +80,17 @@ func ParseParams(description string) Params {
 	return p
 }
 
+// ParseAlgorithm normalizes a user-supplied algorithm name (sha1, sha256,
+// sha512, any case) to the form stored in Params.
+func ParseAlgorithm(name string) (string, error) {
+	switch upper := strings.ToUpper(strings.TrimSpace(name)); upper {
+	case "SHA1", "SHA256", "SHA512":
+		return upper, nil
+	default:
+		return "", fmt.Errorf("unsupported TOTP algorithm %q (use sha1, sha256 or sha512)", name)
+	}
+}
+
 func algorithmFromName(name string) otp.Algorithm {
 	switch strings.ToUpper(name) {
 	case "SHA256":
@@ -87,7 +125,22 @@ func validateOptsFromParams(p Params) totp.ValidateOpts {
 
 // ValidateAndNormalizeSecret validates and normalizes a base32-encoded TOTP secret.
 // It handles common formatting issues like spaces, lowercase letters, and missing padding.
+// The rules, in order: whitespace is removed, letters are uppercased, any
+// trailing "=" padding is dropped, what remains must be base32 (A-Z, 2-7),
+// and padding is added back to a multiple of 8 characters.
 func ValidateAndNormalizeSecret(secret string) (string, error) {
+	return normalizeSecret(secret, false)
+}
+
+// ValidateSecretStrict is ValidateAndNormalizeSecret for --strict: input
+// that would need fixing (whitespace, lowercase letters, or padding that
+// isn't the right length for the secret) is rejected instead. Missing
+// padding is still added, since most issuers leave it off.
+func ValidateSecretStrict(secret string) (string, error) {
+	return normalizeSecret(secret, true)
+}
+
+func normalizeSecret(secret string, strict bool) (string, error) {
 	if secret == "" {
 		return "", fmt.Errorf("secret cannot be empty")
 	}
@@ -97,27 +150,40 @@ func ValidateAndNormalizeSecret(secret string) (string, error) {
 	if cleaned == "" {
 		return "", fmt.Errorf("secret cannot be empty")
 	}
+	if strict {
+		if i := strings.IndexAny(secret, " \t\n\r"); i >= 0 {
+			return "", fmt.Errorf("whitespace at position %d - --strict requires the secret without spaces or line breaks", i)
+		}
+		if i := strings.IndexFunc(secret, unicode.IsLower); i >= 0 {
+			return "", fmt.Errorf("lowercase '%c' at position %d - --strict requires an uppercase secret", secret[i], i)
+		}
+	}
 
 	cleaned = strings.ToUpper(cleaned)
+	body := strings.TrimRight(cleaned, "=")
+	padding := len(cleaned) - len(body)
 
-	for i, char := range cleaned {
-		if (char < 'A' || char > 'Z') && (char < '2' || char > '7') && char != '=' {
-			return "", fmt.Errorf("invalid character '%c' at position %d - base32 secrets can only contain A-Z, 2-7, and =", char, i)
+	for i, char := range body {
+		if char == '=' {
+			return "", fmt.Errorf("invalid character '=' at position %d - padding may only appear at the end", i)
+		}
+		if (char < 'A' || char > 'Z') && (char < '2' || char > '7') {
+			return "", fmt.Errorf("invalid character '%c' at position %d - base32 secrets can only contain A-Z, 2-7, and trailing =", char, i)
 		}
 	}
 
 	// Check minimum length - RFC 4226 recommends 128 bits (26 base32 chars),
 	// but many providers use shorter secrets. Accept anything >= 64 bits (13 chars)
-	if len(cleaned) < 13 {
-		return "", fmt.Errorf("secret too short (%d characters) - TOTP secrets should be at least 13 characters (64 bits)", len(cleaned))
+	if len(body) < 13 {
+		return "", fmt.Errorf("secret too short (%d characters) - TOTP secrets should be at least 13 characters (64 bits)", len(body))
 	}
 
 	// Base32 requires padding to make length a multiple of 8
-	remainder := len(cleaned) % 8
-	if remainder != 0 {
-		padLength := 8 - remainder
-		cleaned += strings.Repeat("=", padLength)
+	wantPadding := (8 - len(body)%8) % 8
+	if strict && padding != 0 && padding != wantPadding {
+		return "", fmt.Errorf("secret has %d padding characters but its length needs %d - --strict requires correct padding or none", padding, wantPadding)
 	}
+	cleaned = body + strings.Repeat("=", wantPadding)
 
 	_, err := Generate(cleaned)
 	if err != nil {
@@ -287,8 +353,18 @@ const MaxTOTPPeriodSeconds = 86400
 // GenerateConsecutiveCodesBytesWithParams generates consecutive codes using non-standard
 // TOTP parameters. Falls back to defaults (6 digits, 30s, SHA1) for zero-value params.
 func GenerateConsecutiveCodesBytesWithParams(secret []byte, params Params) (current, next string, err error) {
+	return GenerateConsecutiveCodesForTimeBytesWithParams(secret, params, time.Now())
+}
+
+// GenerateConsecutiveCodesForTimeBytesWithParams is GenerateConsecutiveCodesBytesWithParams
+// for a given base time.
+func GenerateConsecutiveCodesForTimeBytesWithParams(secret []byte, params Params, baseTime time.Time) (current, next string, err error) {
+	if err := ValidateTimeOffset(params.TimeOffset); err != nil {
+		return "", "", err
+	}
+	baseTime = params.Shift(baseTime)
 	if params.IsDefault() {
-		return GenerateConsecutiveCodesBytes(secret)
+		return GenerateConsecutiveCodesForTimeBytes(secret, baseTime)
 	}
 
 	if len(secret) == 0 {
@@ -314,13 +390,12 @@ func GenerateConsecutiveCodesBytesWithParams(secret []byte, params Params) (curr
 		period = time.Duration(params.Period) * time.Second
 	}
 
-	now := time.Now()
-	current, err = totp.GenerateCodeCustom(secretStr, now, opts)
+	current, err = totp.GenerateCodeCustom(secretStr, baseTime, opts)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to generate current TOTP: %w", err)
 	}
 
-	next, err = totp.GenerateCodeCustom(secretStr, now.Add(period), opts)
+	next, err = totp.GenerateCodeCustom(secretStr, baseTime.Add(period), opts)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to generate next TOTP: %w", err)
 	}
@@ -328,6 +403,51 @@ func GenerateConsecutiveCodesBytesWithParams(secret []byte, params Params) (curr
 	return current, next, nil
 }
 
+// MatchWindow reports which time window around t, if any, produced code.
+// Windows are checked nearest first, up to skew periods either side; the
+// returned offset is 0 for the current window, -1 for the previous, +1 for
+// the next, and so on.
+func MatchWindow(secret string, params Params, code string, t time.Time, skew int) (offset int, ok bool, err error) {
+	if params.Period > MaxTOTPPeriodSeconds {
+		return 0, false, fmt.Errorf("TOTP period %d seconds exceeds maximum of %d", params.Period, MaxTOTPPeriodSeconds)
+	}
+
+	opts := validateOptsFromParams(params)
+	period := time.Duration(opts.Period) * time.Second
+	code = strings.TrimSpace(code)
+	t = params.Shift(t)
+
+	offsets := []int{0}
+	for i := 1; i <= skew; i++ {
+		offsets = append(offsets, -i, i)
+	}
+	for _, off := range offsets {
+		got, genErr := totp.GenerateCodeCustom(secret, t.Add(time.Duration(off)*period), opts)
+		if genErr != nil {
+			return 0, false, fmt.Errorf("failed to generate TOTP: %w", genErr)
+		}
+		if got == code {
+			return off, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// GenerateForTimeWithParams generates the code for time t using the given
+// params (algorithm, digits, period, time offset), with the usual defaults
+// for zero values.
+func GenerateForTimeWithParams(secret string, params Params, t time.Time) (string, error) {
+	if params.Period > MaxTOTPPeriodSeconds {
+		return "", fmt.Errorf("TOTP period %d seconds exceeds maximum of %d", params.Period, MaxTOTPPeriodSeconds)
+	}
+
+	code, err := totp.GenerateCodeCustom(secret, params.Shift(t), validateOptsFromParams(params))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate TOTP: %w", err)
+	}
+	return code, nil
+}
+
 // GenerateForTimeBytes generates a TOTP code for a specific time from a byte slice secret
 // The secret is expected to be a byte slice containing a base32-encoded string
 func GenerateForTimeBytes(secret []byte, t time.Time) (string, error) {
@@ -282,6 +282,73 @@ func TestValidateAndNormalizeSecret(t *testing.T) {
 	}
 }
 
+func TestValidateSecretStrict(t *testing.T) {
+	tests := map[string]struct {
+		input      string
+		lenient    string
+		lenientErr string
+		strictErr  string
+	}{
+		"lowercase": {
+			input:     "jbswy3dpehpk3pxp",
+			lenient:   "JBSWY3DPEHPK3PXP",
+			strictErr: "lowercase 'j' at position 0",
+		},
+		"correctly padded": {
+			input:   "JBSWY3DPEHPK3PX=",
+			lenient: "JBSWY3DPEHPK3PX=",
+		},
+		"unpadded": {
+			input:   "JBSWY3DPEHPK3PX",
+			lenient: "JBSWY3DPEHPK3PX=",
+		},
+		"excess padding": {
+			input:     "JBSWY3DPEHPK3PX===",
+			lenient:   "JBSWY3DPEHPK3PX=",
+			strictErr: "secret has 3 padding characters but its length needs 1",
+		},
+		"spaced": {
+			input:     "JBSW Y3DP EHPK 3PXP",
+			lenient:   "JBSWY3DPEHPK3PXP",
+			strictErr: "whitespace at position 4",
+		},
+		"padding in the middle": {
+			input:      "JBSWY3DP=EHPK3PXP",
+			lenientErr: "invalid character '=' at position 8",
+			strictErr:  "invalid character '=' at position 8",
+		},
+		"genuinely invalid": {
+			input:      "JBSWY3DP1HPK3PXP",
+			lenientErr: "invalid character '1'",
+			strictErr:  "invalid character '1'",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ValidateAndNormalizeSecret(tc.input)
+			if tc.lenientErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.lenientErr) {
+					t.Errorf("lenient error = %v, want %q", err, tc.lenientErr)
+				}
+			} else if err != nil || got != tc.lenient {
+				t.Errorf("lenient = %q, %v; want %q", got, err, tc.lenient)
+			}
+
+			got, err = ValidateSecretStrict(tc.input)
+			if tc.strictErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.strictErr) {
+					t.Errorf("strict error = %v, want %q", err, tc.strictErr)
+				}
+				return
+			}
+			if err != nil || got != tc.lenient {
+				t.Errorf("strict = %q, %v; want %q", got, err, tc.lenient)
+			}
+		})
+	}
+}
+
 // TestRealWorldTOTPSecrets tests with actual secrets from major providers
 func TestRealWorldTOTPSecrets(t *testing.T) {
 	tests := map[string]struct {
@@ -673,6 +740,31 @@ func TestAlgorithmFromName(t *testing.T) {
 	}
 }
 
+func TestParseAlgorithm(t *testing.T) {
+	tests := map[string]struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		"lowercase sha1":   {name: "sha1", want: "SHA1"},
+		"mixed case":       {name: "Sha256", want: "SHA256"},
+		"uppercase sha512": {name: "SHA512", want: "SHA512"},
+		"unsupported":      {name: "md5", wantErr: true},
+		"empty":            {name: "", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseAlgorithm(tc.name)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseAlgorithm(%q) error = %v, wantErr %v", tc.name, err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("ParseAlgorithm(%q) = %q, want %q", tc.name, got, tc.want)
+			}
+		})
+	}
+}
+
 func TestParams_IsDefault(t *testing.T) {
 	tests := map[string]struct {
 		p    Params
@@ -683,6 +775,7 @@ func TestParams_IsDefault(t *testing.T) {
 		"digits set":          {p: Params{Digits: 6}, want: false},
 		"period set":          {p: Params{Period: 30}, want: false},
 		"algorithm set":       {p: Params{Algorithm: "SHA256"}, want: false},
+		"time offset set":     {p: Params{TimeOffset: -60}, want: false},
 		"all non-default set": {p: Params{Algorithm: "SHA1", Digits: 6, Period: 30}, want: false},
 	}
 	for name, tc := range tests {
@@ -709,6 +802,10 @@ func TestParams_MarshalDescription(t *testing.T) {
 			p:       Params{Issuer: "Example"},
 			wantSub: `"issuer":"Example"`,
 		},
+		"url alone is serialized": {
+			p:       Params{URL: "https://example.com/login"},
+			wantSub: `"url":"https://example.com/login"`,
+		},
 		"non-default params are serialized": {
 			p:       Params{Algorithm: "SHA256", Digits: 8, Period: 60},
 			wantSub: `"algorithm":"SHA256"`,
@@ -817,6 +914,67 @@ func TestGenerateConsecutiveCodesBytesWithParams(t *testing.T) {
 	})
 }
 
+func TestGenerateConsecutiveCodesForTimeBytesWithParams_TimeOffset(t *testing.T) {
+	secret := []byte("JBSWY3DPEHPK3PXP")
+	// Mid-window, so ±60s lands two windows away rather than on a boundary.
+	now := time.Unix(1_699_999_995, 0)
+
+	plain, _, err := GenerateConsecutiveCodesForTimeBytesWithParams(secret, Params{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		offset     int
+		wantErrMsg string
+	}{
+		"clock 60s slow": {offset: 60},
+		"clock 60s fast": {offset: -60},
+		"offset above cap": {
+			offset:     MaxTimeOffsetSeconds + 1,
+			wantErrMsg: "exceeds the maximum",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			params := Params{TimeOffset: tc.offset}
+			cur, next, err := GenerateConsecutiveCodesForTimeBytesWithParams(secret, params, now)
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantCur, wantNext, err := GenerateConsecutiveCodesForTimeBytes(secret, now.Add(time.Duration(tc.offset)*time.Second))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cur != wantCur || next != wantNext {
+				t.Errorf("codes = %s/%s, want %s/%s (the unshifted generator at now%+ds)", cur, next, wantCur, wantNext, tc.offset)
+			}
+			if cur == plain {
+				t.Errorf("offset code %s equals the no-offset code", cur)
+			}
+
+			code, err := GenerateForTimeWithParams(string(secret), params, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code != cur {
+				t.Errorf("GenerateForTimeWithParams() = %s, want %s", code, cur)
+			}
+			if off, ok, err := MatchWindow(string(secret), params, cur, now, 0); err != nil || !ok || off != 0 {
+				t.Errorf("MatchWindow() = %d, %v, %v; want the current window", off, ok, err)
+			}
+		})
+	}
+}
+
 func TestValidateOptsFromParams(t *testing.T) {
 	tests := map[string]struct {
 		params     Params
@@ -902,3 +1060,62 @@ func TestValidateOptsFromParams(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchWindow(t *testing.T) {
+	secret := "JBSWY3DPEHPK3PXP"
+	now := time.Unix(1_700_000_000, 0)
+
+	codeAt := func(t *testing.T, at time.Time) string {
+		t.Helper()
+		code, err := GenerateForTime(secret, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := map[string]struct {
+		code       func(t *testing.T) string
+		skew       int
+		wantOffset int
+		wantOK     bool
+	}{
+		"current window": {
+			code:   func(t *testing.T) string { return codeAt(t, now) },
+			skew:   1,
+			wantOK: true,
+		},
+		"previous window": {
+			code:       func(t *testing.T) string { return codeAt(t, now.Add(-30*time.Second)) },
+			skew:       1,
+			wantOffset: -1,
+			wantOK:     true,
+		},
+		"next window": {
+			code:       func(t *testing.T) string { return codeAt(t, now.Add(30*time.Second)) },
+			skew:       1,
+			wantOffset: 1,
+			wantOK:     true,
+		},
+		"outside skew": {
+			code: func(t *testing.T) string { return codeAt(t, now.Add(90*time.Second)) },
+			skew: 1,
+		},
+		"wrong code": {
+			code: func(t *testing.T) string { return "000000" },
+			skew: 1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			offset, ok, err := MatchWindow(secret, Params{}, tc.code(t), now, tc.skew)
+			if err != nil {
+				t.Fatalf("MatchWindow() unexpected error: %v", err)
+			}
+			if ok != tc.wantOK || offset != tc.wantOffset {
+				t.Errorf("MatchWindow() = (%d, %v), want (%d, %v)", offset, ok, tc.wantOffset, tc.wantOK)
+			}
+		})
+	}
+}
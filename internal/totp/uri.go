@@ -0,0 +1,48 @@
+package totp
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// URI builds the otpauth://totp/ Key URI for a secret, the form
+// authenticator apps import: label "issuer:account", the secret, the issuer
+// and any non-default algorithm, digits and period. TimeOffset is
+// sesh-local and not part of the URI. Spaces are encoded as %20 in both the
+// label and the query, since some apps show a "+" in the issuer as is.
+func URI(issuer, account, secret string, params Params) string {
+	label := escapeLabelPart(account)
+	if issuer != "" {
+		label = escapeLabelPart(issuer) + ":" + label
+	}
+
+	q := url.Values{}
+	q.Set("secret", strings.ToUpper(secret))
+	if issuer != "" {
+		q.Set("issuer", issuer)
+	}
+	if params.Algorithm != "" && params.Algorithm != "SHA1" {
+		q.Set("algorithm", params.Algorithm)
+	}
+	if params.Digits != 0 && params.Digits != 6 {
+		q.Set("digits", strconv.Itoa(params.Digits))
+	}
+	if params.Period != 0 && params.Period != 30 {
+		q.Set("period", strconv.Itoa(params.Period))
+	}
+
+	// Encode escapes a literal "+" as %2B, so every "+" left is a space
+	query := strings.ReplaceAll(q.Encode(), "+", "%20")
+	return "otpauth://totp/" + label + "?" + query
+}
+
+// labelEscaper escapes what url.PathEscape leaves alone but a label half
+// can't hold as is: the colon, since the first literal colon separates
+// issuer from account, and "+", which some apps read as a space.
+var labelEscaper = strings.NewReplacer(":", "%3A", "+", "%2B")
+
+// escapeLabelPart path-escapes one half of the label.
+func escapeLabelPart(s string) string {
+	return labelEscaper.Replace(url.PathEscape(s))
+}
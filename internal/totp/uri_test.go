@@ -0,0 +1,92 @@
+package totp
+
+import (
+	"testing"
+
+	"github.com/bashhack/sesh/internal/qrcode"
+)
+
+func TestURI(t *testing.T) {
+	tests := map[string]struct {
+		issuer  string
+		account string
+		secret  string
+		params  Params
+		want    string
+	}{
+		"defaults": {
+			issuer:  "GitHub",
+			account: "alice",
+			secret:  "jbswy3dpehpk3pxp",
+			want:    "otpauth://totp/GitHub:alice?issuer=GitHub&secret=JBSWY3DPEHPK3PXP",
+		},
+		"no issuer": {
+			account: "alice",
+			secret:  "JBSWY3DPEHPK3PXP",
+			want:    "otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP",
+		},
+		"non-default params": {
+			issuer:  "Acme",
+			account: "ops",
+			secret:  "JBSWY3DPEHPK3PXP",
+			params:  Params{Algorithm: "SHA256", Digits: 8, Period: 60, TimeOffset: 5},
+			want:    "otpauth://totp/Acme:ops?algorithm=SHA256&digits=8&issuer=Acme&period=60&secret=JBSWY3DPEHPK3PXP",
+		},
+		"escaped label": {
+			issuer:  "My Co",
+			account: "a:b@x.com",
+			secret:  "JBSWY3DPEHPK3PXP",
+			want:    "otpauth://totp/My%20Co:a%3Ab@x.com?issuer=My%20Co&secret=JBSWY3DPEHPK3PXP",
+		},
+		"special characters": {
+			issuer:  "R&D + Ops",
+			account: "50% off/me?#",
+			secret:  "JBSWY3DPEHPK3PXP",
+			want:    "otpauth://totp/R&D%20%2B%20Ops:50%25%20off%2Fme%3F%23?issuer=R%26D%20%2B%20Ops&secret=JBSWY3DPEHPK3PXP",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := URI(tc.issuer, tc.account, tc.secret, tc.params); got != tc.want {
+				t.Errorf("URI() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestURI_RoundTrip(t *testing.T) {
+	tests := map[string]struct {
+		issuer  string
+		account string
+		params  Params
+	}{
+		"plain":            {issuer: "GitHub", account: "alice"},
+		"spaces":           {issuer: "My Company", account: "Alice Smith"},
+		"colons":           {issuer: "Acme: EU", account: "ops:prod"},
+		"email account":    {issuer: "Google", account: "alice+work@example.com"},
+		"special":          {issuer: "R&D = 100%", account: "a/b?c#d"},
+		"unicode":          {issuer: "Société Générale", account: "zoë"},
+		"no issuer":        {account: "alice smith"},
+		"non-default args": {issuer: "Acme Corp", account: "ops", params: Params{Algorithm: "SHA512", Digits: 8, Period: 60}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			uri := URI(tc.issuer, tc.account, "JBSWY3DPEHPK3PXP", tc.params)
+			info, err := qrcode.ExtractTOTPFullInfo(uri)
+			if err != nil {
+				t.Fatalf("ExtractTOTPFullInfo(%q) error = %v", uri, err)
+			}
+			if info.Issuer != tc.issuer || info.Account != tc.account {
+				t.Errorf("round trip of %q = issuer %q, account %q; want %q, %q", uri, info.Issuer, info.Account, tc.issuer, tc.account)
+			}
+			if info.Secret != "JBSWY3DPEHPK3PXP" {
+				t.Errorf("round trip secret = %q", info.Secret)
+			}
+			if info.Algorithm != tc.params.Algorithm || info.Digits != tc.params.Digits || info.Period != tc.params.Period {
+				t.Errorf("round trip params = %s/%d/%d, want %s/%d/%d", info.Algorithm, info.Digits, info.Period, tc.params.Algorithm, tc.params.Digits, tc.params.Period)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/bashhack/sesh/internal/provider"
+	"github.com/bashhack/sesh/internal/subshell"
+	"github.com/bashhack/sesh/internal/theme"
+)
+
+// RunCommand runs command as a child process with the provider's credentials
+// in its environment (`sesh --service aws -- terraform apply`) and returns
+// the child's exit status for sesh to exit with. The environment is built
+// the same way as the subshell's, so the child also sees the SESH_* markers.
+// With --reauth-on-expiry, a child that fails with an expired-token error
+// is run once more with fresh credentials.
+func (a *App) RunCommand(serviceName string, command []string) (int, error) {
+	if len(command) == 0 {
+		return 0, fmt.Errorf("no command given after --")
+	}
+
+	p, err := a.Registry.GetProvider(serviceName)
+	if err != nil {
+		return 0, fmt.Errorf("provider not found: %w", err)
+	}
+
+	if err := p.ValidateRequest(); err != nil {
+		return 0, err
+	}
+
+	quiet := isQuietProvider(p)
+
+	if !quiet {
+		if _, err := fmt.Fprintf(a.Stderr, "🔐 Generating credentials for %s...\n", serviceName); err != nil {
+			return 0, fmt.Errorf("failed to write to stderr: %w", err)
+		}
+	}
+	startTime := time.Now()
+
+	creds, err := p.GetCredentials()
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate credentials: %w", err)
+	}
+	if len(creds.Variables) == 0 {
+		return 0, fmt.Errorf("provider %s has no environment credentials to run a command with", serviceName)
+	}
+	a.audit(serviceName, "exec")
+
+	if !quiet {
+		elapsedTime := time.Since(startTime)
+		if _, err := fmt.Fprintf(a.Stderr, theme.OK()+" Credentials acquired in %.2fs\n", elapsedTime.Seconds()); err != nil {
+			return 0, fmt.Errorf("failed to write to stderr: %w", err)
+		}
+	}
+
+	path, err := a.ExecLookPath(command[0])
+	if err != nil {
+		return 0, fmt.Errorf("command not found: %s", command[0])
+	}
+
+	var tail *tailBuffer
+	if a.ReauthOnExpiry {
+		tail = &tailBuffer{max: expiryScanBytes}
+	}
+	code, err := a.runChild(path, command, creds, serviceName, tail)
+	if err != nil || code == 0 || tail == nil || !credentialsExpired(tail.Bytes()) {
+		return code, err
+	}
+
+	// One retry only: a command that fails the same way with fresh
+	// credentials has a different problem.
+	if _, err := fmt.Fprintf(a.Stderr, theme.Warning()+" %s exited with an expired-credentials error; re-authenticating and retrying once\n", command[0]); err != nil {
+		return 0, fmt.Errorf("failed to write to stderr: %w", err)
+	}
+	creds, err = p.GetCredentials()
+	if err != nil {
+		return 0, fmt.Errorf("failed to re-authenticate: %w", err)
+	}
+	a.audit(serviceName, "exec")
+	return a.runChild(path, command, creds, serviceName, nil)
+}
+
+// runChild runs the resolved command once with creds in its environment.
+// When tail is non-nil it also gets a copy of the child's stderr.
+func (a *App) runChild(path string, command []string, creds provider.Credentials, serviceName string, tail *tailBuffer) (int, error) {
+	cmd := exec.Command(path, command[1:]...) //nolint:gosec // running the user's command is the point of `sesh -- cmd`
+	cmd.Stdin = a.Stdin
+	cmd.Stdout = a.Stdout
+	cmd.Stderr = a.Stderr
+	if tail != nil {
+		cmd.Stderr = io.MultiWriter(a.Stderr, tail)
+	}
+	cmd.Env = subshell.BuildEnv(subshell.Config{
+		Variables:   creds.Variables,
+		Expiry:      creds.Expiry,
+		ServiceName: serviceName,
+	})
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitStatus(exitErr), nil
+		}
+		return 0, fmt.Errorf("failed to run %s: %w", command[0], err)
+	}
+	return 0, nil
+}
+
+// expiredCredentialPatterns are what the AWS CLI and SDKs print when a
+// session token has expired mid-run.
+var expiredCredentialPatterns = [][]byte{
+	[]byte("ExpiredToken"), // also matches ExpiredTokenException
+	[]byte("security token included in the request is expired"),
+	[]byte("The provided token has expired"),
+}
+
+// expiryScanBytes is how much of the end of the child's stderr
+// --reauth-on-expiry keeps to look for an expiry error; a failing command
+// reports it last.
+const expiryScanBytes = 64 << 10
+
+// credentialsExpired reports whether stderr output says the credentials
+// had expired.
+func credentialsExpired(stderr []byte) bool {
+	for _, p := range expiredCredentialPatterns {
+		if bytes.Contains(stderr, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// tailBuffer is an io.Writer that keeps only the last max bytes written.
+type tailBuffer struct {
+	buf []byte
+	max int
+}
+
+// Write implements io.Writer
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - t.max; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// Bytes returns the kept bytes.
+func (t *tailBuffer) Bytes() []byte {
+	return t.buf
+}
+
+// exitStatus maps a child's exit to the status a shell would report:
+// its exit code, or 128+signal if it was killed by a signal.
+func exitStatus(exitErr *exec.ExitError) int {
+	if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	if code := exitErr.ExitCode(); code > 0 {
+		return code
+	}
+	return 1
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bashhack/sesh/internal/provider"
+)
+
+func newExecTestApp(vars map[string]string) (*App, *bytes.Buffer, *bytes.Buffer) {
+	registry := provider.NewRegistry()
+	registry.RegisterProvider(&MockProvider{
+		NameFunc: func() string { return "mock" },
+		GetCredentialsFunc: func() (provider.Credentials, error) {
+			return provider.Credentials{
+				Provider:  "mock",
+				Expiry:    time.Now().Add(time.Hour),
+				Variables: vars,
+			}, nil
+		},
+	})
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	return &App{
+		Registry:     registry,
+		ExecLookPath: exec.LookPath,
+		Exit:         func(int) {},
+		TimeNow:      time.Now,
+		Stdin:        bytes.NewReader(nil),
+		Stdout:       stdout,
+		Stderr:       stderr,
+	}, stdout, stderr
+}
+
+func TestApp_RunCommand(t *testing.T) {
+	tests := map[string]struct {
+		vars       map[string]string
+		command    []string
+		wantCode   int
+		wantOut    string
+		wantErrMsg string
+	}{
+		"child sees credential env vars": {
+			vars:    map[string]string{"SESH_TEST_TOKEN": "abc123"},
+			command: []string{"sh", "-c", `echo "$SESH_TEST_TOKEN $SESH_SERVICE $SESH_ACTIVE"`},
+			wantOut: "abc123 mock 1\n",
+		},
+		"exit code propagates": {
+			vars:     map[string]string{"SESH_TEST_TOKEN": "abc123"},
+			command:  []string{"sh", "-c", "exit 7"},
+			wantCode: 7,
+		},
+		"signal maps to 128+n": {
+			vars:     map[string]string{"SESH_TEST_TOKEN": "abc123"},
+			command:  []string{"sh", "-c", "kill -TERM $$"},
+			wantCode: 128 + 15,
+		},
+		"no command": {
+			vars:       map[string]string{"SESH_TEST_TOKEN": "abc123"},
+			wantErrMsg: "no command given",
+		},
+		"unknown command": {
+			vars:       map[string]string{"SESH_TEST_TOKEN": "abc123"},
+			command:    []string{"sesh-no-such-command-xyz"},
+			wantErrMsg: "command not found",
+		},
+		"provider without env credentials": {
+			vars:       map[string]string{},
+			command:    []string{"true"},
+			wantErrMsg: "no environment credentials",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			app, stdout, _ := newExecTestApp(tc.vars)
+
+			code, err := app.RunCommand("mock", tc.command)
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("RunCommand() error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RunCommand() error = %v", err)
+			}
+			if code != tc.wantCode {
+				t.Errorf("RunCommand() code = %d, want %d", code, tc.wantCode)
+			}
+			if tc.wantOut != "" && stdout.String() != tc.wantOut {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tc.wantOut)
+			}
+		})
+	}
+}
+
+func TestRun_CommandAfterDashes(t *testing.T) {
+	app, stdout, stderr := newExecTestApp(map[string]string{"SESH_TEST_TOKEN": "abc123"})
+	exitCode := -1
+	app.Exit = func(code int) { exitCode = code }
+
+	// --json after -- belongs to the child, not sesh.
+	run(app, []string{"sesh", "--service", "mock", "--", "sh", "-c", `echo "$SESH_TEST_TOKEN $1"; exit 4`, "sh", "--json"})
+
+	if exitCode != 4 {
+		t.Fatalf("exit code = %d, want 4 (stderr: %s)", exitCode, stderr.String())
+	}
+	if got, want := stdout.String(), "abc123 --json\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+	if app.JSONOutput {
+		t.Error("--json after -- should not enable JSON output")
+	}
+}
+
+func TestCommandAfterDashes(t *testing.T) {
+	tests := map[string]struct {
+		args    []string
+		rest    []string
+		want    []string
+		wantDsh bool
+	}{
+		"command after dashes":      {args: []string{"--service", "aws", "--", "ls", "-l"}, rest: []string{"ls", "-l"}, want: []string{"ls", "-l"}, wantDsh: true},
+		"dashes without command":    {args: []string{"--service", "aws", "--"}, rest: []string{}, want: []string{}, wantDsh: true},
+		"no dashes":                 {args: []string{"--service", "aws"}, rest: []string{}},
+		"positional without dashes": {args: []string{"--service", "aws", "ls"}, rest: []string{"ls"}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := commandAfterDashes(tc.args, tc.rest)
+			if ok != tc.wantDsh || strings.Join(got, " ") != strings.Join(tc.want, " ") {
+				t.Errorf("commandAfterDashes() = (%v, %v), want (%v, %v)", got, ok, tc.want, tc.wantDsh)
+			}
+		})
+	}
+}
+
+func TestApp_RunCommand_ReauthOnExpiry(t *testing.T) {
+	// Fails like the AWS CLI while it has the first token, succeeds with any other
+	const script = `if [ "$SESH_TEST_TOKEN" = t1 ]; then
+  echo "An error occurred (ExpiredToken) when calling the ListBuckets operation" >&2
+  exit 254
+fi
+echo "ok $SESH_TEST_TOKEN"`
+
+	tests := map[string]struct {
+		reauth    bool
+		command   []string
+		wantCode  int
+		wantOut   string
+		wantCalls int
+		wantRetry bool
+	}{
+		"expired token is retried with fresh credentials": {
+			reauth:    true,
+			command:   []string{"sh", "-c", script},
+			wantOut:   "ok t2\n",
+			wantCalls: 2,
+			wantRetry: true,
+		},
+		"without the flag the failure stands": {
+			command:   []string{"sh", "-c", script},
+			wantCode:  254,
+			wantCalls: 1,
+		},
+		"other failures are not retried": {
+			reauth:    true,
+			command:   []string{"sh", "-c", "echo 'AccessDenied' >&2; exit 3"},
+			wantCode:  3,
+			wantCalls: 1,
+		},
+		"only one retry": {
+			reauth:    true,
+			command:   []string{"sh", "-c", "echo 'ExpiredTokenException' >&2; exit 255"},
+			wantCode:  255,
+			wantCalls: 2,
+			wantRetry: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			registry := provider.NewRegistry()
+			registry.RegisterProvider(&MockProvider{
+				NameFunc: func() string { return "mock" },
+				GetCredentialsFunc: func() (provider.Credentials, error) {
+					calls++
+					return provider.Credentials{
+						Provider:  "mock",
+						Expiry:    time.Now().Add(time.Hour),
+						Variables: map[string]string{"SESH_TEST_TOKEN": fmt.Sprintf("t%d", calls)},
+					}, nil
+				},
+			})
+			stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+			app := &App{
+				Registry:       registry,
+				ExecLookPath:   exec.LookPath,
+				Exit:           func(int) {},
+				TimeNow:        time.Now,
+				Stdin:          bytes.NewReader(nil),
+				Stdout:         stdout,
+				Stderr:         stderr,
+				ReauthOnExpiry: tc.reauth,
+			}
+
+			code, err := app.RunCommand("mock", tc.command)
+			if err != nil {
+				t.Fatalf("RunCommand() error = %v", err)
+			}
+			if code != tc.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tc.wantCode)
+			}
+			if stdout.String() != tc.wantOut {
+				t.Errorf("stdout = %q, want %q", stdout.String(), tc.wantOut)
+			}
+			if calls != tc.wantCalls {
+				t.Errorf("GetCredentials called %d times, want %d", calls, tc.wantCalls)
+			}
+			if got := strings.Contains(stderr.String(), "retrying once"); got != tc.wantRetry {
+				t.Errorf("retry notice shown = %v, want %v; stderr:\n%s", got, tc.wantRetry, stderr.String())
+			}
+		})
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	tb := &tailBuffer{max: 8}
+	for _, s := range []string{"abc", "defgh", "ijk"} {
+		if n, err := tb.Write([]byte(s)); n != len(s) || err != nil {
+			t.Fatalf("Write(%q) = %d, %v", s, n, err)
+		}
+	}
+	if got := string(tb.Bytes()); got != "defghijk" {
+		t.Errorf("Bytes() = %q, want %q", got, "defghijk")
+	}
+}
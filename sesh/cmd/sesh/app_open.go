@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"runtime"
+
+	"github.com/bashhack/sesh/internal/provider"
+	"github.com/bashhack/sesh/internal/theme"
+)
+
+// startBrowser runs the browser opener at path on loginURL without
+// waiting for it. It is a variable so we can swap it out in tests.
+var startBrowser = func(path, loginURL string) error {
+	cmd := exec.Command(path, loginURL) //nolint:gosec // path is the OS's URL opener found on PATH
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// The opener hands off to the browser and exits; reap it in the background
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
+
+// validateLoginURL checks that a login URL is an absolute http(s) URL, so
+// --open never hands the opener a file path or another scheme. It runs on
+// --url at setup and again on the stored value before opening it.
+func validateLoginURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", raw)
+	}
+	return nil
+}
+
+// browserCommand returns the command that opens a URL in the default
+// browser on goos, or "" where sesh doesn't know one.
+func browserCommand(goos string) string {
+	switch goos {
+	case "darwin":
+		return "open"
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return "xdg-open"
+	}
+	return ""
+}
+
+// OpenLogin implements --open: it copies the current code like --clip,
+// then opens the entry's login page in the browser. The URL is resolved
+// before anything is copied, so a missing one fails fast. Without a
+// browser opener the URL is printed instead.
+func (a *App) OpenLogin(serviceName string) error {
+	p, err := a.Registry.GetProvider(serviceName)
+	if err != nil {
+		return fmt.Errorf("provider not found: %w", err)
+	}
+	lp, ok := p.(provider.LoginURLProvider)
+	if !ok {
+		return provider.UsageError("--open is not supported by the %s provider", serviceName)
+	}
+
+	if err := p.ValidateRequest(); err != nil {
+		return err
+	}
+	loginURL, err := lp.LoginURL()
+	if err != nil {
+		return err
+	}
+	// The stored URL may predate the --url check or have been edited since
+	if err := validateLoginURL(loginURL); err != nil {
+		return fmt.Errorf("refusing to open the login URL: %w", err)
+	}
+
+	if err := a.CopyToClipboard(serviceName); err != nil {
+		return err
+	}
+
+	opener := browserCommand(runtime.GOOS)
+	if opener == "" {
+		return a.printLoginURL(fmt.Sprintf("don't know how to open a browser on %s", runtime.GOOS), loginURL)
+	}
+	path, err := a.ExecLookPath(opener)
+	if err != nil {
+		return a.printLoginURL(fmt.Sprintf("%s not found on PATH", opener), loginURL)
+	}
+	if err := startBrowser(path, loginURL); err != nil {
+		return a.printLoginURL(fmt.Sprintf("failed to run %s: %v", opener, err), loginURL)
+	}
+
+	if _, err := fmt.Fprintf(a.Stderr, theme.OK()+" Opened %s\n", loginURL); err != nil {
+		return fmt.Errorf("failed to write to stderr: %w", err)
+	}
+	return nil
+}
+
+// printLoginURL explains why the browser wasn't opened and prints loginURL for
+// the user to open by hand. The code is already on the clipboard, so this
+// isn't an error.
+func (a *App) printLoginURL(reason, loginURL string) error {
+	if _, err := fmt.Fprintf(a.Stderr, theme.Warning()+" Not opening the browser: %s\n", reason); err != nil {
+		return fmt.Errorf("failed to write to stderr: %w", err)
+	}
+	if _, err := fmt.Fprintf(a.Stderr, theme.Info()+" Log in at %s\n", loginURL); err != nil {
+		return fmt.Errorf("failed to write to stderr: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bashhack/sesh/internal/provider"
+)
+
+// loginMockProvider is a MockProvider with a login page, like the TOTP
+// provider.
+type loginMockProvider struct {
+	MockProvider
+	loginURL string
+	err      error
+}
+
+func (l *loginMockProvider) LoginURL() (string, error) { return l.loginURL, l.err }
+
+func TestApp_OpenLogin(t *testing.T) {
+	opener := browserCommand(runtime.GOOS)
+	if opener == "" {
+		t.Skipf("no browser opener on %s", runtime.GOOS)
+	}
+
+	mock := MockProvider{
+		NameFunc:            func() string { return "totp" },
+		ValidateRequestFunc: func() error { return nil },
+		GetClipboardValueFunc: func() (provider.Credentials, error) {
+			return provider.Credentials{CopyValue: "123456", ClipboardDescription: "TOTP code"}, nil
+		},
+	}
+
+	tests := map[string]struct {
+		provider    provider.ServiceProvider
+		lookPathErr error
+		startErr    error
+		wantErr     string
+		wantCopied  string
+		wantOpened  string
+		wantStderr  []string
+	}{
+		"copies the code and opens the url": {
+			provider:   &loginMockProvider{MockProvider: mock, loginURL: "https://github.com/login"},
+			wantCopied: "123456",
+			wantOpened: "/usr/bin/" + opener + " https://github.com/login",
+			wantStderr: []string{"TOTP code copied to clipboard", "Opened https://github.com/login"},
+		},
+		"unresolved url copies nothing": {
+			provider: &loginMockProvider{MockProvider: mock, err: errors.New("no login URL known for intranet")},
+			wantErr:  "no login URL known for intranet",
+		},
+		"stored non-http url is refused before copying": {
+			provider: &loginMockProvider{MockProvider: mock, loginURL: "file:///etc/passwd"},
+			wantErr:  "refusing to open the login URL",
+		},
+		"provider without login pages": {
+			provider: &mock,
+			wantErr:  "--open is not supported by the totp provider",
+		},
+		"missing opener prints the url": {
+			provider:    &loginMockProvider{MockProvider: mock, loginURL: "https://github.com/login"},
+			lookPathErr: errors.New("not found"),
+			wantCopied:  "123456",
+			wantStderr:  []string{opener + " not found on PATH", "Log in at https://github.com/login"},
+		},
+		"failed launch prints the url": {
+			provider:   &loginMockProvider{MockProvider: mock, loginURL: "https://github.com/login"},
+			startErr:   errors.New("exec format error"),
+			wantCopied: "123456",
+			wantStderr: []string{"failed to run " + opener + ": exec format error", "Log in at https://github.com/login"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			origStart := startBrowser
+			defer func() { startBrowser = origStart }()
+			var opened string
+			startBrowser = func(path, loginURL string) error {
+				opened = path + " " + loginURL
+				return tc.startErr
+			}
+
+			var copied string
+			stderr := &bytes.Buffer{}
+			app := &App{
+				Registry: provider.NewRegistry(),
+				ExecLookPath: func(file string) (string, error) {
+					if tc.lookPathErr != nil {
+						return "", tc.lookPathErr
+					}
+					return "/usr/bin/" + file, nil
+				},
+				ClipboardCopy: func(text string) error {
+					copied = text
+					return nil
+				},
+				Stdout: &bytes.Buffer{},
+				Stderr: stderr,
+			}
+			app.Registry.RegisterProvider(tc.provider)
+
+			err := app.OpenLogin("totp")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("OpenLogin() error = %v, want containing %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("OpenLogin() error = %v", err)
+			}
+			if copied != tc.wantCopied {
+				t.Errorf("copied %q, want %q", copied, tc.wantCopied)
+			}
+			if tc.startErr == nil && opened != tc.wantOpened {
+				t.Errorf("opened %q, want %q", opened, tc.wantOpened)
+			}
+			for _, want := range tc.wantStderr {
+				if !strings.Contains(stderr.String(), want) {
+					t.Errorf("stderr = %q, want it to contain %q", stderr.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestBrowserCommand(t *testing.T) {
+	tests := map[string]struct {
+		goos string
+		want string
+	}{
+		"macOS":   {goos: "darwin", want: "open"},
+		"linux":   {goos: "linux", want: "xdg-open"},
+		"freebsd": {goos: "freebsd", want: "xdg-open"},
+		"windows": {goos: "windows", want: ""},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := browserCommand(tc.goos); got != tc.want {
+				t.Errorf("browserCommand(%q) = %q, want %q", tc.goos, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateLoginURL(t *testing.T) {
+	tests := map[string]struct {
+		raw     string
+		wantErr bool
+	}{
+		"https":        {raw: "https://github.com/login"},
+		"http":         {raw: "http://intranet.example.com/sso"},
+		"no scheme":    {raw: "github.com/login", wantErr: true},
+		"file url":     {raw: "file:///etc/passwd", wantErr: true},
+		"other scheme": {raw: "javascript:alert(1)", wantErr: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateLoginURL(tc.raw)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("validateLoginURL(%q) error = %v, wantErr %v", tc.raw, err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bashhack/sesh/internal/keychain"
+	"github.com/bashhack/sesh/internal/provider"
+	"github.com/bashhack/sesh/internal/theme"
+)
+
+// SessionStatus queries a provider's current session state without fetching
+// credentials. Providers that don't implement provider.SessionStatusProvider
+// return provider.ErrNotSupported.
+func (a *App) SessionStatus(serviceName string) (bool, time.Time, error) {
+	p, err := a.Registry.GetProvider(serviceName)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("provider not found: %w", err)
+	}
+
+	sp, ok := p.(provider.SessionStatusProvider)
+	if !ok {
+		return false, time.Time{}, &provider.Error{
+			Code: provider.CodeNotSupported,
+			Msg:  fmt.Sprintf("session status is not supported by the %s provider", serviceName),
+		}
+	}
+	return sp.SessionStatus()
+}
+
+// ShowStatus prints whether the provider has an active session.
+func (a *App) ShowStatus(serviceName string) error {
+	active, expiry, err := a.SessionStatus(serviceName)
+	if err != nil {
+		return err
+	}
+
+	line := a.statusLine(serviceName, active, expiry)
+	if _, err := fmt.Fprintln(a.Stdout, line); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// statusLine describes a provider's session state for humans.
+func (a *App) statusLine(serviceName string, active bool, expiry time.Time) string {
+	switch {
+	case active && expiry.IsZero():
+		return fmt.Sprintf(theme.OK()+" Active %s session (expiry unknown)", serviceName)
+	case active:
+		remaining := expiry.Sub(a.TimeNow()).Round(time.Second)
+		return fmt.Sprintf(theme.OK()+" Active %s session, expires at %s (%s left)",
+			serviceName, expiry.Local().Format("2006-01-02 15:04:05"), remaining)
+	case !expiry.IsZero():
+		return fmt.Sprintf(theme.Warning()+" %s session expired at %s", serviceName, expiry.Local().Format("2006-01-02 15:04:05"))
+	default:
+		return fmt.Sprintf("No active %s session", serviceName)
+	}
+}
+
+// ProviderStatus is one provider's section of `--status --all`.
+type ProviderStatus struct {
+	Provider string                   `json:"provider"`
+	Entries  []provider.ProviderEntry `json:"entries"`
+	// Session is nil for providers without sessions.
+	Session *SessionInfo `json:"session"`
+	// Error holds a failure listing entries or querying the session. The
+	// rest of the report is still produced.
+	Error string `json:"error,omitempty"`
+}
+
+// SessionInfo is a provider's session state as reported by SessionStatus.
+type SessionInfo struct {
+	Active bool       `json:"active"`
+	Expiry *time.Time `json:"expiry,omitempty"`
+}
+
+// statusParallelism bounds how many providers AllStatus queries at once.
+// It is a variable so we can swap it out in tests.
+var statusParallelism = 4
+
+// AllStatus collects the entries, and session state where supported, of
+// every registered provider, sorted by provider name. A failing provider
+// is reported in its Error field rather than aborting the whole report.
+// Providers are queried concurrently, after one shared keychain unlock.
+func (a *App) AllStatus() []ProviderStatus {
+	providers := a.Registry.ListProviders()
+	out := make([]ProviderStatus, len(providers))
+
+	a.unlockKeychain()
+	forEachBounded(len(providers), statusParallelism, func(i int) {
+		out[i] = providerStatus(providers[i])
+	})
+	return out
+}
+
+// unlockKeychain makes one cheap read of the shared keychain, so that a
+// locked store prompts once here rather than once per concurrent provider
+// call. Errors are left for the providers' own reads to report.
+func (a *App) unlockKeychain() {
+	if a.Keychain == nil {
+		return
+	}
+	_, _ = a.Keychain.List(keychain.EntryFilter{ServiceType: "sesh-unlock"})
+}
+
+// forEachBounded calls fn(0) through fn(n-1) on at most limit goroutines
+// at a time and returns once all calls have.
+func forEachBounded(n, limit int, fn func(i int)) {
+	sem := make(chan struct{}, max(limit, 1))
+	var wg sync.WaitGroup
+	for i := range n {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			fn(i)
+		})
+	}
+	wg.Wait()
+}
+
+// providerStatus is one provider's ProviderStatus.
+func providerStatus(p provider.ServiceProvider) ProviderStatus {
+	st := ProviderStatus{Provider: p.Name(), Entries: []provider.ProviderEntry{}}
+
+	entries, err := p.ListEntries()
+	if err != nil {
+		st.Error = fmt.Sprintf("failed to list entries: %v", err)
+	} else if entries != nil {
+		st.Entries = entries
+	}
+
+	if sp, ok := p.(provider.SessionStatusProvider); ok {
+		active, expiry, err := sp.SessionStatus()
+		switch {
+		case err != nil && st.Error == "":
+			st.Error = fmt.Sprintf("failed to query session: %v", err)
+		case err == nil:
+			st.Session = &SessionInfo{Active: active}
+			if !expiry.IsZero() {
+				st.Session.Expiry = &expiry
+			}
+		}
+	}
+
+	return st
+}
+
+// ShowAllStatus prints AllStatus as a single JSON document under --json,
+// or one summary line per provider otherwise.
+func (a *App) ShowAllStatus() error {
+	statuses := a.AllStatus()
+
+	if a.JSONOutput {
+		if err := json.NewEncoder(a.Stdout).Encode(statuses); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		return nil
+	}
+
+	for _, st := range statuses {
+		line := fmt.Sprintf("%-10s %d entries", st.Provider, len(st.Entries))
+		switch {
+		case st.Error != "":
+			line += "  " + theme.Error() + " " + st.Error
+		case st.Session != nil:
+			var expiry time.Time
+			if st.Session.Expiry != nil {
+				expiry = *st.Session.Expiry
+			}
+			line += "  " + a.statusLine(st.Provider, st.Session.Active, expiry)
+		}
+		if _, err := fmt.Fprintln(a.Stdout, line); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+	return nil
+}
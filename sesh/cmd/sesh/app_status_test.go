@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bashhack/sesh/internal/keychain"
+	"github.com/bashhack/sesh/internal/keychain/mocks"
+	"github.com/bashhack/sesh/internal/provider"
+)
+
+func TestRun_Status(t *testing.T) {
+	now := time.Now()
+
+	tests := map[string]struct {
+		token   string
+		expiry  string
+		wantOut string
+	}{
+		"active": {
+			token:   "token",
+			expiry:  strconv.FormatInt(now.Add(time.Hour).Unix(), 10),
+			wantOut: "✅ Active aws session, expires at",
+		},
+		"expired": {
+			token:   "token",
+			expiry:  strconv.FormatInt(now.Add(-time.Hour).Unix(), 10),
+			wantOut: "⚠️  aws session expired at",
+		},
+		"absent": {
+			wantOut: "No active aws session",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("AWS_SESSION_TOKEN", tc.token)
+			t.Setenv("SESH_SERVICE", "aws")
+			t.Setenv("SESH_EXPIRY", tc.expiry)
+
+			h := newTestHarness()
+			exitCode := -1
+			h.app.Exit = func(code int) { exitCode = code }
+
+			run(h.app, []string{"sesh", "--service", "aws", "--status"})
+
+			if exitCode != -1 {
+				t.Fatalf("unexpected exit %d, stderr: %s", exitCode, h.stderr.String())
+			}
+			if !strings.HasPrefix(h.stdout.String(), tc.wantOut) {
+				t.Errorf("stdout = %q, want prefix %q", h.stdout.String(), tc.wantOut)
+			}
+		})
+	}
+}
+
+func TestApp_SessionStatus_NotSupported(t *testing.T) {
+	registry := provider.NewRegistry()
+	registry.RegisterProvider(&MockProvider{NameFunc: func() string { return "mock" }})
+	app := &App{Registry: registry}
+
+	_, _, err := app.SessionStatus("mock")
+	if !errors.Is(err, provider.ErrNotSupported) {
+		t.Errorf("SessionStatus() error = %v, want ErrNotSupported", err)
+	}
+}
+
+// sessionMockProvider adds provider.SessionStatusProvider to MockProvider.
+type sessionMockProvider struct {
+	MockProvider
+	SessionStatusFunc func() (bool, time.Time, error)
+}
+
+func (m *sessionMockProvider) SessionStatus() (bool, time.Time, error) {
+	return m.SessionStatusFunc()
+}
+
+func TestApp_ShowAllStatus_JSON(t *testing.T) {
+	expiry := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	registry := provider.NewRegistry()
+	registry.RegisterProvider(&sessionMockProvider{
+		MockProvider: MockProvider{
+			NameFunc: func() string { return "aws" },
+			ListEntriesFunc: func() ([]provider.ProviderEntry, error) {
+				return []provider.ProviderEntry{{Name: "default", ID: "sesh-aws/default", Type: "aws"}}, nil
+			},
+		},
+		SessionStatusFunc: func() (bool, time.Time, error) { return true, expiry, nil },
+	})
+	registry.RegisterProvider(&MockProvider{
+		NameFunc: func() string { return "totp" },
+		ListEntriesFunc: func() ([]provider.ProviderEntry, error) {
+			return nil, nil
+		},
+	})
+	registry.RegisterProvider(&MockProvider{
+		NameFunc: func() string { return "password" },
+		ListEntriesFunc: func() ([]provider.ProviderEntry, error) {
+			return nil, errors.New("store locked")
+		},
+	})
+
+	h := newTestHarness()
+	h.app.Registry = registry
+	h.app.JSONOutput = true
+
+	if err := h.app.ShowAllStatus(); err != nil {
+		t.Fatalf("ShowAllStatus() error = %v", err)
+	}
+
+	var got []map[string]json.RawMessage
+	if err := json.Unmarshal(h.stdout.Bytes(), &got); err != nil {
+		t.Fatalf("output is not a JSON array: %v (raw %q)", err, h.stdout.String())
+	}
+
+	want := map[string]struct {
+		entries string
+		session string
+		errMsg  string
+	}{
+		"aws":      {entries: `[{"name":"default","description":"","id":"sesh-aws/default","type":"aws"}]`, session: `{"active":true,"expiry":"2026-01-02T03:04:05Z"}`},
+		"password": {entries: `[]`, session: `null`, errMsg: "store locked"},
+		"totp":     {entries: `[]`, session: `null`},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d providers, want %d: %s", len(got), len(want), h.stdout.String())
+	}
+	for i, name := range []string{"aws", "password", "totp"} {
+		st := got[i]
+		if p := string(st["provider"]); p != strconv.Quote(name) {
+			t.Fatalf("provider[%d] = %s, want %q (sorted by name)", i, p, name)
+		}
+		w := want[name]
+		if e := string(st["entries"]); e != w.entries {
+			t.Errorf("%s entries = %s, want %s", name, e, w.entries)
+		}
+		if s, ok := st["session"]; !ok || string(s) != w.session {
+			t.Errorf("%s session = %s (present %v), want %s", name, s, ok, w.session)
+		}
+		e, hasErr := st["error"]
+		if hasErr != (w.errMsg != "") || !strings.Contains(string(e), w.errMsg) {
+			t.Errorf("%s error = %s, want %q", name, e, w.errMsg)
+		}
+	}
+}
+
+func TestRun_StatusAll(t *testing.T) {
+	h := newTestHarness()
+	exitCode := -1
+	h.app.Exit = func(code int) { exitCode = code }
+
+	run(h.app, []string{"sesh", "--status", "--all", "--json"})
+
+	if exitCode != -1 {
+		t.Fatalf("unexpected exit %d, stderr: %s", exitCode, h.stderr.String())
+	}
+	var got []ProviderStatus
+	if err := json.Unmarshal(h.stdout.Bytes(), &got); err != nil {
+		t.Fatalf("output is not a JSON array: %v (raw %q)", err, h.stdout.String())
+	}
+	if len(got) != len(h.app.Registry.ListProviders()) {
+		t.Errorf("got %d providers, want one per registered provider", len(got))
+	}
+}
+
+func TestApp_AllStatus_ConcurrentSharedUnlock(t *testing.T) {
+	orig := statusParallelism
+	defer func() { statusParallelism = orig }()
+	statusParallelism = 2
+
+	var mu sync.Mutex
+	unlocks, active, peak := 0, 0, 0
+	started := make(chan struct{}, 5)
+	release := make(chan struct{})
+
+	registry := provider.NewRegistry()
+	names := []string{"a", "b", "c", "d", "e"}
+	for _, name := range names {
+		registry.RegisterProvider(&MockProvider{
+			NameFunc: func() string { return name },
+			ListEntriesFunc: func() ([]provider.ProviderEntry, error) {
+				mu.Lock()
+				if unlocks != 1 {
+					t.Errorf("%s listed after %d unlock reads, want 1", name, unlocks)
+				}
+				active++
+				peak = max(peak, active)
+				mu.Unlock()
+
+				started <- struct{}{}
+				<-release
+
+				mu.Lock()
+				active--
+				mu.Unlock()
+				return []provider.ProviderEntry{{Name: name}}, nil
+			},
+		})
+	}
+
+	h := newTestHarness()
+	h.app.Registry = registry
+	h.app.Keychain = &mocks.MockProvider{
+		ListFunc: func(keychain.EntryFilter) ([]keychain.KeychainEntryMeta, error) {
+			mu.Lock()
+			unlocks++
+			mu.Unlock()
+			return nil, nil
+		},
+	}
+
+	done := make(chan []ProviderStatus)
+	go func() { done <- h.app.AllStatus() }()
+
+	// Two providers run at once, and no third starts while they hold
+	// the pool.
+	for range statusParallelism {
+		<-started
+	}
+	select {
+	case <-started:
+		t.Fatal("a third provider started while two were still running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+
+	statuses := <-done
+	if unlocks != 1 {
+		t.Errorf("keychain read %d times up front, want 1", unlocks)
+	}
+	if peak != statusParallelism {
+		t.Errorf("peak concurrency = %d, want %d", peak, statusParallelism)
+	}
+	for i, st := range statuses {
+		if st.Provider != names[i] || len(st.Entries) != 1 || st.Entries[0].Name != names[i] {
+			t.Errorf("statuses[%d] = %+v, want provider %q in registry order", i, st, names[i])
+		}
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"os"
 	"os/exec"
 
+	"github.com/bashhack/sesh/internal/notify"
 	"github.com/bashhack/sesh/internal/provider"
 	"github.com/bashhack/sesh/internal/subshell"
+	"github.com/bashhack/sesh/internal/theme"
 )
 
 // LaunchSubshell launches a new shell with credentials loaded
@@ -30,6 +32,7 @@ func (a *App) LaunchSubshell(serviceName string) error {
 	if err != nil {
 		return fmt.Errorf("failed to generate credentials: %w", err)
 	}
+	a.audit(serviceName, "subshell")
 
 	subshellP, ok := p.(provider.SubshellProvider)
 	if !ok {
@@ -42,6 +45,22 @@ func (a *App) LaunchSubshell(serviceName string) error {
 		return fmt.Errorf("provider %s returned invalid subshell configuration", serviceName)
 	}
 
+	shell, fellBack := a.usableShell()
+	if shell == "" {
+		// Don't throw away credentials that were just minted: print them
+		// as --no-subshell would.
+		if _, err := fmt.Fprintf(a.Stderr, theme.Warning()+" No usable shell found ($SHELL or %s); printing credentials instead\n", subshell.FallbackShell); err != nil {
+			return fmt.Errorf("failed to write to stderr: %w", err)
+		}
+		return a.PrintCredentials(&creds)
+	}
+	if fellBack {
+		if _, err := fmt.Fprintf(a.Stderr, theme.Warning()+" Shell %s not found; falling back to %s\n", os.Getenv("SHELL"), shell); err != nil {
+			return fmt.Errorf("failed to write to stderr: %w", err)
+		}
+	}
+	config.Shell = shell
+
 	shellConfig, err := subshell.GetShellConfig(config)
 	if err != nil {
 		return err
@@ -63,6 +82,11 @@ func (a *App) LaunchSubshell(serviceName string) error {
 	cmd.Stderr = os.Stderr
 	cmd.Env = shellConfig.Env
 
+	if a.Notify {
+		stop := a.watchExpiry(serviceName, shellConfig.Env)
+		defer stop()
+	}
+
 	if _, err := fmt.Fprintf(a.Stdout, "Starting secure shell with %s credentials\n", serviceName); err != nil {
 		return fmt.Errorf("failed to write to stdout: %w", err)
 	}
@@ -92,3 +116,51 @@ func (a *App) LaunchSubshell(serviceName string) error {
 
 	return nil
 }
+
+// newNotifier is a variable so we can swap it out in tests
+var newNotifier = notify.New
+
+// watchExpiry starts the --notify watcher for a subshell whose environment
+// is env and returns a func that stops it. Not being able to notify is only
+// a warning; the subshell still starts.
+func (a *App) watchExpiry(serviceName string, env []string) (stop func()) {
+	expiry, ok := notify.ExpiryFromEnv(env)
+	if !ok {
+		_, _ = fmt.Fprintf(a.Stderr, theme.Warning()+" --notify: %s credentials have no expiry to watch\n", serviceName) //nolint:errcheck // best-effort notice
+		return func() {}
+	}
+	n, err := newNotifier()
+	if err != nil {
+		_, _ = fmt.Fprintf(a.Stderr, theme.Warning()+" --notify: %v\n", err) //nolint:errcheck // best-effort notice
+		return func() {}
+	}
+
+	w := notify.NewWatcher(n, a.NotifyLead)
+	w.Now = a.TimeNow
+	done := make(chan struct{})
+	go func() {
+		if err := w.Watch(expiry, serviceName, done); err != nil {
+			_, _ = fmt.Fprintf(a.Stderr, theme.Warning()+" --notify: %v\n", err) //nolint:errcheck // best-effort notice
+		}
+	}()
+	return func() { close(done) }
+}
+
+// usableShell picks the shell to launch: $SHELL if it can be found,
+// otherwise /bin/sh (reported via fellBack). It returns "" if neither exists.
+func (a *App) usableShell() (shell string, fellBack bool) {
+	shell = os.Getenv("SHELL")
+	if shell == "" {
+		shell = subshell.FallbackShell
+	}
+	if _, err := a.ExecLookPath(shell); err == nil {
+		return shell, false
+	}
+	if shell == subshell.FallbackShell {
+		return "", false
+	}
+	if _, err := a.ExecLookPath(subshell.FallbackShell); err != nil {
+		return "", false
+	}
+	return subshell.FallbackShell, true
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/bashhack/sesh/internal/theme"
+)
+
+// auditLogEnv names the environment variable that turns on the invocation
+// audit log. Its value is the path of the log file.
+const auditLogEnv = "SESH_AUDIT_LOG"
+
+// parentProcessName returns the command name of process pid, or "" if it
+// can't be determined. It is a variable so tests can stub it out.
+var parentProcessName = func(pid int) string {
+	if comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid)); err == nil {
+		return strings.TrimSpace(string(comm))
+	}
+	// No procfs on macOS; ps reports the same name.
+	out, err := exec.Command("ps", "-o", "comm=", "-p", fmt.Sprint(pid)).Output() //nolint:gosec // pid is our own parent's
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// audit appends a line recording which process asked sesh for credentials
+// to the log at a.AuditLog, if one is configured. Only invocation metadata
+// is written, never credential values. A failure to write is reported but
+// doesn't fail the command, since the credentials were already produced.
+func (a *App) audit(serviceName, op string) {
+	if a.AuditLog == "" {
+		return
+	}
+	if err := appendAuditLine(a.AuditLog, a.auditLine(serviceName, op)); err != nil {
+		_, _ = fmt.Fprintf(a.Stderr, theme.Warning()+" Failed to write audit log: %v\n", err)
+	}
+}
+
+// auditLine formats one audit record as space-separated key=value fields.
+func (a *App) auditLine(serviceName, op string) string {
+	ppid := os.Getppid()
+	parent := parentProcessName(ppid)
+	if parent == "" {
+		parent = "unknown"
+	}
+	return fmt.Sprintf("%s op=%s service=%s pid=%d ppid=%d parent=%q\n",
+		a.TimeNow().UTC().Format(time.RFC3339), op, serviceName, os.Getpid(), ppid, parent)
+}
+
+// appendAuditLine appends line to the log at path, creating it with 0600
+// permissions. An existing log readable or writable by other users is
+// refused rather than written to.
+func appendAuditLine(path, line string) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600) //nolint:gosec // path comes from SESH_AUDIT_LOG
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close %s: %w", path, closeErr)
+		}
+	}()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", path, err)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("refusing to write %s: permissions %04o allow access by other users", path, info.Mode().Perm())
+	}
+
+	if _, err := f.WriteString(line); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bashhack/sesh/internal/provider"
+)
+
+func TestApp_GenerateCredentials_AuditLog(t *testing.T) {
+	origParent := parentProcessName
+	defer func() { parentProcessName = origParent }()
+	parentProcessName = func(int) string { return "terraform" }
+
+	now := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+	registry := provider.NewRegistry()
+	registry.RegisterProvider(&MockProvider{
+		NameFunc: func() string { return "mock" },
+		GetCredentialsFunc: func() (provider.Credentials, error) {
+			return provider.Credentials{
+				Provider:  "mock",
+				Variables: map[string]string{"MOCK_TOKEN": "s3cret-token"},
+			}, nil
+		},
+	})
+
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	if err := os.WriteFile(logPath, []byte("earlier line\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stderr := new(bytes.Buffer)
+	app := &App{
+		Registry: registry,
+		TimeNow:  func() time.Time { return now },
+		Stdout:   new(bytes.Buffer),
+		Stderr:   stderr,
+		AuditLog: logPath,
+	}
+
+	if err := app.GenerateCredentials("mock"); err != nil {
+		t.Fatalf("GenerateCredentials() error = %v", err)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 || lines[0] != "earlier line" {
+		t.Fatalf("log = %q, want the earlier line plus one appended line", data)
+	}
+
+	got := lines[1]
+	for _, want := range []string{
+		"2026-10-18T09:30:00Z",
+		"op=generate",
+		"service=mock",
+		fmt.Sprintf("pid=%d", os.Getpid()),
+		fmt.Sprintf("ppid=%d", os.Getppid()),
+		`parent="terraform"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("audit line %q missing %q", got, want)
+		}
+	}
+	if strings.Contains(got, "s3cret-token") {
+		t.Errorf("audit line leaks a credential value: %q", got)
+	}
+	if strings.Contains(stderr.String(), "audit log") {
+		t.Errorf("unexpected audit warning: %s", stderr.String())
+	}
+}
+
+func TestAppendAuditLine(t *testing.T) {
+	tests := map[string]struct {
+		existingPerm os.FileMode // 0 means the log doesn't exist yet
+		wantErrMsg   string
+	}{
+		"creates the log": {},
+		"appends to a private log": {
+			existingPerm: 0o600,
+		},
+		"refuses a world-readable log": {
+			existingPerm: 0o644,
+			wantErrMsg:   "allow access by other users",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+			if tc.existingPerm != 0 {
+				if err := os.WriteFile(path, nil, tc.existingPerm); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(path, tc.existingPerm); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := appendAuditLine(path, "line\n")
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("appendAuditLine() error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("appendAuditLine() error = %v", err)
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if perm := info.Mode().Perm(); perm != 0o600 {
+				t.Errorf("log permissions = %04o, want 0600", perm)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/bashhack/sesh/internal/aws"
+)
+
+// runDecode implements --decode, the companion to AWS --format base64: it
+// reads the encoded value from its argument, or from stdin when there is
+// none, and prints the JSON credential object it holds.
+func runDecode(app *App, args []string) error {
+	var encoded string
+	switch len(args) {
+	case 0:
+		data, err := io.ReadAll(app.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+		encoded = string(data)
+	case 1:
+		encoded = args[0]
+	default:
+		return fmt.Errorf("--decode takes one value, or reads it from stdin")
+	}
+
+	creds, err := aws.DecodeCredentials(encoded)
+	if err != nil {
+		return err
+	}
+	defer creds.ZeroSecrets()
+
+	if err := json.NewEncoder(app.Stdout).Encode(creds); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bashhack/sesh/internal/aws"
+)
+
+func TestRunDecode(t *testing.T) {
+	creds := aws.Credentials{
+		AccessKeyID:     "ASIAEXAMPLE",
+		SecretAccessKey: "secret'with\"quotes",
+		SessionToken:    "token$(x)",
+		Expiration:      "2030-01-01T00:00:00Z",
+	}
+	encoded, err := aws.EncodeCredentials(creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		args       []string
+		stdin      string
+		wantErrMsg string
+	}{
+		"value as argument": {
+			args: []string{"sesh", "--decode", encoded},
+		},
+		"value on stdin": {
+			args:  []string{"sesh", "--decode"},
+			stdin: encoded + "\n",
+		},
+		"not base64": {
+			args:       []string{"sesh", "--decode", "%%%"},
+			wantErrMsg: "not valid base64",
+		},
+		"too many values": {
+			args:       []string{"sesh", "--decode", encoded, encoded},
+			wantErrMsg: "--decode takes one value",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := newTestHarness()
+			h.app.Stdin = strings.NewReader(tc.stdin)
+			exitCode := 0
+			h.app.Exit = func(code int) { exitCode = code }
+
+			run(h.app, tc.args)
+
+			if tc.wantErrMsg != "" {
+				if exitCode == 0 || !strings.Contains(h.stderr.String(), tc.wantErrMsg) {
+					t.Fatalf("exit = %d, stderr = %q, want an error containing %q", exitCode, h.stderr.String(), tc.wantErrMsg)
+				}
+				return
+			}
+			if exitCode != 0 {
+				t.Fatalf("exit = %d, stderr = %q", exitCode, h.stderr.String())
+			}
+			var got aws.Credentials
+			if err := json.Unmarshal(h.stdout.Bytes(), &got); err != nil {
+				t.Fatalf("stdout %q is not JSON: %v", h.stdout.String(), err)
+			}
+			if got != creds {
+				t.Errorf("decoded = %+v, want %+v", got, creds)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// dirDefaultsFile is the per-directory defaults file, looked up from the
+// working directory upward, stopping at $HOME or a filesystem boundary.
+const dirDefaultsFile = ".sesh"
+
+// currentUID is a variable so we can swap it out in tests
+var currentUID = os.Getuid
+
+// dirDefaultKeys are the only keys a .sesh file may set. Anything else is
+// rejected rather than ignored, so a .sesh dropped into a shared or cloned
+// directory can't smuggle in other flags.
+var dirDefaultKeys = map[string]bool{
+	"service":      true,
+	"profile":      true,
+	"service-name": true,
+	"qr-retries":   true,
+}
+
+// DirDefaults holds the settings from the nearest .sesh file.
+type DirDefaults struct {
+	Path   string
+	Values map[string]string
+	// ServiceApplied is set when the file's service was inserted into the
+	// arguments because none was given.
+	ServiceApplied bool
+}
+
+// findDirDefaults returns the path of the nearest .sesh file at or above
+// dir, or "" if there is none. The walk ends after home, or before
+// crossing onto another filesystem, so a .sesh in a shared parent such as
+// / or a mount point isn't picked up.
+func findDirDefaults(dir, home string) (string, error) {
+	for {
+		path := filepath.Join(dir, dirDefaultsFile)
+		info, err := os.Stat(path)
+		if err == nil && info.Mode().IsRegular() {
+			return path, nil
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("stat %s: %w", path, err)
+		}
+		parent := filepath.Dir(dir)
+		if dir == home || parent == dir || !sameFilesystem(dir, parent) {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// sameFilesystem reports whether directories a and b are on the same
+// device. If either can't be read it reports false, ending the walk.
+func sameFilesystem(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	statA, okA := infoA.Sys().(*syscall.Stat_t)
+	statB, okB := infoB.Sys().(*syscall.Stat_t)
+	return okA && okB && statA.Dev == statB.Dev
+}
+
+// loadDirDefaults finds and parses the nearest .sesh file. It returns
+// empty defaults when there is none.
+func loadDirDefaults() (DirDefaults, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return DirDefaults{}, fmt.Errorf("cannot determine working directory: %w", err)
+	}
+	// Without a home directory the walk still stops at a filesystem boundary
+	home, _ := os.UserHomeDir()
+	if resolved, err := filepath.EvalSymlinks(home); err == nil {
+		home = resolved
+	}
+	path, err := findDirDefaults(cwd, home)
+	if err != nil || path == "" {
+		return DirDefaults{}, err
+	}
+	values, err := parseDirDefaults(path)
+	if err != nil {
+		return DirDefaults{}, err
+	}
+	return DirDefaults{Path: path, Values: values}, nil
+}
+
+// parseDirDefaults reads `key = value` lines from a .sesh file. Blank lines
+// and # comments are skipped; unknown keys and malformed values are errors.
+func parseDirDefaults(path string) (map[string]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	if info.Mode().Perm()&0o022 != 0 {
+		return nil, fmt.Errorf("refusing to use %s: it is writable by other users", path)
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != currentUID() {
+		return nil, fmt.Errorf("refusing to use %s: it is owned by another user", path)
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec // path is a .sesh file found from the working directory
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key = value", path, i+1)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !dirDefaultKeys[key] {
+			return nil, fmt.Errorf("%s:%d: unknown key %q (allowed: service, profile, service-name, qr-retries)", path, i+1, key)
+		}
+		if value == "" || strings.HasPrefix(value, "-") || strings.ContainsAny(value, " \t") {
+			return nil, fmt.Errorf("%s:%d: invalid value %q for %s", path, i+1, value, key)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// usesDirDefaults reports whether the command in args (including the
+// program name) reads .sesh defaults. Global commands such as --version,
+// --help and --list-services don't, so a broken .sesh can't stop them.
+func usesDirDefaults(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	action, _ := preParseGlobal(args[1:])
+	return action == actionNone && !statusAllRequested(args[1:])
+}
+
+// withService inserts the .sesh service into args (including the program
+// name) when none was given on the command line and no global command was
+// requested.
+func (d DirDefaults) withService(args []string) []string {
+	service := d.Values["service"]
+	if service == "" || !usesDirDefaults(args) || extractServiceName(args) != "" {
+		return args
+	}
+	out := make([]string, 0, len(args)+2)
+	out = append(out, args[0], "--service", service)
+	return append(out, args[1:]...)
+}
+
+// applyFlagDefaults sets the .sesh values on fs before parsing, so flags
+// given on the command line still win. Keys the selected provider doesn't
+// define (e.g. service-name for aws), and keys whose default came from an
+// environment override (envSet), are skipped. It returns the keys it set.
+func (d DirDefaults) applyFlagDefaults(fs *flag.FlagSet, envSet map[string]string) (map[string]bool, error) {
+	applied := make(map[string]bool)
+	for key, value := range d.Values {
+		if key == "service" || fs.Lookup(key) == nil {
+			continue
+		}
+		if _, ok := envSet[key]; ok {
+			continue
+		}
+		if err := fs.Set(key, value); err != nil {
+			return nil, fmt.Errorf("invalid %s in %s: %w", key, d.Path, err)
+		}
+		applied[key] = true
+	}
+	return applied, nil
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeDotSesh(t *testing.T, dir, content string, perm os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(dir, dirDefaultsFile)
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDirDefaults_NestedDirectory(t *testing.T) {
+	root := t.TempDir()
+	path := writeDotSesh(t, root, "# project defaults\nservice = totp\nservice-name = github\n", 0o600)
+	nested := filepath.Join(root, "src", "pkg")
+	if err := os.MkdirAll(nested, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(nested)
+
+	got, err := loadDirDefaults()
+	if err != nil {
+		t.Fatalf("loadDirDefaults() error = %v", err)
+	}
+	// TempDir may sit behind a symlink (e.g. /var → /private/var on macOS).
+	wantPath, _ := filepath.EvalSymlinks(path)
+	gotPath, _ := filepath.EvalSymlinks(got.Path)
+	if gotPath != wantPath {
+		t.Errorf("Path = %q, want %q", got.Path, path)
+	}
+	if got.Values["service"] != "totp" || got.Values["service-name"] != "github" {
+		t.Errorf("Values = %v, want service=totp service-name=github", got.Values)
+	}
+}
+
+func TestFindDirDefaults_StopsAtHome(t *testing.T) {
+	root := t.TempDir()
+	writeDotSesh(t, root, "service = aws\n", 0o600)
+	home := filepath.Join(root, "home")
+	project := filepath.Join(home, "project")
+	if err := os.MkdirAll(project, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		home string
+		want string
+	}{
+		"above home is not searched": {home: home, want: ""},
+		"outside home walks up":      {home: "/nonexistent-home", want: filepath.Join(root, dirDefaultsFile)},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := findDirDefaults(project, tc.home)
+			if err != nil {
+				t.Fatalf("findDirDefaults() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("findDirDefaults() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUsesDirDefaults(t *testing.T) {
+	tests := map[string]struct {
+		args []string
+		want bool
+	}{
+		"bare sesh":       {args: []string{"sesh"}, want: true},
+		"service command": {args: []string{"sesh", "--service", "aws"}, want: true},
+		"version":         {args: []string{"sesh", "--version"}},
+		"help":            {args: []string{"sesh", "--help"}},
+		"list services":   {args: []string{"sesh", "--list-services"}},
+		"status all":      {args: []string{"sesh", "--status", "--all"}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := usesDirDefaults(tc.args); got != tc.want {
+				t.Errorf("usesDirDefaults(%v) = %v, want %v", tc.args, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseDirDefaults(t *testing.T) {
+	tests := map[string]struct {
+		content      string
+		perm         os.FileMode
+		foreignOwner bool
+		want         map[string]string
+		wantErrMsg   string
+	}{
+		"known keys": {
+			content: "service=aws\nprofile = work\n",
+			perm:    0o600,
+			want:    map[string]string{"service": "aws", "profile": "work"},
+		},
+		"qr retries": {
+			content: "qr-retries = 0\n",
+			perm:    0o600,
+			want:    map[string]string{"qr-retries": "0"},
+		},
+		"unknown key": {
+			content:    "service = aws\nno-subshell = true\n",
+			perm:       0o600,
+			wantErrMsg: `unknown key "no-subshell"`,
+		},
+		"missing equals": {
+			content:    "service aws\n",
+			perm:       0o600,
+			wantErrMsg: "expected key = value",
+		},
+		"value that looks like a flag": {
+			content:    "profile = --setup\n",
+			perm:       0o600,
+			wantErrMsg: "invalid value",
+		},
+		"writable by others": {
+			content:    "service = aws\n",
+			perm:       0o666,
+			wantErrMsg: "writable by other users",
+		},
+		"owned by another user": {
+			content:      "service = aws\n",
+			perm:         0o644,
+			foreignOwner: true,
+			wantErrMsg:   "owned by another user",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := writeDotSesh(t, t.TempDir(), tc.content, tc.perm)
+			if tc.foreignOwner {
+				orig := currentUID
+				defer func() { currentUID = orig }()
+				currentUID = func() int { return os.Getuid() + 1 }
+			}
+
+			got, err := parseDirDefaults(path)
+			if tc.wantErrMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrMsg) {
+					t.Fatalf("parseDirDefaults() error = %v, want to contain %q", err, tc.wantErrMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDirDefaults() error = %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("parseDirDefaults() = %v, want %v", got, tc.want)
+			}
+			for k, v := range tc.want {
+				if got[k] != v {
+					t.Errorf("%s = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestDirDefaults_withService(t *testing.T) {
+	d := DirDefaults{Values: map[string]string{"service": "totp"}}
+
+	tests := map[string]struct {
+		args []string
+		want []string
+	}{
+		"bare sesh gets the service": {
+			args: []string{"sesh"},
+			want: []string{"sesh", "--service", "totp"},
+		},
+		"explicit service wins": {
+			args: []string{"sesh", "--service", "aws"},
+			want: []string{"sesh", "--service", "aws"},
+		},
+		"global command is left alone": {
+			args: []string{"sesh", "--version"},
+			want: []string{"sesh", "--version"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := d.withService(tc.args); !slices.Equal(got, tc.want) {
+				t.Errorf("withService(%v) = %v, want %v", tc.args, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRun_DirDefaultsPrecedence(t *testing.T) {
+	tests := map[string]struct {
+		args        []string
+		seshProfile string // SESH_PROFILE
+		wantService string
+	}{
+		".sesh values are used": {
+			args:        []string{"sesh", "--service", "totp"},
+			wantService: "sesh-totp/github/work",
+		},
+		"command-line flags override .sesh": {
+			args:        []string{"sesh", "--service", "totp", "--service-name", "gitlab", "--profile", "personal"},
+			wantService: "sesh-totp/gitlab/personal",
+		},
+		".sesh profile is used for aws": {
+			args:        []string{"sesh", "--service", "aws"},
+			wantService: "sesh-aws/work",
+		},
+		"SESH_PROFILE overrides .sesh": {
+			args:        []string{"sesh", "--service", "aws"},
+			seshProfile: "ci",
+			wantService: "sesh-aws/ci",
+		},
+		"--profile overrides SESH_PROFILE": {
+			args:        []string{"sesh", "--service", "aws", "--profile", "dev"},
+			seshProfile: "ci",
+			wantService: "sesh-aws/dev",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("SESH_PROFILE", tc.seshProfile)
+			t.Setenv("AWS_PROFILE", "")
+			h := newTestHarness()
+			h.app.DirDefaults = DirDefaults{
+				Path:   "/project/.sesh",
+				Values: map[string]string{"service": "totp", "service-name": "github", "profile": "work"},
+			}
+			var requested string
+			h.keychain.GetSecretFunc = func(_, service string) ([]byte, error) {
+				requested = service
+				return nil, errors.New("stop here")
+			}
+
+			run(h.app, tc.args)
+
+			if requested != tc.wantService {
+				t.Errorf("requested secret %q, want %q (stderr: %s)", requested, tc.wantService, h.stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// awsCLIVersion returns the first line of `aws --version`, or "" if the AWS
+// CLI isn't installed or doesn't answer. It is a variable so we can swap it
+// out in tests.
+var awsCLIVersion = func(lookPath ExecLookPathFunc) string {
+	path, err := lookPath("aws")
+	if err != nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "--version").CombinedOutput() //nolint:gosec // path is the aws CLI found on PATH
+	if err != nil {
+		return ""
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return line
+}
+
+// runDumpRedacted implements --dump-redacted: a plain-text report to paste
+// into a bug report. It only ever prints names, types, counts and
+// timestamps. Secrets are never read, and entry descriptions, IDs and the
+// values of SESH_* variables other than the backend settings are left out
+// too, since they can hold anything the user typed.
+func runDumpRedacted(app *App) error {
+	var b strings.Builder
+	var warnings []string
+
+	fmt.Fprintln(&b, "sesh diagnostic report")
+	fmt.Fprintln(&b, "(names, types, counts and timestamps only; no secrets)")
+
+	fmt.Fprintln(&b, "\n[environment]")
+	fmt.Fprintf(&b, "sesh:     %s (%s) built on %s\n", app.VersionInfo.Version, app.VersionInfo.Commit, app.VersionInfo.Date)
+	fmt.Fprintf(&b, "os/arch:  %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "go:       %s\n", runtime.Version())
+	awsVersion := awsCLIVersion(app.ExecLookPath)
+	if awsVersion == "" {
+		awsVersion = "not found"
+		warnings = append(warnings, "aws CLI not found on PATH")
+	}
+	fmt.Fprintf(&b, "aws cli:  %s\n", awsVersion)
+	fmt.Fprintf(&b, "backend:  %s\n", envOr("SESH_BACKEND", "keychain"))
+	fmt.Fprintf(&b, "key src:  %s\n", envOr("SESH_KEY_SOURCE", "keychain"))
+	fmt.Fprintf(&b, "env set:  %s\n", orNone(seshEnvNames()))
+
+	fmt.Fprintln(&b, "\n[config]")
+	if app.DirDefaults.Path == "" {
+		fmt.Fprintln(&b, ".sesh:    none")
+	} else {
+		keys := slices.Sorted(maps.Keys(app.DirDefaults.Values))
+		fmt.Fprintf(&b, ".sesh:    %s (keys: %s)\n", app.DirDefaults.Path, orNone(keys))
+	}
+	fmt.Fprintf(&b, "audit:    %t\n", app.AuditLog != "")
+
+	fmt.Fprintln(&b, "\n[entries]")
+	for _, st := range app.AllStatus() {
+		fmt.Fprintf(&b, "%s: %d entries\n", st.Provider, len(st.Entries))
+		for _, e := range st.Entries {
+			kind := e.Type
+			if e.EntryType != "" {
+				kind += "/" + e.EntryType
+			}
+			updated := "unknown"
+			if !e.UpdatedAt.IsZero() {
+				updated = e.UpdatedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(&b, "  - %s [%s] updated %s\n", e.Name, kind, updated)
+		}
+		if st.Error != "" {
+			warnings = append(warnings, st.Provider+": "+st.Error)
+		}
+	}
+
+	fmt.Fprintln(&b, "\n[warnings]")
+	if len(warnings) == 0 {
+		fmt.Fprintln(&b, "none")
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(&b, "- %s\n", w)
+	}
+
+	if _, err := fmt.Fprint(app.Stdout, b.String()); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// seshEnvNames returns the names, never the values, of the SESH_*
+// variables that are set. SESH_MASTER_PASSWORD is one of them.
+func seshEnvNames() []string {
+	var names []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, "SESH_") {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// envOr returns the value of the environment variable name, or def when it
+// is unset or empty.
+func envOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// orNone joins names with commas, or returns "none" for an empty list.
+func orNone(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	awsMocks "github.com/bashhack/sesh/internal/aws/mocks"
+	"github.com/bashhack/sesh/internal/keychain"
+	"github.com/bashhack/sesh/internal/keychain/mocks"
+	"github.com/bashhack/sesh/internal/provider"
+	awsProvider "github.com/bashhack/sesh/internal/provider/aws"
+	passwordProvider "github.com/bashhack/sesh/internal/provider/password"
+	totpProvider "github.com/bashhack/sesh/internal/provider/totp"
+	totpMocks "github.com/bashhack/sesh/internal/totp/mocks"
+)
+
+func TestRunDumpRedacted_NoSecrets(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP-not-for-bug-reports"
+
+	origAWSCLIVersion := awsCLIVersion
+	defer func() { awsCLIVersion = origAWSCLIVersion }()
+	awsCLIVersion = func(ExecLookPathFunc) string { return "aws-cli/2.15.0 Python/3.11.6" }
+
+	t.Setenv("SESH_MASTER_PASSWORD", secret)
+	t.Setenv("SESH_BACKEND", "")
+
+	updated := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	kc := &mocks.MockProvider{
+		ListEntriesFunc: func(prefix string) ([]keychain.KeychainEntry, error) {
+			all := []keychain.KeychainEntry{
+				{Service: "sesh-aws/prod", Account: "alice", Description: secret, UpdatedAt: updated},
+				{Service: "sesh-totp/github", Account: "alice", Description: secret, UpdatedAt: updated},
+				{Service: "sesh-password/password/github/alice", Account: "alice", Description: secret, UpdatedAt: updated},
+				{Service: "sesh-password/api_key/stripe", Account: "alice", Description: secret},
+			}
+			var out []keychain.KeychainEntry
+			for _, e := range all {
+				if strings.HasPrefix(e.Service, prefix) {
+					out = append(out, e)
+				}
+			}
+			return out, nil
+		},
+		GetSecretFunc:         func(string, string) ([]byte, error) { return []byte(secret), nil },
+		GetSecretStringFunc:   func(string, string) (string, error) { return secret, nil },
+		GetMFASerialBytesFunc: func(string, string) ([]byte, error) { return []byte(secret), nil },
+	}
+
+	registry := provider.NewRegistry()
+	registry.RegisterProvider(awsProvider.NewProvider(&awsMocks.MockProvider{}, kc, &totpMocks.MockProvider{}))
+	registry.RegisterProvider(totpProvider.NewProvider(kc, &totpMocks.MockProvider{}))
+	passwords := passwordProvider.NewProvider(kc)
+	passwords.User = "alice"
+	registry.RegisterProvider(passwords)
+
+	stdout := &bytes.Buffer{}
+	app := &App{
+		Registry:     registry,
+		Keychain:     kc,
+		ExecLookPath: func(string) (string, error) { return "/usr/local/bin/aws", nil },
+		TimeNow:      time.Now,
+		Stdout:       stdout,
+		Stderr:       &bytes.Buffer{},
+		VersionInfo:  VersionInfo{Version: "1.2.3", Commit: "abc123", Date: "2025-06-01"},
+		DirDefaults:  DirDefaults{Path: "/work/.sesh", Values: map[string]string{"service": "aws", "profile": "prod"}},
+	}
+
+	if err := runDumpRedacted(app); err != nil {
+		t.Fatalf("runDumpRedacted() error = %v", err)
+	}
+	out := stdout.String()
+
+	if strings.Contains(out, secret) {
+		t.Fatalf("report leaks secret material:\n%s", out)
+	}
+	for _, want := range []string{
+		"sesh:     1.2.3 (abc123)",
+		"aws cli:  aws-cli/2.15.0",
+		"SESH_MASTER_PASSWORD",
+		"/work/.sesh (keys: profile, service)",
+		"aws: 1 entries",
+		"password: 2 entries",
+		"github (alice) [password/password] updated 2025-06-01T12:00:00Z",
+		"stripe [password/api_key] updated unknown",
+		"[warnings]\nnone",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+}
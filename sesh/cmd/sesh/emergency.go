@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bashhack/sesh/internal/keychain"
+	"github.com/bashhack/sesh/internal/password"
+	"github.com/bashhack/sesh/internal/secure"
+)
+
+// emergencyPassphrase reads the passphrase of the --emergency-store
+// bundle. It is a variable so we can swap it out in tests.
+var emergencyPassphrase = func() ([]byte, error) {
+	return terminalPrompt("Emergency store passphrase: ")
+}
+
+// emergencyStorePath returns the --emergency-store value in args (without
+// the program name), or "" when it isn't given. It is read ahead of flag
+// parsing because the store wraps the credential store run() receives.
+func emergencyStorePath(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "emergency-store" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// withEmergencyStore wraps kc so reads that fail because the keychain is
+// locked are served from the encrypted export at path. The bundle is only
+// decrypted, and its passphrase only asked for, on the first such read.
+func withEmergencyStore(kc keychain.Provider, path string) *keychain.Failover {
+	return keychain.NewFailover(kc, func() (*keychain.EmergencyStore, error) {
+		return loadEmergencyStore(path)
+	}, os.Stderr)
+}
+
+// loadEmergencyStore decrypts the password export at path into memory,
+// keying each entry the way the password provider stores it.
+func loadEmergencyStore(path string) (*keychain.EmergencyStore, error) {
+	f, err := os.Open(path) //nolint:gosec // path is the user's own --emergency-store
+	if err != nil {
+		return nil, fmt.Errorf("open emergency store: %w", err)
+	}
+	defer f.Close() //nolint:errcheck // read-only
+
+	pw, err := emergencyPassphrase()
+	if err != nil {
+		return nil, fmt.Errorf("read emergency store passphrase: %w", err)
+	}
+	defer secure.SecureZeroBytes(pw)
+
+	exported, err := password.DecryptExport(f, pw)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt emergency store: %w", err)
+	}
+
+	entries := make([]keychain.EmergencyEntry, 0, len(exported))
+	for _, e := range exported {
+		service, err := password.ServiceKey(e.Service, e.Username, e.Type)
+		if err != nil {
+			return nil, fmt.Errorf("emergency store entry %q: %w", e.Service, err)
+		}
+		entries = append(entries, keychain.EmergencyEntry{
+			Service: service,
+			Secret:  []byte(e.Secret),
+		})
+	}
+	return keychain.NewEmergencyStore(entries), nil
+}
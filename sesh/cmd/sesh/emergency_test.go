@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bashhack/sesh/internal/keychain"
+	"github.com/bashhack/sesh/internal/keychain/mocks"
+	"github.com/bashhack/sesh/internal/password"
+)
+
+func TestEmergencyStorePath(t *testing.T) {
+	tests := map[string]struct {
+		args []string
+		want string
+	}{
+		"absent":        {args: []string{"--service", "password"}},
+		"separate":      {args: []string{"--service", "password", "--emergency-store", "/tmp/b.json"}, want: "/tmp/b.json"},
+		"equals":        {args: []string{"-emergency-store=/tmp/b.json", "--service", "password"}, want: "/tmp/b.json"},
+		"after dashes":  {args: []string{"--service", "aws", "--", "--emergency-store", "/tmp/b.json"}},
+		"missing value": {args: []string{"--service", "password", "--emergency-store"}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := emergencyStorePath(tc.args); got != tc.want {
+				t.Errorf("emergencyStorePath(%q) = %q, want %q", tc.args, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithEmergencyStore_FailsOverWhenLocked(t *testing.T) {
+	// Export a bundle from an in-memory password store.
+	data := map[string][]byte{}
+	source := &mocks.MockProvider{
+		SetSecretFunc: func(_, service string, secret []byte) error {
+			data[service] = bytes.Clone(secret)
+			return nil
+		},
+		GetSecretFunc: func(_, service string) ([]byte, error) {
+			return bytes.Clone(data[service]), nil
+		},
+		ListEntriesFunc: func(prefix string) ([]keychain.KeychainEntry, error) {
+			var entries []keychain.KeychainEntry
+			for svc := range data {
+				if strings.HasPrefix(svc, prefix) {
+					entries = append(entries, keychain.KeychainEntry{Service: svc, Account: "alice"})
+				}
+			}
+			return entries, nil
+		},
+	}
+	mgr := password.NewManager(source, "alice")
+	if err := mgr.StorePasswordString("github", "alice", "gh-secret", password.EntryTypePassword); err != nil {
+		t.Fatal(err)
+	}
+	var bundle bytes.Buffer
+	if _, err := mgr.ExportEncrypted(&bundle, password.ExportOptions{}, []byte("correct horse")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bundle.json")
+	if err := os.WriteFile(path, bundle.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	orig := emergencyPassphrase
+	defer func() { emergencyPassphrase = orig }()
+	prompts := 0
+	emergencyPassphrase = func() ([]byte, error) {
+		prompts++
+		return []byte("correct horse"), nil
+	}
+
+	// The live store is locked, so the same read is served from the bundle.
+	locked := &mocks.MockProvider{
+		GetSecretFunc: func(_, _ string) ([]byte, error) {
+			return nil, keychain.ErrKeychainLocked
+		},
+	}
+	kc := withEmergencyStore(locked, path)
+	defer kc.Close()
+
+	got, err := password.NewManager(kc, "alice").GetPasswordString("github", "alice", password.EntryTypePassword)
+	if err != nil {
+		t.Fatalf("GetPasswordString() error = %v", err)
+	}
+	if got != "gh-secret" {
+		t.Errorf("GetPasswordString() = %q, want %q", got, "gh-secret")
+	}
+	if _, err := kc.GetSecret("", "sesh-totp/missing"); !errors.Is(err, keychain.ErrNotFound) {
+		t.Errorf("GetSecret() for an entry not in the bundle error = %v, want ErrNotFound", err)
+	}
+	if prompts != 1 {
+		t.Errorf("asked for the passphrase %d times, want 1", prompts)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -17,8 +19,13 @@ import (
 	"github.com/bashhack/sesh/internal/database"
 	"github.com/bashhack/sesh/internal/keychain"
 	"github.com/bashhack/sesh/internal/migration"
+	"github.com/bashhack/sesh/internal/notify"
 	"github.com/bashhack/sesh/internal/provider"
+	awsProvider "github.com/bashhack/sesh/internal/provider/aws"
 	"github.com/bashhack/sesh/internal/secure"
+	"github.com/bashhack/sesh/internal/setup"
+	"github.com/bashhack/sesh/internal/theme"
+	"github.com/bashhack/sesh/internal/totp"
 )
 
 // Version information (set by ldflags during build)
@@ -36,20 +43,35 @@ func main() {
 		Date:    date,
 	}
 
+	// A .sesh file in this directory or above can supply the service, so
+	// apply it before deciding whether the command needs the store.
+	dirDefaults, err := loadDirDefaults()
+	if err != nil {
+		if usesDirDefaults(os.Args) {
+			fmt.Fprintf(os.Stderr, theme.Error()+" %v\n", err)
+			os.Exit(1)
+		}
+		// Commands that don't read the defaults run without them
+		fmt.Fprintf(os.Stderr, theme.Warning()+" Ignoring .sesh defaults: %v\n", err)
+		dirDefaults = DirDefaults{}
+	}
+	args := dirDefaults.withService(os.Args)
+	dirDefaults.ServiceApplied = len(args) != len(os.Args)
+
 	// Only open the credential store if the command will actually use it.
-	// --version, --help, --list-services, and --migrate either just print
-	// information or open their own store internally. Skipping buildProvider
+	// --version, --help, --list-services, --decode, --selftest, --migrate and
+	// --migrate-legacy either just print information or open their own
+	// store internally. Skipping buildProvider
 	// here means SESH_BACKEND=sqlite doesn't pointlessly open the DB (or
 	// acquire the key-init flock on first run) for those commands.
 	var (
 		kc     keychain.Provider
 		closer io.Closer
 	)
-	if needsCredentialStore(os.Args) {
-		var err error
+	if needsCredentialStore(args) {
 		kc, closer, err = buildProvider()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			fmt.Fprintf(os.Stderr, theme.Error()+" %v\n", err)
 			os.Exit(1)
 		}
 		if closer != nil {
@@ -59,35 +81,68 @@ func main() {
 				}
 			}()
 		}
+		if path := emergencyStorePath(args[1:]); path != "" && extractServiceName(args) == "password" {
+			failover := withEmergencyStore(kc, path)
+			defer failover.Close()
+			kc = failover
+		}
 	} else {
 		kc = noopCredentialStore{}
 	}
 
 	app := NewDefaultApp(versionInfo, kc)
-	run(app, os.Args)
+	app.DirDefaults = dirDefaults
+	run(app, args)
 }
 
 // needsCredentialStore reports whether the given command-line invocation
 // will touch the credential store. Commands that just print information
-// (--help/--version/--list-services) or open their own store internally
-// (--migrate) return false.
+// (--help/--version/--list-services/--status/--print-config) or open their
+// own store internally (--migrate) return false; --dump-redacted needs it
+// to list entry names.
 func needsCredentialStore(args []string) bool {
 	if len(args) <= 1 {
 		return false
 	}
+	if action, _ := preParseGlobal(args[1:]); action != actionNone {
+		// --dump-redacted lists every provider's entry names
+		return action == actionDumpRedacted
+	}
 	for _, a := range args[1:] {
-		switch a {
-		case "--help", "-help", "-h",
-			"--version", "-version",
-			"--list-services", "-list-services",
-			"--migrate", "-migrate",
-			"--rekey", "-rekey":
+		if a == "--" {
+			break
+		}
+		if a == "--status" || a == "-status" {
+			// --status --all also lists every provider's entries.
+			return statusAllRequested(args[1:])
+		}
+		if a == "--print-config" || a == "-print-config" {
 			return false
 		}
 	}
 	return true
 }
 
+// statusAllRequested reports whether args (without the program name) ask
+// for `--status --all`, the cross-provider report that needs no --service.
+func statusAllRequested(args []string) bool {
+	var status, all bool
+	for _, a := range args {
+		switch a {
+		case "--":
+			return false
+		case "--status", "-status":
+			status = true
+		case "--all", "-all":
+			all = true
+		}
+		if status && all {
+			return true
+		}
+	}
+	return false
+}
+
 // noopCredentialStore is a keychain.Provider stand-in used for commands
 // that don't touch the credential store. Every method returns an error so
 // that a routing bug (e.g. a command that should have needed the store
@@ -109,6 +164,9 @@ func (noopCredentialStore) GetMFASerialBytes(_, _ string) ([]byte, error) {
 func (noopCredentialStore) ListEntries(_ string) ([]keychain.KeychainEntry, error) {
 	return nil, errNoStore
 }
+func (noopCredentialStore) List(_ keychain.EntryFilter) ([]keychain.KeychainEntryMeta, error) {
+	return nil, errNoStore
+}
 func (noopCredentialStore) DeleteEntry(_, _ string) error       { return errNoStore }
 func (noopCredentialStore) SetDescription(_, _, _ string) error { return errNoStore }
 
@@ -432,55 +490,234 @@ func remainingArgs(args []string, name string) []string {
 	return nil
 }
 
-// fatal prints an error to stderr and exits
+// fatal prints an error to stderr and exits. Under --json the error is
+// written as a single JSON object and the exit status reflects its code.
+// A command line that doesn't parse exits 2, as flag.ExitOnError would.
 func fatal(app *App, err error) {
-	if _, printErr := fmt.Fprintf(app.Stderr, "❌ %v\n", err); printErr != nil {
+	if app.JSONOutput {
+		fatalJSON(app, err)
+		return
+	}
+	if _, printErr := fmt.Fprintf(app.Stderr, theme.Error()+" %v\n", err); printErr != nil {
+		app.Exit(2)
+		return
+	}
+	if errors.Is(err, provider.ErrUsage) {
 		app.Exit(2)
 		return
 	}
 	app.Exit(1)
 }
 
-// run is the testable entrypoint for the application
-func run(app *App, args []string) {
-	// Early exit for version/list-services that don't need service
-	for _, arg := range args[1:] {
-		switch arg {
-		case "--version", "-version":
-			if err := app.ShowVersion(); err != nil {
-				fatal(app, err)
-			}
-			return
-		case "--list-services", "-list-services":
-			if err := app.ListProviders(); err != nil {
-				fatal(app, err)
-			}
-			return
-		case "--migrate", "-migrate":
-			if err := runMigrate(app); err != nil {
-				fatal(app, err)
-			}
-			return
-		case "--rekey", "-rekey":
-			rest := remainingArgs(args, arg)
-			if err := runRekey(app, rest, keychain.NewDefaultProvider()); err != nil {
-				fatal(app, err)
+// jsonError is the --json shape of a fatal error.
+type jsonError struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// errorCode maps err to its machine-readable code and process exit status.
+// Untyped errors fall back to the generic "error" code and exit 1.
+func errorCode(err error) (string, int) {
+	var pe *provider.Error
+	if !errors.As(err, &pe) {
+		return "error", 1
+	}
+	switch pe.Code {
+	case provider.CodeUnknownProvider, provider.CodeUsage:
+		return string(pe.Code), 2
+	case provider.CodeNotSetup:
+		return string(pe.Code), 3
+	default:
+		return string(pe.Code), 1
+	}
+}
+
+// fatalJSON writes err to stderr as a jsonError and exits with its mapped code.
+func fatalJSON(app *App, err error) {
+	code, exitCode := errorCode(err)
+	if encErr := json.NewEncoder(app.Stderr).Encode(jsonError{Error: err.Error(), Code: code}); encErr != nil {
+		app.Exit(2)
+		return
+	}
+	app.Exit(exitCode)
+}
+
+// jsonRequested reports whether --json was passed. It is checked before flag
+// parsing so errors raised ahead of the provider flagset honor it too.
+func jsonRequested(args []string) bool {
+	enabled := false
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "json" {
+			continue
+		}
+		if !hasValue {
+			enabled = true
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		enabled = err == nil && b
+	}
+	return enabled
+}
+
+// themeRequested returns the value of --theme, or "" if it wasn't passed.
+// Like --json it is read before flag parsing, so global commands and
+// early errors use the theme too.
+func themeRequested(args []string) string {
+	name := ""
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		switch {
+		case arg == "--theme" || arg == "-theme":
+			if i+1 < len(args) {
+				name = args[i+1]
 			}
-			return
+		case strings.HasPrefix(arg, "--theme="):
+			name = strings.TrimPrefix(arg, "--theme=")
+		case strings.HasPrefix(arg, "-theme="):
+			name = strings.TrimPrefix(arg, "-theme=")
 		}
 	}
+	return name
+}
+
+// globalAction is a command recognized before provider selection.
+type globalAction int
+
+const (
+	actionNone globalAction = iota
+	actionVersion
+	actionListServices
+	actionMigrate
+	actionRekey
+	actionDecode
+	actionSelftest
+	actionMigrateLegacy
+	actionDumpRedacted
+	actionHelp
+)
 
-	// Check if help is requested without a service
-	hasHelp := false
-	for _, arg := range args[1:] {
-		if arg == "--help" || arg == "-help" || arg == "-h" {
-			hasHelp = true
+// globalFlags maps every accepted spelling of a global flag to its action.
+// These are matched before the provider flagset exists, so they work with
+// or without a valid --service.
+var globalFlags = map[string]globalAction{
+	"--version": actionVersion, "-version": actionVersion, "-v": actionVersion, "-V": actionVersion,
+	"--list-services": actionListServices, "-list-services": actionListServices,
+	"--migrate": actionMigrate, "-migrate": actionMigrate,
+	"--rekey": actionRekey, "-rekey": actionRekey,
+	"--decode": actionDecode, "-decode": actionDecode,
+	"--selftest": actionSelftest, "-selftest": actionSelftest,
+	"--migrate-legacy": actionMigrateLegacy, "-migrate-legacy": actionMigrateLegacy,
+	"--dump-redacted": actionDumpRedacted, "-dump-redacted": actionDumpRedacted,
+	"--help": actionHelp, "-help": actionHelp, "-h": actionHelp,
+}
+
+// preParseGlobal finds the global command in args (without the program
+// name). The first command flag wins; help only applies when there is no
+// other command, since with a service it selects provider help instead.
+// It returns the action and the index of the flag that selected it.
+func preParseGlobal(args []string) (globalAction, int) {
+	helpAt := -1
+	for i, arg := range args {
+		if arg == "--" {
 			break
 		}
+		switch action := globalFlags[arg]; action {
+		case actionNone:
+		case actionHelp:
+			if helpAt < 0 {
+				helpAt = i
+			}
+		default:
+			return action, i
+		}
+	}
+	if helpAt >= 0 {
+		return actionHelp, helpAt
 	}
+	return actionNone, -1
+}
+
+// run is the testable entrypoint for the application
+func run(app *App, args []string) {
+	if jsonRequested(args[1:]) {
+		app.JSONOutput = true
+	}
+	// Before anything is printed, including errors from global commands
+	t, err := theme.Select(themeRequested(args[1:]), !app.RedirectedOutput)
+	if err != nil {
+		fatal(app, fmt.Errorf("--theme: %w", err))
+		return
+	}
+	theme.Set(t)
+
+	// Early exit for commands that don't need a service
+	action, at := preParseGlobal(args[1:])
+	switch action {
+	case actionVersion:
+		if err := app.ShowVersion(); err != nil {
+			fatal(app, err)
+		}
+		return
+	case actionListServices:
+		if err := app.ListProviders(); err != nil {
+			fatal(app, err)
+		}
+		return
+	case actionMigrate:
+		if err := runMigrate(app); err != nil {
+			fatal(app, err)
+		}
+		return
+	case actionRekey:
+		rest := remainingArgs(args, args[1:][at])
+		if err := runRekey(app, rest, keychain.NewDefaultProvider()); err != nil {
+			fatal(app, err)
+		}
+		return
+	case actionDecode:
+		if err := runDecode(app, remainingArgs(args, args[1:][at])); err != nil {
+			fatal(app, err)
+		}
+		return
+	case actionSelftest:
+		if err := runSelftest(app); err != nil {
+			fatal(app, err)
+		}
+		return
+	case actionMigrateLegacy:
+		u, err := user.Current()
+		if err != nil {
+			fatal(app, fmt.Errorf("determine current user: %w", err))
+			return
+		}
+		if err := runMigrateLegacy(app, keychain.NewDefaultProvider(), u.Username); err != nil {
+			fatal(app, err)
+		}
+		return
+	case actionDumpRedacted:
+		if err := runDumpRedacted(app); err != nil {
+			fatal(app, err)
+		}
+		return
+	}
+
+	hasHelp := action == actionHelp
 
 	// Extract service name from args
 	serviceName := extractServiceName(args)
+	if serviceName == "" && statusAllRequested(args[1:]) {
+		if err := app.ShowAllStatus(); err != nil {
+			fatal(app, err)
+		}
+		return
+	}
 	if serviceName == "" {
 		if hasHelp {
 			if err := app.PrintUsage(); err != nil {
@@ -499,11 +736,21 @@ func run(app *App, args []string) {
 	// Validate service exists
 	svcProvider, err := app.Registry.GetProvider(serviceName)
 	if err != nil {
+		if app.JSONOutput {
+			fatal(app, err)
+			return
+		}
+		// Print the error, and any "did you mean" suggestion it carries,
+		// ahead of the full provider list so it isn't scrolled past.
+		if _, printErr := fmt.Fprintf(app.Stderr, theme.Error()+" %v\n", err); printErr != nil {
+			app.Exit(2)
+			return
+		}
 		if listErr := app.ListProviders(); listErr != nil {
 			fatal(app, listErr)
 			return
 		}
-		fatal(app, err)
+		app.Exit(1)
 		return
 	}
 
@@ -524,22 +771,156 @@ func run(app *App, args []string) {
 	showHelp := fs.Bool("help", false, "Show usage")
 	listServices := fs.Bool("list-services", false, "List available service providers")
 	listEntries := fs.Bool("list", false, "List entries for selected service")
+	var listOpts ListOptions
+	fs.BoolVar(&listOpts.Accounts, "accounts", false, "With --list, show the keychain account for each entry")
+	fs.IntVar(&listOpts.Count, "count", 0, "With --list, show at most this many entries after sorting")
+	showStatus := fs.Bool("status", false, "Show whether a session is active for selected service")
 	deleteEntry := fs.String("delete", "", "Delete entry for selected service")
 	runSetup := fs.Bool("setup", false, "Run setup wizard for selected service")
+	var setupOpts setup.Options
+	fs.BoolVar(&setupOpts.NoMetadata, "no-metadata", false, "With --setup, skip writing the listing metadata index")
+	fs.BoolVar(&setupOpts.Resume, "resume", false, "With --setup, continue an interrupted AWS setup")
+	fs.BoolVar(&setupOpts.VerifyWithService, "verify-with-service", false, "With --setup, check a code from the service against the stored TOTP secret")
+	fs.BoolVar(&setupOpts.ExistingDevice, "existing-device", false, "With --setup, skip the AWS console walkthrough for an already-assigned MFA device")
+	fs.BoolVar(&setupOpts.NoConsoleWait, "no-console-wait", false, "With --setup, don't pause for AWS console confirmation or retry the MFA device lookup")
+	fs.StringVar(&setupOpts.ProfileFromARN, "profile-from-arn", "", "With --setup, pick the AWS profile whose account matches this MFA ARN")
+	fs.StringVar(&setupOpts.SecretEnv, "secret-env", "", "With --setup, read the TOTP secret from this environment variable")
+	fs.StringVar(&setupOpts.QRImage, "qr-image", "", "With --setup, decode the TOTP QR code from this PNG file (- for stdin)")
+	fs.IntVar(&setupOpts.QRRetries, "qr-retries", setup.DefaultQRRetries, "With --setup, QR screen captures to attempt before offering manual entry (0 skips straight to manual entry)")
+	fs.BoolVar(&setupOpts.StrictSecret, "strict", false, "With --setup, reject a TOTP secret with spaces, lowercase, or wrong padding instead of normalizing it")
+	fs.StringVar(&setupOpts.Issuer, "issuer", "", "With --setup, a friendly issuer name (e.g. GitHub) to show for the TOTP entry")
+	fs.StringVar(&setupOpts.URL, "url", "", "With --setup, the service's login page, opened by --open")
+	fs.BoolVar(&setupOpts.CopyFirstCode, "copy-first-code", false, "With --setup, copy the first verification code to the clipboard")
+	fs.BoolVar(&setupOpts.ClipTwo, "clip-two", false, "With --setup, copy both verification codes, space separated, to the clipboard")
+	fs.IntVar(&setupOpts.TimeOffset, "time-offset", 0, "With --setup, seconds to add to this machine's clock when generating the entry's codes")
+	fs.BoolVar(&setupOpts.PrintEnvExample, "print-env-example", false, "With AWS --setup, print how to write the profile's session into ~/.aws/credentials")
+	fs.BoolVar(&setupOpts.ShowURI, "show-uri", false, "With --setup, print the otpauth:// URI at the end for backup (terminal only unless --force)")
 	copyClipboard := fs.Bool("clip", false, "Copy code to clipboard")
+	openLogin := fs.Bool("open", false, "Copy the code and open the service's login page in the browser")
+	fs.BoolVar(&app.JSONOutput, "json", app.JSONOutput, "Emit machine-readable JSON output")
+	fs.BoolVar(&app.MaskOutput, "mask-output", false, "Redact the middle of printed credential values")
+	fs.DurationVar(&app.ClipTimeout, "clip-timeout", defaultClipTimeout, "With --clip, clear the clipboard after this long (e.g. 10s)")
+	fs.BoolVar(&app.CopyValueOnly, "copy-value-only", false, "With --clip, print one success line instead of the full display info")
+	fs.DurationVar(&setupOpts.PromptTimeout, "prompt-timeout", 0, "Give up on a hardware MFA code or AWS console confirmation prompt after this long (e.g. 2m)")
+	fs.BoolVar(&app.ReauthOnExpiry, "reauth-on-expiry", false, "With -- command, re-authenticate and rerun it once if it fails on expired credentials")
+	fs.BoolVar(&app.Notify, "notify", false, "In a subshell, show a desktop notification before the credentials expire")
+	fs.DurationVar(&app.NotifyLead, "notify-lead", notify.DefaultLead, "With --notify, how long before expiry to notify")
+	// Read by main() before parsing, to wrap the credential store. The
+	// export only holds password entries, so other services reject it.
+	if serviceName == "password" {
+		fs.String("emergency-store", "", "Serve secrets from this encrypted password export when the keychain is locked")
+	}
+	// Applied by run() before parsing, so it also covers global commands
+	fs.String("theme", "", "Status symbols: emoji, ascii or nerd (default emoji, ascii when stderr isn't a terminal)")
+	printConfig := fs.Bool("print-config", false, "Print each setting's effective value and where it came from (flag, config, env, default)")
+	debug := fs.Bool("debug", false, "Print diagnostics (keychain latency, AWS code retry decisions) to stderr")
 
 	// Register provider-specific flags
 	if err := svcProvider.SetupFlags(fs); err != nil {
 		fatal(app, fmt.Errorf("error setting up provider flags: %w", err))
 		return
 	}
+	// A provider with its own --sort (password) keeps it
+	if fs.Lookup("sort") == nil {
+		fs.StringVar(&listOpts.Sort, "sort", "name", "With --list, order entries by name, profile, service, recent or type")
+	}
+	// Likewise --force, which --setup --show-uri reads below
+	if fs.Lookup("force") == nil {
+		fs.Bool("force", false, "With --setup --show-uri, print the URI even when stdout isn't a terminal")
+	}
+
+	// .sesh values act as defaults; environment overrides and flags on the
+	// command line take precedence over them
+	var envSet map[string]string
+	if o, ok := svcProvider.(provider.EnvOverrider); ok {
+		envSet = o.EnvOverrides()
+	}
+	dirApplied, err := app.DirDefaults.applyFlagDefaults(fs, envSet)
+	if err != nil {
+		fatal(app, err)
+		return
+	}
 
 	// Parse all flags
 	if err := fs.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			return
 		}
-		fatal(app, fmt.Errorf("error parsing arguments: %w", err))
+		// The flag package has already printed the problem and the usage
+		fatal(app, provider.UsageError("error parsing arguments: %v", err))
+		return
+	}
+	if n, ok := svcProvider.(provider.FlagNormalizer); ok {
+		n.NormalizeFlags()
+	}
+
+	if *debug {
+		keychain.SetDebugOutput(app.Stderr)
+		awsProvider.SetDebugOutput(app.Stderr)
+	}
+
+	// --algorithm belongs to the totp provider but also steers its setup
+	if f := fs.Lookup("algorithm"); f != nil && f.Value.String() != "" {
+		algorithm, err := totp.ParseAlgorithm(f.Value.String())
+		if err != nil {
+			fatal(app, err)
+			return
+		}
+		setupOpts.Algorithm = algorithm
+	}
+	if f := fs.Lookup("keychain-user"); f != nil {
+		setupOpts.KeychainUser = f.Value.String()
+	}
+	setupOpts.Force = fs.Lookup("force").Value.String() == "true"
+	if app.ClipTimeout <= 0 {
+		fatal(app, fmt.Errorf("--clip-timeout must be positive, got %s", app.ClipTimeout))
+		return
+	}
+	setupOpts.ClipTimeout = app.ClipTimeout
+	if listOpts.Count < 0 {
+		fatal(app, fmt.Errorf("--count must not be negative, got %d", listOpts.Count))
+		return
+	}
+	if setupOpts.QRRetries < 0 {
+		fatal(app, fmt.Errorf("--qr-retries must not be negative, got %d", setupOpts.QRRetries))
+		return
+	}
+	if setupOpts.QRRetries == 0 {
+		setupOpts.QRRetries = setup.SkipQRCapture
+	}
+	if setupOpts.PromptTimeout < 0 {
+		fatal(app, fmt.Errorf("--prompt-timeout must not be negative, got %s", setupOpts.PromptTimeout))
+		return
+	}
+	awsProvider.SetPromptTimeout(setupOpts.PromptTimeout)
+	if app.NotifyLead <= 0 {
+		fatal(app, fmt.Errorf("--notify-lead must be positive, got %s", app.NotifyLead))
+		return
+	}
+	if setupOpts.ClipTwo && setupOpts.CopyFirstCode {
+		fatal(app, fmt.Errorf("--clip-two already copies the first code; drop --copy-first-code"))
+		return
+	}
+	if err := totp.ValidateTimeOffset(setupOpts.TimeOffset); err != nil {
+		fatal(app, fmt.Errorf("--time-offset: %w", err))
+		return
+	}
+	if setupOpts.Issuer != "" && setupOpts.NoMetadata {
+		fatal(app, fmt.Errorf("--issuer is stored in metadata and cannot be used with --no-metadata"))
+		return
+	}
+	if setupOpts.URL != "" {
+		if err := validateLoginURL(setupOpts.URL); err != nil {
+			fatal(app, fmt.Errorf("--url: %w", err))
+			return
+		}
+		if setupOpts.NoMetadata {
+			fatal(app, fmt.Errorf("--url is stored in metadata and cannot be used with --no-metadata"))
+			return
+		}
+	}
+	if setupOpts.TimeOffset != 0 && setupOpts.NoMetadata {
+		fatal(app, fmt.Errorf("--time-offset is stored in metadata and cannot be used with --no-metadata"))
 		return
 	}
 
@@ -549,6 +930,14 @@ func run(app *App, args []string) {
 		return
 	}
 
+	if *printConfig {
+		values := resolveConfig(fs, args[1:], app.DirDefaults, dirApplied, envSourcesFor(svcProvider))
+		if err := app.PrintConfig(values); err != nil {
+			fatal(app, err)
+		}
+		return
+	}
+
 	// Handle commands that were re-parsed
 	if *showVersion {
 		if err := app.ShowVersion(); err != nil {
@@ -570,8 +959,14 @@ func run(app *App, args []string) {
 	}
 
 	// Provider-specific operations
+	if *showStatus {
+		if err := app.ShowStatus(serviceName); err != nil {
+			fatal(app, err)
+		}
+		return
+	}
 	if *listEntries {
-		if err := app.ListEntries(serviceName); err != nil {
+		if err := app.ListEntries(serviceName, listOpts); err != nil {
 			fatal(app, err)
 		}
 		return
@@ -583,14 +978,51 @@ func run(app *App, args []string) {
 		return
 	}
 	if *runSetup {
-		if err := app.RunSetup(serviceName); err != nil {
+		if err := app.RunSetup(serviceName, setupOpts); err != nil {
 			fatal(app, fmt.Errorf("setup failed: %w", err))
 		}
 		return
 	}
 
+	// `sesh --service aws -- cmd args...` runs cmd with the credentials
+	if command, ok := commandAfterDashes(args[1:], fs.Args()); ok {
+		if *copyClipboard {
+			fatal(app, fmt.Errorf("--clip cannot be used with a command after --"))
+			return
+		}
+		if *openLogin {
+			fatal(app, fmt.Errorf("--open cannot be used with a command after --"))
+			return
+		}
+		exitCode, err := app.RunCommand(serviceName, command)
+		if err != nil {
+			fatal(app, err)
+			return
+		}
+		if exitCode != 0 {
+			app.Exit(exitCode)
+		}
+		return
+	}
+
+	if app.ReauthOnExpiry {
+		fatal(app, fmt.Errorf("--reauth-on-expiry only applies to a command after --"))
+		return
+	}
+
 	// Main operation - generate credentials
-	if *copyClipboard {
+	if cd, ok := svcProvider.(provider.ClipboardDecider); ok && cd.ShouldCopyToClipboard() {
+		*copyClipboard = true
+	}
+	if app.CopyValueOnly && !*copyClipboard && !*openLogin {
+		fatal(app, fmt.Errorf("--copy-value-only requires --clip"))
+		return
+	}
+	if *openLogin {
+		if err := app.OpenLogin(serviceName); err != nil {
+			fatal(app, err)
+		}
+	} else if *copyClipboard {
 		if err := app.CopyToClipboard(serviceName); err != nil {
 			fatal(app, err)
 		}
@@ -605,9 +1037,24 @@ func run(app *App, args []string) {
 	}
 }
 
+// commandAfterDashes returns the command following a "--" terminator, given
+// the args (without the program name) and the flagset's leftover args. The
+// flag package drops the "--" itself, so it is found just before the rest.
+func commandAfterDashes(args, rest []string) ([]string, bool) {
+	i := len(args) - len(rest) - 1
+	if i < 0 || args[i] != "--" {
+		return nil, false
+	}
+	return rest, true
+}
+
 // extractServiceName manually parses args to find --service value
 func extractServiceName(args []string) string {
 	for i := 1; i < len(args); i++ {
+		// Everything after -- belongs to the command being run
+		if args[i] == "--" {
+			break
+		}
 		// Handle --service <value>
 		if args[i] == "--service" || args[i] == "-service" {
 			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
@@ -633,16 +1080,57 @@ func (a *App) PrintUsage() error {
 		"\nCommon options:",
 		"  --service, -service           Service provider to use (aws, totp, password) [REQUIRED]",
 		"  --list, -list                 List entries for selected service",
+		"  --accounts, -accounts         With --list, show the keychain account for each entry",
+		"  --sort, -sort KEY             With --list, order by name, profile, service, recent or type",
+		"  --count, -count N             With --list, show at most N entries after sorting",
+		"  --status, -status             Show whether a session is active (no credentials fetched)",
+		"  --status --all                Without --service, report every provider's entries and session",
 		"  --delete, -delete string      Delete entry for selected service",
 		"  --setup, -setup               Run setup wizard for selected service",
+		"  --no-metadata, -no-metadata   With --setup, don't index the entry for --list",
+		"  --secret-env, -secret-env VAR With --setup, read the TOTP secret from $VAR",
+		"  --resume, -resume             With --setup, continue an interrupted AWS setup",
+		"  --verify-with-service         With --setup, confirm the TOTP secret against a code from the service",
+		"  --existing-device             With --setup, store an already-assigned AWS MFA device (no console walkthrough)",
+		"  --no-console-wait             With --setup, skip the AWS console confirmation pause and device-lookup retries",
+		"  --profile-from-arn ARN        With --setup, pick the AWS profile whose account matches ARN",
+		"  --qr-image FILE               With --setup, decode the TOTP QR code from a PNG file (- for stdin)",
+		"  --qr-retries N                With --setup, QR screen captures to attempt before manual entry (default 2; 0 skips capture)",
+		"  --issuer NAME                 With --setup, a friendly issuer name to show for the TOTP entry",
+		"  --url URL                     With --setup, the service's login page, opened by --open",
+		"  --strict                      With --setup, reject a TOTP secret that needs normalizing instead of fixing it",
+		"  --copy-first-code             With --setup, copy the first verification code to the clipboard",
+		"  --clip-two                    With --setup, copy both verification codes, space separated, to the clipboard",
+		"  --time-offset SECONDS         With --setup, store a clock correction applied whenever the entry's codes are generated",
+		"  --show-uri                    With --setup, print the otpauth:// URI for backup (add --force when not a terminal)",
+		"  --print-env-example           With AWS --setup, print how to use the profile from ~/.aws/credentials",
 		"  --clip, -clip                 Copy code to clipboard",
+		"  --open, -open                 Copy the code and open the service's login page in the browser",
+		"  --clip-timeout DURATION       With --clip, clear the clipboard after DURATION (default 30s)",
+		"  --copy-value-only             With --clip, print one success line instead of the full display info",
+		"  --prompt-timeout DURATION     Give up on a hardware MFA code or AWS console prompt after DURATION",
+		"  --reauth-on-expiry            With -- command, rerun it once with fresh credentials if they expired",
+		"  --notify                      In a subshell, desktop-notify before the credentials expire",
+		"  --notify-lead DURATION        With --notify, how long before expiry to notify (default 2m)",
+		"  --json, -json                 Emit machine-readable JSON output (including errors)",
+		"  --mask-output, -mask-output   Redact the middle of printed credentials (for screen sharing)",
+		"  --theme NAME                  Status symbols: emoji, ascii or nerd (ascii when stderr isn't a terminal)",
+		"  --debug, -debug               Print diagnostics (keychain latency, AWS code retries) to stderr",
+		"  --print-config                Print each setting's value and source (flag, config, env, default)",
 		"  --list-services, -list-services  List available service providers",
-		"  --version, -version           Show version information",
+		"  --decode [VALUE]              Print the JSON inside AWS --format base64 output (VALUE or stdin)",
+		"  --selftest                    Check this build's TOTP code against the RFC 6238 test vectors",
+		"  --migrate-legacy              Move AWS entries stored under old sesh-mfa keys to the current keys",
+		"  --dump-redacted               Print a diagnostic report for bug reports (entry names and types, no secrets)",
+		"  --version, -version, -v, -V   Show version information",
 		"  --help, -help                 Show usage",
 		"\nExamples:",
 		"  sesh --service aws                     Generate AWS credentials",
+		"  sesh --service aws -- terraform apply  Run a command with AWS credentials",
 		"  sesh --service totp --service-name github   Generate TOTP code for GitHub",
 		"  sesh --list-services                   List available providers",
+		"  sesh --list-services --json            Provider catalog with flags and capabilities",
+		"  sesh --status --all --json             Entries and session state for every provider",
 		"\nFor provider-specific help:",
 		"  sesh --service <provider> --help",
 	}
@@ -665,11 +1153,47 @@ func (a *App) PrintProviderUsage(serviceName string, p provider.ServiceProvider)
 		"Common options:",
 		"  --service string              Service provider to use",
 		"  --list                        List entries for selected service",
+		"  --accounts                    With --list, show the keychain account for each entry",
+		"  --sort KEY                    With --list, order by name, profile, service, recent or type",
+		"  --count N                     With --list, show at most N entries after sorting",
+		"  --status                      Show whether a session is active (no credentials fetched)",
 		"  --delete string               Delete entry for selected service",
 		"  --setup                       Run setup wizard for selected service",
+		"  --no-metadata                 With --setup, don't index the entry for --list",
+		"  --secret-env VAR              With --setup, read the TOTP secret from $VAR",
+		"  --resume                      With --setup, continue an interrupted AWS setup",
+		"  --verify-with-service         With --setup, confirm the TOTP secret against a code from the service",
+		"  --existing-device             With --setup, store an already-assigned AWS MFA device (no console walkthrough)",
+		"  --no-console-wait             With --setup, skip the AWS console confirmation pause and device-lookup retries",
+		"  --profile-from-arn ARN        With --setup, pick the AWS profile whose account matches ARN",
+		"  --qr-image FILE               With --setup, decode the TOTP QR code from a PNG file (- for stdin)",
+		"  --qr-retries N                With --setup, QR screen captures to attempt before manual entry (default 2; 0 skips capture)",
+		"  --issuer NAME                 With --setup, a friendly issuer name to show for the TOTP entry",
+		"  --url URL                     With --setup, the service's login page, opened by --open",
+		"  --strict                      With --setup, reject a TOTP secret that needs normalizing instead of fixing it",
+		"  --copy-first-code             With --setup, copy the first verification code to the clipboard",
+		"  --clip-two                    With --setup, copy both verification codes, space separated, to the clipboard",
+		"  --time-offset SECONDS         With --setup, store a clock correction applied whenever the entry's codes are generated",
+		"  --show-uri                    With --setup, print the otpauth:// URI for backup (add --force when not a terminal)",
+		"  --print-env-example           With AWS --setup, print how to use the profile from ~/.aws/credentials",
 		"  --clip                        Copy code to clipboard",
+		"  --open                        Copy the code and open the service's login page in the browser",
+		"  --clip-timeout DURATION       With --clip, clear the clipboard after DURATION (default 30s)",
+		"  --copy-value-only             With --clip, print one success line instead of the full display info",
+		"  --prompt-timeout DURATION     Give up on a hardware MFA code or AWS console prompt after DURATION",
+		"  --reauth-on-expiry            With -- command, rerun it once with fresh credentials if they expired",
+		"  --notify                      In a subshell, desktop-notify before the credentials expire",
+		"  --notify-lead DURATION        With --notify, how long before expiry to notify (default 2m)",
+		"  --json                        Emit machine-readable JSON output (including errors)",
+		"  --mask-output                 Redact the middle of printed credentials (for screen sharing)",
+		"  --theme NAME                  Status symbols: emoji, ascii or nerd (ascii when stderr isn't a terminal)",
+		"  --debug                       Print diagnostics (keychain latency, AWS code retries) to stderr",
+		"  --print-config                Print each setting's value and source (flag, config, env, default)",
 		"  --help                        Show this help",
-		"  --version                     Show version information",
+		"  --version, -v                 Show version information",
+	}
+	if serviceName == "password" {
+		commonLines = append(commonLines, "  --emergency-store PATH        Serve secrets from this encrypted password export when the keychain is locked")
 	}
 	for _, line := range commonLines {
 		if _, err := fmt.Fprintln(w, line); err != nil {
@@ -706,11 +1230,19 @@ func (a *App) PrintProviderUsage(serviceName string, p provider.ServiceProvider)
 			"  sesh --service aws --no-subshell       Print AWS credentials",
 			"  sesh --service aws --profile dev       Use 'dev' AWS profile",
 			"  sesh --service aws --setup             Set up AWS credentials",
+			"  sesh --service aws --copy-serial       Copy the MFA device ARN to the clipboard",
+			"  sesh --service aws --profile dev --whoami   Show the ARN and account 'dev' authenticates as",
+			"  sesh --service aws --format ini --output-file ~/.aws/credentials   Write a [<profile>-sesh] section",
+			"  sesh --service aws -- terraform apply  Run one command with AWS credentials",
 		}
 	case "totp":
 		examples = []string{
 			"  sesh --service totp --service-name github     Generate TOTP for GitHub",
 			"  sesh --service totp --service-name github --clip   Copy TOTP to clipboard",
+			"  sesh --service totp --service-name github --open   Copy TOTP and open the GitHub login page",
+			"  sesh --service totp --service-name legacy --algorithm sha256   Override a missing or wrong stored algorithm",
+			"  sesh --service totp --service-name github --debug-codes   Show codes for nearby windows to diagnose clock drift",
+			"  sesh --service totp --keychain-service com.example.otp --clip   Use a keychain item another tool created",
 			"  sesh --service totp --setup            Set up new TOTP service",
 			"  sesh --service totp --list             List all TOTP services",
 		}
@@ -724,6 +1256,7 @@ func (a *App) PrintProviderUsage(serviceName string, p provider.ServiceProvider)
 			"  sesh --service password --action search --query github",
 			"  sesh --service password --action export --file backup.json",
 			"  sesh --service password --action import --file backup.json --on-conflict skip",
+			"  sesh --service password --action backup --file backup.enc --max-age 24h",
 			"  sesh --service password --list",
 			"  sesh --service password --delete <entry-id>",
 		}
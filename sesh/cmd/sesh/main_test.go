@@ -2,14 +2,17 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	awsInternal "github.com/bashhack/sesh/internal/aws"
 	awsMocks "github.com/bashhack/sesh/internal/aws/mocks"
 	"github.com/bashhack/sesh/internal/database"
 	"github.com/bashhack/sesh/internal/keychain"
@@ -17,7 +20,9 @@ import (
 	"github.com/bashhack/sesh/internal/provider"
 	awsProvider "github.com/bashhack/sesh/internal/provider/aws"
 	totpProvider "github.com/bashhack/sesh/internal/provider/totp"
+	"github.com/bashhack/sesh/internal/setup"
 	"github.com/bashhack/sesh/internal/testutil"
+	"github.com/bashhack/sesh/internal/theme"
 	totpMocks "github.com/bashhack/sesh/internal/totp/mocks"
 )
 
@@ -88,6 +93,192 @@ func TestVersionFlag(t *testing.T) {
 	}
 }
 
+func TestRun_ListServicesJSON(t *testing.T) {
+	h := newTestHarness()
+	run(h.app, []string{"sesh", "--list-services", "--json"})
+
+	var catalog []providerInfo
+	if err := json.Unmarshal(h.stdout.Bytes(), &catalog); err != nil {
+		t.Fatalf("output is not a JSON catalog: %v\n%s", err, h.stdout.String())
+	}
+	byName := make(map[string]providerInfo)
+	for _, p := range catalog {
+		byName[p.Name] = p
+	}
+
+	tests := map[string]struct {
+		want     providerCapabilities
+		wantFlag string
+	}{
+		"aws": {
+			want:     providerCapabilities{Subshell: true, Clipboard: true, Setup: true, Status: true},
+			wantFlag: "profile",
+		},
+		"totp": {
+			want:     providerCapabilities{Clipboard: true, Setup: true},
+			wantFlag: "service-name",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, ok := byName[name]
+			if !ok {
+				t.Fatalf("%s missing from catalog: %v", name, catalog)
+			}
+			if p.Description == "" {
+				t.Error("description is empty")
+			}
+			if p.Capabilities != tc.want {
+				t.Errorf("capabilities = %+v, want %+v", p.Capabilities, tc.want)
+			}
+			if !slices.ContainsFunc(p.Flags, func(f provider.FlagInfo) bool { return f.Name == tc.wantFlag }) {
+				t.Errorf("flags %v missing %q", p.Flags, tc.wantFlag)
+			}
+		})
+	}
+}
+
+func TestRun_PrintConfig(t *testing.T) {
+	tests := map[string]struct {
+		args       []string
+		env        map[string]string
+		dir        DirDefaults
+		wantValue  string
+		wantSource string
+		wantOrigin string
+	}{
+		"flag": {
+			args:       []string{"--profile", "cli"},
+			env:        map[string]string{"SESH_PROFILE": "env"},
+			dir:        DirDefaults{Path: "/work/.sesh", Values: map[string]string{"profile": "dir"}},
+			wantValue:  "cli",
+			wantSource: sourceFlag,
+		},
+		"SESH_PROFILE": {
+			env:        map[string]string{"SESH_PROFILE": "env"},
+			dir:        DirDefaults{Path: "/work/.sesh", Values: map[string]string{"profile": "dir"}},
+			wantValue:  "env",
+			wantSource: sourceEnv,
+			wantOrigin: "SESH_PROFILE",
+		},
+		".sesh file": {
+			env:        map[string]string{"AWS_PROFILE": "aws"},
+			dir:        DirDefaults{Path: "/work/.sesh", Values: map[string]string{"profile": "dir"}},
+			wantValue:  "dir",
+			wantSource: sourceConfig,
+			wantOrigin: "/work/.sesh",
+		},
+		"AWS_PROFILE": {
+			env:        map[string]string{"AWS_PROFILE": "aws"},
+			wantValue:  "aws",
+			wantSource: sourceEnv,
+			wantOrigin: "AWS_PROFILE",
+		},
+		"default": {
+			wantValue:  "",
+			wantSource: sourceDefault,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("SESH_PROFILE", "")
+			t.Setenv("AWS_PROFILE", "")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			h := newTestHarness()
+			h.app.DirDefaults = tc.dir
+			run(h.app, append([]string{"sesh", "--service", "aws", "--print-config", "--json"}, tc.args...))
+
+			var values []configValue
+			if err := json.Unmarshal(h.stdout.Bytes(), &values); err != nil {
+				t.Fatalf("output is not JSON: %v\n%s%s", err, h.stdout.String(), h.stderr.String())
+			}
+			i := slices.IndexFunc(values, func(v configValue) bool { return v.Name == "profile" })
+			if i < 0 {
+				t.Fatalf("profile missing from %v", values)
+			}
+			want := configValue{Name: "profile", Value: tc.wantValue, Source: tc.wantSource, Origin: tc.wantOrigin}
+			if values[i] != want {
+				t.Errorf("profile = %+v, want %+v", values[i], want)
+			}
+		})
+	}
+}
+
+func TestRun_PrintConfig_ServiceFromDir(t *testing.T) {
+	h := newTestHarness()
+	h.app.DirDefaults = DirDefaults{Path: "/work/.sesh", Values: map[string]string{"service": "totp"}, ServiceApplied: true}
+	run(h.app, []string{"sesh", "--service", "totp", "--print-config"})
+
+	first, _, _ := strings.Cut(h.stdout.String(), "\n")
+	if fields := strings.Fields(first); !slices.Equal(fields, []string{"service", "totp", "config", "(/work/.sesh)"}) {
+		t.Errorf("first line = %q, want the service from /work/.sesh", first)
+	}
+}
+
+func TestRun_VersionAliases(t *testing.T) {
+	tests := map[string]struct {
+		args []string
+	}{
+		"--version":               {args: []string{"sesh", "--version"}},
+		"-version":                {args: []string{"sesh", "-version"}},
+		"-v":                      {args: []string{"sesh", "-v"}},
+		"-V":                      {args: []string{"sesh", "-V"}},
+		"--version with service":  {args: []string{"sesh", "--service", "totp", "--version"}},
+		"-version with service":   {args: []string{"sesh", "--service", "totp", "-version"}},
+		"-v with service":         {args: []string{"sesh", "--service", "totp", "-v"}},
+		"-V with service":         {args: []string{"sesh", "-service", "aws", "-V"}},
+		"-v with unknown service": {args: []string{"sesh", "--service", "nope", "-v"}},
+		"-v before service":       {args: []string{"sesh", "-v", "--service", "totp"}},
+		"-v wins over help":       {args: []string{"sesh", "--help", "-v"}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := newTestHarness()
+			exitCode := -1
+			h.app.Exit = func(code int) { exitCode = code }
+
+			run(h.app, tc.args)
+
+			if exitCode != -1 {
+				t.Fatalf("unexpected exit %d, stderr: %s", exitCode, h.stderr.String())
+			}
+			if !strings.Contains(h.stdout.String(), "test-version") {
+				t.Errorf("expected version output, got: %q", h.stdout.String())
+			}
+		})
+	}
+}
+
+func TestPreParseGlobal(t *testing.T) {
+	tests := map[string]struct {
+		args       []string
+		wantAction globalAction
+		wantAt     int
+	}{
+		"no global flag":      {args: []string{"--service", "totp"}, wantAction: actionNone, wantAt: -1},
+		"help alone":          {args: []string{"-h"}, wantAction: actionHelp, wantAt: 0},
+		"command beats help":  {args: []string{"-h", "--list-services"}, wantAction: actionListServices, wantAt: 1},
+		"first command wins":  {args: []string{"--rekey", "--version"}, wantAction: actionRekey, wantAt: 0},
+		"stops at --":         {args: []string{"--service", "aws", "--", "aws", "-v"}, wantAction: actionNone, wantAt: -1},
+		"single-dash migrate": {args: []string{"-migrate"}, wantAction: actionMigrate, wantAt: 0},
+		"value is not a flag": {args: []string{"--service-name", "version"}, wantAction: actionNone, wantAt: -1},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			action, at := preParseGlobal(tc.args)
+			if action != tc.wantAction || at != tc.wantAt {
+				t.Errorf("preParseGlobal(%v) = (%v, %d), want (%v, %d)", tc.args, action, at, tc.wantAction, tc.wantAt)
+			}
+		})
+	}
+}
+
 func TestPrintUsage(t *testing.T) {
 	h := newTestHarness()
 	if err := h.app.PrintUsage(); err != nil {
@@ -302,7 +493,7 @@ func TestRun_ProviderSpecificFlags(t *testing.T) {
 			setupMocks: func(h *testHarness) {
 				// Should fail during flag parsing
 			},
-			wantExitCode: 1,
+			wantExitCode: 2,
 			checkOutput: func(t *testing.T, stdout, stderr string) {
 				if !strings.Contains(stderr, "flag provided but not defined") || !strings.Contains(stderr, "service-name") {
 					t.Error("Expected error about undefined flag --service-name")
@@ -314,13 +505,76 @@ func TestRun_ProviderSpecificFlags(t *testing.T) {
 			setupMocks: func(h *testHarness) {
 				// Should fail during flag parsing
 			},
-			wantExitCode: 1,
+			wantExitCode: 2,
 			checkOutput: func(t *testing.T, stdout, stderr string) {
 				if !strings.Contains(stderr, "flag provided but not defined") || !strings.Contains(stderr, "no-subshell") {
 					t.Error("Expected error about undefined flag --no-subshell")
 				}
 			},
 		},
+		"emergency store is password only": {
+			args:         []string{"sesh", "--service", "totp", "--emergency-store", "/tmp/bundle.enc"},
+			wantExitCode: 2,
+			checkOutput: func(t *testing.T, stdout, stderr string) {
+				if !strings.Contains(stderr, "flag provided but not defined: -emergency-store") {
+					t.Errorf("stderr = %q, want the undefined flag error", stderr)
+				}
+			},
+		},
+		"unknown flag prints provider usage": {
+			args:         []string{"sesh", "--service", "totp", "--bogus"},
+			wantExitCode: 2,
+			checkOutput: func(t *testing.T, stdout, stderr string) {
+				if !strings.Contains(stderr, "flag provided but not defined: -bogus") {
+					t.Errorf("stderr = %q, want the undefined flag error", stderr)
+				}
+				if !strings.Contains(stdout, "Usage: sesh --service totp [options]") {
+					t.Errorf("stdout = %q, want the totp usage", stdout)
+				}
+			},
+		},
+		"bad flag value": {
+			args:         []string{"sesh", "--service", "aws", "--count", "many"},
+			wantExitCode: 2,
+			checkOutput: func(t *testing.T, stdout, stderr string) {
+				if !strings.Contains(stderr, `invalid value "many" for flag -count`) {
+					t.Errorf("stderr = %q, want the invalid value error", stderr)
+				}
+			},
+		},
+		"blank service name": {
+			args:         []string{"sesh", "--service", "totp", "--service-name", "  "},
+			wantExitCode: 2,
+			checkOutput: func(t *testing.T, stdout, stderr string) {
+				if !strings.Contains(stderr, "--service-name must not be blank") {
+					t.Errorf("stderr = %q, want the blank service name error", stderr)
+				}
+			},
+		},
+		"blank service name with --clip": {
+			args: []string{"sesh", "--service", "totp", "--service-name", "\t", "--clip"},
+			setupMocks: func(h *testHarness) {
+				h.keychain.GetSecretFunc = func(_, service string) ([]byte, error) {
+					t.Errorf("keychain read for %q despite a blank service name", service)
+					return nil, keychain.ErrNotFound
+				}
+			},
+			wantExitCode: 2,
+			checkOutput: func(t *testing.T, stdout, stderr string) {
+				if !strings.Contains(stderr, "--service-name must not be blank") {
+					t.Errorf("stderr = %q, want the blank service name error", stderr)
+				}
+			},
+		},
+		"bad flag with --json": {
+			args:         []string{"sesh", "--service", "totp", "--json", "--bogus"},
+			wantExitCode: 2,
+			checkOutput: func(t *testing.T, stdout, stderr string) {
+				if !strings.Contains(stderr, `"code":"usage"`) {
+					t.Errorf("stderr = %q, want a usage JSON error", stderr)
+				}
+			},
+		},
 	}
 
 	for name, tc := range tests {
@@ -485,7 +739,7 @@ func TestRun_Commands(t *testing.T) {
 			args: []string{"sesh", "--service", "aws", "--setup"},
 			setupMocks: func(h *testHarness) {
 				h.app.SetupService = &MockSetupService{
-					SetupServiceFunc: func(serviceName string) error {
+					SetupServiceFunc: func(serviceName string, _ setup.Options) error {
 						return fmt.Errorf("setup wizard failed")
 					},
 				}
@@ -557,6 +811,42 @@ func TestRun_FlagValidation(t *testing.T) {
 				}
 			},
 		},
+		"copy-value-only without clip": {
+			args:         []string{"sesh", "--service", "aws", "--copy-value-only"},
+			wantExitCode: 1,
+			checkStderr: func(t *testing.T, stderr string) {
+				if !strings.Contains(stderr, "--copy-value-only requires --clip") {
+					t.Errorf("Expected --copy-value-only error, got: %q", stderr)
+				}
+			},
+		},
+		"reauth-on-expiry without a command": {
+			args:         []string{"sesh", "--service", "aws", "--reauth-on-expiry"},
+			wantExitCode: 1,
+			checkStderr: func(t *testing.T, stderr string) {
+				if !strings.Contains(stderr, "--reauth-on-expiry only applies to a command after --") {
+					t.Errorf("Expected --reauth-on-expiry error, got: %q", stderr)
+				}
+			},
+		},
+		"clip-two with copy-first-code": {
+			args:         []string{"sesh", "--service", "aws", "--setup", "--clip-two", "--copy-first-code"},
+			wantExitCode: 1,
+			checkStderr: func(t *testing.T, stderr string) {
+				if !strings.Contains(stderr, "--clip-two already copies the first code") {
+					t.Errorf("Expected --clip-two conflict error, got: %q", stderr)
+				}
+			},
+		},
+		"misspelled service suggests closest provider": {
+			args:         []string{"sesh", "--service", "asw"},
+			wantExitCode: 1,
+			checkStderr: func(t *testing.T, stderr string) {
+				if !strings.Contains(stderr, "did you mean 'aws'?") {
+					t.Errorf("Expected suggestion for aws, got: %q", stderr)
+				}
+			},
+		},
 		"totp without required service-name": {
 			args: []string{"sesh", "--service", "totp"},
 			setupMocks: func(h *testHarness) {
@@ -601,6 +891,325 @@ func TestRun_FlagValidation(t *testing.T) {
 	}
 }
 
+func TestRun_QRRetries(t *testing.T) {
+	tests := map[string]struct {
+		args        []string
+		dirValues   map[string]string
+		wantRetries int
+		wantErr     string
+	}{
+		"default": {
+			args:        []string{"sesh", "--service", "totp", "--setup"},
+			wantRetries: setup.DefaultQRRetries,
+		},
+		"zero skips capture": {
+			args:        []string{"sesh", "--service", "totp", "--setup", "--qr-retries", "0"},
+			wantRetries: setup.SkipQRCapture,
+		},
+		"configured": {
+			args:        []string{"sesh", "--service", "aws", "--setup", "--qr-retries", "3"},
+			wantRetries: 3,
+		},
+		"from .sesh": {
+			args:        []string{"sesh", "--service", "totp", "--setup"},
+			dirValues:   map[string]string{"qr-retries": "5"},
+			wantRetries: 5,
+		},
+		"flag overrides .sesh": {
+			args:        []string{"sesh", "--service", "totp", "--setup", "--qr-retries", "1"},
+			dirValues:   map[string]string{"qr-retries": "5"},
+			wantRetries: 1,
+		},
+		"negative": {
+			args:    []string{"sesh", "--service", "totp", "--setup", "--qr-retries", "-1"},
+			wantErr: "--qr-retries must not be negative",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := newTestHarness()
+			exitCode := 0
+			h.app.Exit = func(code int) { exitCode = code }
+			h.app.DirDefaults = DirDefaults{Path: "/project/.sesh", Values: tc.dirValues}
+			gotRetries := -100
+			h.app.SetupService = &MockSetupService{
+				SetupServiceFunc: func(_ string, opts setup.Options) error {
+					gotRetries = opts.QRRetries
+					return nil
+				},
+			}
+
+			run(h.app, tc.args)
+
+			if tc.wantErr != "" {
+				if exitCode != 1 || !strings.Contains(h.stderr.String(), tc.wantErr) {
+					t.Errorf("exit %d, stderr %q; want exit 1 with %q", exitCode, h.stderr.String(), tc.wantErr)
+				}
+				return
+			}
+			if exitCode != 0 {
+				t.Fatalf("exit %d, stderr %q", exitCode, h.stderr.String())
+			}
+			if gotRetries != tc.wantRetries {
+				t.Errorf("QRRetries = %d, want %d", gotRetries, tc.wantRetries)
+			}
+		})
+	}
+}
+
+func TestRun_CopySerial(t *testing.T) {
+	const arn = "arn:aws:iam::123456789012:mfa/testuser"
+	h := newTestHarness()
+
+	exitCode := -1
+	h.app.Exit = func(code int) { exitCode = code }
+
+	var copied string
+	h.app.ClipboardCopy = func(text string) error {
+		copied = text
+		return nil
+	}
+	h.keychain.GetSecretFunc = func(account, service string) ([]byte, error) {
+		switch service {
+		case "sesh-aws/default":
+			return []byte("JBSWY3DPEHPK3PXP"), nil
+		case "sesh-aws-serial/default":
+			return []byte(arn), nil
+		}
+		return nil, keychain.ErrNotFound
+	}
+
+	run(h.app, []string{"sesh", "--service", "aws", "--profile", "", "--copy-serial"})
+
+	if exitCode != -1 {
+		t.Fatalf("Exit called with %d; stderr: %q", exitCode, h.stderr.String())
+	}
+	if copied != arn {
+		t.Errorf("clipboard = %q, want %q", copied, arn)
+	}
+	if !strings.Contains(h.stderr.String(), "MFA device ARN copied to clipboard") {
+		t.Errorf("expected clipboard confirmation, got %q", h.stderr.String())
+	}
+}
+
+func TestRun_ProfileDefaultIsNormalized(t *testing.T) {
+	h := newTestHarness()
+
+	exitCode := -1
+	h.app.Exit = func(code int) { exitCode = code }
+
+	gotProfile := "unset"
+	h.aws.GetCallerIdentityFunc = func(profile string) (awsInternal.CallerIdentity, error) {
+		gotProfile = profile
+		return awsInternal.CallerIdentity{Account: "123456789012", Arn: "arn:aws:iam::123456789012:user/alice"}, nil
+	}
+
+	run(h.app, []string{"sesh", "--service", "aws", "--profile", "default", "--whoami"})
+
+	if exitCode != -1 {
+		t.Fatalf("Exit called with %d; stderr: %q", exitCode, h.stderr.String())
+	}
+	if gotProfile != "" {
+		t.Errorf("profile passed to AWS = %q, want the omitted-profile value \"\"", gotProfile)
+	}
+}
+
+func TestRun_ClipMissingEntry(t *testing.T) {
+	tests := map[string]struct {
+		wantErrMsg string
+		args       []string
+	}{
+		"totp": {
+			args:       []string{"sesh", "--service", "totp", "--service-name", "nonexistent", "--clip"},
+			wantErrMsg: "no TOTP entry found for service 'nonexistent'. Run 'sesh --service totp --setup' first",
+		},
+		"aws": {
+			args:       []string{"sesh", "--service", "aws", "--profile", "ghost", "--clip"},
+			wantErrMsg: "no AWS entry found for profile 'ghost'. Run 'sesh --service aws --setup' first",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := newTestHarness()
+
+			exitCode := -1
+			h.app.Exit = func(code int) { exitCode = code }
+
+			copied := false
+			h.app.ClipboardCopy = func(string) error {
+				copied = true
+				return nil
+			}
+			h.keychain.GetSecretFunc = func(account, service string) ([]byte, error) {
+				return nil, keychain.ErrNotFound
+			}
+
+			run(h.app, tc.args)
+
+			if exitCode != 1 {
+				t.Errorf("Exit code = %d, want 1", exitCode)
+			}
+			if copied {
+				t.Error("clipboard should not be touched when the entry is missing")
+			}
+			stderr := h.stderr.String()
+			if !strings.Contains(stderr, tc.wantErrMsg) {
+				t.Errorf("stderr = %q, want to contain %q", stderr, tc.wantErrMsg)
+			}
+			// The pre-check runs before any code generation work starts.
+			if strings.Contains(stderr, "Generating credentials") {
+				t.Errorf("expected validation to fail before generation, got %q", stderr)
+			}
+		})
+	}
+}
+
+func TestRun_JSONErrors(t *testing.T) {
+	tests := map[string]struct {
+		setupMocks   func(*testHarness)
+		wantCode     string
+		wantContains string
+		args         []string
+		wantExitCode int
+	}{
+		"totp entry not set up": {
+			args: []string{"sesh", "--service", "totp", "--service-name", "github", "--json"},
+			setupMocks: func(h *testHarness) {
+				h.keychain.GetSecretFunc = func(account, service string) ([]byte, error) {
+					return nil, keychain.ErrNotFound
+				}
+			},
+			wantCode:     "not_setup",
+			wantContains: "no TOTP entry found for service 'github'",
+			wantExitCode: 3,
+		},
+		"aws entry not set up": {
+			args: []string{"sesh", "--json", "--service", "aws", "--no-subshell"},
+			setupMocks: func(h *testHarness) {
+				h.keychain.GetSecretFunc = func(account, service string) ([]byte, error) {
+					return nil, keychain.ErrNotFound
+				}
+			},
+			wantCode:     "not_setup",
+			wantContains: "no AWS entry found",
+			wantExitCode: 3,
+		},
+		"unknown provider": {
+			args:         []string{"sesh", "--service", "invalid", "--json"},
+			wantCode:     "unknown_provider",
+			wantContains: "invalid",
+			wantExitCode: 2,
+		},
+		"untyped error": {
+			args:         []string{"sesh", "--service", "totp", "--json"},
+			wantCode:     "error",
+			wantContains: "service-name",
+			wantExitCode: 1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := newTestHarness()
+
+			exitCode := -1
+			h.app.Exit = func(code int) { exitCode = code }
+
+			if tc.setupMocks != nil {
+				tc.setupMocks(h)
+			}
+
+			run(h.app, tc.args)
+
+			if exitCode != tc.wantExitCode {
+				t.Errorf("Exit code = %d, want %d", exitCode, tc.wantExitCode)
+			}
+			if h.stdout.Len() != 0 {
+				t.Errorf("expected no stdout under --json error, got %q", h.stdout.String())
+			}
+
+			var got jsonError
+			if err := json.Unmarshal(h.stderr.Bytes(), &got); err != nil {
+				t.Fatalf("stderr is not a JSON error object: %v (%q)", err, h.stderr.String())
+			}
+			if got.Code != tc.wantCode {
+				t.Errorf("code = %q, want %q", got.Code, tc.wantCode)
+			}
+			if !strings.Contains(got.Error, tc.wantContains) {
+				t.Errorf("error = %q, want to contain %q", got.Error, tc.wantContains)
+			}
+		})
+	}
+}
+
+func TestRun_NotSetupHumanModeUnchanged(t *testing.T) {
+	h := newTestHarness()
+	exitCode := -1
+	h.app.Exit = func(code int) { exitCode = code }
+	h.keychain.GetSecretFunc = func(account, service string) ([]byte, error) {
+		return nil, keychain.ErrNotFound
+	}
+
+	run(h.app, []string{"sesh", "--service", "totp", "--service-name", "github"})
+
+	if exitCode != 1 {
+		t.Errorf("Exit code = %d, want 1", exitCode)
+	}
+	if !strings.HasPrefix(h.stderr.String(), "❌ no TOTP entry found") {
+		t.Errorf("expected human error output, got %q", h.stderr.String())
+	}
+}
+
+func TestRun_Theme(t *testing.T) {
+	defer theme.Set(theme.Emoji)
+
+	tests := map[string]struct {
+		args       []string
+		redirected bool
+		wantPrefix string
+	}{
+		"emoji on a terminal": {
+			wantPrefix: "❌ no TOTP entry found",
+		},
+		"ascii when stderr is redirected": {
+			redirected: true,
+			wantPrefix: "[ERROR] no TOTP entry found",
+		},
+		"--theme overrides the redirect default": {
+			args:       []string{"--theme", "emoji"},
+			redirected: true,
+			wantPrefix: "❌ no TOTP entry found",
+		},
+		"--theme=nerd": {
+			args:       []string{"--theme=nerd"},
+			wantPrefix: "\uf00d no TOTP entry found",
+		},
+		"unknown theme": {
+			args:       []string{"--theme", "sparkles"},
+			wantPrefix: `❌ --theme: unknown theme "sparkles"`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			theme.Set(theme.Emoji)
+			h := newTestHarness()
+			h.app.RedirectedOutput = tc.redirected
+			h.keychain.GetSecretFunc = func(account, service string) ([]byte, error) {
+				return nil, keychain.ErrNotFound
+			}
+
+			run(h.app, append([]string{"sesh", "--service", "totp", "--service-name", "github"}, tc.args...))
+
+			if !strings.HasPrefix(h.stderr.String(), tc.wantPrefix) {
+				t.Errorf("stderr = %q, want prefix %q", h.stderr.String(), tc.wantPrefix)
+			}
+		})
+	}
+}
+
 // flockMockKC satisfies the two-method interface that database.KeychainSource
 // consumes. It is goroutine-safe and tracks call counts so tests can assert on
 // how many times ensureMasterKey crossed into the generate-and-store branch.
@@ -738,6 +1347,10 @@ func TestNeedsCredentialStore(t *testing.T) {
 		"--service aws --help":  {args: []string{"sesh", "--service", "aws", "--help"}, want: false},
 		"--service aws --list":  {args: []string{"sesh", "--service", "aws", "--list"}, want: true},
 		"--service aws --setup": {args: []string{"sesh", "--service", "aws", "--setup"}, want: true},
+		"--status":              {args: []string{"sesh", "--service", "aws", "--status"}, want: false},
+		"--status --all":        {args: []string{"sesh", "--status", "--all", "--json"}, want: true},
+		"--print-config":        {args: []string{"sesh", "--service", "aws", "--print-config"}, want: false},
+		"--dump-redacted":       {args: []string{"sesh", "--dump-redacted"}, want: true},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -815,3 +1428,70 @@ func TestResolvePasswordPrompt_NonTTYIsTerminalPromptButNotInteractive(t *testin
 		t.Fatal("prompt callback should be set even when not interactive")
 	}
 }
+
+func TestRun_ListJSON(t *testing.T) {
+	tests := map[string]struct {
+		args    []string
+		entries []keychain.KeychainEntry
+		want    []provider.ProviderEntry
+	}{
+		"aws entries carry type, profile and account": {
+			args: []string{"sesh", "--service", "aws", "--list", "--json"},
+			entries: []keychain.KeychainEntry{
+				{Service: "sesh-aws/work", Account: "alice"},
+				{Service: "sesh-aws-serial/work", Account: "alice"},
+			},
+			want: []provider.ProviderEntry{{
+				Name:        "AWS (work)",
+				Description: "AWS MFA for profile (work)",
+				ID:          "sesh-aws/work:alice",
+				Type:        "aws",
+				Profile:     "work",
+				Account:     "alice",
+			}},
+		},
+		"totp entries carry service name and profile": {
+			args: []string{"sesh", "--service", "totp", "--list", "--json"},
+			entries: []keychain.KeychainEntry{
+				{Service: "sesh-totp/github/personal", Account: "alice"},
+			},
+			want: []provider.ProviderEntry{{
+				Name:        "github (personal)",
+				Description: "TOTP for github profile personal",
+				ID:          "sesh-totp/github/personal:alice",
+				Type:        "totp",
+				Profile:     "personal",
+				ServiceName: "github",
+				Account:     "alice",
+			}},
+		},
+		"no entries is an empty array": {
+			args: []string{"sesh", "--service", "totp", "--list", "--json"},
+			want: []provider.ProviderEntry{},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := newTestHarness()
+			exitCode := -1
+			h.app.Exit = func(code int) { exitCode = code }
+			h.keychain.ListEntriesFunc = func(string) ([]keychain.KeychainEntry, error) {
+				return tc.entries, nil
+			}
+
+			run(h.app, tc.args)
+
+			if exitCode != -1 {
+				t.Fatalf("unexpected exit %d, stderr: %s", exitCode, h.stderr.String())
+			}
+			var got []provider.ProviderEntry
+			if err := json.Unmarshal(h.stdout.Bytes(), &got); err != nil {
+				t.Fatalf("stdout is not a JSON entry list: %v\n%s", err, h.stdout.String())
+			}
+			if got == nil || fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("entries = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
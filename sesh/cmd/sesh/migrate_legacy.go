@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bashhack/sesh/internal/keychain"
+	"github.com/bashhack/sesh/internal/migration"
+)
+
+// runMigrateLegacy implements --migrate-legacy: it moves AWS entries that
+// older versions stored under flat sesh-mfa keys to the keys the current
+// version reads, and prints each move. Running it again is harmless.
+func runMigrateLegacy(app *App, kc keychain.Provider, account string) error {
+	result, err := migration.MigrateLegacy(kc, account)
+	if err != nil {
+		return err
+	}
+
+	if len(result.Moved) == 0 && len(result.Skipped) == 0 && len(result.Errors) == 0 {
+		if _, err := fmt.Fprintln(app.Stderr, "No legacy sesh-mfa entries found. Nothing to migrate."); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	for _, m := range result.Moved {
+		if _, err := fmt.Fprintf(app.Stderr, "  migrated %s → %s\n", m.From, m.To); err != nil {
+			return err
+		}
+	}
+	for _, m := range result.Skipped {
+		if _, err := fmt.Fprintf(app.Stderr, "  skipped %s: %s already exists (remove one of them to resolve)\n", m.From, m.To); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(app.Stderr, "Migrated %d legacy entries", len(result.Moved)); err != nil {
+		return err
+	}
+	if len(result.Skipped) > 0 {
+		if _, err := fmt.Fprintf(app.Stderr, ", skipped %d", len(result.Skipped)); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintln(app.Stderr); err != nil {
+		return err
+	}
+
+	if len(result.Errors) > 0 {
+		for _, e := range result.Errors {
+			if _, err := fmt.Fprintf(app.Stderr, "  %s\n", e); err != nil {
+				return err
+			}
+		}
+		return fmt.Errorf("%d legacy entries could not be migrated", len(result.Errors))
+	}
+	return nil
+}
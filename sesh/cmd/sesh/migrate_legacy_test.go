@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bashhack/sesh/internal/keychain"
+	"github.com/bashhack/sesh/internal/keychain/mocks"
+)
+
+func TestRunMigrateLegacy(t *testing.T) {
+	tests := map[string]struct {
+		stored     map[string]string
+		wantStderr []string
+		wantStored map[string]string
+	}{
+		"legacy entries are moved": {
+			stored: map[string]string{
+				"sesh-mfa-dev":        "SECRET",
+				"sesh-mfa-serial-dev": "arn:aws:iam::123:mfa/user",
+			},
+			wantStderr: []string{
+				"migrated sesh-mfa-serial-dev → sesh-aws-serial/dev",
+				"migrated sesh-mfa-dev → sesh-aws/dev",
+				"Migrated 2 legacy entries",
+			},
+			wantStored: map[string]string{
+				"sesh-aws/dev":        "SECRET",
+				"sesh-aws-serial/dev": "arn:aws:iam::123:mfa/user",
+			},
+		},
+		"nothing to migrate": {
+			stored:     map[string]string{"sesh-aws/dev": "SECRET"},
+			wantStderr: []string{"No legacy sesh-mfa entries found"},
+			wantStored: map[string]string{"sesh-aws/dev": "SECRET"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			stored := make(map[string][]byte)
+			for k, v := range tc.stored {
+				stored[k] = []byte(v)
+			}
+			kc := &mocks.MockProvider{
+				ListFunc: func(filter keychain.EntryFilter) ([]keychain.KeychainEntryMeta, error) {
+					var entries []keychain.KeychainEntryMeta
+					for svc := range stored {
+						if strings.HasPrefix(svc, filter.ServicePrefix) {
+							entries = append(entries, keychain.KeychainEntryMeta{Service: svc, Account: "alice"})
+						}
+					}
+					return entries, nil
+				},
+				GetSecretFunc: func(_, service string) ([]byte, error) {
+					v, ok := stored[service]
+					if !ok {
+						return nil, keychain.ErrNotFound
+					}
+					return bytes.Clone(v), nil
+				},
+				SetSecretFunc: func(_, service string, secret []byte) error {
+					stored[service] = bytes.Clone(secret)
+					return nil
+				},
+				DeleteEntryFunc: func(_, service string) error {
+					delete(stored, service)
+					return nil
+				},
+			}
+			h := newTestHarness()
+
+			if err := runMigrateLegacy(h.app, kc, "alice"); err != nil {
+				t.Fatalf("runMigrateLegacy() error = %v", err)
+			}
+			for _, want := range tc.wantStderr {
+				if !strings.Contains(h.stderr.String(), want) {
+					t.Errorf("stderr = %q, want it to contain %q", h.stderr.String(), want)
+				}
+			}
+			if len(stored) != len(tc.wantStored) {
+				t.Errorf("stored = %v, want %v", stored, tc.wantStored)
+			}
+			for k, want := range tc.wantStored {
+				if got := string(stored[k]); got != want {
+					t.Errorf("%s = %q, want %q", k, got, want)
+				}
+			}
+		})
+	}
+}
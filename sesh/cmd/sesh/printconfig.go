@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bashhack/sesh/internal/provider"
+)
+
+// Where a --print-config value came from, in order of precedence.
+const (
+	sourceFlag    = "flag"
+	sourceConfig  = "config"
+	sourceEnv     = "env"
+	sourceDefault = "default"
+)
+
+// configValue is one line of --print-config output. Origin names the .sesh
+// file or environment variable behind a config or env value.
+type configValue struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Origin string `json:"origin,omitempty"`
+}
+
+// configCommandFlags are flags that pick an operation rather than a
+// setting, so --print-config leaves them out.
+var configCommandFlags = map[string]bool{
+	"service":       true,
+	"version":       true,
+	"help":          true,
+	"list-services": true,
+	"list":          true,
+	"status":        true,
+	"setup":         true,
+	"delete":        true,
+	"print-config":  true,
+}
+
+// resolveConfig reports every setting on the parsed flagset with its value
+// and source. args are the arguments fs parsed, dirApplied the keys the
+// .sesh file set, and envSources maps flags whose defaults came from the
+// environment to the variable that set them.
+func resolveConfig(fs *flag.FlagSet, args []string, dir DirDefaults, dirApplied map[string]bool, envSources map[string]string) []configValue {
+	given := commandLineFlags(fs, args)
+
+	service := configValue{Name: "service", Value: fs.Lookup("service").Value.String(), Source: sourceFlag}
+	if dir.ServiceApplied {
+		service.Source, service.Origin = sourceConfig, dir.Path
+	}
+	values := []configValue{service}
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if configCommandFlags[f.Name] {
+			return
+		}
+		v := configValue{Name: f.Name, Value: f.Value.String(), Source: sourceDefault}
+		switch {
+		case given[f.Name]:
+			v.Source = sourceFlag
+		case dirApplied[f.Name]:
+			v.Source, v.Origin = sourceConfig, dir.Path
+		case envSources[f.Name] != "":
+			v.Source, v.Origin = sourceEnv, envSources[f.Name]
+		}
+		values = append(values, v)
+	})
+
+	// The storage backend has no flag; only the environment picks it
+	for _, s := range []struct{ name, env, def string }{
+		{"backend", "SESH_BACKEND", "keychain"},
+		{"key-source", "SESH_KEY_SOURCE", "keychain"},
+	} {
+		v := configValue{Name: s.name, Value: s.def, Source: sourceDefault}
+		if env := os.Getenv(s.env); env != "" {
+			v.Value, v.Source, v.Origin = env, sourceEnv, s.env
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// envSourcesFor returns the variables behind svc's environment-set flag
+// defaults, or nil for a provider without any.
+func envSourcesFor(svc provider.ServiceProvider) map[string]string {
+	if s, ok := svc.(provider.EnvSourcer); ok {
+		return s.EnvSources()
+	}
+	return nil
+}
+
+// seenValue stands in for a flag's value when re-parsing the command line
+// to learn which flags it set, leaving the real values alone.
+type seenValue struct{ isBool bool }
+
+func (v seenValue) String() string   { return "" }
+func (v seenValue) Set(string) error { return nil }
+func (v seenValue) IsBoolFlag() bool { return v.isBool }
+
+// commandLineFlags returns the names of the flags args set. fs.Visit can't
+// answer this, since .sesh defaults are applied with fs.Set and count as
+// set too.
+func commandLineFlags(fs *flag.FlagSet, args []string) map[string]bool {
+	shadow := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+	shadow.SetOutput(io.Discard)
+	fs.VisitAll(func(f *flag.Flag) {
+		b, ok := f.Value.(interface{ IsBoolFlag() bool })
+		shadow.Var(seenValue{isBool: ok && b.IsBoolFlag()}, f.Name, f.Usage)
+	})
+	// fs already parsed args successfully, so this can't fail
+	_ = shadow.Parse(args)
+
+	given := make(map[string]bool)
+	shadow.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	return given
+}
+
+// PrintConfig writes the resolved settings, one per line, or as a JSON
+// array with --json.
+func (a *App) PrintConfig(values []configValue) error {
+	if a.JSONOutput {
+		if err := json.NewEncoder(a.Stdout).Encode(values); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		return nil
+	}
+
+	nameWidth, valueWidth := 0, 0
+	for _, v := range values {
+		nameWidth = max(nameWidth, len(v.Name))
+		valueWidth = max(valueWidth, len(v.Value))
+	}
+	for _, v := range values {
+		source := v.Source
+		if v.Origin != "" {
+			source += " (" + v.Origin + ")"
+		}
+		if _, err := fmt.Fprintf(a.Stdout, "%-*s  %-*s  %s\n", nameWidth, v.Name, valueWidth, v.Value, source); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+	return nil
+}
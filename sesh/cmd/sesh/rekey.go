@@ -14,6 +14,7 @@ import (
 	"github.com/bashhack/sesh/internal/database"
 	"github.com/bashhack/sesh/internal/keychain"
 	"github.com/bashhack/sesh/internal/migration"
+	"github.com/bashhack/sesh/internal/provider"
 	"github.com/bashhack/sesh/internal/secure"
 )
 
@@ -45,7 +46,10 @@ func runRekey(app *App, args []string, kc keychain.Provider) (err error) {
 	fs.SetOutput(app.Stderr)
 	target := fs.String("to", "", "Target key source: keychain or password")
 	if err := fs.Parse(args); err != nil {
-		return err
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return provider.UsageError("rekey: %v", err)
 	}
 	if *target != "keychain" && *target != "password" {
 		return fmt.Errorf("--to must be 'keychain' or 'password', got %q", *target)
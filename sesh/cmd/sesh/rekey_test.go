@@ -112,7 +112,10 @@ func (m *kcMock) GetSecretString(_, _ string) (string, error)            { retur
 func (m *kcMock) SetSecretString(_, _, _ string) error                   { return nil }
 func (m *kcMock) GetMFASerialBytes(_, _ string) ([]byte, error)          { return nil, keychain.ErrNotFound }
 func (m *kcMock) ListEntries(_ string) ([]keychain.KeychainEntry, error) { return nil, nil }
-func (m *kcMock) SetDescription(_, _, _ string) error                    { return nil }
+func (m *kcMock) List(_ keychain.EntryFilter) ([]keychain.KeychainEntryMeta, error) {
+	return nil, nil
+}
+func (m *kcMock) SetDescription(_, _, _ string) error { return nil }
 func (m *kcMock) DeleteEntry(account, service string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
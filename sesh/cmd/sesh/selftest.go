@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bashhack/sesh/internal/theme"
+	"github.com/bashhack/sesh/internal/totp"
+)
+
+// runSelftest implements --selftest: it runs the RFC 6238 vectors through
+// the binary's own TOTP code and prints a line per check. It fails if any
+// check does.
+func runSelftest(app *App) error {
+	failed := 0
+	results := totp.SelfTest()
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+			fmt.Fprintf(app.Stdout, theme.Error()+" %s: %v\n", r.Name, r.Err)
+		case !r.Passed():
+			failed++
+			fmt.Fprintf(app.Stdout, theme.Error()+" %s: got %s, want %s\n", r.Name, r.Got, r.Want)
+		default:
+			fmt.Fprintf(app.Stdout, theme.OK()+" %s: %s\n", r.Name, r.Got)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("self-test failed: %d of %d checks", failed, len(results))
+	}
+	fmt.Fprintf(app.Stdout, "All %d checks passed\n", len(results))
+	return nil
+}
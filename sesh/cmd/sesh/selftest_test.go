@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunSelftest(t *testing.T) {
+	h := newTestHarness()
+	exitCode := 0
+	h.app.Exit = func(code int) { exitCode = code }
+
+	run(h.app, []string{"sesh", "--selftest"})
+
+	if exitCode != 0 {
+		t.Fatalf("exit = %d, stderr = %q", exitCode, h.stderr.String())
+	}
+	out := h.stdout.String()
+	if strings.Contains(out, "❌") {
+		t.Errorf("unexpected failure in output:\n%s", out)
+	}
+	for _, want := range []string{"✅ SHA1/59 string: 94287082", "✅ SHA512/20000000000 bytes: 47863826", "✅ SHA1/59 default bytes: 287082", "All 48 checks passed"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}